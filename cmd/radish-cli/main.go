@@ -25,7 +25,7 @@ func main() {
 		log.Fatal("Interactive mode is not implemented yet")
 	}
 
-	address := fmt.Sprintf("%s:%d", *hostname, *port)
+	address := net.JoinHostPort(*hostname, strconv.Itoa(*port))
 	conn, err := net.Dial("tcp", address)
 	if err != nil {
 		log.Fatalf("Could not connect to Radish: %s", err)
@@ -34,10 +34,11 @@ func main() {
 
 	writer := radish.NewWriter(conn)
 
-	_ = writer.WriteArray(len(args))
-	for _, arg := range args {
-		_ = writer.WriteString(arg)
+	cmd := make([][]byte, len(args))
+	for i, arg := range args {
+		cmd[i] = []byte(arg)
 	}
+	_ = writer.WriteCommandArgs(cmd)
 	if err := writer.Flush(); err != nil {
 		log.Fatalf("Could not write a command: %s", err)
 	}
@@ -47,48 +48,100 @@ func main() {
 }
 
 func readReaponse(reader *radish.Reader, indent string) {
-	dt, v, err := reader.ReadAny()
+	v, err := reader.ReadValue()
 	if err != nil {
 		log.Fatalf("Could not read the response: %s", err)
 	}
 
-	switch dt {
-	case radish.DataTypeSimpleString:
-		fmt.Printf("%s\n", v.(string))
+	fmt.Print(formatResponse(&v, indent))
+}
+
+// formatResponse formats a response like the redis-cli does. Attributes are
+// auxiliary data of the response, so they are skipped.
+func formatResponse(v *radish.Value, indent string) string {
+	if v.IsNull() {
+		return "(nil)\n"
+	}
 
-	case radish.DataTypeError:
-		e := v.(*radish.Error)
-		fmt.Printf("(error) %s %s\n", e.Kind, e.Msg)
+	switch v.Type {
+	case radish.DataTypeSimpleString, radish.DataTypeVerbatimString:
+		return v.Str + "\n"
+
+	case radish.DataTypeError, radish.DataTypeBulkError:
+		return fmt.Sprintf("(error) %s %s\n", v.Err.Kind, v.Err.Msg)
 
 	case radish.DataTypeInteger:
-		fmt.Printf("(integer) %d\n", v.(int))
+		return fmt.Sprintf("(integer) %d\n", v.Int)
 
 	case radish.DataTypeBulkString:
-		fmt.Printf("%q\n", v.(string))
+		return fmt.Sprintf("%q\n", v.Str)
 
-	case radish.DataTypeArray:
-		length := v.(int)
-		if length == 0 {
-			fmt.Print("(empty array)\n")
-		} else {
-			prefixWidth := int(math.Log10(float64(length))) + 1
-			prefixFormat := "%" + strconv.Itoa(prefixWidth) + "d) " // "%2d"-like.
-			nextIndent := indent + strings.Repeat(" ", prefixWidth+len(") "))
-
-			for i := 0; i < length; i++ {
-				if i != 0 {
-					fmt.Print(indent)
-				}
-				fmt.Printf(prefixFormat, i+1)
-
-				readReaponse(reader, nextIndent)
-			}
-		}
+	case radish.DataTypeBoolean:
+		return fmt.Sprintf("(%t)\n", v.Bool)
 
-	case radish.DataTypeNull:
-		fmt.Print("(nil)\n")
+	case radish.DataTypeDouble:
+		return fmt.Sprintf("(double) %s\n", strconv.FormatFloat(v.Double, 'g', -1, 64))
+
+	case radish.DataTypeBigNumber:
+		return fmt.Sprintf("(big number) %s\n", v.BigNumber)
+
+	case radish.DataTypeArray, radish.DataTypeSet, radish.DataTypePush, radish.DataTypeMap:
+		return formatAggregate(v, indent)
 
 	default:
-		log.Fatalf("Unknown data type: %q", dt)
+		log.Fatalf("Unknown data type: %q", v.Type)
+		return ""
+	}
+}
+
+func formatAggregate(v *radish.Value, indent string) string {
+	pairs := v.Type == radish.DataTypeMap
+
+	length := len(v.Elems)
+	if pairs {
+		length /= 2
+	}
+
+	if length == 0 {
+		switch v.Type {
+		case radish.DataTypeMap:
+			return "(empty hash)\n"
+		case radish.DataTypeSet:
+			return "(empty set)\n"
+		default:
+			return "(empty array)\n"
+		}
+	}
+
+	// Like the redis-cli, sets are marked with "~", maps with "#" and other
+	// aggregates with ")".
+	mark := ")"
+	switch v.Type {
+	case radish.DataTypeSet:
+		mark = "~"
+	case radish.DataTypeMap:
+		mark = "#"
+	}
+
+	prefixWidth := int(math.Log10(float64(length))) + 1
+	prefixFormat := "%" + strconv.Itoa(prefixWidth) + "d" + mark + " " // "%2d)"-like.
+	nextIndent := indent + strings.Repeat(" ", prefixWidth+len(mark+" "))
+
+	var sb strings.Builder
+	for i := 0; i < length; i++ {
+		if i != 0 {
+			sb.WriteString(indent)
+		}
+		fmt.Fprintf(&sb, prefixFormat, i+1)
+
+		if pairs {
+			key := strings.TrimSuffix(formatResponse(&v.Elems[2*i], nextIndent), "\n")
+			sb.WriteString(key)
+			sb.WriteString(" => ")
+			sb.WriteString(formatResponse(&v.Elems[2*i+1], nextIndent+strings.Repeat(" ", len(key)+len(" => "))))
+		} else {
+			sb.WriteString(formatResponse(&v.Elems[i], nextIndent))
+		}
 	}
+	return sb.String()
 }
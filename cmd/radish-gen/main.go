@@ -0,0 +1,382 @@
+// Command radish-gen generates Go code from the JSON command spec: typed
+// command methods of the radish client.
+//
+// Usage:
+//
+//	radish-gen -spec commands.json -o output.go
+//
+// It is meant to be run by go generate.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"go/format"
+	"io/ioutil"
+	"log"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+var (
+	specPath = flag.String("spec", "commands.json", "path to the command spec")
+	output   = flag.String("o", "", "output file")
+)
+
+// Spec is the command spec.
+type Spec struct {
+	Options  []Options `json:"options"`
+	Commands []Command `json:"commands"`
+}
+
+// Options describes an options struct of typed methods.
+type Options struct {
+	Name   string   `json:"name"`
+	Doc    string   `json:"doc"`
+	Fields []Option `json:"fields"`
+}
+
+// Option is a field of an options struct.
+type Option struct {
+	Name  string `json:"name"`
+	Token string `json:"token"`
+	Type  string `json:"type"` // "flag", "int", "float" or "string".
+	Doc   string `json:"doc"`
+}
+
+// Command describes a command, like in the Redis command table.
+type Command struct {
+	Name    string   `json:"name"`
+	Arity   int      `json:"arity"` // Checked against the server by its tests.
+	Group   string   `json:"group"`
+	Summary string   `json:"summary"`
+	Methods []Method `json:"methods"`
+}
+
+// Method describes a typed method of the client for a command.
+type Method struct {
+	Name string `json:"name"`
+
+	// Doc is the description of the method, after its name. If empty, the
+	// summary of the command is used.
+	Doc string `json:"doc"`
+
+	// Args are the arguments of the command after its name, in order. They
+	// are the parameters of the method, except options, which are the last.
+	Args []Arg `json:"args"`
+
+	// Tokens are appended to the arguments of the command, e.g. WITHSCORES.
+	Tokens []string `json:"tokens"`
+
+	// Reply is the kind of the reply, see the replyKinds.
+	Reply string `json:"reply"`
+}
+
+// Arg is an argument of a method.
+type Arg struct {
+	Name     string `json:"name"`
+	Type     string `json:"type"` // "string", "int", "float", "value" or a name of options.
+	Variadic bool   `json:"variadic"`
+}
+
+// argTypes maps types of arguments to Go types.
+var argTypes = map[string]string{
+	"string": "string",
+	"int":    "int64",
+	"float":  "float64",
+	"value":  "interface{}",
+}
+
+// optionTypes maps types of options fields to Go types.
+var optionTypes = map[string]string{
+	"flag":   "bool",
+	"int":    "int64",
+	"float":  "float64",
+	"string": "string",
+}
+
+// replyKind is a Go type of a reply and the client function that reads it.
+type replyKind struct {
+	Type string
+	Read string
+}
+
+var replyKinds = map[string]replyKind{
+	"status":    {"string", "readString"},
+	"string":    {"string", "readString"},
+	"int":       {"int64", "readInt"},
+	"float":     {"float64", "readFloat"},
+	"bool":      {"bool", "readBool"},
+	"strings":   {"[]string", "readStrings"},
+	"stringMap": {"map[string]string", "readStringMap"},
+	"zslice":    {"[]Z", "readZSlice"},
+}
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("radish-gen: ")
+	flag.Parse()
+
+	if *output == "" {
+		log.Fatal("The -o flag is required")
+	}
+
+	data, err := ioutil.ReadFile(*specPath)
+	if err != nil {
+		log.Fatalf("Could not read the spec: %s", err)
+	}
+
+	var spec Spec
+	if err := json.Unmarshal(data, &spec); err != nil {
+		log.Fatalf("Could not parse the spec: %s", err)
+	}
+
+	src, err := generateClient(&spec, filepath.Base(*specPath))
+	if err != nil {
+		log.Fatalf("Could not generate the code: %s", err)
+	}
+
+	if err := ioutil.WriteFile(*output, src, 0644); err != nil {
+		log.Fatalf("Could not write the code: %s", err)
+	}
+}
+
+// clientMethod is a Method prepared for the template.
+type clientMethod struct {
+	Method
+	Command  string // The upper-case name of the command.
+	Doc      []string
+	Params   string // Parameters of the method after the context.
+	Reply    replyKind
+	Options  string // The name of the options parameter, if any.
+	Variadic bool   // Arguments of the command need to be built.
+}
+
+func generateClient(spec *Spec, specName string) ([]byte, error) {
+	options := make(map[string]bool)
+	for _, opts := range spec.Options {
+		for _, field := range opts.Fields {
+			if _, ok := optionTypes[field.Type]; !ok {
+				return nil, fmt.Errorf("unknown type of the %s.%s: %q", opts.Name, field.Name, field.Type)
+			}
+		}
+		options[opts.Name] = true
+	}
+
+	var methods []clientMethod
+	for _, cmd := range spec.Commands {
+		for _, m := range cmd.Methods {
+			cm, err := newClientMethod(&cmd, m, options)
+			if err != nil {
+				return nil, err
+			}
+			methods = append(methods, cm)
+		}
+	}
+	sort.Slice(methods, func(i, j int) bool {
+		return methods[i].Name < methods[j].Name
+	})
+
+	return execute(clientTemplate, map[string]interface{}{
+		"Spec":    specName,
+		"Options": spec.Options,
+		"Methods": methods,
+	})
+}
+
+func newClientMethod(cmd *Command, m Method, options map[string]bool) (clientMethod, error) {
+	cm := clientMethod{
+		Method:  m,
+		Command: strings.ToUpper(cmd.Name),
+	}
+
+	reply, ok := replyKinds[m.Reply]
+	if !ok {
+		return cm, fmt.Errorf("unknown reply of the %s: %q", m.Name, m.Reply)
+	}
+	cm.Reply = reply
+
+	doc := m.Doc
+	if doc == "" {
+		doc = lowerFirst(cmd.Summary)
+	}
+	cm.Doc = wrap(m.Name+" "+doc, 77)
+
+	var (
+		params []string
+		usage  = []string{cm.Command}
+		last   string
+	)
+	for i, arg := range m.Args {
+		if options[arg.Type] {
+			cm.Options = arg.Name
+			cm.Variadic = true
+			last = arg.Name + " *" + arg.Type
+			usage = append(usage, "[options]")
+			continue
+		}
+
+		typ, ok := argTypes[arg.Type]
+		if !ok {
+			return cm, fmt.Errorf("unknown type of the %s argument %s: %q", m.Name, arg.Name, arg.Type)
+		}
+		if arg.Variadic {
+			if i != len(m.Args)-1 {
+				return cm, fmt.Errorf("variadic argument %s of the %s is not the last", arg.Name, m.Name)
+			}
+			cm.Variadic = true
+			typ = "..." + typ
+			usage = append(usage, singular(arg.Name), "["+singular(arg.Name), "...]")
+		} else {
+			usage = append(usage, arg.Name)
+		}
+		params = append(params, arg.Name+" "+typ)
+	}
+	if last != "" {
+		params = append(params, last)
+	}
+	usage = append(usage, m.Tokens...)
+	cm.Params = strings.Join(params, ", ")
+
+	cm.Doc = append(cm.Doc, "", "\t"+strings.Join(usage, " "))
+
+	return cm, nil
+}
+
+func execute(tmpl *template.Template, data interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return nil, err
+	}
+
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("%s\n%s", err, buf.Bytes())
+	}
+	return src, nil
+}
+
+func lowerFirst(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToLower(s[:1]) + s[1:]
+}
+
+// singular returns a singular form of names of variadic arguments, e.g.
+// "key" for "keys".
+func singular(s string) string {
+	return strings.TrimSuffix(s, "s")
+}
+
+// wrap splits the text into lines of at most width characters, if words
+// allow.
+func wrap(text string, width int) []string {
+	var (
+		lines []string
+		line  string
+	)
+	for _, word := range strings.Fields(text) {
+		if line != "" && len(line)+1+len(word) > width {
+			lines = append(lines, line)
+			line = ""
+		}
+		if line != "" {
+			line += " "
+		}
+		line += word
+	}
+	if line != "" {
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+var funcs = template.FuncMap{
+	"optionType": func(typ string) string { return optionTypes[typ] },
+	"wrap":       wrap,
+}
+
+var clientTemplate = template.Must(template.New("client").Funcs(funcs).Parse(`// Code generated by radish-gen from {{.Spec}}. DO NOT EDIT.
+
+package client
+
+import (
+	"context"
+
+	"github.com/SuperPaintman/mini-redis/radish"
+)
+{{range .Options}}
+{{range wrap .Doc 77}}// {{.}}
+{{end -}}
+type {{.Name}} struct {
+{{- range $i, $f := .Fields}}
+{{- if $i}}
+{{end}}
+	{{range wrap .Doc 73}}// {{.}}
+	{{end -}}
+	{{.Name}} {{optionType .Type}}
+{{- end}}
+}
+
+func (o *{{.Name}}) appendArgs(args []interface{}) []interface{} {
+	if o == nil {
+		return args
+	}
+{{- range .Fields}}
+{{- if eq .Type "flag"}}
+	if o.{{.Name}} {
+		args = append(args, {{printf "%q" .Token}})
+	}
+{{- else if eq .Type "string"}}
+	if o.{{.Name}} != "" {
+		args = append(args, {{printf "%q" .Token}}, o.{{.Name}})
+	}
+{{- else}}
+	if o.{{.Name}} != 0 {
+		args = append(args, {{printf "%q" .Token}}, o.{{.Name}})
+	}
+{{- end}}
+{{- end}}
+	return args
+}
+{{end}}
+{{- range .Methods}}
+{{range .Doc}}//{{if .}} {{.}}{{end}}
+{{end -}}
+func (c *Client) {{.Name}}(ctx context.Context{{if .Params}}, {{.Params}}{{end}}) ({{.Reply.Type}}, error) {
+{{- if .Variadic}}
+	args := make([]interface{}, 0, {{len .Args}}{{range .Args}}{{if .Variadic}}+len({{.Name}}){{end}}{{end}})
+{{- $opts := .Options}}
+{{- range .Args}}
+{{- if eq .Name $opts}}
+	args = {{.Name}}.appendArgs(args)
+{{- else if .Variadic}}
+	for _, arg := range {{.Name}} {
+		args = append(args, arg)
+	}
+{{- else}}
+	args = append(args, {{.Name}})
+{{- end}}
+{{- end}}
+{{- range .Tokens}}
+	args = append(args, {{printf "%q" .}})
+{{- end}}
+{{end}}
+	var res {{.Reply.Type}}
+	err := c.call(ctx, func(w *radish.Writer) error {
+		return w.WriteCommand({{printf "%q" .Command}}
+		{{- if .Variadic}}, args...{{else}}{{range .Args}}, {{.Name}}{{end}}{{range .Tokens}}, {{printf "%q" .}}{{end}}{{end -}}
+		)
+	}, func(r *radish.Reader) (err error) {
+		res, err = {{.Reply.Read}}(r)
+		return err
+	})
+	return res, err
+}
+{{end -}}
+`))
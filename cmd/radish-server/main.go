@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"net"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+
+	"github.com/SuperPaintman/mini-redis/radish/server"
+)
+
+var (
+	hostname = flag.String("h", "127.0.0.1", "server hostname")
+	port     = flag.Int("p", 6379, "server port")
+)
+
+func main() {
+	flag.Parse()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	ks := server.NewKeyspace()
+	go ks.ActiveExpire(ctx)
+
+	mux := server.NewMux()
+	ks.Register(mux)
+
+	srv := &server.Server{
+		Addr:    net.JoinHostPort(*hostname, strconv.Itoa(*port)),
+		Handler: mux,
+	}
+
+	log.Printf("Ready to accept connections on %s", srv.Addr)
+
+	if err := srv.ListenAndServe(ctx); err != nil {
+		log.Fatalf("Could not serve: %s", err)
+	}
+
+	log.Print("Radish is now ready to exit, bye bye...")
+}
@@ -0,0 +1,159 @@
+package radish
+
+import (
+	"bufio"
+	"io"
+)
+
+// BulkReader reads the body of a RESP bulk string directly from the
+// underlying reader, without holding it in memory. It implements io.Reader
+// and io.WriterTo.
+//
+// The <CRLF> terminator of the bulk string is validated when the body is
+// read to the end: if it is missing, the ErrBulkLength is returned instead of
+// the io.EOF.
+type BulkReader struct {
+	r      *bufio.Reader
+	remain int // Remaining bytes of the body.
+	err    error
+}
+
+// ReadBulkReader reads the header of a RESP bulk string from the underlying
+// reader and returns a BulkReader over its body, so large values (e.g.
+// RESTORE payloads) can be streamed to disk or sockets.
+//
+// The body must be read to the end or the BulkReader closed before the next
+// read from the Reader. The BulkReader is reused by the next call of the
+// ReadBulkReader.
+func (r *Reader) ReadBulkReader() (br *BulkReader, null bool, err error) {
+	cmd := newCommand()
+	defer commandPool.Put(cmd)
+
+	n, err := r.readValue(DataTypeBulkString, cmd)
+	if err != nil {
+		if err == errValue {
+			err = ErrBulkLength
+		}
+		return nil, false, err
+	}
+	if n < 0 {
+		return nil, true, nil
+	}
+	if n > r.opts.MaxBulkLength {
+		return nil, false, ErrBulkLength
+	}
+
+	r.bulk = BulkReader{
+		r:      r.r,
+		remain: n,
+	}
+	return &r.bulk, false, nil
+}
+
+// Len returns the number of unread bytes of the body.
+func (br *BulkReader) Len() int {
+	return br.remain
+}
+
+// Read reads up to len(p) bytes of the body into p.
+func (br *BulkReader) Read(p []byte) (n int, err error) {
+	if br.err != nil {
+		return 0, br.err
+	}
+
+	if br.remain > 0 {
+		if len(p) > br.remain {
+			p = p[:br.remain]
+		}
+
+		n, err = br.r.Read(p)
+		br.remain -= n
+		if err != nil {
+			br.err = unexpectedEOF(err)
+			return n, br.err
+		}
+	}
+
+	if br.remain == 0 {
+		br.err = br.readTerminator()
+	}
+	return n, br.err
+}
+
+// WriteTo writes the rest of the body to the w. It writes directly from the
+// buffer of the Reader without extra copying.
+func (br *BulkReader) WriteTo(w io.Writer) (n int64, err error) {
+	for br.err == nil && br.remain > 0 {
+		// Fill the buffer if it is empty.
+		if br.r.Buffered() == 0 {
+			if _, err := br.r.Peek(1); err != nil {
+				br.err = unexpectedEOF(err)
+				break
+			}
+		}
+
+		size := br.r.Buffered()
+		if size > br.remain {
+			size = br.remain
+		}
+		chunk, _ := br.r.Peek(size)
+
+		m, err := w.Write(chunk)
+		_, _ = br.r.Discard(m)
+		n += int64(m)
+		br.remain -= m
+		if err != nil {
+			return n, err
+		}
+	}
+
+	if br.err == nil {
+		br.err = br.readTerminator()
+	}
+	if br.err == io.EOF {
+		return n, nil
+	}
+	return n, br.err
+}
+
+// Close discards the unread rest of the body, so the next value can be read
+// from the Reader. It returns an error if the body is broken.
+func (br *BulkReader) Close() error {
+	if br.err == nil && br.remain > 0 {
+		n, err := br.r.Discard(br.remain)
+		br.remain -= n
+		if err != nil {
+			br.err = unexpectedEOF(err)
+		}
+	}
+
+	if br.err == nil {
+		br.err = br.readTerminator()
+	}
+	if br.err == io.EOF {
+		return nil
+	}
+	return br.err
+}
+
+// readTerminator reads the <CRLF> after the body and returns the io.EOF if it
+// is valid.
+func (br *BulkReader) readTerminator() error {
+	crlf, err := br.r.Peek(2)
+	if err != nil {
+		return unexpectedEOF(err)
+	}
+	if crlf[0] != '\r' || crlf[1] != '\n' {
+		return ErrBulkLength
+	}
+
+	_, _ = br.r.Discard(2)
+	return io.EOF
+}
+
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
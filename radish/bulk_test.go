@@ -0,0 +1,154 @@
+package radish
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+var testBulkReaders = []struct {
+	name    string
+	input   string
+	want    string
+	wantErr error
+}{
+	{
+		name:  "empty",
+		input: "$0\r\n\r\n",
+		want:  "",
+	},
+	{
+		name:  "string",
+		input: "$5\r\nhello\r\n",
+		want:  "hello",
+	},
+	{
+		name:  "with newlines",
+		input: "$8\r\nhe\r\nl\nlo\r\n",
+		want:  "he\r\nl\nlo",
+	},
+	{
+		name:  "long string",
+		input: "$" + strconv.Itoa(len(longString)) + "\r\n" + longString + "\r\n",
+		want:  longString,
+	},
+	{
+		name:    "invalid terminator",
+		input:   "$5\r\nhello!!",
+		want:    "hello",
+		wantErr: ErrBulkLength,
+	},
+	{
+		name:    "missing terminator",
+		input:   "$5\r\nhello",
+		want:    "hello",
+		wantErr: io.ErrUnexpectedEOF,
+	},
+	{
+		name:    "truncated body",
+		input:   "$5\r\nhel",
+		want:    "hel",
+		wantErr: io.ErrUnexpectedEOF,
+	},
+}
+
+func TestReader_ReadBulkReader(t *testing.T) {
+	reads := []struct {
+		name string
+		read func(br *BulkReader) ([]byte, error)
+	}{
+		{"ReadAll", func(br *BulkReader) ([]byte, error) {
+			return ioutil.ReadAll(br)
+		}},
+		{"OneByteReader", func(br *BulkReader) ([]byte, error) {
+			return ioutil.ReadAll(iotest.OneByteReader(br))
+		}},
+		{"WriteTo", func(br *BulkReader) ([]byte, error) {
+			var buf bytes.Buffer
+			_, err := br.WriteTo(&buf)
+			return buf.Bytes(), err
+		}},
+	}
+
+	for _, tc := range testBulkReaders {
+		for _, rc := range reads {
+			t.Run(tc.name+"/"+rc.name, func(t *testing.T) {
+				input := tc.input
+				if tc.wantErr == nil {
+					input += ":1\r\n"
+				}
+				reader := NewReader(strings.NewReader(input))
+
+				br, null, err := reader.ReadBulkReader()
+				if err != nil {
+					t.Fatalf("ReadBulkReader(): unexpected error: %v", err)
+				}
+				if null {
+					t.Fatalf("ReadBulkReader() returned null")
+				}
+
+				got, err := rc.read(br)
+				if err != tc.wantErr {
+					t.Errorf("%s() error = %v, want %v", rc.name, err, tc.wantErr)
+				}
+				if string(got) != tc.want {
+					t.Errorf("%s() = %q, want %q", rc.name, got, tc.want)
+				}
+				if tc.wantErr != nil {
+					return
+				}
+
+				// The next value must be readable.
+				if i, err := reader.ReadInteger(); err != nil || i != 1 {
+					t.Errorf("ReadInteger() after the bulk = %d, %v, want 1, nil", i, err)
+				}
+			})
+		}
+	}
+}
+
+func TestReader_ReadBulkReader_null(t *testing.T) {
+	reader := NewReader(strings.NewReader("$-1\r\n"))
+
+	br, null, err := reader.ReadBulkReader()
+	if err != nil || !null || br != nil {
+		t.Errorf("ReadBulkReader() = %v, %v, %v, want nil, true, nil", br, null, err)
+	}
+}
+
+func TestReader_ReadBulkReader_limit(t *testing.T) {
+	reader := NewReaderOptions(strings.NewReader("$5\r\nhello\r\n"), ReaderOptions{MaxBulkLength: 4})
+
+	if _, _, err := reader.ReadBulkReader(); err != ErrBulkLength {
+		t.Errorf("ReadBulkReader() error = %v, want %v", err, ErrBulkLength)
+	}
+}
+
+func TestBulkReader_Close(t *testing.T) {
+	reader := NewReader(strings.NewReader("$11\r\nhello world\r\n+OK\r\n"))
+
+	br, _, err := reader.ReadBulkReader()
+	if err != nil {
+		t.Fatalf("ReadBulkReader(): unexpected error: %v", err)
+	}
+
+	buf := make([]byte, 5)
+	if _, err := io.ReadFull(br, buf); err != nil || string(buf) != "hello" {
+		t.Fatalf("ReadFull() = %q, %v, want %q, nil", buf, err, "hello")
+	}
+	if br.Len() != 6 {
+		t.Errorf("Len() = %d, want 6", br.Len())
+	}
+
+	if err := br.Close(); err != nil {
+		t.Fatalf("Close(): unexpected error: %v", err)
+	}
+
+	if s, err := reader.ReadSimpleString(); err != nil || s != "OK" {
+		t.Errorf("ReadSimpleString() after Close() = %q, %v, want %q, nil", s, err, "OK")
+	}
+}
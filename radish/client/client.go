@@ -0,0 +1,478 @@
+// Package client implements a client for Redis-compatible servers on top of
+// the radish RESP reader and writer, with a pool of connections.
+package client
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/SuperPaintman/mini-redis/radish"
+)
+
+// ErrClosed is returned by methods of a closed Client.
+var ErrClosed = errors.New("radish: client is closed")
+
+var errNoArgs = errors.New("radish: command without arguments")
+
+const (
+	DefaultAddr         = "127.0.0.1:6379"
+	DefaultDialTimeout  = 5 * time.Second
+	DefaultReapInterval = time.Minute
+
+	DefaultPipelineBatchSize = 1000
+)
+
+// Options configures a Client. Zero values mean the defaults.
+type Options struct {
+	// Addr is the TCP address of the server in the form "host:port". If
+	// empty, the DefaultAddr is used.
+	Addr string
+
+	// Dial optionally specifies a function to create connections. If nil,
+	// the net.Dialer is used.
+	Dial func(ctx context.Context, network, addr string) (net.Conn, error)
+
+	// DialTimeout limits the time of dialing a connection. If zero, the
+	// DefaultDialTimeout is used.
+	DialTimeout time.Duration
+
+	// ReadTimeout and WriteTimeout limit the time of reading a reply and
+	// writing a command. Zero means no timeout, besides the deadline of the
+	// context.
+	ReadTimeout  time.Duration
+	WriteTimeout time.Duration
+
+	// MinIdleConns is the number of idle connections the Client keeps open,
+	// so commands do not wait for dialing.
+	MinIdleConns int
+
+	// MaxActiveConns limits the number of open connections. Commands wait
+	// for a free connection once the limit is reached. Zero means no limit.
+	MaxActiveConns int
+
+	// IdleTimeout closes connections that have been idle for longer. Zero
+	// means idle connections are never closed.
+	IdleTimeout time.Duration
+
+	// MaxConnLifetime closes connections that have been open for longer,
+	// after their current command. Zero means no limit.
+	MaxConnLifetime time.Duration
+
+	// HealthCheckInterval makes the Client check connections that have been
+	// idle for longer with a PING before using them, and replace the broken
+	// ones. Zero disables health checks.
+	HealthCheckInterval time.Duration
+
+	// ReapInterval is how often the Client closes expired idle connections
+	// and dials new ones up to the MinIdleConns. If zero, the
+	// DefaultReapInterval is used.
+	ReapInterval time.Duration
+
+	// PipelineBatchSize limits the number of commands of a Pipeline sent at
+	// once. Larger pipelines are sent in batches, each after the replies of
+	// the previous one are read, so neither the client nor the server buffers
+	// an unbounded number of commands and replies. If zero, the
+	// DefaultPipelineBatchSize is used.
+	PipelineBatchSize int
+
+	// Multiplex makes the Do send commands of all goroutines over a single
+	// shared connection, instead of taking a connection from the pool for
+	// each command. Commands queued at the same time are sent at once, like
+	// in a Pipeline, so concurrent callers get the throughput of pipelining.
+	//
+	// Blocking commands, e.g. BLPOP, hold up all commands behind them, so
+	// they must not be sent with the Do in this mode. Pipelines still use
+	// the pool. The shared connection is not limited by the pool options and
+	// is redialed after a failure.
+	Multiplex bool
+
+	// ReaderOptions specifies limits of replies read from the server, see
+	// radish.ReaderOptions.
+	ReaderOptions radish.ReaderOptions
+}
+
+func (o Options) withDefaults() Options {
+	if o.Addr == "" {
+		o.Addr = DefaultAddr
+	}
+	if o.Dial == nil {
+		var d net.Dialer
+		o.Dial = d.DialContext
+	}
+	if o.DialTimeout <= 0 {
+		o.DialTimeout = DefaultDialTimeout
+	}
+	if o.ReapInterval <= 0 {
+		o.ReapInterval = DefaultReapInterval
+	}
+	if o.PipelineBatchSize <= 0 {
+		o.PipelineBatchSize = DefaultPipelineBatchSize
+	}
+	return o
+}
+
+// Stats are statistics of the connection pool of a Client.
+type Stats struct {
+	TotalConns int // The number of open connections.
+	IdleConns  int // The number of idle connections.
+}
+
+// Client is a client of a Redis-compatible server. It is safe for concurrent
+// use by multiple goroutines.
+//
+// Each command takes a connection from the pool for the time of the call, so
+// concurrent commands are sent over different connections, unless the
+// Multiplex option is set.
+type Client struct {
+	opts Options
+	sem  chan struct{} // Limits active connections, nil if unlimited.
+
+	mu       sync.Mutex
+	idle     []*conn // The most recently used connection is the last.
+	numConns int
+	closed   bool
+
+	muxMu sync.Mutex
+	mux   *muxConn // The shared connection of the Multiplex mode.
+
+	reapMu sync.Mutex // Serializes the reap, so it does not over-dial.
+	done   chan struct{}
+	wg     sync.WaitGroup
+}
+
+// New returns a new Client with the opts. It does not dial the server until
+// the first command, except to open the MinIdleConns in the background.
+func New(opts Options) *Client {
+	c := &Client{
+		opts: opts.withDefaults(),
+		done: make(chan struct{}),
+	}
+	if c.opts.MaxActiveConns > 0 {
+		c.sem = make(chan struct{}, c.opts.MaxActiveConns)
+	}
+
+	c.wg.Add(1)
+	go c.reaper()
+
+	return c
+}
+
+// Do sends a command of the args, formatted by the radish.Writer.WriteArg, and
+// returns the reply.
+//
+// If the reply is a RESP error, it is returned as the *radish.Error along with
+// the reply.
+func (c *Client) Do(ctx context.Context, args ...interface{}) (radish.Value, error) {
+	if len(args) == 0 {
+		return radish.Value{}, errNoArgs
+	}
+
+	var (
+		v   radish.Value
+		err error
+	)
+	if c.opts.Multiplex {
+		v, err = c.doMultiplexed(ctx, args)
+	} else {
+		v, err = c.doPooled(ctx, args)
+	}
+	if err != nil {
+		return v, err
+	}
+
+	if v.Err != nil {
+		return v, v.Err
+	}
+	return v, nil
+}
+
+func (c *Client) doPooled(ctx context.Context, args []interface{}) (radish.Value, error) {
+	cn, err := c.get(ctx)
+	if err != nil {
+		return radish.Value{}, err
+	}
+
+	v, err := cn.do(ctx, &c.opts, args)
+	c.put(cn, err)
+	return v, err
+}
+
+func (c *Client) doMultiplexed(ctx context.Context, args []interface{}) (radish.Value, error) {
+	if err := ctx.Err(); err != nil {
+		return radish.Value{}, err
+	}
+
+	m, err := c.getMux(ctx)
+	if err != nil {
+		return radish.Value{}, err
+	}
+	return m.do(ctx, args)
+}
+
+// getMux returns the shared connection, dialing a new one if there is no
+// connection yet or the previous one has failed.
+func (c *Client) getMux(ctx context.Context) (*muxConn, error) {
+	c.muxMu.Lock()
+	defer c.muxMu.Unlock()
+
+	if c.mux != nil && !c.mux.isDead() {
+		return c.mux, nil
+	}
+
+	c.mu.Lock()
+	closed := c.closed
+	c.mu.Unlock()
+	if closed {
+		return nil, ErrClosed
+	}
+
+	cn, err := c.dial(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mux = newMuxConn(cn, &c.opts)
+	c.mux.start()
+	return c.mux, nil
+}
+
+// Stats returns statistics of the connection pool.
+func (c *Client) Stats() Stats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return Stats{
+		TotalConns: c.numConns,
+		IdleConns:  len(c.idle),
+	}
+}
+
+// Close closes idle connections and makes the Client reject new commands.
+// Active connections are closed after their current commands.
+func (c *Client) Close() error {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return ErrClosed
+	}
+	c.closed = true
+	idle := c.idle
+	c.idle = nil
+	c.mu.Unlock()
+
+	close(c.done)
+	c.wg.Wait()
+
+	c.muxMu.Lock()
+	if c.mux != nil {
+		c.mux.close()
+	}
+	c.muxMu.Unlock()
+
+	var firstErr error
+	for _, cn := range idle {
+		if err := c.closeConn(cn); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// get takes an idle connection from the pool or dials a new one.
+func (c *Client) get(ctx context.Context) (*conn, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	if c.sem != nil {
+		select {
+		case c.sem <- struct{}{}:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-c.done:
+			return nil, ErrClosed
+		}
+	}
+
+	cn, err := c.getConn(ctx)
+	if err != nil && c.sem != nil {
+		<-c.sem
+	}
+	return cn, err
+}
+
+func (c *Client) getConn(ctx context.Context) (*conn, error) {
+	for {
+		c.mu.Lock()
+		if c.closed {
+			c.mu.Unlock()
+			return nil, ErrClosed
+		}
+		if len(c.idle) == 0 {
+			c.numConns++ // Reserve a place for the new connection.
+			c.mu.Unlock()
+			break
+		}
+		cn := c.idle[len(c.idle)-1]
+		c.idle[len(c.idle)-1] = nil
+		c.idle = c.idle[:len(c.idle)-1]
+		c.mu.Unlock()
+
+		now := time.Now()
+		if c.expired(cn, now) {
+			_ = c.closeConn(cn)
+			continue
+		}
+
+		if c.opts.HealthCheckInterval > 0 && now.Sub(cn.usedAt) >= c.opts.HealthCheckInterval {
+			if err := cn.ping(ctx, &c.opts); err != nil {
+				_ = c.closeConn(cn)
+				if ctx.Err() != nil {
+					return nil, ctx.Err()
+				}
+				continue
+			}
+		}
+
+		return cn, nil
+	}
+
+	cn, err := c.dial(ctx)
+	if err != nil {
+		c.mu.Lock()
+		c.numConns--
+		c.mu.Unlock()
+		return nil, err
+	}
+	return cn, nil
+}
+
+// put returns the connection to the pool, or closes it if the err is an I/O
+// or protocol error, after which the connection can not be trusted. Errors of
+// replies, see the replyError, keep the connection.
+func (c *Client) put(cn *conn, err error) {
+	if c.sem != nil {
+		defer func() { <-c.sem }()
+	}
+
+	if (err != nil && !isReplyError(err)) || c.expired(cn, time.Now()) {
+		_ = c.closeConn(cn)
+		return
+	}
+
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		_ = c.closeConn(cn)
+		return
+	}
+	c.idle = append(c.idle, cn)
+	c.mu.Unlock()
+}
+
+func (c *Client) dial(ctx context.Context) (*conn, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.opts.DialTimeout)
+	defer cancel()
+
+	nc, err := c.opts.Dial(ctx, "tcp", c.opts.Addr)
+	if err != nil {
+		return nil, err
+	}
+
+	return newConn(nc, c.opts.ReaderOptions), nil
+}
+
+func (c *Client) closeConn(cn *conn) error {
+	c.mu.Lock()
+	c.numConns--
+	c.mu.Unlock()
+
+	return cn.nc.Close()
+}
+
+func (c *Client) expired(cn *conn, now time.Time) bool {
+	if c.opts.IdleTimeout > 0 && now.Sub(cn.usedAt) >= c.opts.IdleTimeout {
+		return true
+	}
+	if c.opts.MaxConnLifetime > 0 && now.Sub(cn.createdAt) >= c.opts.MaxConnLifetime {
+		return true
+	}
+	return false
+}
+
+func (c *Client) reaper() {
+	defer c.wg.Done()
+
+	ticker := time.NewTicker(c.opts.ReapInterval)
+	defer ticker.Stop()
+
+	for {
+		c.reap()
+
+		select {
+		case <-ticker.C:
+		case <-c.done:
+			return
+		}
+	}
+}
+
+// reap closes expired idle connections and dials new ones up to the
+// MinIdleConns.
+func (c *Client) reap() {
+	c.reapMu.Lock()
+	defer c.reapMu.Unlock()
+
+	now := time.Now()
+
+	var stale []*conn
+	c.mu.Lock()
+	idle := c.idle[:0]
+	for _, cn := range c.idle {
+		if c.expired(cn, now) {
+			stale = append(stale, cn)
+		} else {
+			idle = append(idle, cn)
+		}
+	}
+	for i := len(idle); i < len(c.idle); i++ {
+		c.idle[i] = nil
+	}
+	c.idle = idle
+	c.mu.Unlock()
+
+	for _, cn := range stale {
+		_ = c.closeConn(cn)
+	}
+
+	for {
+		c.mu.Lock()
+		full := c.opts.MaxActiveConns > 0 && c.numConns >= c.opts.MaxActiveConns
+		if c.closed || len(c.idle) >= c.opts.MinIdleConns || full {
+			c.mu.Unlock()
+			return
+		}
+		c.numConns++
+		c.mu.Unlock()
+
+		cn, err := c.dial(context.Background())
+		if err != nil {
+			c.mu.Lock()
+			c.numConns--
+			c.mu.Unlock()
+			return
+		}
+
+		c.mu.Lock()
+		if c.closed {
+			c.mu.Unlock()
+			_ = c.closeConn(cn)
+			return
+		}
+		c.idle = append(c.idle, cn)
+		c.mu.Unlock()
+	}
+}
@@ -0,0 +1,281 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/SuperPaintman/mini-redis/radish"
+	"github.com/SuperPaintman/mini-redis/radish/server"
+)
+
+// testHandler replies to PING, ECHO and ID like Redis, sleeps on SLOW and
+// closes the connection on KILL. Other commands get errors.
+func testHandler(c *server.Conn, cmd *radish.Command) {
+	w := c.Writer()
+
+	switch strings.ToUpper(string(cmd.Args[0])) {
+	case "PING":
+		_ = w.WriteSimpleString("PONG")
+	case "ECHO":
+		_ = w.WriteBytes(cmd.Args[1])
+	case "ID":
+		_ = w.WriteInt64(c.ID())
+	case "SLOW":
+		time.Sleep(100 * time.Millisecond)
+		_ = w.WriteSimpleString("OK")
+	case "KILL":
+		c.Close()
+	default:
+		_ = w.WriteRawError("ERR", "unknown command '"+string(cmd.Args[0])+"'")
+	}
+}
+
+func startTestServer(t testing.TB) string {
+	t.Helper()
+	return serveTest(t, server.HandlerFunc(testHandler))
+}
+
+// serveTest starts a server with the h handler and returns its address.
+func serveTest(t testing.TB, h server.Handler) string {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unexpected error: failed to listen: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	srv := &server.Server{Handler: h}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- srv.Serve(ctx, l)
+	}()
+
+	t.Cleanup(func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Errorf("Serve() returned unexpected error: %v", err)
+		}
+	})
+
+	return l.Addr().String()
+}
+
+func newTestClient(t testing.TB, opts Options) *Client {
+	t.Helper()
+
+	opts.Addr = startTestServer(t)
+	c := New(opts)
+	t.Cleanup(func() {
+		_ = c.Close()
+	})
+	return c
+}
+
+func mustID(t testing.TB, c *Client) int64 {
+	t.Helper()
+
+	v, err := c.Do(context.Background(), "ID")
+	if err != nil {
+		t.Fatalf("Do(ID): unexpected error: %v", err)
+	}
+	return v.Int
+}
+
+func TestClient_Do(t *testing.T) {
+	c := newTestClient(t, Options{})
+
+	tt := []struct {
+		name    string
+		args    []interface{}
+		want    radish.Value
+		wantErr error
+	}{
+		{
+			name: "simple string",
+			args: []interface{}{"PING"},
+			want: radish.Value{Type: radish.DataTypeSimpleString, Str: "PONG"},
+		},
+		{
+			name: "bulk string",
+			args: []interface{}{"ECHO", 1.5},
+			want: radish.Value{Type: radish.DataTypeBulkString, Str: "1.5"},
+		},
+		{
+			name:    "error",
+			args:    []interface{}{"NOPE"},
+			want:    radish.Value{Type: radish.DataTypeError, Err: &radish.Error{Kind: "ERR", Msg: "unknown command 'NOPE'"}},
+			wantErr: &radish.Error{Kind: "ERR", Msg: "unknown command 'NOPE'"},
+		},
+		{
+			name:    "no args",
+			args:    nil,
+			wantErr: errNoArgs,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := c.Do(context.Background(), tc.args...)
+			if !reflect.DeepEqual(err, tc.wantErr) {
+				t.Fatalf("Do(): got error = %v, want error = %v", err, tc.wantErr)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("Do(): got = %#v, want = %#v", got, tc.want)
+			}
+		})
+	}
+
+	// All commands, including the failed one, must reuse the connection.
+	if stats := c.Stats(); stats.TotalConns != 1 || stats.IdleConns != 1 {
+		t.Errorf("Stats(): got = %+v, want 1 connection", stats)
+	}
+}
+
+func TestClient_Do_context(t *testing.T) {
+	c := newTestClient(t, Options{})
+
+	t.Run("canceled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		if _, err := c.Do(ctx, "PING"); err != context.Canceled {
+			t.Fatalf("Do(): got error = %v, want error = %v", err, context.Canceled)
+		}
+	})
+
+	t.Run("deadline", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+
+		if _, err := c.Do(ctx, "SLOW"); err != context.DeadlineExceeded {
+			t.Fatalf("Do(): got error = %v, want error = %v", err, context.DeadlineExceeded)
+		}
+
+		// The interrupted connection must be closed.
+		if stats := c.Stats(); stats.TotalConns != 0 {
+			t.Errorf("Stats(): got = %+v, want no connections", stats)
+		}
+	})
+}
+
+func TestClient_MaxActiveConns(t *testing.T) {
+	c := newTestClient(t, Options{MaxActiveConns: 1})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			if _, err := c.Do(context.Background(), "SLOW"); err != nil {
+				t.Errorf("Do(): unexpected error: %v", err)
+			}
+		}()
+	}
+
+	// The command waits for a free connection until the deadline.
+	time.Sleep(10 * time.Millisecond)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := c.Do(ctx, "PING"); err != context.DeadlineExceeded {
+		t.Errorf("Do(): got error = %v, want error = %v", err, context.DeadlineExceeded)
+	}
+
+	wg.Wait()
+
+	if stats := c.Stats(); stats.TotalConns != 1 {
+		t.Errorf("Stats(): got = %+v, want 1 connection", stats)
+	}
+}
+
+func TestClient_reap(t *testing.T) {
+	c := newTestClient(t, Options{
+		MinIdleConns: 2,
+		IdleTimeout:  50 * time.Millisecond,
+		ReapInterval: time.Hour, // Reaped manually.
+	})
+
+	c.reap()
+	if stats := c.Stats(); stats.TotalConns != 2 || stats.IdleConns != 2 {
+		t.Fatalf("Stats(): got = %+v, want 2 idle connections", stats)
+	}
+	id := mustID(t, c)
+
+	time.Sleep(50 * time.Millisecond)
+	c.reap()
+
+	// Expired connections are replaced with new ones.
+	if stats := c.Stats(); stats.TotalConns != 2 || stats.IdleConns != 2 {
+		t.Fatalf("Stats(): got = %+v, want 2 idle connections", stats)
+	}
+	if got := mustID(t, c); got == id {
+		t.Errorf("Do(ID): got the expired connection %d", got)
+	}
+}
+
+func TestClient_MaxConnLifetime(t *testing.T) {
+	c := newTestClient(t, Options{MaxConnLifetime: 50 * time.Millisecond})
+
+	id := mustID(t, c)
+	if got := mustID(t, c); got != id {
+		t.Fatalf("Do(ID): got = %d, want the same connection %d", got, id)
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	if got := mustID(t, c); got == id {
+		t.Errorf("Do(ID): got the expired connection %d", got)
+	}
+}
+
+func TestClient_HealthCheckInterval(t *testing.T) {
+	c := newTestClient(t, Options{HealthCheckInterval: time.Nanosecond})
+
+	id := mustID(t, c)
+
+	// The server closes the connection without a reply.
+	if _, err := c.Do(context.Background(), "KILL"); err == nil {
+		t.Fatal("Do(KILL): expected an error")
+	}
+	if got := mustID(t, c); got == id {
+		t.Fatalf("Do(ID): got the killed connection %d", got)
+	}
+
+	// Close the connection behind the back of the pool.
+	c.mu.Lock()
+	_ = c.idle[0].nc.(*net.TCPConn).CloseWrite()
+	c.mu.Unlock()
+
+	if _, err := c.Do(context.Background(), "PING"); err != nil {
+		t.Fatalf("Do(): unexpected error: %v", err)
+	}
+	if stats := c.Stats(); stats.TotalConns != 1 {
+		t.Errorf("Stats(): got = %+v, want 1 connection", stats)
+	}
+}
+
+func TestClient_Close(t *testing.T) {
+	c := newTestClient(t, Options{})
+
+	mustID(t, c)
+	if err := c.Close(); err != nil {
+		t.Fatalf("Close(): unexpected error: %v", err)
+	}
+
+	if stats := c.Stats(); stats.TotalConns != 0 {
+		t.Errorf("Stats(): got = %+v, want no connections", stats)
+	}
+	if _, err := c.Do(context.Background(), "PING"); !errors.Is(err, ErrClosed) {
+		t.Errorf("Do(): got error = %v, want error = %v", err, ErrClosed)
+	}
+	if err := c.Close(); err != ErrClosed {
+		t.Errorf("Close(): got error = %v, want error = %v", err, ErrClosed)
+	}
+}
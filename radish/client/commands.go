@@ -0,0 +1,334 @@
+package client
+
+//go:generate go run ../../cmd/radish-gen -spec ../commands.json -o commands_gen.go
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strconv"
+
+	"github.com/SuperPaintman/mini-redis/radish"
+)
+
+// ErrNil is returned by typed commands for null replies, e.g. by the Get for
+// a missing key.
+var ErrNil = errors.New("radish: nil reply")
+
+// maxPrealloc limits the capacity of slices and maps allocated for replies
+// ahead, since their lengths come from the server.
+const maxPrealloc = 1024
+
+// Z is a member of a sorted set with its score.
+type Z struct {
+	Member string
+	Score  float64
+}
+
+// call sends a command written by the write and reads its reply with the
+// read, over a pooled or the multiplexed connection. It is the base of the
+// typed commands.
+func (c *Client) call(ctx context.Context, write func(*radish.Writer) error, read func(*radish.Reader) error) error {
+	var err error
+	if c.opts.Multiplex {
+		err = c.callMultiplexed(ctx, write, read)
+	} else {
+		err = c.callPooled(ctx, write, read)
+	}
+
+	if re, ok := err.(replyError); ok {
+		return re.err
+	}
+	return err
+}
+
+func (c *Client) callPooled(ctx context.Context, write func(*radish.Writer) error, read func(*radish.Reader) error) error {
+	cn, err := c.get(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = cn.call(ctx, &c.opts, write, read)
+	c.put(cn, err)
+	return err
+}
+
+func (c *Client) callMultiplexed(ctx context.Context, write func(*radish.Writer) error, read func(*radish.Reader) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	m, err := c.getMux(ctx)
+	if err != nil {
+		return err
+	}
+	return m.call(ctx, write, read)
+}
+
+// readErrorReply skips attributes and reads the next value if it is a RESP
+// error, returning it as the replyError.
+func readErrorReply(r *radish.Reader) error {
+	for {
+		b, err := r.Peek(1)
+		if err != nil {
+			return err
+		}
+
+		switch radish.DataType(b[0]) {
+		case radish.DataTypeAttribute:
+			n, err := r.ReadAttribute()
+			if err != nil {
+				return err
+			}
+			for i := 0; i < 2*n; i++ {
+				if _, err := r.ReadValue(); err != nil {
+					return err
+				}
+			}
+
+		case radish.DataTypeError, radish.DataTypeBulkError:
+			v, err := r.ReadValue()
+			if err != nil {
+				return err
+			}
+			return replyError{v.Err}
+
+		default:
+			return nil
+		}
+	}
+}
+
+// scanReply reads a scalar reply into the dst, see the radish.Reader.Scan.
+func scanReply(r *radish.Reader, dst interface{}) error {
+	if err := readErrorReply(r); err != nil {
+		return err
+	}
+
+	err := r.Scan(dst)
+	if err != nil && !isProtocolError(err) {
+		return replyError{err}
+	}
+	return err
+}
+
+// isProtocolError reports whether the err of the Scan is not caused by the
+// value, e.g. a *radish.ProtocolError or an I/O error. RESP errors nested in
+// the value are *radish.Error.
+func isProtocolError(err error) bool {
+	switch err.(type) {
+	case *radish.UnmarshalTypeError, *radish.ScanArrayLengthError, *radish.Error:
+		return false
+	default:
+		return true
+	}
+}
+
+// nullString is a string that might be null.
+type nullString struct {
+	s    string
+	null bool
+}
+
+func (ns *nullString) ScanRESP(dt radish.DataType, b []byte) error {
+	switch dt {
+	case radish.DataTypeNull:
+		ns.s, ns.null = "", true
+	case radish.DataTypeVerbatimString:
+		ns.s, ns.null = string(b[4:]), false // Without the format.
+	default:
+		ns.s, ns.null = string(b), false
+	}
+	return nil
+}
+
+func readString(r *radish.Reader) (string, error) {
+	var ns nullString
+	if err := scanReply(r, &ns); err != nil {
+		return "", err
+	}
+	if ns.null {
+		return "", replyError{ErrNil}
+	}
+	return ns.s, nil
+}
+
+func readInt(r *radish.Reader) (int64, error) {
+	var n int64
+	err := scanReply(r, &n)
+	return n, err
+}
+
+func readFloat(r *radish.Reader) (float64, error) {
+	s, err := readString(r)
+	if err != nil {
+		return 0, err
+	}
+
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, replyError{err}
+	}
+	return f, nil
+}
+
+func readBool(r *radish.Reader) (bool, error) {
+	var b bool
+	err := scanReply(r, &b)
+	return b, err
+}
+
+// readLength reads the header of an aggregate reply and returns the number of
+// its elements, keys and values of maps are counted separately. Nulls have
+// a -1 length.
+func readLength(r *radish.Reader) (int, error) {
+	if err := readErrorReply(r); err != nil {
+		return 0, err
+	}
+
+	dt, v, err := r.ReadAny()
+	if err != nil {
+		return 0, err
+	}
+
+	switch dt {
+	case radish.DataTypeArray, radish.DataTypeSet, radish.DataTypePush:
+		return v.(int), nil
+	case radish.DataTypeMap:
+		return 2 * v.(int), nil
+	case radish.DataTypeNull:
+		return -1, nil
+	default:
+		// The ReadAny has read the whole scalar value.
+		return 0, replyError{&radish.UnmarshalTypeError{Value: dt, Type: reflect.TypeOf([]string(nil))}}
+	}
+}
+
+// scanElements reads n elements of an aggregate with the scan, even after an
+// error of an element, so the reader stays at the start of the next reply.
+func scanElements(r *radish.Reader, n int, scan func(i int) error) error {
+	var firstErr error
+	for i := 0; i < n; i++ {
+		err := scan(i)
+		if err != nil && !isReplyError(err) {
+			return err
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+func prealloc(n int) int {
+	if n > maxPrealloc {
+		return maxPrealloc
+	}
+	return n
+}
+
+func readStrings(r *radish.Reader) ([]string, error) {
+	n, err := readLength(r)
+	if err != nil || n < 0 {
+		return nil, err
+	}
+
+	res := make([]string, 0, prealloc(n))
+	err = scanElements(r, n, func(int) error {
+		var ns nullString
+		err := scanReply(r, &ns)
+		res = append(res, ns.s)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func readStringMap(r *radish.Reader) (map[string]string, error) {
+	n, err := readLength(r)
+	if err != nil || n < 0 {
+		return nil, err
+	}
+
+	res := make(map[string]string, prealloc(n/2))
+	var key string
+	err = scanElements(r, n, func(i int) error {
+		var ns nullString
+		err := scanReply(r, &ns)
+		if i%2 == 0 {
+			key = ns.s
+		} else {
+			res[key] = ns.s
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if n%2 != 0 {
+		return nil, replyError{&radish.ScanArrayLengthError{Length: n, Dst: n + 1}}
+	}
+	return res, nil
+}
+
+// readZSlice reads members with scores, either as a flat array of members and
+// scores, or as an array of pairs, like in RESP3.
+func readZSlice(r *radish.Reader) ([]Z, error) {
+	n, err := readLength(r)
+	if err != nil || n < 0 {
+		return nil, err
+	}
+	if n == 0 {
+		return []Z{}, nil
+	}
+
+	b, err := r.Peek(1)
+	if err != nil {
+		return nil, err
+	}
+
+	if radish.DataType(b[0]) == radish.DataTypeArray {
+		res := make([]Z, 0, prealloc(n))
+		err = scanElements(r, n, func(int) error {
+			res = append(res, Z{})
+			z := &res[len(res)-1]
+
+			var member nullString
+			if err := readErrorReply(r); err != nil {
+				return err
+			}
+			if err := r.ScanArray(&member, &z.Score); err != nil {
+				if isProtocolError(err) {
+					return err
+				}
+				return replyError{err}
+			}
+			z.Member = member.s
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		return res, nil
+	}
+
+	res := make([]Z, 0, prealloc(n/2))
+	err = scanElements(r, n, func(i int) error {
+		if i%2 == 0 {
+			var member nullString
+			err := scanReply(r, &member)
+			res = append(res, Z{Member: member.s})
+			return err
+		}
+		return scanReply(r, &res[len(res)-1].Score)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if n%2 != 0 {
+		return nil, replyError{&radish.ScanArrayLengthError{Length: n, Dst: n + 1}}
+	}
+	return res, nil
+}
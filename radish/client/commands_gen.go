@@ -0,0 +1,826 @@
+// Code generated by radish-gen from commands.json. DO NOT EDIT.
+
+package client
+
+import (
+	"context"
+
+	"github.com/SuperPaintman/mini-redis/radish"
+)
+
+// SetOptions are options of the Set.
+type SetOptions struct {
+	// EX sets the expiration time in seconds.
+	EX int64
+
+	// PX sets the expiration time in milliseconds.
+	PX int64
+
+	// EXAT sets the expiration time as a Unix timestamp in seconds.
+	EXAT int64
+
+	// PXAT sets the expiration time as a Unix timestamp in milliseconds.
+	PXAT int64
+
+	// NX only sets the key if it does not exist. Otherwise the ErrNil is
+	// returned.
+	NX bool
+
+	// XX only sets the key if it already exists. Otherwise the ErrNil is
+	// returned.
+	XX bool
+
+	// KeepTTL retains the expiration time of the key.
+	KeepTTL bool
+}
+
+func (o *SetOptions) appendArgs(args []interface{}) []interface{} {
+	if o == nil {
+		return args
+	}
+	if o.EX != 0 {
+		args = append(args, "EX", o.EX)
+	}
+	if o.PX != 0 {
+		args = append(args, "PX", o.PX)
+	}
+	if o.EXAT != 0 {
+		args = append(args, "EXAT", o.EXAT)
+	}
+	if o.PXAT != 0 {
+		args = append(args, "PXAT", o.PXAT)
+	}
+	if o.NX {
+		args = append(args, "NX")
+	}
+	if o.XX {
+		args = append(args, "XX")
+	}
+	if o.KeepTTL {
+		args = append(args, "KEEPTTL")
+	}
+	return args
+}
+
+// ZAddOptions are options of the ZAdd.
+type ZAddOptions struct {
+	// NX only adds new members and does not update existing ones.
+	NX bool
+
+	// XX only updates existing members and does not add new ones.
+	XX bool
+
+	// GT only updates existing members if the new score is greater.
+	GT bool
+
+	// LT only updates existing members if the new score is less.
+	LT bool
+
+	// CH makes the reply count changed members, not only added ones.
+	CH bool
+}
+
+func (o *ZAddOptions) appendArgs(args []interface{}) []interface{} {
+	if o == nil {
+		return args
+	}
+	if o.NX {
+		args = append(args, "NX")
+	}
+	if o.XX {
+		args = append(args, "XX")
+	}
+	if o.GT {
+		args = append(args, "GT")
+	}
+	if o.LT {
+		args = append(args, "LT")
+	}
+	if o.CH {
+		args = append(args, "CH")
+	}
+	return args
+}
+
+// Append appends a string to the value of a key. Creates the key if it doesn't
+// exist.
+//
+//	APPEND key value
+func (c *Client) Append(ctx context.Context, key string, value interface{}) (int64, error) {
+	var res int64
+	err := c.call(ctx, func(w *radish.Writer) error {
+		return w.WriteCommand("APPEND", key, value)
+	}, func(r *radish.Reader) (err error) {
+		res, err = readInt(r)
+		return err
+	})
+	return res, err
+}
+
+// Decr decrements the integer value of a key by one. Uses 0 as initial value if
+// the key doesn't exist.
+//
+//	DECR key
+func (c *Client) Decr(ctx context.Context, key string) (int64, error) {
+	var res int64
+	err := c.call(ctx, func(w *radish.Writer) error {
+		return w.WriteCommand("DECR", key)
+	}, func(r *radish.Reader) (err error) {
+		res, err = readInt(r)
+		return err
+	})
+	return res, err
+}
+
+// DecrBy decrements a number from the integer value of a key. Uses 0 as initial
+// value if the key doesn't exist.
+//
+//	DECRBY key decrement
+func (c *Client) DecrBy(ctx context.Context, key string, decrement int64) (int64, error) {
+	var res int64
+	err := c.call(ctx, func(w *radish.Writer) error {
+		return w.WriteCommand("DECRBY", key, decrement)
+	}, func(r *radish.Reader) (err error) {
+		res, err = readInt(r)
+		return err
+	})
+	return res, err
+}
+
+// Del deletes one or more keys.
+//
+//	DEL key [key ...]
+func (c *Client) Del(ctx context.Context, keys ...string) (int64, error) {
+	args := make([]interface{}, 0, 1+len(keys))
+	for _, arg := range keys {
+		args = append(args, arg)
+	}
+
+	var res int64
+	err := c.call(ctx, func(w *radish.Writer) error {
+		return w.WriteCommand("DEL", args...)
+	}, func(r *radish.Reader) (err error) {
+		res, err = readInt(r)
+		return err
+	})
+	return res, err
+}
+
+// Echo returns the given string.
+//
+//	ECHO message
+func (c *Client) Echo(ctx context.Context, message string) (string, error) {
+	var res string
+	err := c.call(ctx, func(w *radish.Writer) error {
+		return w.WriteCommand("ECHO", message)
+	}, func(r *radish.Reader) (err error) {
+		res, err = readString(r)
+		return err
+	})
+	return res, err
+}
+
+// Exists determines whether one or more keys exist.
+//
+//	EXISTS key [key ...]
+func (c *Client) Exists(ctx context.Context, keys ...string) (int64, error) {
+	args := make([]interface{}, 0, 1+len(keys))
+	for _, arg := range keys {
+		args = append(args, arg)
+	}
+
+	var res int64
+	err := c.call(ctx, func(w *radish.Writer) error {
+		return w.WriteCommand("EXISTS", args...)
+	}, func(r *radish.Reader) (err error) {
+		res, err = readInt(r)
+		return err
+	})
+	return res, err
+}
+
+// Expire sets the expiration time of a key in seconds.
+//
+//	EXPIRE key seconds
+func (c *Client) Expire(ctx context.Context, key string, seconds int64) (bool, error) {
+	var res bool
+	err := c.call(ctx, func(w *radish.Writer) error {
+		return w.WriteCommand("EXPIRE", key, seconds)
+	}, func(r *radish.Reader) (err error) {
+		res, err = readBool(r)
+		return err
+	})
+	return res, err
+}
+
+// Get returns the string value of a key.
+//
+//	GET key
+func (c *Client) Get(ctx context.Context, key string) (string, error) {
+	var res string
+	err := c.call(ctx, func(w *radish.Writer) error {
+		return w.WriteCommand("GET", key)
+	}, func(r *radish.Reader) (err error) {
+		res, err = readString(r)
+		return err
+	})
+	return res, err
+}
+
+// GetDel returns the string value of a key after deleting the key.
+//
+//	GETDEL key
+func (c *Client) GetDel(ctx context.Context, key string) (string, error) {
+	var res string
+	err := c.call(ctx, func(w *radish.Writer) error {
+		return w.WriteCommand("GETDEL", key)
+	}, func(r *radish.Reader) (err error) {
+		res, err = readString(r)
+		return err
+	})
+	return res, err
+}
+
+// HDel deletes one or more fields and their values from a hash. Deletes the
+// hash if no fields remain.
+//
+//	HDEL key field [field ...]
+func (c *Client) HDel(ctx context.Context, key string, fields ...string) (int64, error) {
+	args := make([]interface{}, 0, 2+len(fields))
+	args = append(args, key)
+	for _, arg := range fields {
+		args = append(args, arg)
+	}
+
+	var res int64
+	err := c.call(ctx, func(w *radish.Writer) error {
+		return w.WriteCommand("HDEL", args...)
+	}, func(r *radish.Reader) (err error) {
+		res, err = readInt(r)
+		return err
+	})
+	return res, err
+}
+
+// HExists determines whether a field exists in a hash.
+//
+//	HEXISTS key field
+func (c *Client) HExists(ctx context.Context, key string, field string) (bool, error) {
+	var res bool
+	err := c.call(ctx, func(w *radish.Writer) error {
+		return w.WriteCommand("HEXISTS", key, field)
+	}, func(r *radish.Reader) (err error) {
+		res, err = readBool(r)
+		return err
+	})
+	return res, err
+}
+
+// HGet returns the value of a field in a hash.
+//
+//	HGET key field
+func (c *Client) HGet(ctx context.Context, key string, field string) (string, error) {
+	var res string
+	err := c.call(ctx, func(w *radish.Writer) error {
+		return w.WriteCommand("HGET", key, field)
+	}, func(r *radish.Reader) (err error) {
+		res, err = readString(r)
+		return err
+	})
+	return res, err
+}
+
+// HGetAll returns all fields and values in a hash.
+//
+//	HGETALL key
+func (c *Client) HGetAll(ctx context.Context, key string) (map[string]string, error) {
+	var res map[string]string
+	err := c.call(ctx, func(w *radish.Writer) error {
+		return w.WriteCommand("HGETALL", key)
+	}, func(r *radish.Reader) (err error) {
+		res, err = readStringMap(r)
+		return err
+	})
+	return res, err
+}
+
+// HIncrBy increments the integer value of a field in a hash by a number. Uses 0
+// as initial value if the field doesn't exist.
+//
+//	HINCRBY key field increment
+func (c *Client) HIncrBy(ctx context.Context, key string, field string, increment int64) (int64, error) {
+	var res int64
+	err := c.call(ctx, func(w *radish.Writer) error {
+		return w.WriteCommand("HINCRBY", key, field, increment)
+	}, func(r *radish.Reader) (err error) {
+		res, err = readInt(r)
+		return err
+	})
+	return res, err
+}
+
+// HKeys returns all fields in a hash.
+//
+//	HKEYS key
+func (c *Client) HKeys(ctx context.Context, key string) ([]string, error) {
+	var res []string
+	err := c.call(ctx, func(w *radish.Writer) error {
+		return w.WriteCommand("HKEYS", key)
+	}, func(r *radish.Reader) (err error) {
+		res, err = readStrings(r)
+		return err
+	})
+	return res, err
+}
+
+// HLen returns the number of fields in a hash.
+//
+//	HLEN key
+func (c *Client) HLen(ctx context.Context, key string) (int64, error) {
+	var res int64
+	err := c.call(ctx, func(w *radish.Writer) error {
+		return w.WriteCommand("HLEN", key)
+	}, func(r *radish.Reader) (err error) {
+		res, err = readInt(r)
+		return err
+	})
+	return res, err
+}
+
+// HSet creates or modifies the value of a field in a hash.
+//
+//	HSET key field value
+func (c *Client) HSet(ctx context.Context, key string, field string, value interface{}) (int64, error) {
+	var res int64
+	err := c.call(ctx, func(w *radish.Writer) error {
+		return w.WriteCommand("HSET", key, field, value)
+	}, func(r *radish.Reader) (err error) {
+		res, err = readInt(r)
+		return err
+	})
+	return res, err
+}
+
+// HVals returns all values in a hash.
+//
+//	HVALS key
+func (c *Client) HVals(ctx context.Context, key string) ([]string, error) {
+	var res []string
+	err := c.call(ctx, func(w *radish.Writer) error {
+		return w.WriteCommand("HVALS", key)
+	}, func(r *radish.Reader) (err error) {
+		res, err = readStrings(r)
+		return err
+	})
+	return res, err
+}
+
+// Incr increments the integer value of a key by one. Uses 0 as initial value if
+// the key doesn't exist.
+//
+//	INCR key
+func (c *Client) Incr(ctx context.Context, key string) (int64, error) {
+	var res int64
+	err := c.call(ctx, func(w *radish.Writer) error {
+		return w.WriteCommand("INCR", key)
+	}, func(r *radish.Reader) (err error) {
+		res, err = readInt(r)
+		return err
+	})
+	return res, err
+}
+
+// IncrBy increments the integer value of a key by a number. Uses 0 as initial
+// value if the key doesn't exist.
+//
+//	INCRBY key increment
+func (c *Client) IncrBy(ctx context.Context, key string, increment int64) (int64, error) {
+	var res int64
+	err := c.call(ctx, func(w *radish.Writer) error {
+		return w.WriteCommand("INCRBY", key, increment)
+	}, func(r *radish.Reader) (err error) {
+		res, err = readInt(r)
+		return err
+	})
+	return res, err
+}
+
+// IncrByFloat increments the floating point value of a key by a number. Uses 0
+// as initial value if the key doesn't exist.
+//
+//	INCRBYFLOAT key increment
+func (c *Client) IncrByFloat(ctx context.Context, key string, increment float64) (float64, error) {
+	var res float64
+	err := c.call(ctx, func(w *radish.Writer) error {
+		return w.WriteCommand("INCRBYFLOAT", key, increment)
+	}, func(r *radish.Reader) (err error) {
+		res, err = readFloat(r)
+		return err
+	})
+	return res, err
+}
+
+// LLen returns the length of a list.
+//
+//	LLEN key
+func (c *Client) LLen(ctx context.Context, key string) (int64, error) {
+	var res int64
+	err := c.call(ctx, func(w *radish.Writer) error {
+		return w.WriteCommand("LLEN", key)
+	}, func(r *radish.Reader) (err error) {
+		res, err = readInt(r)
+		return err
+	})
+	return res, err
+}
+
+// LPop returns the first elements in a list after removing it. Deletes the list
+// if the last element was popped.
+//
+//	LPOP key
+func (c *Client) LPop(ctx context.Context, key string) (string, error) {
+	var res string
+	err := c.call(ctx, func(w *radish.Writer) error {
+		return w.WriteCommand("LPOP", key)
+	}, func(r *radish.Reader) (err error) {
+		res, err = readString(r)
+		return err
+	})
+	return res, err
+}
+
+// LPush prepends one or more elements to a list. Creates the key if it doesn't
+// exist.
+//
+//	LPUSH key element [element ...]
+func (c *Client) LPush(ctx context.Context, key string, elements ...interface{}) (int64, error) {
+	args := make([]interface{}, 0, 2+len(elements))
+	args = append(args, key)
+	for _, arg := range elements {
+		args = append(args, arg)
+	}
+
+	var res int64
+	err := c.call(ctx, func(w *radish.Writer) error {
+		return w.WriteCommand("LPUSH", args...)
+	}, func(r *radish.Reader) (err error) {
+		res, err = readInt(r)
+		return err
+	})
+	return res, err
+}
+
+// LRange returns a range of elements from a list.
+//
+//	LRANGE key start stop
+func (c *Client) LRange(ctx context.Context, key string, start int64, stop int64) ([]string, error) {
+	var res []string
+	err := c.call(ctx, func(w *radish.Writer) error {
+		return w.WriteCommand("LRANGE", key, start, stop)
+	}, func(r *radish.Reader) (err error) {
+		res, err = readStrings(r)
+		return err
+	})
+	return res, err
+}
+
+// MGet atomically returns the string values of one or more keys.
+//
+//	MGET key [key ...]
+func (c *Client) MGet(ctx context.Context, keys ...string) ([]string, error) {
+	args := make([]interface{}, 0, 1+len(keys))
+	for _, arg := range keys {
+		args = append(args, arg)
+	}
+
+	var res []string
+	err := c.call(ctx, func(w *radish.Writer) error {
+		return w.WriteCommand("MGET", args...)
+	}, func(r *radish.Reader) (err error) {
+		res, err = readStrings(r)
+		return err
+	})
+	return res, err
+}
+
+// Persist removes the expiration time of a key.
+//
+//	PERSIST key
+func (c *Client) Persist(ctx context.Context, key string) (bool, error) {
+	var res bool
+	err := c.call(ctx, func(w *radish.Writer) error {
+		return w.WriteCommand("PERSIST", key)
+	}, func(r *radish.Reader) (err error) {
+		res, err = readBool(r)
+		return err
+	})
+	return res, err
+}
+
+// Ping returns the server's liveliness response.
+//
+//	PING
+func (c *Client) Ping(ctx context.Context) (string, error) {
+	var res string
+	err := c.call(ctx, func(w *radish.Writer) error {
+		return w.WriteCommand("PING")
+	}, func(r *radish.Reader) (err error) {
+		res, err = readString(r)
+		return err
+	})
+	return res, err
+}
+
+// RPop returns and removes the last elements of a list. Deletes the list if the
+// last element was popped.
+//
+//	RPOP key
+func (c *Client) RPop(ctx context.Context, key string) (string, error) {
+	var res string
+	err := c.call(ctx, func(w *radish.Writer) error {
+		return w.WriteCommand("RPOP", key)
+	}, func(r *radish.Reader) (err error) {
+		res, err = readString(r)
+		return err
+	})
+	return res, err
+}
+
+// RPush appends one or more elements to a list. Creates the key if it doesn't
+// exist.
+//
+//	RPUSH key element [element ...]
+func (c *Client) RPush(ctx context.Context, key string, elements ...interface{}) (int64, error) {
+	args := make([]interface{}, 0, 2+len(elements))
+	args = append(args, key)
+	for _, arg := range elements {
+		args = append(args, arg)
+	}
+
+	var res int64
+	err := c.call(ctx, func(w *radish.Writer) error {
+		return w.WriteCommand("RPUSH", args...)
+	}, func(r *radish.Reader) (err error) {
+		res, err = readInt(r)
+		return err
+	})
+	return res, err
+}
+
+// SAdd adds one or more members to a set. Creates the key if it doesn't exist.
+//
+//	SADD key member [member ...]
+func (c *Client) SAdd(ctx context.Context, key string, members ...interface{}) (int64, error) {
+	args := make([]interface{}, 0, 2+len(members))
+	args = append(args, key)
+	for _, arg := range members {
+		args = append(args, arg)
+	}
+
+	var res int64
+	err := c.call(ctx, func(w *radish.Writer) error {
+		return w.WriteCommand("SADD", args...)
+	}, func(r *radish.Reader) (err error) {
+		res, err = readInt(r)
+		return err
+	})
+	return res, err
+}
+
+// SCard returns the number of members in a set.
+//
+//	SCARD key
+func (c *Client) SCard(ctx context.Context, key string) (int64, error) {
+	var res int64
+	err := c.call(ctx, func(w *radish.Writer) error {
+		return w.WriteCommand("SCARD", key)
+	}, func(r *radish.Reader) (err error) {
+		res, err = readInt(r)
+		return err
+	})
+	return res, err
+}
+
+// SIsMember determines whether a member belongs to a set.
+//
+//	SISMEMBER key member
+func (c *Client) SIsMember(ctx context.Context, key string, member interface{}) (bool, error) {
+	var res bool
+	err := c.call(ctx, func(w *radish.Writer) error {
+		return w.WriteCommand("SISMEMBER", key, member)
+	}, func(r *radish.Reader) (err error) {
+		res, err = readBool(r)
+		return err
+	})
+	return res, err
+}
+
+// SMembers returns all members of a set.
+//
+//	SMEMBERS key
+func (c *Client) SMembers(ctx context.Context, key string) ([]string, error) {
+	var res []string
+	err := c.call(ctx, func(w *radish.Writer) error {
+		return w.WriteCommand("SMEMBERS", key)
+	}, func(r *radish.Reader) (err error) {
+		res, err = readStrings(r)
+		return err
+	})
+	return res, err
+}
+
+// SRem removes one or more members from a set. Deletes the set if the last
+// member was removed.
+//
+//	SREM key member [member ...]
+func (c *Client) SRem(ctx context.Context, key string, members ...interface{}) (int64, error) {
+	args := make([]interface{}, 0, 2+len(members))
+	args = append(args, key)
+	for _, arg := range members {
+		args = append(args, arg)
+	}
+
+	var res int64
+	err := c.call(ctx, func(w *radish.Writer) error {
+		return w.WriteCommand("SREM", args...)
+	}, func(r *radish.Reader) (err error) {
+		res, err = readInt(r)
+		return err
+	})
+	return res, err
+}
+
+// Set sets the string value of a key, ignoring its type. The key is created if
+// it doesn't exist.
+//
+//	SET key value [options]
+func (c *Client) Set(ctx context.Context, key string, value interface{}, opts *SetOptions) (string, error) {
+	args := make([]interface{}, 0, 3)
+	args = append(args, key)
+	args = append(args, value)
+	args = opts.appendArgs(args)
+
+	var res string
+	err := c.call(ctx, func(w *radish.Writer) error {
+		return w.WriteCommand("SET", args...)
+	}, func(r *radish.Reader) (err error) {
+		res, err = readString(r)
+		return err
+	})
+	return res, err
+}
+
+// StrLen returns the length of a string value.
+//
+//	STRLEN key
+func (c *Client) StrLen(ctx context.Context, key string) (int64, error) {
+	var res int64
+	err := c.call(ctx, func(w *radish.Writer) error {
+		return w.WriteCommand("STRLEN", key)
+	}, func(r *radish.Reader) (err error) {
+		res, err = readInt(r)
+		return err
+	})
+	return res, err
+}
+
+// TTL returns the expiration time in seconds of a key.
+//
+//	TTL key
+func (c *Client) TTL(ctx context.Context, key string) (int64, error) {
+	var res int64
+	err := c.call(ctx, func(w *radish.Writer) error {
+		return w.WriteCommand("TTL", key)
+	}, func(r *radish.Reader) (err error) {
+		res, err = readInt(r)
+		return err
+	})
+	return res, err
+}
+
+// Type determines the type of value stored at a key.
+//
+//	TYPE key
+func (c *Client) Type(ctx context.Context, key string) (string, error) {
+	var res string
+	err := c.call(ctx, func(w *radish.Writer) error {
+		return w.WriteCommand("TYPE", key)
+	}, func(r *radish.Reader) (err error) {
+		res, err = readString(r)
+		return err
+	})
+	return res, err
+}
+
+// ZAdd adds one or more members to a sorted set, or updates their scores.
+// Creates the key if it doesn't exist.
+//
+//	ZADD key [options] score member
+func (c *Client) ZAdd(ctx context.Context, key string, score float64, member interface{}, opts *ZAddOptions) (int64, error) {
+	args := make([]interface{}, 0, 4)
+	args = append(args, key)
+	args = opts.appendArgs(args)
+	args = append(args, score)
+	args = append(args, member)
+
+	var res int64
+	err := c.call(ctx, func(w *radish.Writer) error {
+		return w.WriteCommand("ZADD", args...)
+	}, func(r *radish.Reader) (err error) {
+		res, err = readInt(r)
+		return err
+	})
+	return res, err
+}
+
+// ZCard returns the number of members in a sorted set.
+//
+//	ZCARD key
+func (c *Client) ZCard(ctx context.Context, key string) (int64, error) {
+	var res int64
+	err := c.call(ctx, func(w *radish.Writer) error {
+		return w.WriteCommand("ZCARD", key)
+	}, func(r *radish.Reader) (err error) {
+		res, err = readInt(r)
+		return err
+	})
+	return res, err
+}
+
+// ZIncrBy increments the score of a member in a sorted set.
+//
+//	ZINCRBY key increment member
+func (c *Client) ZIncrBy(ctx context.Context, key string, increment float64, member string) (float64, error) {
+	var res float64
+	err := c.call(ctx, func(w *radish.Writer) error {
+		return w.WriteCommand("ZINCRBY", key, increment, member)
+	}, func(r *radish.Reader) (err error) {
+		res, err = readFloat(r)
+		return err
+	})
+	return res, err
+}
+
+// ZRange returns members in a sorted set within a range of indexes.
+//
+//	ZRANGE key start stop
+func (c *Client) ZRange(ctx context.Context, key string, start int64, stop int64) ([]string, error) {
+	var res []string
+	err := c.call(ctx, func(w *radish.Writer) error {
+		return w.WriteCommand("ZRANGE", key, start, stop)
+	}, func(r *radish.Reader) (err error) {
+		res, err = readStrings(r)
+		return err
+	})
+	return res, err
+}
+
+// ZRangeWithScores returns members with their scores in a sorted set within a
+// range of indexes.
+//
+//	ZRANGE key start stop WITHSCORES
+func (c *Client) ZRangeWithScores(ctx context.Context, key string, start int64, stop int64) ([]Z, error) {
+	var res []Z
+	err := c.call(ctx, func(w *radish.Writer) error {
+		return w.WriteCommand("ZRANGE", key, start, stop, "WITHSCORES")
+	}, func(r *radish.Reader) (err error) {
+		res, err = readZSlice(r)
+		return err
+	})
+	return res, err
+}
+
+// ZRem removes one or more members from a sorted set. Deletes the sorted set if
+// all members were removed.
+//
+//	ZREM key member [member ...]
+func (c *Client) ZRem(ctx context.Context, key string, members ...interface{}) (int64, error) {
+	args := make([]interface{}, 0, 2+len(members))
+	args = append(args, key)
+	for _, arg := range members {
+		args = append(args, arg)
+	}
+
+	var res int64
+	err := c.call(ctx, func(w *radish.Writer) error {
+		return w.WriteCommand("ZREM", args...)
+	}, func(r *radish.Reader) (err error) {
+		res, err = readInt(r)
+		return err
+	})
+	return res, err
+}
+
+// ZScore returns the score of a member in a sorted set.
+//
+//	ZSCORE key member
+func (c *Client) ZScore(ctx context.Context, key string, member string) (float64, error) {
+	var res float64
+	err := c.call(ctx, func(w *radish.Writer) error {
+		return w.WriteCommand("ZSCORE", key, member)
+	}, func(r *radish.Reader) (err error) {
+		res, err = readFloat(r)
+		return err
+	})
+	return res, err
+}
@@ -0,0 +1,297 @@
+package client
+
+import (
+	"context"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/SuperPaintman/mini-redis/radish"
+	"github.com/SuperPaintman/mini-redis/radish/server"
+)
+
+func newKeyspaceClient(t testing.TB, opts Options) *Client {
+	t.Helper()
+
+	mux := server.NewMux()
+	server.NewKeyspace().Register(mux)
+
+	opts.Addr = serveTest(t, mux)
+	c := New(opts)
+	t.Cleanup(func() { _ = c.Close() })
+	return c
+}
+
+func TestClient_commands(t *testing.T) {
+	for _, multiplex := range []bool{false, true} {
+		name := "pool"
+		if multiplex {
+			name = "multiplex"
+		}
+
+		t.Run(name, func(t *testing.T) {
+			testCommands(t, newKeyspaceClient(t, Options{Multiplex: multiplex}))
+		})
+	}
+}
+
+func testCommands(t *testing.T, c *Client) {
+	ctx := context.Background()
+
+	check := func(name string, got interface{}, err error, want interface{}) {
+		t.Helper()
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", name, err)
+			return
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: got = %#v, want = %#v", name, got, want)
+		}
+	}
+
+	// Strings.
+	got, err := c.Set(ctx, "key", 42, nil)
+	check("Set()", got, err, "OK")
+	got, err = c.Get(ctx, "key")
+	check("Get()", got, err, "42")
+	if _, err := c.Get(ctx, "missing"); err != ErrNil {
+		t.Errorf("Get(missing): got error = %v, want error = %v", err, ErrNil)
+	}
+	if _, err := c.Set(ctx, "key", "new", &SetOptions{NX: true}); err != ErrNil {
+		t.Errorf("Set(NX): got error = %v, want error = %v", err, ErrNil)
+	}
+	got, err = c.Set(ctx, "ttl", "v", &SetOptions{EX: 100})
+	check("Set(EX)", got, err, "OK")
+	n, err := c.TTL(ctx, "ttl")
+	check("TTL()", n, err, int64(100))
+	n, err = c.IncrBy(ctx, "key", 8)
+	check("IncrBy()", n, err, int64(50))
+	f, err := c.IncrByFloat(ctx, "key", 0.5)
+	check("IncrByFloat()", f, err, 50.5)
+	strs, err := c.MGet(ctx, "key", "missing", "ttl")
+	check("MGet()", strs, err, []string{"50.5", "", "v"})
+	n, err = c.Del(ctx, "key", "missing", "ttl")
+	check("Del()", n, err, int64(2))
+
+	// Hashes.
+	n, err = c.HSet(ctx, "hash", "a", 1)
+	check("HSet()", n, err, int64(1))
+	n, err = c.HSet(ctx, "hash", "b", "two")
+	check("HSet()", n, err, int64(1))
+	m, err := c.HGetAll(ctx, "hash")
+	check("HGetAll()", m, err, map[string]string{"a": "1", "b": "two"})
+	m, err = c.HGetAll(ctx, "missing")
+	check("HGetAll(missing)", m, err, map[string]string{})
+	ok, err := c.HExists(ctx, "hash", "b")
+	check("HExists()", ok, err, true)
+
+	// Lists and sets.
+	n, err = c.RPush(ctx, "list", "a", "b", 3)
+	check("RPush()", n, err, int64(3))
+	strs, err = c.LRange(ctx, "list", 0, -1)
+	check("LRange()", strs, err, []string{"a", "b", "3"})
+	n, err = c.SAdd(ctx, "set", "x", "y")
+	check("SAdd()", n, err, int64(2))
+	ok, err = c.SIsMember(ctx, "set", "z")
+	check("SIsMember()", ok, err, false)
+
+	// Sorted sets.
+	n, err = c.ZAdd(ctx, "zset", 2, "b", nil)
+	check("ZAdd()", n, err, int64(1))
+	n, err = c.ZAdd(ctx, "zset", 1.5, "a", nil)
+	check("ZAdd()", n, err, int64(1))
+	n, err = c.ZAdd(ctx, "zset", 3, "a", &ZAddOptions{XX: true, CH: true})
+	check("ZAdd(XX CH)", n, err, int64(1))
+	zs, err := c.ZRangeWithScores(ctx, "zset", 0, -1)
+	check("ZRangeWithScores()", zs, err, []Z{{"b", 2}, {"a", 3}})
+	f, err = c.ZScore(ctx, "zset", "a")
+	check("ZScore()", f, err, 3.0)
+	if _, err := c.ZScore(ctx, "zset", "missing"); err != ErrNil {
+		t.Errorf("ZScore(missing): got error = %v, want error = %v", err, ErrNil)
+	}
+
+	// Errors of replies keep the connection.
+	wantErr := &radish.Error{Kind: "WRONGTYPE", Msg: "Operation against a key holding the wrong kind of value"}
+	if _, err := c.Get(ctx, "hash"); !reflect.DeepEqual(err, wantErr) {
+		t.Errorf("Get(hash): got error = %v, want error = %v", err, wantErr)
+	}
+	if !c.opts.Multiplex {
+		if stats := c.Stats(); stats.TotalConns != 1 {
+			t.Errorf("Stats(): got = %+v, want one connection", stats)
+		}
+	}
+	got, err = c.Ping(ctx)
+	check("Ping()", got, err, "PONG")
+}
+
+func TestReadReplies(t *testing.T) {
+	tt := []struct {
+		name    string
+		read    func(r *radish.Reader) (interface{}, error)
+		input   string
+		want    interface{}
+		wantErr error
+	}{
+		{
+			name:  "string",
+			read:  func(r *radish.Reader) (interface{}, error) { return readString(r) },
+			input: "$5\r\nhello\r\n",
+			want:  "hello",
+		},
+		{
+			name:  "verbatim string",
+			read:  func(r *radish.Reader) (interface{}, error) { return readString(r) },
+			input: "=9\r\ntxt:hello\r\n",
+			want:  "hello",
+		},
+		{
+			name:    "null string",
+			read:    func(r *radish.Reader) (interface{}, error) { return readString(r) },
+			input:   "_\r\n",
+			want:    "",
+			wantErr: replyError{ErrNil},
+		},
+		{
+			name:    "error",
+			read:    func(r *radish.Reader) (interface{}, error) { return readInt(r) },
+			input:   "-ERR oops\r\n",
+			want:    int64(0),
+			wantErr: replyError{&radish.Error{Kind: "ERR", Msg: "oops"}},
+		},
+		{
+			name:  "attribute",
+			read:  func(r *radish.Reader) (interface{}, error) { return readInt(r) },
+			input: "|1\r\n+key\r\n+value\r\n:42\r\n",
+			want:  int64(42),
+		},
+		{
+			name:  "double",
+			read:  func(r *radish.Reader) (interface{}, error) { return readFloat(r) },
+			input: ",inf\r\n",
+			want:  math.Inf(1),
+		},
+		{
+			name:  "bool",
+			read:  func(r *radish.Reader) (interface{}, error) { return readBool(r) },
+			input: "#t\r\n",
+			want:  true,
+		},
+		{
+			name:  "set",
+			read:  func(r *radish.Reader) (interface{}, error) { return readStrings(r) },
+			input: "~2\r\n+a\r\n:1\r\n",
+			want:  []string{"a", "1"},
+		},
+		{
+			name:  "null array",
+			read:  func(r *radish.Reader) (interface{}, error) { return readStrings(r) },
+			input: "*-1\r\n",
+			want:  []string(nil),
+		},
+		{
+			name:    "nested array",
+			read:    func(r *radish.Reader) (interface{}, error) { return readStrings(r) },
+			input:   "*2\r\n*1\r\n+a\r\n+b\r\n",
+			want:    []string(nil),
+			wantErr: replyError{&radish.UnmarshalTypeError{Value: radish.DataTypeArray, Type: reflect.TypeOf(&nullString{})}},
+		},
+		{
+			name:    "array with an error",
+			read:    func(r *radish.Reader) (interface{}, error) { return readStrings(r) },
+			input:   "*2\r\n-ERR oops\r\n+b\r\n",
+			want:    []string(nil),
+			wantErr: replyError{&radish.Error{Kind: "ERR", Msg: "oops"}},
+		},
+		{
+			name:    "not array",
+			read:    func(r *radish.Reader) (interface{}, error) { return readStrings(r) },
+			input:   ":1\r\n",
+			want:    []string(nil),
+			wantErr: replyError{&radish.UnmarshalTypeError{Value: radish.DataTypeInteger, Type: reflect.TypeOf([]string(nil))}},
+		},
+		{
+			name:  "map",
+			read:  func(r *radish.Reader) (interface{}, error) { return readStringMap(r) },
+			input: "%2\r\n+a\r\n:1\r\n+b\r\n$1\r\n2\r\n",
+			want:  map[string]string{"a": "1", "b": "2"},
+		},
+		{
+			name:  "flat zslice",
+			read:  func(r *radish.Reader) (interface{}, error) { return readZSlice(r) },
+			input: "*4\r\n$1\r\na\r\n$3\r\n1.5\r\n$1\r\nb\r\n$1\r\n2\r\n",
+			want:  []Z{{"a", 1.5}, {"b", 2}},
+		},
+		{
+			name:  "nested zslice",
+			read:  func(r *radish.Reader) (interface{}, error) { return readZSlice(r) },
+			input: "*2\r\n*2\r\n$1\r\na\r\n,1.5\r\n*2\r\n$1\r\nb\r\n,2\r\n",
+			want:  []Z{{"a", 1.5}, {"b", 2}},
+		},
+		{
+			name:    "nested zslice with an error",
+			read:    func(r *radish.Reader) (interface{}, error) { return readZSlice(r) },
+			input:   "*2\r\n*2\r\n$1\r\na\r\n-ERR oops\r\n*2\r\n$1\r\nb\r\n,2\r\n",
+			want:    []Z(nil),
+			wantErr: replyError{&radish.Error{Kind: "ERR", Msg: "oops"}},
+		},
+		{
+			name:  "empty zslice",
+			read:  func(r *radish.Reader) (interface{}, error) { return readZSlice(r) },
+			input: "*0\r\n",
+			want:  []Z{},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			// The next reply must stay unread.
+			r := radish.NewReader(strings.NewReader(tc.input + "+NEXT\r\n"))
+
+			got, err := tc.read(r)
+			if !reflect.DeepEqual(err, tc.wantErr) {
+				t.Fatalf("got error = %v, want error = %v", err, tc.wantErr)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got = %#v, want = %#v", got, tc.want)
+			}
+
+			if next, err := r.ReadSimpleString(); err != nil || next != "NEXT" {
+				t.Errorf("the next reply: got = %q, %v, want = %q", next, err, "NEXT")
+			}
+		})
+	}
+}
+
+func TestReadReplies_protocolError(t *testing.T) {
+	tt := []struct {
+		name  string
+		read  func(r *radish.Reader) (interface{}, error)
+		input string
+	}{
+		{
+			name:  "string",
+			read:  func(r *radish.Reader) (interface{}, error) { return readString(r) },
+			input: "$x\r\n",
+		},
+		{
+			name:  "array element",
+			read:  func(r *radish.Reader) (interface{}, error) { return readStrings(r) },
+			input: "*2\r\n+a\r\n?b\r\n",
+		},
+		{
+			name:  "nested zslice",
+			read:  func(r *radish.Reader) (interface{}, error) { return readZSlice(r) },
+			input: "*1\r\n*2\r\n$1\r\na\r\n$x\r\n",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := tc.read(radish.NewReader(strings.NewReader(tc.input)))
+			if _, ok := err.(*radish.ProtocolError); !ok {
+				t.Errorf("got error = %#v, want a *radish.ProtocolError", err)
+			}
+		})
+	}
+}
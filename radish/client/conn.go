@@ -0,0 +1,206 @@
+package client
+
+import (
+	"context"
+	"net"
+	"time"
+
+	"github.com/SuperPaintman/mini-redis/radish"
+)
+
+// aLongTimeAgo is a deadline in the past, to interrupt blocked reads and
+// writes.
+var aLongTimeAgo = time.Unix(1, 0)
+
+// conn is a connection to the server.
+type conn struct {
+	nc net.Conn
+	r  *radish.Reader
+	w  *radish.Writer
+
+	createdAt time.Time
+	usedAt    time.Time
+}
+
+func newConn(nc net.Conn, opts radish.ReaderOptions) *conn {
+	now := time.Now()
+	return &conn{
+		nc:        nc,
+		r:         radish.NewReaderOptions(nc, opts),
+		w:         radish.NewWriter(nc),
+		createdAt: now,
+		usedAt:    now,
+	}
+}
+
+// replyError is an error of a reply that has been read completely: a RESP
+// error, a null, or a value of an unexpected type. The connection stays at
+// the start of the next reply and can be reused.
+type replyError struct {
+	err error
+}
+
+func (e replyError) Error() string {
+	return e.err.Error()
+}
+
+func isReplyError(err error) bool {
+	_, ok := err.(replyError)
+	return ok
+}
+
+// do sends a command and reads its reply. Errors are I/O or protocol errors,
+// RESP errors are returned in the reply.
+func (cn *conn) do(ctx context.Context, opts *Options, args []interface{}) (v radish.Value, err error) {
+	err = cn.call(ctx, opts, func(w *radish.Writer) error {
+		return writeCommand(w, args)
+	}, func(r *radish.Reader) (err error) {
+		v, err = r.ReadValue()
+		return err
+	})
+	return v, err
+}
+
+// call sends a command written by the write and reads its reply with the
+// read. Errors of the read wrapped into the replyError leave the connection
+// usable, others are I/O or protocol errors.
+func (cn *conn) call(ctx context.Context, opts *Options, write func(*radish.Writer) error, read func(*radish.Reader) error) (err error) {
+	stop := cn.watch(ctx)
+	defer func() {
+		stop()
+		if err != nil && !isReplyError(err) {
+			err = contextError(ctx, err)
+		}
+		cn.usedAt = time.Now()
+	}()
+
+	if err := cn.nc.SetWriteDeadline(deadline(ctx, opts.WriteTimeout)); err != nil {
+		return err
+	}
+	if err := write(cn.w); err != nil {
+		return err
+	}
+	if err := cn.w.Flush(); err != nil {
+		return err
+	}
+
+	if err := cn.nc.SetReadDeadline(deadline(ctx, opts.ReadTimeout)); err != nil {
+		return err
+	}
+	return read(cn.r)
+}
+
+// pipeline sends the commands at once and reads their replies in order. The
+// replies and RESP errors are stored in the commands, the returned errors
+// are I/O or protocol errors.
+func (cn *conn) pipeline(ctx context.Context, opts *Options, cmds []*Cmd) (err error) {
+	stop := cn.watch(ctx)
+	defer func() {
+		stop()
+		if err != nil {
+			err = contextError(ctx, err)
+		}
+		cn.usedAt = time.Now()
+	}()
+
+	if err := cn.nc.SetWriteDeadline(deadline(ctx, opts.WriteTimeout)); err != nil {
+		return err
+	}
+	for _, cmd := range cmds {
+		if err := writeCommand(cn.w, cmd.args); err != nil {
+			return err
+		}
+	}
+	if err := cn.w.Flush(); err != nil {
+		return err
+	}
+
+	if err := cn.nc.SetReadDeadline(deadline(ctx, opts.ReadTimeout)); err != nil {
+		return err
+	}
+	for _, cmd := range cmds {
+		v, err := cn.r.ReadValue()
+		if err != nil {
+			return err
+		}
+		cmd.setReply(v)
+	}
+	return nil
+}
+
+// writeCommand writes a command of the args into the w.
+func writeCommand(w *radish.Writer, args []interface{}) error {
+	_ = w.WriteArray(len(args))
+	for _, arg := range args {
+		if err := w.WriteArg(arg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ping checks the connection with a PING.
+func (cn *conn) ping(ctx context.Context, opts *Options) error {
+	v, err := cn.do(ctx, opts, []interface{}{"PING"})
+	if err != nil {
+		return err
+	}
+	if v.Err != nil {
+		return v.Err
+	}
+	return nil
+}
+
+// watch interrupts reads and writes of the connection when the ctx is done,
+// until the stop is called.
+func (cn *conn) watch(ctx context.Context) (stop func()) {
+	done := ctx.Done()
+	if done == nil {
+		return func() {}
+	}
+
+	stopc := make(chan struct{})
+	exited := make(chan struct{})
+	go func() {
+		defer close(exited)
+
+		select {
+		case <-done:
+			_ = cn.nc.SetDeadline(aLongTimeAgo)
+		case <-stopc:
+		}
+	}()
+
+	return func() {
+		close(stopc)
+		<-exited
+	}
+}
+
+// contextError returns the error of the ctx if it caused the err, since the
+// deadline of the connection might expire a bit before the ctx is done.
+func contextError(ctx context.Context, err error) error {
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return ctxErr
+	}
+
+	if ne, ok := err.(net.Error); ok && ne.Timeout() {
+		if d, ok := ctx.Deadline(); ok && !time.Now().Before(d) {
+			return context.DeadlineExceeded
+		}
+	}
+	return err
+}
+
+// deadline returns the earliest of the deadline of the ctx and the timeout
+// from now. A zero time means no deadline.
+func deadline(ctx context.Context, timeout time.Duration) time.Time {
+	var t time.Time
+	if timeout > 0 {
+		t = time.Now().Add(timeout)
+	}
+	if d, ok := ctx.Deadline(); ok && (t.IsZero() || d.Before(t)) {
+		t = d
+	}
+	return t
+}
@@ -0,0 +1,287 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/SuperPaintman/mini-redis/radish"
+)
+
+// States of a request.
+const (
+	requestPending  int32 = iota
+	requestReading        // The read is called by the reader goroutine.
+	requestCanceled       // The caller has gone, the reply is skipped.
+)
+
+// request is a command sent over a multiplexed connection.
+type request struct {
+	ctx   context.Context
+	write func(*radish.Writer) error
+	read  func(*radish.Reader) error
+
+	state int32
+	err   error
+	done  chan struct{} // Closed after the err is set.
+}
+
+func (req *request) finish(err error) {
+	req.err = err
+	close(req.done)
+}
+
+// cancel marks the request as canceled, unless its reply is being read. After
+// a successful cancel the read is never called.
+func (req *request) cancel() bool {
+	return atomic.CompareAndSwapInt32(&req.state, requestPending, requestCanceled)
+}
+
+// muxConn is a connection shared by concurrent commands. The writer goroutine
+// sends all queued commands at once, and the reader goroutine reads their
+// replies in the same order and hands them to the waiting callers.
+type muxConn struct {
+	cn   *conn
+	opts *Options
+
+	queue   chan *request // Commands to send.
+	pending chan *request // Sent commands, waiting for replies.
+
+	// buf accumulates a batch of commands written by the w, so a command
+	// that fails to be written can be cut off without breaking the stream.
+	buf bytes.Buffer
+	w   *radish.Writer
+
+	failOnce sync.Once
+	err      error
+	dead     chan struct{} // Closed after the err is set.
+	wg       sync.WaitGroup
+}
+
+func newMuxConn(cn *conn, opts *Options) *muxConn {
+	m := &muxConn{
+		cn:      cn,
+		opts:    opts,
+		queue:   make(chan *request, opts.PipelineBatchSize),
+		pending: make(chan *request, opts.PipelineBatchSize),
+		dead:    make(chan struct{}),
+	}
+	m.w = radish.NewWriter(&m.buf)
+	return m
+}
+
+// start starts the writer and reader goroutines.
+func (m *muxConn) start() {
+	m.wg.Add(2)
+	go m.writeLoop()
+	go m.readLoop()
+}
+
+// do queues a command and waits for its reply, see the call.
+func (m *muxConn) do(ctx context.Context, args []interface{}) (v radish.Value, err error) {
+	err = m.call(ctx, func(w *radish.Writer) error {
+		return writeCommand(w, args)
+	}, func(r *radish.Reader) (err error) {
+		v, err = r.ReadValue()
+		return err
+	})
+	return v, err
+}
+
+// call queues a command written by the write and waits for its reply, read by
+// the read in the reader goroutine. If the ctx is done before the command is
+// sent, it is not sent at all.
+func (m *muxConn) call(ctx context.Context, write func(*radish.Writer) error, read func(*radish.Reader) error) error {
+	req := &request{
+		ctx:   ctx,
+		write: write,
+		read:  read,
+		done:  make(chan struct{}),
+	}
+
+	select {
+	case m.queue <- req:
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-m.dead:
+		return m.err
+	}
+
+	var err error
+	select {
+	case <-req.done:
+		return req.err
+	case <-ctx.Done():
+		err = ctx.Err()
+	case <-m.dead:
+		err = m.err
+	}
+
+	if req.cancel() {
+		return err
+	}
+	// The reply is being read, and the read may not outlive the call.
+	<-req.done
+	return req.err
+}
+
+// isDead reports whether the connection has failed or been closed.
+func (m *muxConn) isDead() bool {
+	select {
+	case <-m.dead:
+		return true
+	default:
+		return false
+	}
+}
+
+// fail closes the connection with the err. Only the first err is kept.
+func (m *muxConn) fail(err error) {
+	m.failOnce.Do(func() {
+		m.err = err
+		close(m.dead)
+		_ = m.cn.nc.Close()
+	})
+}
+
+// close closes the connection and waits for its goroutines.
+func (m *muxConn) close() {
+	m.fail(ErrClosed)
+	m.wg.Wait()
+}
+
+func (m *muxConn) writeLoop() {
+	defer m.wg.Done()
+
+	batch := make([]*request, 0, m.opts.PipelineBatchSize)
+	for {
+		var req *request
+		select {
+		case req = <-m.queue:
+		case <-m.dead:
+			return
+		}
+
+		// Coalesce all commands queued so far.
+		batch = append(batch[:0], req)
+	coalesce:
+		for len(batch) < m.opts.PipelineBatchSize {
+			select {
+			case req := <-m.queue:
+				batch = append(batch, req)
+			default:
+				break coalesce
+			}
+		}
+
+		if err := m.writeBatch(batch); err != nil {
+			m.fail(err)
+			return
+		}
+	}
+}
+
+// writeBatch sends the commands of the batch with a single write, and passes
+// them to the reader.
+func (m *muxConn) writeBatch(batch []*request) error {
+	sent := batch[:0]
+	for _, req := range batch {
+		// Do not send commands nobody waits for.
+		if err := req.ctx.Err(); err != nil {
+			req.finish(err)
+			continue
+		}
+
+		mark := m.buf.Len()
+		err := req.write(m.w)
+		if err == nil {
+			err = m.w.Flush()
+		}
+		if err != nil {
+			m.w.Reset(&m.buf)
+			m.buf.Truncate(mark)
+			req.finish(err)
+			continue
+		}
+
+		sent = append(sent, req)
+	}
+	if len(sent) == 0 {
+		return nil
+	}
+
+	if err := m.cn.nc.SetWriteDeadline(deadline(context.Background(), m.opts.WriteTimeout)); err != nil {
+		return err
+	}
+	_, err := m.cn.nc.Write(m.buf.Bytes())
+	m.buf.Reset()
+
+	for _, req := range sent {
+		if err != nil {
+			req.finish(err)
+			continue
+		}
+
+		select {
+		case m.pending <- req:
+		case <-m.dead:
+			req.finish(m.err)
+		}
+	}
+	return err
+}
+
+func (m *muxConn) readLoop() {
+	defer m.wg.Done()
+
+	for {
+		var req *request
+		select {
+		case req = <-m.pending:
+		case <-m.dead:
+			m.drain()
+			return
+		}
+
+		var t time.Time
+		if m.opts.ReadTimeout > 0 {
+			t = time.Now().Add(m.opts.ReadTimeout)
+		}
+		if err := m.cn.nc.SetReadDeadline(t); err != nil {
+			req.finish(err)
+			m.fail(err)
+			m.drain()
+			return
+		}
+
+		// Wait for the reply before deciding whether to read or skip it.
+		_, err := m.cn.r.Peek(1)
+		if err == nil {
+			if atomic.CompareAndSwapInt32(&req.state, requestPending, requestReading) {
+				err = req.read(m.cn.r)
+			} else {
+				_, err = m.cn.r.ReadValue()
+			}
+		}
+		req.finish(err)
+		if err != nil && !isReplyError(err) {
+			m.fail(err)
+			m.drain()
+			return
+		}
+	}
+}
+
+// drain fails the commands that will never get their replies.
+func (m *muxConn) drain() {
+	for {
+		select {
+		case req := <-m.pending:
+			req.finish(m.err)
+		default:
+			return
+		}
+	}
+}
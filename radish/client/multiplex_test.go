@@ -0,0 +1,171 @@
+package client
+
+import (
+	"context"
+	"io"
+	"net"
+	"reflect"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/SuperPaintman/mini-redis/radish"
+)
+
+func TestClient_Multiplex(t *testing.T) {
+	c := newTestClient(t, Options{Multiplex: true})
+	id := mustID(t, c)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			v, err := c.Do(context.Background(), "ECHO", i)
+			if err != nil {
+				t.Errorf("Do(ECHO %d): unexpected error: %v", i, err)
+				return
+			}
+			if want := strconv.Itoa(i); v.Str != want {
+				t.Errorf("Do(ECHO %d): got = %q, want = %q", i, v.Str, want)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	// All commands are sent over the same connection, besides the pool.
+	if got := mustID(t, c); got != id {
+		t.Errorf("Do(ID): got = %d, want the same connection %d", got, id)
+	}
+	if stats := c.Stats(); stats.TotalConns != 0 {
+		t.Errorf("Stats(): got = %+v, want no pooled connections", stats)
+	}
+}
+
+func TestMuxConn_coalesce(t *testing.T) {
+	addr := startTestServer(t)
+
+	nc, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("unexpected error: failed to dial: %v", err)
+	}
+	var writes int64
+	nc = &countingConn{nc, &writes}
+
+	opts := Options{}.withDefaults()
+	m := newMuxConn(newConn(nc, opts.ReaderOptions), &opts)
+	defer m.close()
+
+	// Queue the commands before the writer starts, so they are sent at once.
+	reqs := make([]*request, 3)
+	vals := make([]radish.Value, len(reqs))
+	for i := range reqs {
+		i, args := i, []interface{}{"ECHO", i}
+		reqs[i] = &request{
+			ctx: context.Background(),
+			write: func(w *radish.Writer) error {
+				return writeCommand(w, args)
+			},
+			read: func(r *radish.Reader) (err error) {
+				vals[i], err = r.ReadValue()
+				return err
+			},
+			done: make(chan struct{}),
+		}
+		m.queue <- reqs[i]
+	}
+	m.start()
+
+	for i, req := range reqs {
+		<-req.done
+		if req.err != nil || vals[i].Str != strconv.Itoa(i) {
+			t.Errorf("reqs[%d]: got = %q, %v, want = %q", i, vals[i].Str, req.err, strconv.Itoa(i))
+		}
+	}
+
+	if got := atomic.LoadInt64(&writes); got != 1 {
+		t.Errorf("got %d writes, want 1", got)
+	}
+}
+
+func TestClient_Multiplex_errors(t *testing.T) {
+	c := newTestClient(t, Options{Multiplex: true})
+	id := mustID(t, c)
+
+	t.Run("argument", func(t *testing.T) {
+		_, err := c.Do(context.Background(), "ECHO", struct{}{})
+		want := &radish.UnsupportedTypeError{Type: reflect.TypeOf(struct{}{})}
+		if !reflect.DeepEqual(err, want) {
+			t.Fatalf("Do(): got error = %v, want error = %v", err, want)
+		}
+
+		// The command is not sent, so the connection is fine.
+		if got := mustID(t, c); got != id {
+			t.Errorf("Do(ID): got = %d, want the same connection %d", got, id)
+		}
+	})
+
+	t.Run("context", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+
+		if _, err := c.Do(ctx, "SLOW"); err != context.DeadlineExceeded {
+			t.Fatalf("Do(): got error = %v, want error = %v", err, context.DeadlineExceeded)
+		}
+
+		// The late reply of the SLOW is skipped.
+		v, err := c.Do(context.Background(), "ECHO", "next")
+		if err != nil || v.Str != "next" {
+			t.Errorf("Do(ECHO): got = %q, %v, want = %q", v.Str, err, "next")
+		}
+		if got := mustID(t, c); got != id {
+			t.Errorf("Do(ID): got = %d, want the same connection %d", got, id)
+		}
+	})
+
+	t.Run("connection", func(t *testing.T) {
+		if _, err := c.Do(context.Background(), "KILL"); err != io.EOF {
+			t.Fatalf("Do(KILL): got error = %v, want error = %v", err, io.EOF)
+		}
+
+		// The connection is redialed.
+		if got := mustID(t, c); got == id {
+			t.Errorf("Do(ID): got the killed connection %d", got)
+		}
+	})
+
+	t.Run("closed", func(t *testing.T) {
+		if err := c.Close(); err != nil {
+			t.Fatalf("Close(): unexpected error: %v", err)
+		}
+		if _, err := c.Do(context.Background(), "PING"); err != ErrClosed {
+			t.Errorf("Do(): got error = %v, want error = %v", err, ErrClosed)
+		}
+	})
+}
+
+func BenchmarkClient_Do(b *testing.B) {
+	for _, multiplex := range []bool{false, true} {
+		name := "pool"
+		if multiplex {
+			name = "multiplex"
+		}
+
+		b.Run(name, func(b *testing.B) {
+			c := newTestClient(b, Options{Multiplex: multiplex})
+
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					if _, err := c.Do(context.Background(), "PING"); err != nil {
+						b.Error(err)
+						return
+					}
+				}
+			})
+		})
+	}
+}
@@ -0,0 +1,151 @@
+package client
+
+import (
+	"context"
+	"errors"
+
+	"github.com/SuperPaintman/mini-redis/radish"
+)
+
+var errNotExecuted = errors.New("radish: pipeline is not executed")
+
+// Cmd is a command queued in a Pipeline. Its reply is available after the
+// Pipeline.Exec.
+type Cmd struct {
+	args []interface{}
+	val  radish.Value
+	err  error
+}
+
+// Args returns the arguments of the command.
+func (cmd *Cmd) Args() []interface{} {
+	return cmd.args
+}
+
+// Value returns the reply of the command.
+func (cmd *Cmd) Value() radish.Value {
+	return cmd.val
+}
+
+// Err returns the error of the command: a RESP error of the reply as the
+// *radish.Error, an error of the pipeline that prevented the command from
+// getting its reply, or an error if the pipeline has not been executed yet.
+func (cmd *Cmd) Err() error {
+	return cmd.err
+}
+
+// Result returns the reply and the error of the command.
+func (cmd *Cmd) Result() (radish.Value, error) {
+	return cmd.val, cmd.err
+}
+
+func (cmd *Cmd) setReply(v radish.Value) {
+	cmd.val = v
+	if v.Err != nil {
+		cmd.err = v.Err
+	} else {
+		cmd.err = nil
+	}
+}
+
+// Pipeline queues commands and sends them to the server at once, saving a
+// round trip per command. It is not safe for concurrent use.
+//
+// Commands are sent in batches of at most the PipelineBatchSize of the
+// Options, over one connection, and their replies are read in order.
+type Pipeline struct {
+	c    *Client
+	cmds []*Cmd
+}
+
+// Pipeline returns a new empty Pipeline of the Client.
+func (c *Client) Pipeline() *Pipeline {
+	return &Pipeline{c: c}
+}
+
+// Do queues a command of the args, see the Client.Do. The returned Cmd gets
+// its reply after the Exec.
+func (p *Pipeline) Do(args ...interface{}) *Cmd {
+	cmd := &Cmd{
+		args: args,
+		err:  errNotExecuted,
+	}
+	p.cmds = append(p.cmds, cmd)
+	return cmd
+}
+
+// Len returns the number of queued commands.
+func (p *Pipeline) Len() int {
+	return len(p.cmds)
+}
+
+// Discard removes all queued commands.
+func (p *Pipeline) Discard() {
+	for i := range p.cmds {
+		p.cmds[i] = nil
+	}
+	p.cmds = p.cmds[:0]
+}
+
+// Exec sends the queued commands and reads their replies, then empties the
+// Pipeline, so it can be reused. It returns the first error of the commands,
+// if any, see the Cmd.Err.
+func (p *Pipeline) Exec(ctx context.Context) error {
+	defer p.Discard()
+
+	if len(p.cmds) == 0 {
+		return nil
+	}
+
+	// Commands without arguments are not sent at all.
+	cmds := make([]*Cmd, 0, len(p.cmds))
+	for _, cmd := range p.cmds {
+		if len(cmd.args) == 0 {
+			cmd.err = errNoArgs
+			continue
+		}
+		cmds = append(cmds, cmd)
+	}
+
+	if err := p.exec(ctx, cmds); err != nil {
+		for _, cmd := range cmds {
+			if cmd.err == errNotExecuted {
+				cmd.err = err
+			}
+		}
+	}
+
+	for _, cmd := range p.cmds {
+		if cmd.err != nil {
+			return cmd.err
+		}
+	}
+	return nil
+}
+
+func (p *Pipeline) exec(ctx context.Context, cmds []*Cmd) error {
+	if len(cmds) == 0 {
+		return nil
+	}
+
+	cn, err := p.c.get(ctx)
+	if err != nil {
+		return err
+	}
+
+	batchSize := p.c.opts.PipelineBatchSize
+	for len(cmds) > 0 {
+		batch := cmds
+		if len(batch) > batchSize {
+			batch = batch[:batchSize]
+		}
+		cmds = cmds[len(batch):]
+
+		if err = cn.pipeline(ctx, &p.c.opts, batch); err != nil {
+			break
+		}
+	}
+
+	p.c.put(cn, err)
+	return err
+}
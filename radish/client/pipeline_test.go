@@ -0,0 +1,143 @@
+package client
+
+import (
+	"context"
+	"io"
+	"net"
+	"reflect"
+	"sync/atomic"
+	"testing"
+
+	"github.com/SuperPaintman/mini-redis/radish"
+)
+
+func TestPipeline_Exec(t *testing.T) {
+	c := newTestClient(t, Options{})
+	p := c.Pipeline()
+
+	ping := p.Do("PING")
+	echo := p.Do("ECHO", 42)
+	nope := p.Do("NOPE")
+	empty := p.Do()
+	id := p.Do("ID")
+
+	if err := ping.Err(); err != errNotExecuted {
+		t.Errorf("Err(): got error = %v, want error = %v", err, errNotExecuted)
+	}
+	if got := p.Len(); got != 5 {
+		t.Errorf("Len(): got = %d, want = 5", got)
+	}
+
+	nopeErr := &radish.Error{Kind: "ERR", Msg: "unknown command 'NOPE'"}
+	if err := p.Exec(context.Background()); !reflect.DeepEqual(err, nopeErr) {
+		t.Fatalf("Exec(): got error = %v, want error = %v", err, nopeErr)
+	}
+
+	tt := []struct {
+		name    string
+		cmd     *Cmd
+		want    radish.Value
+		wantErr error
+	}{
+		{"ping", ping, radish.Value{Type: radish.DataTypeSimpleString, Str: "PONG"}, nil},
+		{"echo", echo, radish.Value{Type: radish.DataTypeBulkString, Str: "42"}, nil},
+		{"error", nope, radish.Value{Type: radish.DataTypeError, Err: nopeErr}, nopeErr},
+		{"no args", empty, radish.Value{}, errNoArgs},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := tc.cmd.Result()
+			if !reflect.DeepEqual(err, tc.wantErr) {
+				t.Fatalf("Result(): got error = %v, want error = %v", err, tc.wantErr)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("Result(): got = %#v, want = %#v", got, tc.want)
+			}
+		})
+	}
+
+	// All commands are sent over one connection.
+	if got := mustID(t, c); id.Value().Int != got {
+		t.Errorf("ID: got = %d, want = %d", id.Value().Int, got)
+	}
+
+	if got := p.Len(); got != 0 {
+		t.Errorf("Len(): got = %d after the Exec, want = 0", got)
+	}
+	if err := p.Exec(context.Background()); err != nil {
+		t.Errorf("Exec(): unexpected error for an empty pipeline: %v", err)
+	}
+}
+
+type countingConn struct {
+	net.Conn
+	writes *int64
+}
+
+func (c *countingConn) Write(b []byte) (int, error) {
+	atomic.AddInt64(c.writes, 1)
+	return c.Conn.Write(b)
+}
+
+func TestPipeline_batches(t *testing.T) {
+	var writes int64
+	c := newTestClient(t, Options{
+		PipelineBatchSize: 2,
+		Dial: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			var d net.Dialer
+			nc, err := d.DialContext(ctx, network, addr)
+			if err != nil {
+				return nil, err
+			}
+			return &countingConn{nc, &writes}, nil
+		},
+	})
+
+	p := c.Pipeline()
+	cmds := make([]*Cmd, 5)
+	for i := range cmds {
+		cmds[i] = p.Do("ECHO", i)
+	}
+	if err := p.Exec(context.Background()); err != nil {
+		t.Fatalf("Exec(): unexpected error: %v", err)
+	}
+
+	for i, cmd := range cmds {
+		if got := cmd.Value().Str; got != string(rune('0'+i)) {
+			t.Errorf("cmds[%d].Value(): got = %q, want = %q", i, got, string(rune('0'+i)))
+		}
+	}
+
+	if got := atomic.LoadInt64(&writes); got != 3 {
+		t.Errorf("got %d writes, want 3 batches", got)
+	}
+}
+
+func TestPipeline_Exec_error(t *testing.T) {
+	c := newTestClient(t, Options{})
+	p := c.Pipeline()
+
+	ping := p.Do("PING")
+	kill := p.Do("KILL")
+	echo := p.Do("ECHO", "lost")
+
+	if err := p.Exec(context.Background()); err != io.EOF {
+		t.Fatalf("Exec(): got error = %v, want error = %v", err, io.EOF)
+	}
+
+	if err := ping.Err(); err != nil {
+		t.Errorf("ping.Err(): unexpected error: %v", err)
+	}
+	if err := kill.Err(); err != io.EOF {
+		t.Errorf("kill.Err(): got error = %v, want error = %v", err, io.EOF)
+	}
+	if err := echo.Err(); err != io.EOF {
+		t.Errorf("echo.Err(): got error = %v, want error = %v", err, io.EOF)
+	}
+
+	// The broken connection is closed.
+	if stats := c.Stats(); stats.TotalConns != 0 {
+		t.Errorf("Stats(): got = %+v, want no connections", stats)
+	}
+}
@@ -0,0 +1,583 @@
+package radish
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+var errTrailingData = errors.New("radish: unexpected data after the value")
+
+// An UnmarshalTypeError describes a RESP value that was not appropriate for a
+// value of a specific Go type.
+type UnmarshalTypeError struct {
+	Value DataType     // The type of the RESP value.
+	Type  reflect.Type // The type of the Go value it could not be assigned to.
+}
+
+func (e *UnmarshalTypeError) Error() string {
+	return "radish: cannot unmarshal " + strconv.QuoteRune(rune(e.Value)) + " into Go value of type " + e.Type.String()
+}
+
+// An InvalidUnmarshalError describes an invalid argument passed to
+// Unmarshal. The argument must be a non-nil pointer.
+type InvalidUnmarshalError struct {
+	Type reflect.Type
+}
+
+func (e *InvalidUnmarshalError) Error() string {
+	if e.Type == nil {
+		return "radish: Unmarshal(nil)"
+	}
+	if e.Type.Kind() != reflect.Ptr {
+		return "radish: Unmarshal(non-pointer " + e.Type.String() + ")"
+	}
+	return "radish: Unmarshal(nil " + e.Type.String() + ")"
+}
+
+// Unmarshal parses the RESP value in the data and stores the result in the
+// value pointed to by the v.
+//
+// Unmarshal uses the inverse of the encodings that Marshal uses, allocating
+// maps, slices and pointers as necessary, with the following additional
+// rules:
+//   - strings, integers, doubles and big numbers are converted to each other
+//     if possible, since Redis often replies with numbers as bulk strings;
+//   - maps and structs are also decoded from flat arrays of keys and values,
+//     like RESP2 replies of HGETALL;
+//   - struct fields are matched by names from the "radish" tags or names of
+//     fields, preferring an exact match but also accepting a case-insensitive
+//     match; unknown keys are skipped;
+//   - nulls set pointers, maps, slices and interfaces to nil, and do not
+//     change other values;
+//   - an interface{} gets a string, int64, float64, bool, *big.Int, *Error,
+//     []interface{} or map[string]interface{};
+//   - attributes are skipped.
+//
+// If a RESP error is decoded into a value of any type except *Error and
+// interfaces, the error is returned. Such errors and values not appropriate
+// for the Go type do not stop Unmarshal: the value is skipped, the rest of
+// the input is decoded, and the first error is returned.
+func Unmarshal(data []byte, v interface{}) error {
+	r := bytes.NewReader(data)
+	d := NewDecoder(r)
+	if err := d.Decode(v); err != nil {
+		return err
+	}
+
+	if d.r.Buffered() > 0 || r.Len() > 0 {
+		return errTrailingData
+	}
+	return nil
+}
+
+// A Decoder reads and decodes RESP values from an input stream.
+type Decoder struct {
+	r *Reader
+
+	// savedError is the first error of a value that has been read
+	// completely, e.g. an UnmarshalTypeError. Decoding goes on after it, so
+	// the input stays at the start of the next value.
+	savedError error
+}
+
+// NewDecoder returns a new decoder that reads from the r.
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{
+		r: NewReader(r),
+	}
+}
+
+// Decode reads the next RESP value from its input and stores it in the value
+// pointed to by the v.
+//
+// See the documentation for Unmarshal for details about the conversion of
+// RESP into a Go value.
+func (d *Decoder) Decode(v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return &InvalidUnmarshalError{reflect.TypeOf(v)}
+	}
+
+	d.savedError = nil
+	if err := d.decode(rv.Elem(), 0); err != nil {
+		return err
+	}
+	return d.savedError
+}
+
+// saveError saves the first err of a value, see the savedError.
+func (d *Decoder) saveError(err error) {
+	if d.savedError == nil {
+		d.savedError = err
+	}
+}
+
+func (d *Decoder) decode(rv reflect.Value, depth int) error {
+	if depth > d.r.opts.MaxDepth {
+		return ErrMaxDepth
+	}
+
+	if rv.Kind() == reflect.Ptr && rv.Type().Implements(unmarshalerType) {
+		if rv.IsNil() {
+			rv.Set(reflect.New(rv.Type().Elem()))
+		}
+		return rv.Interface().(RESPUnmarshaler).UnmarshalRESP(d.r)
+	}
+	if rv.Kind() != reflect.Ptr && rv.CanAddr() && rv.Addr().Type().Implements(unmarshalerType) {
+		return rv.Addr().Interface().(RESPUnmarshaler).UnmarshalRESP(d.r)
+	}
+
+	// Length of the shortest value, e.g. "_\r\n" or ":1\r\n".
+	const minLength = 3
+
+	first, err := d.r.Peek(minLength)
+	if err != nil {
+		return err
+	}
+
+	dt := DataType(first[0])
+	null := dt == DataTypeRESP3Null || first[1] == '-' && !isScalarType(dt)
+
+	if rv.Kind() == reflect.Ptr && rv.Type() != errorType && dt != DataTypeAttribute {
+		if null {
+			rv.Set(reflect.Zero(rv.Type()))
+			_, err := d.r.ReadValue()
+			return err
+		}
+
+		if rv.IsNil() {
+			rv.Set(reflect.New(rv.Type().Elem()))
+		}
+		return d.decode(rv.Elem(), depth)
+	}
+
+	switch dt {
+	case DataTypeAttribute:
+		n, err := d.r.readAggregate(dt)
+		if err != nil {
+			return err
+		}
+		if err := d.skip(2 * n); err != nil {
+			return err
+		}
+		return d.decode(rv, depth+1)
+
+	case DataTypeArray, DataTypeSet, DataTypePush, DataTypeMap:
+		if rv.Type() == valueType {
+			break
+		}
+
+		n, err := d.r.readAggregate(dt)
+		if err != nil {
+			return err
+		}
+		if n < 0 {
+			setNull(rv)
+			return nil
+		}
+		if dt == DataTypeMap {
+			return d.decodePairs(rv, dt, n, depth)
+		}
+		return d.decodeArray(rv, dt, n, depth)
+	}
+
+	// Scalars and the Value are read as a whole.
+	v, err := d.r.ReadValue()
+	if err != nil {
+		return err
+	}
+	if err := assignValue(rv, &v); err != nil {
+		d.saveError(err)
+	}
+	return nil
+}
+
+// isScalarType reports whether the dt is a type with a value on the first
+// line, which may start with '-'.
+func isScalarType(dt DataType) bool {
+	switch dt {
+	case DataTypeSimpleString, DataTypeError, DataTypeInteger, DataTypeDouble, DataTypeBigNumber:
+		return true
+	}
+	return false
+}
+
+func setNull(rv reflect.Value) {
+	switch rv.Kind() {
+	case reflect.Ptr, reflect.Interface, reflect.Map, reflect.Slice:
+		rv.Set(reflect.Zero(rv.Type()))
+	}
+}
+
+// skip reads and discards n values.
+func (d *Decoder) skip(n int) error {
+	for i := 0; i < n; i++ {
+		if _, err := d.r.ReadValue(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// arrayPrealloc returns the capacity allocated in advance for n elements. The
+// announced length is not trusted, like in the ReadCommand.
+func arrayPrealloc(n int) int {
+	if n > maxArgsPrealloc {
+		return maxArgsPrealloc
+	}
+	return n
+}
+
+func (d *Decoder) decodeArray(rv reflect.Value, dt DataType, n, depth int) error {
+	switch rv.Kind() {
+	case reflect.Interface:
+		if rv.NumMethod() != 0 {
+			break
+		}
+
+		elems := make([]interface{}, 0, arrayPrealloc(n))
+		for i := 0; i < n; i++ {
+			elems = append(elems, nil)
+			if err := d.decode(reflect.ValueOf(&elems[i]).Elem(), depth+1); err != nil {
+				return err
+			}
+		}
+		rv.Set(reflect.ValueOf(elems))
+		return nil
+
+	case reflect.Slice:
+		slice := reflect.MakeSlice(rv.Type(), 0, arrayPrealloc(n))
+		for i := 0; i < n; i++ {
+			slice = reflect.Append(slice, reflect.Zero(rv.Type().Elem()))
+			if err := d.decode(slice.Index(i), depth+1); err != nil {
+				return err
+			}
+		}
+		rv.Set(slice)
+		return nil
+
+	case reflect.Array:
+		for i := 0; i < n; i++ {
+			if i >= rv.Len() {
+				if err := d.skip(1); err != nil {
+					return err
+				}
+				continue
+			}
+			if err := d.decode(rv.Index(i), depth+1); err != nil {
+				return err
+			}
+		}
+		for i := n; i < rv.Len(); i++ {
+			rv.Index(i).Set(reflect.Zero(rv.Type().Elem()))
+		}
+		return nil
+
+	case reflect.Map, reflect.Struct:
+		// A flat array of keys and values.
+		if n%2 == 0 {
+			return d.decodePairs(rv, dt, n/2, depth)
+		}
+	}
+
+	if err := d.skip(n); err != nil {
+		return err
+	}
+	d.saveError(&UnmarshalTypeError{dt, rv.Type()})
+	return nil
+}
+
+func (d *Decoder) decodePairs(rv reflect.Value, dt DataType, n, depth int) error {
+	switch rv.Kind() {
+	case reflect.Interface:
+		if rv.NumMethod() != 0 {
+			break
+		}
+
+		m := make(map[string]interface{})
+		for i := 0; i < n; i++ {
+			var key, value interface{}
+			if err := d.decode(reflect.ValueOf(&key).Elem(), depth+1); err != nil {
+				return err
+			}
+			if err := d.decode(reflect.ValueOf(&value).Elem(), depth+1); err != nil {
+				return err
+			}
+
+			if s, ok := key.(string); ok {
+				m[s] = value
+			} else {
+				m[fmt.Sprint(key)] = value
+			}
+		}
+		rv.Set(reflect.ValueOf(m))
+		return nil
+
+	case reflect.Map:
+		if rv.IsNil() {
+			rv.Set(reflect.MakeMap(rv.Type()))
+		}
+
+		for i := 0; i < n; i++ {
+			key := reflect.New(rv.Type().Key()).Elem()
+			if err := d.decode(key, depth+1); err != nil {
+				return err
+			}
+			value := reflect.New(rv.Type().Elem()).Elem()
+			if err := d.decode(value, depth+1); err != nil {
+				return err
+			}
+			rv.SetMapIndex(key, value)
+		}
+		return nil
+
+	case reflect.Struct:
+		fields := cachedStructFields(rv.Type())
+
+		for i := 0; i < n; i++ {
+			var key string
+			if err := d.decode(reflect.ValueOf(&key).Elem(), depth+1); err != nil {
+				return err
+			}
+
+			f := lookupStructField(fields, key)
+			if f == nil {
+				if err := d.skip(1); err != nil {
+					return err
+				}
+				continue
+			}
+
+			if err := d.decode(rv.FieldByIndex(f.index), depth+1); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if err := d.skip(2 * n); err != nil {
+		return err
+	}
+	d.saveError(&UnmarshalTypeError{dt, rv.Type()})
+	return nil
+}
+
+func lookupStructField(fields []structField, name string) *structField {
+	for i := range fields {
+		if fields[i].name == name {
+			return &fields[i]
+		}
+	}
+	for i := range fields {
+		if strings.EqualFold(fields[i].name, name) {
+			return &fields[i]
+		}
+	}
+	return nil
+}
+
+// assignValue assigns the scalar v to the rv.
+func assignValue(rv reflect.Value, v *Value) error {
+	if rv.Type() == valueType {
+		rv.Set(reflect.ValueOf(*v))
+		return nil
+	}
+
+	if v.IsNull() {
+		setNull(rv)
+		return nil
+	}
+
+	if v.Err != nil {
+		switch {
+		case rv.Type() == errorType:
+			rv.Set(reflect.ValueOf(v.Err))
+			return nil
+		case rv.Kind() == reflect.Interface && errorType.Implements(rv.Type()):
+			rv.Set(reflect.ValueOf(v.Err))
+			return nil
+		default:
+			return v.Err
+		}
+	}
+
+	if rv.Type() == bigIntType {
+		n, ok := valueBigInt(v)
+		if !ok {
+			return &UnmarshalTypeError{v.Type, rv.Type()}
+		}
+		rv.Set(reflect.ValueOf(*n))
+		return nil
+	}
+
+	switch rv.Kind() {
+	case reflect.Interface:
+		if rv.NumMethod() != 0 {
+			break
+		}
+
+		switch v.Type {
+		case DataTypeInteger:
+			rv.Set(reflect.ValueOf(v.Int))
+		case DataTypeBoolean:
+			rv.Set(reflect.ValueOf(v.Bool))
+		case DataTypeDouble:
+			rv.Set(reflect.ValueOf(v.Double))
+		case DataTypeBigNumber:
+			rv.Set(reflect.ValueOf(v.BigNumber))
+		default:
+			rv.Set(reflect.ValueOf(v.Str))
+		}
+		return nil
+
+	case reflect.String:
+		if s, ok := valueString(v); ok {
+			rv.SetString(s)
+			return nil
+		}
+
+	case reflect.Slice:
+		if rv.Type().Elem().Kind() != reflect.Uint8 {
+			break
+		}
+		if s, ok := valueString(v); ok {
+			rv.SetBytes([]byte(s))
+			return nil
+		}
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, ok := valueInt(v)
+		if ok && !rv.OverflowInt(i) {
+			rv.SetInt(i)
+			return nil
+		}
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		i, ok := valueUint(v)
+		if ok && !rv.OverflowUint(i) {
+			rv.SetUint(i)
+			return nil
+		}
+
+	case reflect.Float32, reflect.Float64:
+		f, ok := valueFloat(v)
+		if ok {
+			rv.SetFloat(f)
+			return nil
+		}
+
+	case reflect.Bool:
+		b, ok := valueBool(v)
+		if ok {
+			rv.SetBool(b)
+			return nil
+		}
+	}
+
+	return &UnmarshalTypeError{v.Type, rv.Type()}
+}
+
+func isStringType(dt DataType) bool {
+	switch dt {
+	case DataTypeSimpleString, DataTypeBulkString, DataTypeVerbatimString:
+		return true
+	}
+	return false
+}
+
+func valueString(v *Value) (string, bool) {
+	switch {
+	case isStringType(v.Type):
+		return v.Str, true
+	case v.Type == DataTypeInteger:
+		return strconv.FormatInt(v.Int, 10), true
+	case v.Type == DataTypeDouble:
+		return string(appendFloat(nil, v.Double, 64)), true
+	case v.Type == DataTypeBigNumber:
+		return v.BigNumber.String(), true
+	}
+	return "", false
+}
+
+func valueInt(v *Value) (int64, bool) {
+	switch {
+	case v.Type == DataTypeInteger:
+		return v.Int, true
+	case isStringType(v.Type):
+		i, err := ParseInt([]byte(v.Str))
+		return i, err == nil
+	case v.Type == DataTypeBigNumber && v.BigNumber.IsInt64():
+		return v.BigNumber.Int64(), true
+	}
+	return 0, false
+}
+
+func valueUint(v *Value) (uint64, bool) {
+	switch {
+	case v.Type == DataTypeInteger && v.Int >= 0:
+		return uint64(v.Int), true
+	case isStringType(v.Type):
+		i, err := parseUint(v.Str)
+		return i, err == nil
+	case v.Type == DataTypeBigNumber && v.BigNumber.IsUint64():
+		return v.BigNumber.Uint64(), true
+	}
+	return 0, false
+}
+
+// parseUint interprets the s as a decimal unsigned 64-bit integer, as strict
+// as the ParseInt.
+func parseUint(s string) (uint64, error) {
+	if len(s) == 0 || len(s) > 1 && s[0] == '0' {
+		return 0, errValue
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return 0, errValue
+		}
+	}
+	return strconv.ParseUint(s, 10, 64)
+}
+
+func valueFloat(v *Value) (float64, bool) {
+	switch {
+	case v.Type == DataTypeDouble:
+		return v.Double, true
+	case v.Type == DataTypeInteger:
+		return float64(v.Int), true
+	case isStringType(v.Type):
+		f, err := strconv.ParseFloat(v.Str, 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+func valueBool(v *Value) (bool, bool) {
+	switch {
+	case v.Type == DataTypeBoolean:
+		return v.Bool, true
+	case v.Type == DataTypeInteger && (v.Int == 0 || v.Int == 1):
+		return v.Int == 1, true
+	case isStringType(v.Type) && (v.Str == "0" || v.Str == "1"):
+		return v.Str == "1", true
+	}
+	return false, false
+}
+
+func valueBigInt(v *Value) (*big.Int, bool) {
+	switch {
+	case v.Type == DataTypeBigNumber:
+		return v.BigNumber, true
+	case v.Type == DataTypeInteger:
+		return big.NewInt(v.Int), true
+	case isStringType(v.Type):
+		return new(big.Int).SetString(v.Str, 10)
+	}
+	return nil, false
+}
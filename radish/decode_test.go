@@ -0,0 +1,198 @@
+package radish
+
+import (
+	"math"
+	"math/big"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestUnmarshal(t *testing.T) {
+	tt := []struct {
+		name  string
+		input string
+		v     interface{} // A pointer to the decoded value.
+		want  interface{}
+	}{
+		{"string", "$5\r\nhello\r\n", new(string), "hello"},
+		{"simple string", "+OK\r\n", new(string), "OK"},
+		{"string from integer", ":42\r\n", new(string), "42"},
+		{"string from double", ",1.5\r\n", new(string), "1.5"},
+		{"bytes", "$2\r\nhi\r\n", new([]byte), []byte("hi")},
+		{"int", ":-42\r\n", new(int), -42},
+		{"int from string", "$3\r\n-42\r\n", new(int64), int64(-42)},
+		{"uint", ":42\r\n", new(uint8), uint8(42)},
+		{"float", ",1.5\r\n", new(float64), 1.5},
+		{"float from string", "$3\r\ninf\r\n", new(float64), math.Inf(1)},
+		{"float from integer", ":2\r\n", new(float32), float32(2)},
+		{"bool", "#t\r\n", new(bool), true},
+		{"bool from integer", ":0\r\n", new(bool), false},
+		{"big int", "(12345678901234567890123\r\n", new(*big.Int), mustBigInt("12345678901234567890123")},
+		{"big int from string", "$2\r\n12\r\n", new(big.Int), *big.NewInt(12)},
+		{"pointer", ":7\r\n", new(*int), func() *int { i := 7; return &i }()},
+		{"null pointer", "$-1\r\n", func() interface{} { i := 7; p := &i; return &p }(), (*int)(nil)},
+		{"null string", "_\r\n", func() interface{} { s := "keep"; return &s }(), "keep"},
+		{"slice", "*2\r\n$1\r\na\r\n$1\r\nb\r\n", new([]string), []string{"a", "b"}},
+		{"null slice", "*-1\r\n", func() interface{} { s := []string{"a"}; return &s }(), []string(nil)},
+		{"set", "~2\r\n:1\r\n:2\r\n", new([]int), []int{1, 2}},
+		{"array", "*3\r\n:1\r\n:2\r\n:3\r\n", new([2]int), [2]int{1, 2}},
+		{"short array", "*1\r\n:1\r\n", func() interface{} { a := [2]int{5, 5}; return &a }(), [2]int{1, 0}},
+		{"map", "%2\r\n+a\r\n:1\r\n+b\r\n:2\r\n", new(map[string]int), map[string]int{"a": 1, "b": 2}},
+		{"map from array", "*4\r\n$1\r\na\r\n$1\r\n1\r\n$1\r\nb\r\n$1\r\n2\r\n", new(map[string]int), map[string]int{"a": 1, "b": 2}},
+		{
+			"struct",
+			"*10\r\n$2\r\nid\r\n$1\r\n1\r\n$4\r\nNAME\r\n$1\r\nn\r\n$7\r\nunknown\r\n*1\r\n:1\r\n$4\r\ntags\r\n*1\r\n$1\r\nt\r\n$4\r\nnext\r\n%1\r\n+score\r\n,2.5\r\n",
+			new(testStruct),
+			testStruct{
+				testEmbedded: testEmbedded{ID: 1},
+				Name:         "n",
+				Tags:         []string{"t"},
+				Next:         &testStruct{Score: 2.5},
+			},
+		},
+		{
+			"interface",
+			"*5\r\n+a\r\n:1\r\n#t\r\n%1\r\n+k\r\n,1.5\r\n_\r\n",
+			new(interface{}),
+			[]interface{}{"a", int64(1), true, map[string]interface{}{"k": 1.5}, nil},
+		},
+		{"error", "-ERR failed\r\n", new(*Error), &Error{"ERR", "failed"}},
+		{"error interface", "-ERR failed\r\n", new(error), error(&Error{"ERR", "failed"})},
+		{"value", "*1\r\n:1\r\n", new(Value), Value{Type: DataTypeArray, Elems: []Value{{Type: DataTypeInteger, Int: 1}}}},
+		{"attribute", "|1\r\n+ttl\r\n:1\r\n$1\r\na\r\n", new(string), "a"},
+		{"unmarshaler", "*2\r\n+1:a\r\n+2:b\r\n", new([]testMarshaler), []testMarshaler{{1, "a"}, {2, "b"}}},
+		{"pointer unmarshaler", "+3:c\r\n", new(*testMarshaler), &testMarshaler{3, "c"}},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := Unmarshal([]byte(tc.input), tc.v); err != nil {
+				t.Fatalf("Unmarshal(): unexpected error: %v", err)
+			}
+
+			got := reflect.ValueOf(tc.v).Elem().Interface()
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("Unmarshal() = %#v, want %#v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestUnmarshal_errors(t *testing.T) {
+	tt := []struct {
+		name  string
+		input string
+		v     interface{}
+		want  string
+	}{
+		{"nil", ":1\r\n", nil, "radish: Unmarshal(nil)"},
+		{"non-pointer", ":1\r\n", 1, "radish: Unmarshal(non-pointer int)"},
+		{"nil pointer", ":1\r\n", (*int)(nil), "radish: Unmarshal(nil *int)"},
+		{"type mismatch", "+abc\r\n", new(int), `radish: cannot unmarshal '+' into Go value of type int`},
+		{"overflow", ":256\r\n", new(uint8), `radish: cannot unmarshal ':' into Go value of type uint8`},
+		{"array into string", "*1\r\n:1\r\n", new(string), `radish: cannot unmarshal '*' into Go value of type string`},
+		{"odd array into map", "*1\r\n:1\r\n", new(map[string]int), `radish: cannot unmarshal '*' into Go value of type map[string]int`},
+		{"reply error", "-WRONGTYPE wrong\r\n", new(string), "radish: WRONGTYPE wrong"},
+		{"nested reply error", "*1\r\n-ERR failed\r\n", new([]string), "radish: ERR failed"},
+		{"trailing data", ":1\r\n:2\r\n", new(int), "radish: unexpected data after the value"},
+		{"truncated", "*2\r\n:1\r\n", new([]int), "EOF"},
+		{"huge array into interface", "*2147483647\r\n:1\r\n", new(interface{}), "EOF"},
+		{"int with a plus sign", "$2\r\n+1\r\n", new(int), `radish: cannot unmarshal '$' into Go value of type int`},
+		{"int with a leading zero", "$2\r\n01\r\n", new(int64), `radish: cannot unmarshal '$' into Go value of type int64`},
+		{"negative zero", "$2\r\n-0\r\n", new(int), `radish: cannot unmarshal '$' into Go value of type int`},
+		{"uint with a plus sign", "$2\r\n+1\r\n", new(uint), `radish: cannot unmarshal '$' into Go value of type uint`},
+		{"uint with a leading zero", "$2\r\n01\r\n", new(uint64), `radish: cannot unmarshal '$' into Go value of type uint64`},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			err := Unmarshal([]byte(tc.input), tc.v)
+			if err == nil || err.Error() != tc.want {
+				t.Errorf("Unmarshal() error = %v, want %s", err, tc.want)
+			}
+		})
+	}
+}
+
+func TestDecoder(t *testing.T) {
+	dec := NewDecoder(strings.NewReader("*2\r\n:1\r\n+x\r\n+OK\r\n:3\r\n"))
+
+	var (
+		a  []interface{}
+		ok string
+		n  int
+	)
+	for i, v := range []interface{}{&a, &ok, &n} {
+		if err := dec.Decode(v); err != nil {
+			t.Fatalf("Decode() #%d: unexpected error: %v", i, err)
+		}
+	}
+
+	if !reflect.DeepEqual(a, []interface{}{int64(1), "x"}) || ok != "OK" || n != 3 {
+		t.Errorf("Decode() = %v, %q, %d, want [1 x], OK, 3", a, ok, n)
+	}
+}
+
+func TestDecoder_errors(t *testing.T) {
+	tt := []struct {
+		name  string
+		input string
+		v     interface{}
+		want  string
+	}{
+		{"slice element", "*3\r\n+abc\r\n:1\r\n:2\r\n", new([]int), `radish: cannot unmarshal '+' into Go value of type int`},
+		{"nested reply error", "*3\r\n:1\r\n-ERR failed\r\n:2\r\n", new([]int), "radish: ERR failed"},
+		{"array element", "*3\r\n:1\r\n*1\r\n:2\r\n:3\r\n", new([3]int), `radish: cannot unmarshal '*' into Go value of type int`},
+		{"map value", "%2\r\n+a\r\n+x\r\n+b\r\n:2\r\n", new(map[string]int), `radish: cannot unmarshal '+' into Go value of type int`},
+		{"struct field", "*4\r\n$2\r\nid\r\n+x\r\n$4\r\nname\r\n$1\r\nn\r\n", new(testStruct), `radish: cannot unmarshal '+' into Go value of type int`},
+		{"map into slice", "%1\r\n+a\r\n%1\r\n+k\r\n-ERR failed\r\n", new([]int), `radish: cannot unmarshal '%' into Go value of type []int`},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			// The next value must be decoded after the error.
+			dec := NewDecoder(strings.NewReader(tc.input + "+NEXT\r\n"))
+
+			err := dec.Decode(tc.v)
+			if err == nil || err.Error() != tc.want {
+				t.Errorf("Decode() error = %v, want %s", err, tc.want)
+			}
+
+			var next string
+			if err := dec.Decode(&next); err != nil || next != "NEXT" {
+				t.Errorf("the next Decode() = %q, %v, want %q", next, err, "NEXT")
+			}
+		})
+	}
+}
+
+func TestMarshal_roundTrip(t *testing.T) {
+	v := testStruct{
+		testEmbedded: testEmbedded{ID: 1},
+		Name:         "name",
+		Tags:         []string{"a", "b"},
+		Score:        -1.25,
+		Active:       true,
+		Attrs:        map[string]string{"k": "v"},
+		Next:         &testStruct{Name: "next"},
+		Untagged:     2,
+	}
+
+	for _, protover := range []int{RESP2, RESP3} {
+		var buf strings.Builder
+		enc := NewEncoder(&buf)
+		enc.SetProtocol(protover)
+		if err := enc.Encode(v); err != nil {
+			t.Fatalf("Encode(): unexpected error: %v", err)
+		}
+
+		var got testStruct
+		if err := Unmarshal([]byte(buf.String()), &got); err != nil {
+			t.Fatalf("Unmarshal(): unexpected error: %v", err)
+		}
+		if !reflect.DeepEqual(got, v) {
+			t.Errorf("RESP%d round trip = %+v, want %+v", protover, got, v)
+		}
+	}
+}
@@ -0,0 +1,353 @@
+package radish
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math/big"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// RESPMarshaler is the interface implemented by types that can write
+// themselves as RESP values.
+type RESPMarshaler interface {
+	MarshalRESP(w *Writer) error
+}
+
+// RESPUnmarshaler is the interface implemented by types that can read a RESP
+// value of themselves.
+//
+// UnmarshalRESP must read exactly one value from the r, including all its
+// nested values.
+type RESPUnmarshaler interface {
+	UnmarshalRESP(r *Reader) error
+}
+
+// An UnsupportedTypeError is returned by Marshal when attempting to encode an
+// unsupported value type.
+type UnsupportedTypeError struct {
+	Type reflect.Type
+}
+
+func (e *UnsupportedTypeError) Error() string {
+	return "radish: unsupported type: " + e.Type.String()
+}
+
+var (
+	errorType       = reflect.TypeOf((*Error)(nil))
+	valueType       = reflect.TypeOf(Value{})
+	bigIntType      = reflect.TypeOf(big.Int{})
+	marshalerType   = reflect.TypeOf((*RESPMarshaler)(nil)).Elem()
+	unmarshalerType = reflect.TypeOf((*RESPUnmarshaler)(nil)).Elem()
+)
+
+// Marshal returns the RESP encoding of the v in RESP2.
+//
+// Marshal encodes values as follows:
+//   - strings and byte slices as bulk strings, nil byte slices as nulls;
+//   - signed and unsigned integers as integers;
+//   - floats as doubles, bools as booleans and *big.Int as big numbers;
+//   - slices and arrays as arrays, nil slices as null arrays;
+//   - maps as maps with keys sorted like fmt prints them;
+//   - structs as maps of exported fields;
+//   - *Error as an error and Value as is;
+//   - nil pointers and interfaces as nulls.
+//
+// RESP3 types are downgraded in RESP2, see the Writer.SetProtocol. Values
+// implementing the RESPMarshaler write themselves.
+//
+// The encoding of each struct field can be customized by the "radish" key in
+// the struct field's tag: the name of the field, and the "omitempty" option
+// to skip the field if it has an empty value. The "-" skips the field.
+//
+//	Field int `radish:"field,omitempty"`
+//
+// Fields of embedded structs are encoded as fields of the outer struct.
+func Marshal(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := NewEncoder(&buf).Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// An Encoder writes RESP values of Go values to an output stream.
+type Encoder struct {
+	out io.Writer
+
+	// Values are encoded into the buf first, so that nothing is written to
+	// the out if encoding fails part-way through.
+	buf bytes.Buffer
+	w   *Writer
+}
+
+// NewEncoder returns a new encoder that writes to the w.
+func NewEncoder(w io.Writer) *Encoder {
+	e := &Encoder{out: w}
+	e.w = NewWriter(&e.buf)
+	return e
+}
+
+// SetProtocol sets the version of the RESP protocol of the encoder, see the
+// Writer.SetProtocol.
+func (e *Encoder) SetProtocol(protover int) {
+	e.w.SetProtocol(protover)
+}
+
+// Encode writes the RESP encoding of the v to the stream and flushes it.
+//
+// See the documentation for Marshal for details about the conversion of Go
+// values to RESP.
+func (e *Encoder) Encode(v interface{}) error {
+	defer e.buf.Reset()
+
+	if err := encodeValue(e.w, reflect.ValueOf(v)); err != nil {
+		e.w.Reset(&e.buf) // Discard the partial value.
+		return err
+	}
+	if err := e.w.Flush(); err != nil {
+		return err
+	}
+
+	_, err := e.out.Write(e.buf.Bytes())
+	return err
+}
+
+func encodeValue(w *Writer, rv reflect.Value) error {
+	if !rv.IsValid() {
+		return w.WriteNull()
+	}
+
+	if rv.Type().Implements(marshalerType) {
+		if (rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface) && rv.IsNil() {
+			return w.WriteNull()
+		}
+		return rv.Interface().(RESPMarshaler).MarshalRESP(w)
+	}
+	if rv.Kind() != reflect.Ptr && rv.CanAddr() && rv.Addr().Type().Implements(marshalerType) {
+		return rv.Addr().Interface().(RESPMarshaler).MarshalRESP(w)
+	}
+
+	switch rv.Type() {
+	case errorType:
+		if rv.IsNil() {
+			return w.WriteNull()
+		}
+		return w.WriteError(rv.Interface().(*Error))
+
+	case valueType:
+		return w.WriteValue(rv.Interface().(Value))
+
+	case bigIntType:
+		n := rv.Interface().(big.Int)
+		return w.WriteBigNumber(&n)
+	}
+
+	switch rv.Kind() {
+	case reflect.String:
+		return w.WriteString(rv.String())
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return w.WriteInt64(rv.Int())
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return w.WriteUint64(rv.Uint())
+
+	case reflect.Float32, reflect.Float64:
+		return w.WriteDouble(rv.Float())
+
+	case reflect.Bool:
+		return w.WriteBool(rv.Bool())
+
+	case reflect.Ptr, reflect.Interface:
+		if rv.IsNil() {
+			return w.WriteNull()
+		}
+		return encodeValue(w, rv.Elem())
+
+	case reflect.Slice:
+		if rv.Type().Elem().Kind() == reflect.Uint8 {
+			if rv.IsNil() {
+				return w.WriteNull()
+			}
+			return w.WriteBytes(rv.Bytes())
+		}
+		if rv.IsNil() {
+			return w.WriteArray(-1)
+		}
+		return encodeArray(w, rv)
+
+	case reflect.Array:
+		if rv.Type().Elem().Kind() == reflect.Uint8 {
+			b := make([]byte, rv.Len())
+			reflect.Copy(reflect.ValueOf(b), rv)
+			return w.WriteBytes(b)
+		}
+		return encodeArray(w, rv)
+
+	case reflect.Map:
+		return encodeMap(w, rv)
+
+	case reflect.Struct:
+		return encodeStruct(w, rv)
+
+	default:
+		return &UnsupportedTypeError{rv.Type()}
+	}
+}
+
+func encodeArray(w *Writer, rv reflect.Value) error {
+	_ = w.WriteArray(rv.Len())
+	for i := 0; i < rv.Len(); i++ {
+		if err := encodeValue(w, rv.Index(i)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func encodeMap(w *Writer, rv reflect.Value) error {
+	keys := rv.MapKeys()
+	sort.Slice(keys, func(i, j int) bool {
+		return lessMapKey(keys[i], keys[j])
+	})
+
+	_ = w.WriteMap(len(keys))
+	for _, key := range keys {
+		if err := encodeValue(w, key); err != nil {
+			return err
+		}
+		if err := encodeValue(w, rv.MapIndex(key)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func lessMapKey(a, b reflect.Value) bool {
+	switch a.Kind() {
+	case reflect.String:
+		return a.String() < b.String()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return a.Int() < b.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return a.Uint() < b.Uint()
+	case reflect.Float32, reflect.Float64:
+		return a.Float() < b.Float()
+	default:
+		return fmt.Sprint(a.Interface()) < fmt.Sprint(b.Interface())
+	}
+}
+
+func encodeStruct(w *Writer, rv reflect.Value) error {
+	fields := cachedStructFields(rv.Type())
+
+	n := 0
+	for i := range fields {
+		if !fields[i].omit(rv) {
+			n++
+		}
+	}
+
+	_ = w.WriteMap(n)
+	for i := range fields {
+		f := &fields[i]
+		if f.omit(rv) {
+			continue
+		}
+
+		_ = w.WriteString(f.name)
+		if err := encodeValue(w, rv.FieldByIndex(f.index)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// structField is an encoded field of a struct.
+type structField struct {
+	name      string
+	index     []int
+	omitEmpty bool
+}
+
+func (f *structField) omit(rv reflect.Value) bool {
+	return f.omitEmpty && isEmptyValue(rv.FieldByIndex(f.index))
+}
+
+func isEmptyValue(rv reflect.Value) bool {
+	switch rv.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return rv.Len() == 0
+	case reflect.Bool:
+		return !rv.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return rv.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return rv.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return rv.Float() == 0
+	case reflect.Interface, reflect.Ptr:
+		return rv.IsNil()
+	default:
+		return false
+	}
+}
+
+var structFieldsCache sync.Map // map[reflect.Type][]structField
+
+func cachedStructFields(t reflect.Type) []structField {
+	if fields, ok := structFieldsCache.Load(t); ok {
+		return fields.([]structField)
+	}
+
+	fields, _ := structFieldsCache.LoadOrStore(t, typeStructFields(t, nil))
+	return fields.([]structField)
+}
+
+func typeStructFields(t reflect.Type, index []int) []structField {
+	var fields []structField
+
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+
+		tag := sf.Tag.Get("radish")
+		if tag == "-" {
+			continue
+		}
+
+		name, opts := tag, ""
+		if i := strings.IndexByte(tag, ','); i >= 0 {
+			name, opts = tag[:i], tag[i+1:]
+		}
+
+		fieldIndex := make([]int, len(index)+1)
+		copy(fieldIndex, index)
+		fieldIndex[len(index)] = i
+
+		// Promote fields of embedded structs.
+		if sf.Anonymous && name == "" && sf.Type.Kind() == reflect.Struct {
+			fields = append(fields, typeStructFields(sf.Type, fieldIndex)...)
+			continue
+		}
+
+		if sf.PkgPath != "" { // Unexported.
+			continue
+		}
+
+		if name == "" {
+			name = sf.Name
+		}
+
+		fields = append(fields, structField{
+			name:      name,
+			index:     fieldIndex,
+			omitEmpty: opts == "omitempty",
+		})
+	}
+
+	return fields
+}
@@ -0,0 +1,243 @@
+package radish
+
+import (
+	"bytes"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+type testEmbedded struct {
+	ID int `radish:"id"`
+}
+
+type testStruct struct {
+	testEmbedded
+	Name     string            `radish:"name"`
+	Tags     []string          `radish:"tags,omitempty"`
+	Score    float64           `radish:"score"`
+	Active   bool              `radish:"active,omitempty"`
+	Attrs    map[string]string `radish:"attrs,omitempty"`
+	Next     *testStruct       `radish:"next,omitempty"`
+	Skipped  string            `radish:"-"`
+	Untagged int
+	private  int
+}
+
+// testMarshaler writes itself as "<n>:<s>".
+type testMarshaler struct {
+	N int
+	S string
+}
+
+func (m testMarshaler) MarshalRESP(w *Writer) error {
+	return w.WriteSimpleString(strconv.Itoa(m.N) + ":" + m.S)
+}
+
+func (m *testMarshaler) UnmarshalRESP(r *Reader) error {
+	s, err := r.ReadSimpleString()
+	if err != nil {
+		return err
+	}
+
+	i := strings.IndexByte(s, ':')
+	if i < 0 {
+		return &Error{"ERR", "invalid testMarshaler"}
+	}
+
+	m.N, err = strconv.Atoi(s[:i])
+	m.S = s[i+1:]
+	return err
+}
+
+var testMarshals = []struct {
+	name      string
+	v         interface{}
+	want      string
+	wantRESP3 string // Defaults to the want.
+}{
+	{
+		name:      "nil",
+		v:         nil,
+		want:      "$-1\r\n",
+		wantRESP3: "_\r\n",
+	},
+	{
+		name: "string",
+		v:    "hello",
+		want: "$5\r\nhello\r\n",
+	},
+	{
+		name: "bytes",
+		v:    []byte("hi\r\n"),
+		want: "$4\r\nhi\r\n\r\n",
+	},
+	{
+		name:      "nil bytes",
+		v:         []byte(nil),
+		want:      "$-1\r\n",
+		wantRESP3: "_\r\n",
+	},
+	{
+		name: "byte array",
+		v:    [2]byte{'h', 'i'},
+		want: "$2\r\nhi\r\n",
+	},
+	{
+		name: "int",
+		v:    int8(-42),
+		want: ":-42\r\n",
+	},
+	{
+		name: "uint",
+		v:    uint64(math.MaxUint64),
+		want: ":18446744073709551615\r\n",
+	},
+	{
+		name:      "float",
+		v:         1.5,
+		want:      "$3\r\n1.5\r\n",
+		wantRESP3: ",1.5\r\n",
+	},
+	{
+		name:      "bool",
+		v:         true,
+		want:      ":1\r\n",
+		wantRESP3: "#t\r\n",
+	},
+	{
+		name:      "big int",
+		v:         big.NewInt(-7),
+		want:      "$2\r\n-7\r\n",
+		wantRESP3: "(-7\r\n",
+	},
+	{
+		name: "pointer",
+		v:    func() *int { i := 7; return &i }(),
+		want: ":7\r\n",
+	},
+	{
+		name:      "nil pointer",
+		v:         (*int)(nil),
+		want:      "$-1\r\n",
+		wantRESP3: "_\r\n",
+	},
+	{
+		name: "slice",
+		v:    []interface{}{"a", 1, []int{2}},
+		want: "*3\r\n$1\r\na\r\n:1\r\n*1\r\n:2\r\n",
+	},
+	{
+		name:      "nil slice",
+		v:         []string(nil),
+		want:      "*-1\r\n",
+		wantRESP3: "_\r\n",
+	},
+	{
+		name:      "map",
+		v:         map[string]int{"b": 2, "a": 1},
+		want:      "*4\r\n$1\r\na\r\n:1\r\n$1\r\nb\r\n:2\r\n",
+		wantRESP3: "%2\r\n$1\r\na\r\n:1\r\n$1\r\nb\r\n:2\r\n",
+	},
+	{
+		name:      "int keys",
+		v:         map[int]bool{10: true, 2: false},
+		want:      "*4\r\n:2\r\n:0\r\n:10\r\n:1\r\n",
+		wantRESP3: "%2\r\n:2\r\n#f\r\n:10\r\n#t\r\n",
+	},
+	{
+		name: "struct",
+		v: testStruct{
+			testEmbedded: testEmbedded{ID: 1},
+			Name:         "n",
+			Score:        2,
+			Skipped:      "skipped",
+			Untagged:     3,
+			private:      4,
+		},
+		want:      "*8\r\n$2\r\nid\r\n:1\r\n$4\r\nname\r\n$1\r\nn\r\n$5\r\nscore\r\n$1\r\n2\r\n$8\r\nUntagged\r\n:3\r\n",
+		wantRESP3: "%4\r\n$2\r\nid\r\n:1\r\n$4\r\nname\r\n$1\r\nn\r\n$5\r\nscore\r\n,2\r\n$8\r\nUntagged\r\n:3\r\n",
+	},
+	{
+		name: "error",
+		v:    &Error{"WRONGTYPE", "wrong type"},
+		want: "-WRONGTYPE wrong type\r\n",
+	},
+	{
+		name: "value",
+		v:    Value{Type: DataTypeSimpleString, Str: "OK"},
+		want: "+OK\r\n",
+	},
+	{
+		name: "marshaler",
+		v:    []testMarshaler{{1, "a"}},
+		want: "*1\r\n+1:a\r\n",
+	},
+	{
+		name: "pointer marshaler",
+		v:    &testMarshaler{2, "b"},
+		want: "+2:b\r\n",
+	},
+}
+
+func TestMarshal(t *testing.T) {
+	for _, tc := range testMarshals {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := Marshal(tc.v)
+			if err != nil {
+				t.Fatalf("Marshal(): unexpected error: %v", err)
+			}
+			if string(got) != tc.want {
+				t.Errorf("Marshal() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestEncoder_resp3(t *testing.T) {
+	for _, tc := range testMarshals {
+		t.Run(tc.name, func(t *testing.T) {
+			want := tc.wantRESP3
+			if want == "" {
+				want = tc.want
+			}
+
+			var buf bytes.Buffer
+			enc := NewEncoder(&buf)
+			enc.SetProtocol(RESP3)
+
+			if err := enc.Encode(tc.v); err != nil {
+				t.Fatalf("Encode(): unexpected error: %v", err)
+			}
+			if buf.String() != want {
+				t.Errorf("Encode() = %q, want %q", buf.String(), want)
+			}
+		})
+	}
+}
+
+func TestMarshal_unsupported(t *testing.T) {
+	_, err := Marshal([]interface{}{make(chan int)})
+	if _, ok := err.(*UnsupportedTypeError); !ok {
+		t.Errorf("Marshal() error = %v, want an *UnsupportedTypeError", err)
+	}
+}
+
+func TestEncoder_error(t *testing.T) {
+	var buf bytes.Buffer
+	enc := NewEncoder(&buf)
+
+	if err := enc.Encode([]interface{}{"a", make(chan int)}); err == nil {
+		t.Fatal("Encode(): expected an error")
+	}
+	if err := enc.Encode("b"); err != nil {
+		t.Fatalf("Encode(): unexpected error: %v", err)
+	}
+
+	// Nothing of the failed value is written.
+	if want := "$1\r\nb\r\n"; buf.String() != want {
+		t.Errorf("Encode() = %q, want %q", buf.String(), want)
+	}
+}
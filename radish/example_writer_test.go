@@ -48,3 +48,18 @@ func ExampleWriter() {
 	// Error: "-ERR unknown command 'GO'\r\n"
 	// Array: "*3\r\n$3\r\nSET\r\n$5\r\nmykey\r\n$7\r\nmyvalue\r\n"
 }
+
+func ExampleWriter_WriteCommand() {
+	var output strings.Builder
+	writer := radish.NewWriter(&output)
+
+	_ = writer.WriteCommand("ZADD", "myzset", 1.5, "one")
+	if err := writer.Flush(); err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Printf("%q\n", output.String())
+
+	// Output:
+	// "*4\r\n$4\r\nZADD\r\n$6\r\nmyzset\r\n$3\r\n1.5\r\n$3\r\none\r\n"
+}
@@ -5,13 +5,41 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"math"
+	"math/big"
+	"strconv"
 	"sync"
 )
 
+// A ProtocolError describes malformed RESP input. After it the state of the
+// Reader is unknown. It is reported to the peer as the RESP error with the same
+// Kind and Msg, see the RESPError.
+type ProtocolError struct {
+	Kind string
+	Msg  string
+}
+
+func (e *ProtocolError) Error() string {
+	return e.RESPError().Error()
+}
+
+// RESPError returns the RESP error of the e.
+func (e *ProtocolError) RESPError() *Error {
+	return (*Error)(e)
+}
+
 var (
-	ErrBulkLength      = &Error{"ERR", "Protocol error: invalid bulk length"}
-	ErrMultibulkLength = &Error{"ERR", "Protocol error: invalid multibulk length"}
-	ErrIntegerValue    = &Error{"ERR", "Protocol error: invalid integer value"}
+	ErrBulkLength       = &ProtocolError{"ERR", "Protocol error: invalid bulk length"}
+	ErrMultibulkLength  = &ProtocolError{"ERR", "Protocol error: invalid multibulk length"}
+	ErrIntegerValue     = &ProtocolError{"ERR", "Protocol error: invalid integer value"}
+	ErrBooleanValue     = &ProtocolError{"ERR", "Protocol error: invalid boolean value"}
+	ErrDoubleValue      = &ProtocolError{"ERR", "Protocol error: invalid double value"}
+	ErrBigNumberValue   = &ProtocolError{"ERR", "Protocol error: invalid big number value"}
+	ErrVerbatimString   = &ProtocolError{"ERR", "Protocol error: invalid verbatim string"}
+	ErrInlineLength     = &ProtocolError{"ERR", "Protocol error: too big inline request"}
+	ErrUnbalancedQuotes = &ProtocolError{"ERR", "Protocol error: unbalanced quotes in request"}
+	ErrCommandLength    = &ProtocolError{"ERR", "Protocol error: query buffer limit exceeded"}
+	ErrMaxDepth         = &ProtocolError{"ERR", "Protocol error: max nesting depth exceeded"}
 
 	errValue             = errors.New("invalid value")
 	errLineLimitExceeded = errors.New("line limit exceeded")
@@ -20,13 +48,82 @@ var (
 const (
 	initialCommandRawSize  = 1024 // 1KB
 	initialCommandArgsSize = 4    // More than enough for most of the commands.
+
+	// maxArgsPrealloc limits the number of Args allocated in advance for an
+	// array, so a huge announced length does not allocate memory before the
+	// arguments are actually received. The same as Redis does.
+	maxArgsPrealloc = 1024
+
+	// maxBulkChunk is the most the buffer of a bulk string grows ahead of the
+	// received data.
+	maxBulkChunk = 64 * 1024 // 64KB
+)
+
+// Default limits of the Reader, the same as Redis defaults.
+const (
+	DefaultMaxBulkLength      = 512 * 1024 * 1024  // proto-max-bulk-len, 512MB.
+	DefaultMaxMultibulkLength = math.MaxInt32      // INT_MAX.
+	DefaultMaxInlineLength    = 64 * 1024          // PROTO_INLINE_MAX_SIZE, 64KB.
+	DefaultMaxCommandLength   = 1024 * 1024 * 1024 // client-query-buffer-limit, 1GB.
+	DefaultMaxDepth           = 128
 )
 
+// ReaderOptions are limits of the Reader, that protect it from peers that
+// announce huge lengths to exhaust the memory. Zero values mean the defaults.
+type ReaderOptions struct {
+	// MaxBulkLength is the maximum length of a bulk string. Longer bulk
+	// strings are rejected with the ErrBulkLength.
+	MaxBulkLength int
+
+	// MaxMultibulkLength is the maximum number of elements of an array or
+	// another aggregate type. Longer ones are rejected with the
+	// ErrMultibulkLength.
+	MaxMultibulkLength int
+
+	// MaxInlineLength is the maximum length of an inline command. Longer
+	// commands are rejected with the ErrInlineLength.
+	MaxInlineLength int
+
+	// MaxCommandLength is the maximum length of the Raw of a command read by
+	// the ReadCommand. Longer commands are rejected with the
+	// ErrCommandLength.
+	MaxCommandLength int
+
+	// MaxDepth is the maximum nesting depth of aggregate types read by the
+	// ReadValue. Deeper values are rejected with the ErrMaxDepth.
+	MaxDepth int
+}
+
+func (o ReaderOptions) withDefaults() ReaderOptions {
+	if o.MaxBulkLength <= 0 {
+		o.MaxBulkLength = DefaultMaxBulkLength
+	}
+	if o.MaxMultibulkLength <= 0 {
+		o.MaxMultibulkLength = DefaultMaxMultibulkLength
+	}
+	if o.MaxInlineLength <= 0 {
+		o.MaxInlineLength = DefaultMaxInlineLength
+	}
+	if o.MaxCommandLength <= 0 {
+		o.MaxCommandLength = DefaultMaxCommandLength
+	}
+	if o.MaxDepth <= 0 {
+		o.MaxDepth = DefaultMaxDepth
+	}
+	return o
+}
+
 // Arg represents a byte slice of the raw input.
 type Arg []byte
 
 // Bytes creates a new copy of the underlying byte slice and returns it.
-func (a Arg) Bytes() []byte { return append([]byte(nil), a...) }
+//
+// The returned slice is never nil, even for an empty Arg.
+func (a Arg) Bytes() []byte {
+	b := make([]byte, len(a))
+	copy(b, a)
+	return b
+}
 
 // Command represents a RESP command.
 //
@@ -36,6 +133,9 @@ type Command struct {
 	// Raw contains all bytes of the command, including all prefixes and "\r\n".
 	Raw []byte
 	// Args are bytes slices of the Raw witout "\r\n".
+	//
+	// Arguments of inline commands are unquoted, so they are stored right
+	// after the Raw, in the same underlying array.
 	Args []Arg
 }
 
@@ -54,6 +154,53 @@ func newCommand() *Command {
 	return cmd
 }
 
+// Release puts the Command back into the pool, so its buffers can be reused
+// by the next reading. The Command must not be used after that.
+func (c *Command) Release() {
+	commandPool.Put(c)
+}
+
+// WriteTo writes the command to the w as a RESP array of bulk strings. It
+// implements the io.WriterTo interface.
+//
+// The Raw of commands read as RESP arrays is written as is, so proxies can
+// forward commands byte for byte. Inline commands and commands without the
+// Raw are encoded from the Args.
+func (c *Command) WriteTo(w io.Writer) (n int64, err error) {
+	raw := c.Raw
+	if len(raw) == 0 || raw[0] != byte(DataTypeArray) {
+		raw = appendCommandArgs(make([]byte, 0, commandArgsLen(c.Args)), c.Args)
+	}
+
+	nn, err := w.Write(raw)
+	return int64(nn), err
+}
+
+// commandArgsLen returns the length of the args encoded as a RESP array.
+func commandArgsLen(args []Arg) int {
+	const prefixLength = len("*-9223372036854775808\r\n")
+
+	n := prefixLength
+	for _, arg := range args {
+		n += prefixLength + len(arg) + len("\r\n")
+	}
+	return n
+}
+
+func appendCommandArgs(b []byte, args []Arg) []byte {
+	b = append(b, byte(DataTypeArray))
+	b = strconv.AppendInt(b, int64(len(args)), 10)
+	b = append(b, "\r\n"...)
+	for _, arg := range args {
+		b = append(b, byte(DataTypeBulkString))
+		b = strconv.AppendInt(b, int64(len(arg)), 10)
+		b = append(b, "\r\n"...)
+		b = append(b, arg...)
+		b = append(b, "\r\n"...)
+	}
+	return b
+}
+
 func (c *Command) reset() {
 	*c = Command{
 		Raw:  c.Raw[:0],
@@ -81,23 +228,55 @@ func (c *Command) grow(n int) {
 
 // Reader implements a RESP reader.
 type Reader struct {
-	r *bufio.Reader
+	r    *bufio.Reader
+	opts ReaderOptions
+	bulk BulkReader // Reused by the ReadBulkReader.
 }
 
-// NewReader returns a new Reader.
+// NewReader returns a new Reader with the default limits.
 func NewReader(rd io.Reader) *Reader {
+	return NewReaderOptions(rd, ReaderOptions{})
+}
+
+// NewReaderOptions returns a new Reader with the limits of the opts.
+func NewReaderOptions(rd io.Reader, opts ReaderOptions) *Reader {
 	return &Reader{
-		r: bufio.NewReader(rd),
+		r:    bufio.NewReader(rd),
+		opts: opts.withDefaults(),
 	}
 }
 
+// Options returns the limits of the Reader, with the defaults filled in.
+func (r *Reader) Options() ReaderOptions {
+	return r.opts
+}
+
 // Reset discards any buffered data and switches the reader to read from rd.
 func (r *Reader) Reset(rd io.Reader) {
 	r.r.Reset(rd)
 }
 
+// Buffered returns the number of bytes that can be read from the current
+// buffer without reading from the underlying reader.
+func (r *Reader) Buffered() int {
+	return r.r.Buffered()
+}
+
+// Peek returns the next n bytes without advancing the reader. It blocks until
+// n bytes are available or an error occurs, see bufio.Reader.Peek.
+//
+// The bytes stop being valid at the next read call.
+func (r *Reader) Peek(n int) ([]byte, error) {
+	return r.r.Peek(n)
+}
+
 // ReadCommand reads and returns a Command from the underlying reader.
 //
+// Besides RESP arrays of bulk strings, it reads inline commands: lines of
+// space separated arguments, with quoting and escapes like in redis-cli, e.g.
+// "SET key \"hello world\"\r\n". Such commands are sent by telnet sessions
+// and health-check scripts. Empty lines are skipped.
+//
 // The returned Command might be reused, the client should not store or modify
 // it or its fields.
 func (r *Reader) ReadCommand() (cmd *Command, err error) {
@@ -110,14 +289,25 @@ func (r *Reader) ReadCommand() (cmd *Command, err error) {
 	}()
 
 next:
-	// We don't support paint text commands now.
-	// Just try to parse the input as a array.
-	arrayLength, err := r.readValue(DataTypeArray, cmd)
+	first, err := r.r.Peek(1)
 	if err != nil {
-		if err == errValue {
-			return cmd, ErrMultibulkLength
+		return cmd, err
+	}
+	if first[0] != byte(DataTypeArray) {
+		if err := r.readInline(cmd); err != nil {
+			return cmd, err
+		}
+		// Skip empty commands and read the next one.
+		if len(cmd.Args) == 0 {
+			cmd.reset()
+			goto next
 		}
 
+		return cmd, nil
+	}
+
+	arrayLength, err := r.readLength(DataTypeArray, cmd)
+	if err != nil {
 		return cmd, err
 	}
 	// Skip empty commands and read the next one.
@@ -126,20 +316,27 @@ next:
 		goto next
 	}
 
-	if diff := arrayLength - cap(cmd.Args); diff > 0 {
+	prealloc := arrayLength
+	if prealloc > maxArgsPrealloc {
+		prealloc = maxArgsPrealloc
+	}
+	if diff := prealloc - cap(cmd.Args); diff > 0 {
 		// Grow the Args slice.
 		cmd.Args = append(cmd.Args, make([]Arg, diff)...)[:len(cmd.Args)]
 	}
 
 	// Parse elements.
 	for i := 0; i < arrayLength; i++ {
-		arg, null, err := r.readBulk(cmd)
+		arg, null, err := r.readBulk(DataTypeBulkString, cmd)
 		if err != nil {
 			return cmd, err
 		}
 		if null {
 			return cmd, ErrBulkLength
 		}
+		if len(cmd.Raw) > r.opts.MaxCommandLength {
+			return cmd, ErrCommandLength
+		}
 
 		cmd.Args = append(cmd.Args, arg)
 	}
@@ -147,6 +344,168 @@ next:
 	return cmd, err
 }
 
+// readInline reads an inline command into the cmd.
+func (r *Reader) readInline(cmd *Command) error {
+	for {
+		frag, err := r.r.ReadSlice('\n')
+		cmd.Raw = append(cmd.Raw, frag...)
+		if len(cmd.Raw) > r.opts.MaxInlineLength {
+			return ErrInlineLength
+		}
+
+		if err == nil {
+			break
+		}
+		if err != bufio.ErrBufferFull {
+			return err
+		}
+	}
+
+	// The "\r" is optional, like in Redis.
+	end := len(cmd.Raw) - 1
+	if end > 0 && cmd.Raw[end-1] == '\r' {
+		end--
+	}
+
+	// Unquoted arguments are never longer than the line, so reserve enough
+	// space after the Raw to not reallocate it while splitting.
+	if cap(cmd.Raw)-len(cmd.Raw) < end {
+		raw := make([]byte, len(cmd.Raw), 2*len(cmd.Raw))
+		copy(raw, cmd.Raw)
+		cmd.Raw = raw
+	}
+
+	args, ok := splitInlineArgs(cmd.Args, cmd.Raw[len(cmd.Raw):len(cmd.Raw)], cmd.Raw[:end])
+	if !ok {
+		return ErrUnbalancedQuotes
+	}
+
+	cmd.Args = args
+	return nil
+}
+
+// splitInlineArgs splits the line into arguments like the Redis sdssplitargs
+// does, appends the unquoted arguments to the buf and the args, and returns
+// the args. It reports false if the line has unbalanced quotes.
+//
+// The buf must have enough capacity for all arguments, so they do not
+// overwrite each other.
+func splitInlineArgs(args []Arg, buf, line []byte) ([]Arg, bool) {
+	i := 0
+	for {
+		for i < len(line) && isInlineSpace(line[i]) {
+			i++
+		}
+		if i == len(line) {
+			return args, true
+		}
+
+		start := len(buf)
+		var inq, insq, done bool // In double quotes, in single quotes.
+		for !done && i < len(line) {
+			ch := line[i]
+
+			switch {
+			case inq:
+				switch {
+				case ch == '\\' && i+3 < len(line) && line[i+1] == 'x' &&
+					isHexDigit(line[i+2]) && isHexDigit(line[i+3]):
+					buf = append(buf, hexDigitValue(line[i+2])<<4|hexDigitValue(line[i+3]))
+					i += 3
+
+				case ch == '\\' && i+1 < len(line):
+					i++
+					switch ch = line[i]; ch {
+					case 'n':
+						ch = '\n'
+					case 'r':
+						ch = '\r'
+					case 't':
+						ch = '\t'
+					case 'b':
+						ch = '\b'
+					case 'a':
+						ch = '\a'
+					}
+					buf = append(buf, ch)
+
+				case ch == '"':
+					// The closing quote must be followed by a space or
+					// nothing.
+					if i+1 < len(line) && !isInlineSpace(line[i+1]) {
+						return args, false
+					}
+					inq = false
+					done = true
+
+				default:
+					buf = append(buf, ch)
+				}
+
+			case insq:
+				switch {
+				case ch == '\\' && i+1 < len(line) && line[i+1] == '\'':
+					i++
+					buf = append(buf, '\'')
+
+				case ch == '\'':
+					if i+1 < len(line) && !isInlineSpace(line[i+1]) {
+						return args, false
+					}
+					insq = false
+					done = true
+
+				default:
+					buf = append(buf, ch)
+				}
+
+			default:
+				switch ch {
+				case ' ', '\n', '\r', '\t', 0:
+					done = true
+				case '"':
+					inq = true
+				case '\'':
+					insq = true
+				default:
+					buf = append(buf, ch)
+				}
+			}
+
+			i++
+		}
+
+		if inq || insq {
+			return args, false
+		}
+
+		args = append(args, Arg(buf[start:len(buf):len(buf)]))
+	}
+}
+
+func isInlineSpace(ch byte) bool {
+	switch ch {
+	case ' ', '\t', '\n', '\v', '\f', '\r':
+		return true
+	}
+	return false
+}
+
+func isHexDigit(ch byte) bool {
+	return ('0' <= ch && ch <= '9') || ('a' <= ch && ch <= 'f') || ('A' <= ch && ch <= 'F')
+}
+
+func hexDigitValue(ch byte) byte {
+	switch {
+	case '0' <= ch && ch <= '9':
+		return ch - '0'
+	case 'a' <= ch && ch <= 'f':
+		return ch - 'a' + 10
+	default:
+		return ch - 'A' + 10
+	}
+}
+
 // ReadSimpleString reads and returns a RESP simple string from the underlying
 // reader.
 func (r *Reader) ReadSimpleString() (string, error) {
@@ -170,6 +529,11 @@ func (r *Reader) ReadError() (*Error, error) {
 		return nil, err
 	}
 
+	return parseError(line), nil
+}
+
+// parseError parses the kind and the message of an error.
+func parseError(line []byte) *Error {
 	spacePos := -1
 	for i, ch := range line {
 		if ch == ' ' || ch == '\n' {
@@ -186,7 +550,7 @@ func (r *Reader) ReadError() (*Error, error) {
 		e.Msg = string(line[spacePos+1:])
 	}
 
-	return e, nil
+	return e
 }
 
 // ReadInteger reads and returns a RESP integer from the underlying reader.
@@ -206,7 +570,7 @@ func (r *Reader) ReadString() (s string, null bool, err error) {
 	cmd := newCommand()
 	defer commandPool.Put(cmd)
 
-	b, null, err := r.readBulk(cmd)
+	b, null, err := r.readBulk(DataTypeBulkString, cmd)
 	return string(b), null, err
 }
 
@@ -216,17 +580,152 @@ func (r *Reader) ReadArray() (length int, err error) {
 	cmd := newCommand()
 	defer commandPool.Put(cmd)
 
-	n, err := r.readValue(DataTypeArray, cmd)
-	if err == errValue {
-		err = ErrMultibulkLength
+	return r.readLength(DataTypeArray, cmd)
+}
+
+// ReadNull reads a RESP3 null from the underlying reader.
+func (r *Reader) ReadNull() error {
+	cmd := newCommand()
+	defer commandPool.Put(cmd)
+
+	line, err := r.readLine(DataTypeRESP3Null, 0, cmd)
+	if err != nil {
+		return err
 	}
-	return n, err
+	if len(line) != 0 {
+		return &ProtocolError{"ERR", "Protocol error: invalid null value"}
+	}
+	return nil
+}
+
+// ReadBool reads and returns a RESP3 boolean from the underlying reader.
+func (r *Reader) ReadBool() (bool, error) {
+	cmd := newCommand()
+	defer commandPool.Put(cmd)
+
+	line, err := r.readLine(DataTypeBoolean, 0, cmd)
+	if err != nil {
+		return false, err
+	}
+
+	switch string(line) {
+	case "t":
+		return true, nil
+	case "f":
+		return false, nil
+	default:
+		return false, ErrBooleanValue
+	}
+}
+
+// ReadDouble reads and returns a RESP3 double from the underlying reader.
+func (r *Reader) ReadDouble() (float64, error) {
+	cmd := newCommand()
+	defer commandPool.Put(cmd)
+
+	line, err := r.readLine(DataTypeDouble, 0, cmd)
+	if err != nil {
+		return 0, err
+	}
+
+	return parseDouble(line)
+}
+
+// ReadBigNumber reads and returns a RESP3 big number from the underlying
+// reader.
+func (r *Reader) ReadBigNumber() (*big.Int, error) {
+	cmd := newCommand()
+	defer commandPool.Put(cmd)
+
+	line, err := r.readLine(DataTypeBigNumber, 0, cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	n, ok := new(big.Int).SetString(string(line), 10)
+	if !ok {
+		return nil, ErrBigNumberValue
+	}
+	return n, nil
+}
+
+// ReadBulkError reads and returns a RESP3 bulk error from the underlying
+// reader.
+//
+// The kind of the error is parsed the same way as the ReadError does.
+func (r *Reader) ReadBulkError() (*Error, error) {
+	cmd := newCommand()
+	defer commandPool.Put(cmd)
+
+	b, null, err := r.readBulk(DataTypeBulkError, cmd)
+	if err != nil {
+		return nil, err
+	}
+	if null {
+		return nil, ErrBulkLength
+	}
+
+	return parseError(b), nil
+}
+
+// ReadVerbatimString reads and returns a RESP3 verbatim string and its
+// format (e.g. "txt" or "mkd") from the underlying reader.
+func (r *Reader) ReadVerbatimString() (format, s string, err error) {
+	cmd := newCommand()
+	defer commandPool.Put(cmd)
+
+	b, null, err := r.readBulk(DataTypeVerbatimString, cmd)
+	if err != nil {
+		return "", "", err
+	}
+	if null || len(b) < 4 || b[3] != ':' {
+		return "", "", ErrVerbatimString
+	}
+
+	return string(b[:3]), string(b[4:]), nil
+}
+
+// ReadMap reads and returns the number of key-value pairs of a RESP3 map
+// from the underlying reader.
+func (r *Reader) ReadMap() (length int, err error) {
+	return r.readAggregate(DataTypeMap)
+}
+
+// ReadSet reads and returns the length of a RESP3 set from the underlying
+// reader.
+func (r *Reader) ReadSet() (length int, err error) {
+	return r.readAggregate(DataTypeSet)
+}
+
+// ReadAttribute reads and returns the number of key-value pairs of a RESP3
+// attribute from the underlying reader.
+func (r *Reader) ReadAttribute() (length int, err error) {
+	return r.readAggregate(DataTypeAttribute)
+}
+
+// ReadPush reads and returns the length of a RESP3 push from the underlying
+// reader.
+func (r *Reader) ReadPush() (length int, err error) {
+	return r.readAggregate(DataTypePush)
+}
+
+func (r *Reader) readAggregate(dt DataType) (int, error) {
+	cmd := newCommand()
+	defer commandPool.Put(cmd)
+
+	return r.readLength(dt, cmd)
 }
 
 // ReadAny reads and returns a RESP type and its value from the underlying
 // reader.
 //
-// The data type is determined by the first byte.
+// The data type is determined by the first byte. The value is:
+//   - a string for simple, bulk and verbatim strings (without the format);
+//   - an *Error for errors and bulk errors;
+//   - an int for integers and lengths of arrays, maps, sets, attributes and
+//     pushes;
+//   - a bool, float64 or *big.Int for booleans, doubles and big numbers;
+//   - nil for nulls, both the RESP3 null and the RESP2 null bulk string.
 func (r *Reader) ReadAny() (dt DataType, v interface{}, err error) {
 	first, err := r.r.ReadByte()
 	if err == nil {
@@ -258,11 +757,39 @@ func (r *Reader) ReadAny() (dt DataType, v interface{}, err error) {
 	case DataTypeArray:
 		v, err = r.ReadArray()
 
-	// DataTypeNull is an internal data type. Nulls are handled by
-	// DataTypeBulkString.
+	case DataTypeRESP3Null:
+		err = r.ReadNull()
+		dt = DataTypeNull
+
+	case DataTypeBoolean:
+		v, err = r.ReadBool()
+
+	case DataTypeDouble:
+		v, err = r.ReadDouble()
+
+	case DataTypeBigNumber:
+		v, err = r.ReadBigNumber()
+
+	case DataTypeBulkError:
+		v, err = r.ReadBulkError()
+
+	case DataTypeVerbatimString:
+		_, v, err = r.ReadVerbatimString()
+
+	case DataTypeMap:
+		v, err = r.ReadMap()
+
+	case DataTypeSet:
+		v, err = r.ReadSet()
+
+	case DataTypeAttribute:
+		v, err = r.ReadAttribute()
+
+	case DataTypePush:
+		v, err = r.ReadPush()
 
 	default:
-		return DataTypeNull, nil, &Error{"ERR", fmt.Sprintf("Protocol error, got %q as reply type byte", string(dt))}
+		return DataTypeNull, nil, &ProtocolError{"ERR", fmt.Sprintf("Protocol error, got %q as reply type byte", string(dt))}
 	}
 
 	return dt, v, err
@@ -302,7 +829,7 @@ func (r *Reader) readLine(dt DataType, limit int, cmd *Command) ([]byte, error)
 	}
 
 	if ch := cmd.Raw[start]; ch != byte(dt) {
-		return nil, &Error{"ERR", "expected '" + string(dt) + "', got '" + string(ch) + "'"}
+		return nil, &ProtocolError{"ERR", "expected '" + string(dt) + "', got '" + string(ch) + "'"}
 	}
 
 	return cmd.Raw[start+1 : len(cmd.Raw)-2], nil
@@ -326,21 +853,43 @@ func (r *Reader) readValue(dt DataType, cmd *Command) (int, error) {
 	}
 
 	// Parse the line as an integer.
-	n, err := parseInt(line)
+	n, err := ParseInt(line)
 	if err != nil {
 		return 0, err
 	}
+	if int64(int(n)) != n { // Does not fit in the int.
+		return 0, errValue
+	}
+
+	return int(n), nil
+}
+
+// readLength reads a length of an array or another aggregate type and checks
+// it against the MaxMultibulkLength.
+//
+// It uses the cmd as a buffer and puts all read bytes into the Raw.
+func (r *Reader) readLength(dt DataType, cmd *Command) (int, error) {
+	n, err := r.readValue(dt, cmd)
+	if err != nil {
+		if err == errValue {
+			err = ErrMultibulkLength
+		}
+		return 0, err
+	}
+	if n > r.opts.MaxMultibulkLength {
+		return 0, ErrMultibulkLength
+	}
 
 	return n, nil
 }
 
-// readBulk reads a full RESP bulk string (with the prefix and the <CRLF>)
-// and returns only the content.
+// readBulk reads a full RESP bulk string, bulk error or verbatim string (with
+// the prefix and the <CRLF>) and returns only the content.
 //
 // It uses the cmd as a buffer and puts all read bytes into the Raw.
-func (r *Reader) readBulk(cmd *Command) (bulk []byte, null bool, err error) {
+func (r *Reader) readBulk(dt DataType, cmd *Command) (bulk []byte, null bool, err error) {
 	// Parse a bulk string length.
-	bulkLength, err := r.readValue(DataTypeBulkString, cmd)
+	bulkLength, err := r.readValue(dt, cmd)
 	if err != nil {
 		if err == errValue {
 			err = ErrBulkLength
@@ -350,6 +899,13 @@ func (r *Reader) readBulk(cmd *Command) (bulk []byte, null bool, err error) {
 	if bulkLength < 0 {
 		return nil, true, nil
 	}
+	if bulkLength > r.opts.MaxBulkLength {
+		return nil, false, ErrBulkLength
+	}
+	// Do not allocate memory for a command that exceeds the limit anyway.
+	if len(cmd.Raw)+bulkLength > r.opts.MaxCommandLength {
+		return nil, false, ErrCommandLength
+	}
 
 	// Parse the bulk string content.
 	start := len(cmd.Raw)
@@ -358,15 +914,23 @@ func (r *Reader) readBulk(cmd *Command) (bulk []byte, null bool, err error) {
 	const crlfLength = len("\r\n")
 	remain := bulkLength + crlfLength
 
-	cmd.grow(remain)
-
+	// Grow the buffer as the data arrives, so a peer can not make us allocate
+	// the whole announced length without sending it.
 	for remain > 0 {
-		n, err := r.r.Read(cmd.Raw[si:])
-		if err != nil {
-			return nil, false, err
+		chunk := remain
+		if chunk > maxBulkChunk {
+			chunk = maxBulkChunk
+		}
+		cmd.grow(chunk)
+
+		for end := si + chunk; si < end; {
+			n, err := r.r.Read(cmd.Raw[si:end])
+			if err != nil {
+				return nil, false, err
+			}
+			si += n
 		}
-		remain -= n
-		si += n
+		remain -= chunk
 	}
 
 	if !hasTerminator(cmd.Raw) {
@@ -376,35 +940,65 @@ func (r *Reader) readBulk(cmd *Command) (bulk []byte, null bool, err error) {
 	return cmd.Raw[start : len(cmd.Raw)-2], false, nil
 }
 
-func parseInt(b []byte) (n int, err error) {
+// ParseInt interprets the b as a decimal signed 64-bit integer and returns
+// the result.
+//
+// It is as strict as the Redis string2ll: the b must not have a sign except
+// the leading '-', leading zeros, spaces or any other characters, and must fit
+// in an int64. Otherwise an error is returned.
+func ParseInt(b []byte) (int64, error) {
 	if len(b) == 0 {
 		return 0, errValue
 	}
 
-	var negative bool
+	// Special case: the only number with a leading zero.
+	if len(b) == 1 && b[0] == '0' {
+		return 0, nil
+	}
 
+	var negative bool
 	if b[0] == '-' {
 		negative = true
-		if len(b) < 1 {
+		b = b[1:]
+		if len(b) == 0 {
 			return 0, errValue
 		}
+	}
 
-		b = b[1:]
+	// The first digit must be 1-9.
+	if b[0] < '1' || b[0] > '9' {
+		return 0, errValue
 	}
 
+	const cutoff = math.MaxUint64 / 10
+
+	var n uint64
 	for _, ch := range b {
 		ch -= '0'
 		if ch > 9 {
 			return 0, errValue
 		}
-		n = n*10 + int(ch)
+		if n > cutoff { // Overflow on multiplication.
+			return 0, errValue
+		}
+		n *= 10
+		if n+uint64(ch) < n { // Overflow on addition.
+			return 0, errValue
+		}
+		n += uint64(ch)
 	}
 
 	if negative {
-		n = -1 * n
+		if n > -math.MinInt64 {
+			return 0, errValue
+		}
+		return -int64(n), nil
 	}
 
-	return n, nil
+	if n > math.MaxInt64 {
+		return 0, errValue
+	}
+	return int64(n), nil
 }
 
 func hasTerminator(b []byte) bool {
@@ -3,7 +3,10 @@ package radish
 import (
 	"bytes"
 	"io"
+	"math"
+	"math/big"
 	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -90,6 +93,46 @@ func TestReader_ReadCommand(t *testing.T) {
 				Arg("test-value"),
 			},
 		}),
+		{
+			name:  "inline",
+			input: []byte("PING\r\n"),
+			want: []*Command{
+				{Raw: []byte("PING\r\n"), Args: []Arg{Arg("PING")}},
+			},
+		},
+		{
+			name:  "inline without cr",
+			input: []byte("  SET  key\tvalue \n"),
+			want: []*Command{
+				{Raw: []byte("  SET  key\tvalue \n"), Args: []Arg{Arg("SET"), Arg("key"), Arg("value")}},
+			},
+		},
+		{
+			name:  "inline quotes",
+			input: []byte(`SET "hello world" 'it\'s' "\x41\n\"\q" a"b c" ""` + "\r\n"),
+			want: []*Command{
+				{
+					Raw:  []byte(`SET "hello world" 'it\'s' "\x41\n\"\q" a"b c" ""` + "\r\n"),
+					Args: []Arg{Arg("SET"), Arg("hello world"), Arg("it's"), Arg("A\n\"q"), Arg("ab c"), Arg("")},
+				},
+			},
+		},
+		{
+			name:  "inline and multibulk",
+			input: []byte("\r\n   \r\nPING\r\n*1\r\n$4\r\nPING\r\nECHO hi\r\n"),
+			want: []*Command{
+				{Raw: []byte("PING\r\n"), Args: []Arg{Arg("PING")}},
+				{Raw: []byte("*1\r\n$4\r\nPING\r\n"), Args: []Arg{Arg("PING")}},
+				{Raw: []byte("ECHO hi\r\n"), Args: []Arg{Arg("ECHO"), Arg("hi")}},
+			},
+		},
+		{
+			name:  "long inline",
+			input: []byte("ECHO " + strings.Repeat("a", 8192) + "\r\n"),
+			want: []*Command{
+				{Raw: []byte("ECHO " + strings.Repeat("a", 8192) + "\r\n"), Args: []Arg{Arg("ECHO"), Arg(strings.Repeat("a", 8192))}},
+			},
+		},
 	}
 
 	for _, tc := range tt {
@@ -144,6 +187,150 @@ func TestReader_ReadCommand(t *testing.T) {
 	}
 }
 
+func TestReader_ReadCommand_inlineErrors(t *testing.T) {
+	tt := []struct {
+		name  string
+		input string
+		want  error
+	}{
+		{"unterminated double quotes", "SET \"key\r\n", ErrUnbalancedQuotes},
+		{"unterminated single quotes", "SET 'key\r\n", ErrUnbalancedQuotes},
+		{"unterminated escape", "SET \"key\\\r\n", ErrUnbalancedQuotes},
+		{"no space after quotes", "SET \"key\"value\r\n", ErrUnbalancedQuotes},
+		{"no space after single quotes", "SET 'key'value\r\n", ErrUnbalancedQuotes},
+		{"too big", "SET " + strings.Repeat("a", 64*1024) + "\r\n", ErrInlineLength},
+		{"without newline", "PING", io.EOF},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			reader := NewReader(strings.NewReader(tc.input))
+
+			cmd, err := reader.ReadCommand()
+			if err != tc.want {
+				t.Errorf("ReadCommand() error = %v, want %v", err, tc.want)
+			}
+			if cmd != nil {
+				t.Errorf("ReadCommand() = %q, want nil", cmd.Raw)
+			}
+		})
+	}
+}
+
+func TestReader_limits(t *testing.T) {
+	tt := []struct {
+		name  string
+		opts  ReaderOptions
+		input string
+		read  func(r *Reader) error
+		want  error
+	}{
+		{
+			name:  "bulk length",
+			opts:  ReaderOptions{MaxBulkLength: 4},
+			input: "*2\r\n$4\r\nECHO\r\n$5\r\nhello\r\n",
+			want:  ErrBulkLength,
+		},
+		{
+			name:  "default bulk length",
+			input: "*1\r\n$536870913\r\n",
+			want:  ErrBulkLength,
+		},
+		{
+			name:  "multibulk length",
+			opts:  ReaderOptions{MaxMultibulkLength: 2},
+			input: "*3\r\n$4\r\nECHO\r\n$1\r\na\r\n$1\r\nb\r\n",
+			want:  ErrMultibulkLength,
+		},
+		{
+			name:  "default multibulk length",
+			input: "*2147483648\r\n",
+			want:  ErrMultibulkLength,
+		},
+		{
+			name:  "inline length",
+			opts:  ReaderOptions{MaxInlineLength: 8},
+			input: "ECHO hello\r\n",
+			want:  ErrInlineLength,
+		},
+		{
+			name:  "command length",
+			opts:  ReaderOptions{MaxCommandLength: 32},
+			input: "*3\r\n$4\r\nECHO\r\n$5\r\nhello\r\n$5\r\nworld\r\n",
+			want:  ErrCommandLength,
+		},
+		{
+			name:  "huge announced command",
+			input: "*1000000000\r\n$4\r\nECHO\r\n",
+			want:  io.EOF,
+		},
+		{
+			name:  "ReadString",
+			opts:  ReaderOptions{MaxBulkLength: 4},
+			input: "$5\r\nhello\r\n",
+			read: func(r *Reader) error {
+				_, _, err := r.ReadString()
+				return err
+			},
+			want: ErrBulkLength,
+		},
+		{
+			name:  "ReadArray",
+			opts:  ReaderOptions{MaxMultibulkLength: 4},
+			input: "*5\r\n",
+			read: func(r *Reader) error {
+				_, err := r.ReadArray()
+				return err
+			},
+			want: ErrMultibulkLength,
+		},
+		{
+			name:  "ReadAny map",
+			opts:  ReaderOptions{MaxMultibulkLength: 4},
+			input: "%5\r\n",
+			read: func(r *Reader) error {
+				_, _, err := r.ReadAny()
+				return err
+			},
+			want: ErrMultibulkLength,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			reader := NewReaderOptions(strings.NewReader(tc.input), tc.opts)
+
+			read := tc.read
+			if read == nil {
+				read = func(r *Reader) error {
+					_, err := r.ReadCommand()
+					return err
+				}
+			}
+
+			if err := read(reader); err != tc.want {
+				t.Errorf("error = %v, want %v", err, tc.want)
+			}
+		})
+	}
+}
+
+func TestReader_readBulk_hugeLength(t *testing.T) {
+	reader := NewReader(strings.NewReader("$536870000\r\nhello"))
+
+	cmd := newCommand()
+	defer commandPool.Put(cmd)
+
+	if _, _, err := reader.readBulk(DataTypeBulkString, cmd); err != io.EOF {
+		t.Fatalf("readBulk() error = %v, want %v", err, io.EOF)
+	}
+
+	// The buffer grows with the received data, not with the announced length.
+	if max := 2 * (initialCommandRawSize + maxBulkChunk); cap(cmd.Raw) > max {
+		t.Errorf("readBulk() grew the buffer to %d bytes, want at most %d", cap(cmd.Raw), max)
+	}
+}
+
 func TestReader_ReadAny(t *testing.T) {
 	tt := []struct {
 		name         string
@@ -235,6 +422,84 @@ func TestReader_ReadAny(t *testing.T) {
 			wantDataType: DataTypeArray,
 			wantValue:    -1,
 		},
+		{
+			name:         "resp3 null",
+			input:        []byte("_\r\n"),
+			wantDataType: DataTypeNull,
+			wantValue:    nil,
+		},
+		{
+			name:         "true",
+			input:        []byte("#t\r\n"),
+			wantDataType: DataTypeBoolean,
+			wantValue:    true,
+		},
+		{
+			name:         "false",
+			input:        []byte("#f\r\n"),
+			wantDataType: DataTypeBoolean,
+			wantValue:    false,
+		},
+		{
+			name:         "double",
+			input:        []byte(",-1.23e-4\r\n"),
+			wantDataType: DataTypeDouble,
+			wantValue:    -1.23e-4,
+		},
+		{
+			name:         "inf double",
+			input:        []byte(",inf\r\n"),
+			wantDataType: DataTypeDouble,
+			wantValue:    math.Inf(1),
+		},
+		{
+			name:         "-inf double",
+			input:        []byte(",-inf\r\n"),
+			wantDataType: DataTypeDouble,
+			wantValue:    math.Inf(-1),
+		},
+		{
+			name:         "big number",
+			input:        []byte("(3492890328409238509324850943850943825024385\r\n"),
+			wantDataType: DataTypeBigNumber,
+			wantValue:    mustBigInt("3492890328409238509324850943850943825024385"),
+		},
+		{
+			name:         "bulk error",
+			input:        []byte("!22\r\nSYNTAX invalid\r\nsyntax\r\n"),
+			wantDataType: DataTypeBulkError,
+			wantValue:    &Error{"SYNTAX", "invalid\r\nsyntax"},
+		},
+		{
+			name:         "verbatim string",
+			input:        []byte("=15\r\ntxt:Some string\r\n"),
+			wantDataType: DataTypeVerbatimString,
+			wantValue:    "Some string",
+		},
+		{
+			name:         "map",
+			input:        []byte("%2\r\n"),
+			wantDataType: DataTypeMap,
+			wantValue:    2,
+		},
+		{
+			name:         "set",
+			input:        []byte("~5\r\n"),
+			wantDataType: DataTypeSet,
+			wantValue:    5,
+		},
+		{
+			name:         "attribute",
+			input:        []byte("|1\r\n"),
+			wantDataType: DataTypeAttribute,
+			wantValue:    1,
+		},
+		{
+			name:         "push",
+			input:        []byte(">3\r\n"),
+			wantDataType: DataTypePush,
+			wantValue:    3,
+		},
 	}
 
 	for _, tc := range tt {
@@ -277,6 +542,138 @@ func TestReader_ReadAny(t *testing.T) {
 	}
 }
 
+func mustBigInt(s string) *big.Int {
+	n, ok := new(big.Int).SetString(s, 10)
+	if !ok {
+		panic("invalid big number: " + s)
+	}
+	return n
+}
+
+func TestReader_ReadAny_invalid(t *testing.T) {
+	tt := []struct {
+		name    string
+		input   []byte
+		wantErr error
+	}{
+		{"null", []byte("_x\r\n"), &ProtocolError{"ERR", "Protocol error: invalid null value"}},
+		{"boolean", []byte("#x\r\n"), ErrBooleanValue},
+		{"double", []byte(",1.2.3\r\n"), ErrDoubleValue},
+		{"big number", []byte("(12a\r\n"), ErrBigNumberValue},
+		{"verbatim string", []byte("=3\r\ntxt\r\n"), ErrVerbatimString},
+		{"map", []byte("%x\r\n"), ErrMultibulkLength},
+		{"unknown", []byte("?1\r\n"), &ProtocolError{"ERR", "Protocol error, got \"?\" as reply type byte"}},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			reader := NewReader(bytes.NewBuffer(tc.input))
+
+			_, _, err := reader.ReadAny()
+			if !reflect.DeepEqual(err, tc.wantErr) {
+				t.Errorf("ReadAny() error = %v, want %v", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestCommand_WriteTo(t *testing.T) {
+	tt := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{
+			name:  "array",
+			input: "*2\r\n$3\r\nGET\r\n$3\r\nkey\r\n",
+			want:  "*2\r\n$3\r\nGET\r\n$3\r\nkey\r\n",
+		},
+		{
+			name:  "inline",
+			input: "SET key \"hello world\"\r\n",
+			want:  "*3\r\n$3\r\nSET\r\n$3\r\nkey\r\n$11\r\nhello world\r\n",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			r := NewReader(strings.NewReader(tc.input))
+
+			cmd, err := r.ReadCommand()
+			if err != nil {
+				t.Fatalf("ReadCommand(): %s", err)
+			}
+			defer cmd.Release()
+
+			var buf bytes.Buffer
+			n, err := cmd.WriteTo(&buf)
+			if err != nil {
+				t.Fatalf("WriteTo(): %s", err)
+			}
+			if got := buf.String(); got != tc.want {
+				t.Errorf("WriteTo(): got = %q, want = %q", got, tc.want)
+			}
+			if n != int64(len(tc.want)) {
+				t.Errorf("WriteTo(): got n = %d, want = %d", n, len(tc.want))
+			}
+		})
+	}
+
+	t.Run("args", func(t *testing.T) {
+		cmd := &Command{Args: []Arg{Arg("PING"), Arg("")}}
+		want := "*2\r\n$4\r\nPING\r\n$0\r\n\r\n"
+
+		var buf bytes.Buffer
+		if _, err := cmd.WriteTo(&buf); err != nil {
+			t.Fatalf("WriteTo(): %s", err)
+		}
+		if got := buf.String(); got != want {
+			t.Errorf("WriteTo(): got = %q, want = %q", got, want)
+		}
+	})
+}
+
+func TestParseInt(t *testing.T) {
+	tt := []struct {
+		input   string
+		want    int64
+		wantErr bool
+	}{
+		{input: "0", want: 0},
+		{input: "1", want: 1},
+		{input: "1337", want: 1337},
+		{input: "-1337", want: -1337},
+		{input: "9223372036854775807", want: 9223372036854775807},
+		{input: "-9223372036854775808", want: -9223372036854775808},
+		{input: "9223372036854775808", wantErr: true},
+		{input: "-9223372036854775809", wantErr: true},
+		{input: "18446744073709551616", wantErr: true},
+		{input: "99999999999999999999", wantErr: true},
+		{input: "", wantErr: true},
+		{input: "-", wantErr: true},
+		{input: "+1", wantErr: true},
+		{input: "-0", wantErr: true},
+		{input: "01", wantErr: true},
+		{input: " 1", wantErr: true},
+		{input: "1 ", wantErr: true},
+		{input: "1a", wantErr: true},
+		{input: "1.5", wantErr: true},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.input, func(t *testing.T) {
+			got, err := ParseInt([]byte(tc.input))
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("ParseInt(%q) error = %v, want error %v", tc.input, err, tc.wantErr)
+			}
+
+			if got != tc.want {
+				t.Errorf("ParseInt(%q) = %d, want %d", tc.input, got, tc.want)
+			}
+		})
+	}
+}
+
 var readCommandRes *Command
 
 func BenchmarkReader_ReadCommand(b *testing.B) {
@@ -0,0 +1,388 @@
+package radish
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"strconv"
+)
+
+var errScanNil = errors.New("radish: Scan(nil)")
+
+// Scanner is implemented by types that can scan a RESP value themselves, like
+// the sql.Scanner.
+//
+// The ScanRESP is called with the type of the value and its content: the
+// text of simple strings, errors, integers, doubles, big numbers and
+// booleans, and the content of bulk strings, bulk errors and verbatim
+// strings, with the format. Nulls are passed as the DataTypeNull with a nil
+// b. The b is only valid until the ScanRESP returns, and must be copied to be
+// retained.
+type Scanner interface {
+	ScanRESP(dt DataType, b []byte) error
+}
+
+// A ScanArrayLengthError describes an array with a number of elements that
+// does not match the number of destinations passed to the ScanArray.
+type ScanArrayLengthError struct {
+	Length int // The length of the array.
+	Dst    int // The number of destinations.
+}
+
+func (e *ScanArrayLengthError) Error() string {
+	return "radish: ScanArray: expected " + strconv.Itoa(e.Dst) + " elements, got " + strconv.Itoa(e.Length)
+}
+
+// Scan reads a RESP value for each of the dst, one after another, and stores
+// it in the value pointed to by the dst, without intermediate allocations.
+//
+// The dst may be:
+//   - *string: any scalar value;
+//   - *[]byte: any scalar value, the content is copied into the existing
+//     slice, reusing its capacity;
+//   - *int64, *int: integers and strings with integers;
+//   - *float64: doubles, integers and strings with numbers;
+//   - *bool: booleans, integers and strings with 0 or 1;
+//   - *Value: any value, it is read with the ReadValue;
+//   - Scanner: any scalar value;
+//   - nil: any value, it is skipped.
+//
+// Nulls set the values to zero values. Attributes are skipped.
+//
+// If a value is a RESP error, Scan still reads the rest of the values, to
+// stay at the start of the next reply, and returns the first error, as the
+// *Error. If a value can not be stored in the dst, an *UnmarshalTypeError is
+// returned in the same way. Other errors are protocol or I/O errors, and
+// Scan stops at them.
+func (r *Reader) Scan(dst ...interface{}) error {
+	if len(dst) == 0 {
+		return errScanNil
+	}
+
+	cmd := newCommand()
+	defer commandPool.Put(cmd)
+
+	var firstErr error
+	for _, d := range dst {
+		scanErr, err := r.scan(d, cmd)
+		if err != nil {
+			return err
+		}
+		if firstErr == nil {
+			firstErr = scanErr
+		}
+	}
+
+	return firstErr
+}
+
+// ScanArray reads a RESP array, set, push or map, and stores its elements in
+// the values pointed to by the dst, see the Scan. Keys and values of a map are
+// stored one after another.
+//
+// If the number of elements does not match the number of dst, the elements
+// are scanned into as many dst as possible, the rest of the elements are
+// skipped, and a *ScanArrayLengthError is returned. A null array sets all dst
+// to zero values.
+func (r *Reader) ScanArray(dst ...interface{}) error {
+	cmd := newCommand()
+	defer commandPool.Put(cmd)
+
+	first, err := r.skipAttributes()
+	if err != nil {
+		return err
+	}
+
+	dt := DataType(first)
+	var n int
+	switch dt {
+	case DataTypeArray, DataTypeSet, DataTypePush:
+		n, err = r.readLength(dt, cmd)
+
+	case DataTypeMap:
+		n, err = r.readLength(dt, cmd)
+		n *= 2
+
+	case DataTypeError, DataTypeBulkError:
+		scanErr, err := r.scan(nil, cmd)
+		if err != nil {
+			return err
+		}
+		return scanErr
+
+	default:
+		if _, err := r.scan(nil, cmd); err != nil {
+			return err
+		}
+		return &UnmarshalTypeError{dt, reflect.TypeOf(dst)}
+	}
+	if err != nil {
+		return err
+	}
+
+	if n < 0 { // Null.
+		for _, d := range dst {
+			if err := scanBytes(d, DataTypeNull, nil); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	var firstErr error
+	for i := 0; i < n; i++ {
+		var d interface{}
+		if i < len(dst) {
+			d = dst[i]
+		}
+
+		scanErr, err := r.scan(d, cmd)
+		if err != nil {
+			return err
+		}
+		if firstErr == nil {
+			firstErr = scanErr
+		}
+	}
+
+	if n != len(dst) {
+		return &ScanArrayLengthError{n, len(dst)}
+	}
+	return firstErr
+}
+
+// scan reads a single value into the d. The scanErr is a RESP error or an
+// error of the conversion, after which the reader is still at the start of
+// the next value.
+func (r *Reader) scan(d interface{}, cmd *Command) (scanErr, err error) {
+	if v, ok := d.(*Value); ok {
+		*v, err = r.ReadValue()
+		return nil, err
+	}
+
+	first, err := r.skipAttributes()
+	if err != nil {
+		return nil, err
+	}
+
+	cmd.reset()
+
+	var (
+		dt = DataType(first)
+		b  []byte
+	)
+	switch dt {
+	case DataTypeSimpleString, DataTypeError, DataTypeInteger,
+		DataTypeDouble, DataTypeBigNumber, DataTypeBoolean:
+		b, err = r.readLine(dt, 0, cmd)
+
+	case DataTypeRESP3Null:
+		err = r.ReadNull()
+		dt = DataTypeNull
+
+	case DataTypeBulkString, DataTypeBulkError:
+		var null bool
+		b, null, err = r.readBulk(dt, cmd)
+		if null {
+			dt = DataTypeNull
+		}
+
+	case DataTypeVerbatimString:
+		var null bool
+		b, null, err = r.readBulk(dt, cmd)
+		if err == nil && (null || len(b) < 4 || b[3] != ':') {
+			err = ErrVerbatimString
+		}
+
+	case DataTypeArray, DataTypeSet, DataTypePush, DataTypeMap:
+		var n int
+		n, err = r.readLength(dt, cmd)
+		if err != nil {
+			return nil, err
+		}
+		if n < 0 {
+			return scanBytes(d, DataTypeNull, nil), nil
+		}
+		if dt == DataTypeMap {
+			n *= 2
+		}
+		for i := 0; i < n; i++ {
+			if _, err := r.readValueTree(1); err != nil {
+				return nil, err
+			}
+		}
+		if d == nil {
+			return nil, nil
+		}
+		return &UnmarshalTypeError{dt, reflect.TypeOf(d)}, nil
+
+	default:
+		_, _, err = r.ReadAny() // Returns the protocol error.
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if dt == DataTypeError || dt == DataTypeBulkError {
+		return parseError(b), nil
+	}
+
+	return scanBytes(d, dt, b), nil
+}
+
+// skipAttributes skips RESP3 attributes and returns the first byte of the
+// value after them.
+func (r *Reader) skipAttributes() (byte, error) {
+	for {
+		first, err := r.r.Peek(1)
+		if err != nil {
+			return 0, err
+		}
+		if DataType(first[0]) != DataTypeAttribute {
+			return first[0], nil
+		}
+
+		cmd := newCommand()
+		n, err := r.readLength(DataTypeAttribute, cmd)
+		commandPool.Put(cmd)
+		if err != nil {
+			return 0, err
+		}
+		for i := 0; i < 2*n; i++ {
+			if _, err := r.readValueTree(1); err != nil {
+				return 0, err
+			}
+		}
+	}
+}
+
+// scanBytes stores the scalar value of the dt type with the b content in the
+// d.
+func scanBytes(d interface{}, dt DataType, b []byte) error {
+	if s, ok := d.(Scanner); ok {
+		return s.ScanRESP(dt, b)
+	}
+
+	if dt == DataTypeVerbatimString {
+		b = b[4:]
+	}
+	null := dt == DataTypeNull
+
+	switch d := d.(type) {
+	case nil:
+		return nil
+
+	case *string:
+		*d = string(b)
+		return nil
+
+	case *[]byte:
+		if null {
+			*d = nil
+			return nil
+		}
+		*d = append((*d)[:0], b...)
+		return nil
+
+	case *int64:
+		if null {
+			*d = 0
+			return nil
+		}
+		if i, ok := scanInt(dt, b); ok {
+			*d = i
+			return nil
+		}
+
+	case *int:
+		if null {
+			*d = 0
+			return nil
+		}
+		if i, ok := scanInt(dt, b); ok && int64(int(i)) == i {
+			*d = int(i)
+			return nil
+		}
+
+	case *float64:
+		if null {
+			*d = 0
+			return nil
+		}
+		if f, ok := scanFloat(dt, b); ok {
+			*d = f
+			return nil
+		}
+
+	case *bool:
+		if null {
+			*d = false
+			return nil
+		}
+		if v, ok := scanBool(dt, b); ok {
+			*d = v
+			return nil
+		}
+
+	case *Value: // Only nulls of the ScanArray get here.
+		if null {
+			*d = Value{Type: DataTypeNull}
+			return nil
+		}
+	}
+
+	return &UnmarshalTypeError{dt, reflect.TypeOf(d)}
+}
+
+func scanInt(dt DataType, b []byte) (int64, bool) {
+	switch dt {
+	case DataTypeInteger, DataTypeBigNumber, DataTypeSimpleString,
+		DataTypeBulkString, DataTypeVerbatimString:
+		i, err := ParseInt(b)
+		return i, err == nil
+	}
+	return 0, false
+}
+
+func scanFloat(dt DataType, b []byte) (float64, bool) {
+	switch dt {
+	case DataTypeDouble, DataTypeInteger, DataTypeSimpleString,
+		DataTypeBulkString, DataTypeVerbatimString:
+		f, err := parseDouble(b)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+func scanBool(dt DataType, b []byte) (bool, bool) {
+	switch dt {
+	case DataTypeBoolean:
+		if len(b) == 1 && (b[0] == 't' || b[0] == 'f') {
+			return b[0] == 't', true
+		}
+	case DataTypeInteger, DataTypeSimpleString, DataTypeBulkString,
+		DataTypeVerbatimString:
+		if len(b) == 1 && (b[0] == '0' || b[0] == '1') {
+			return b[0] == '1', true
+		}
+	}
+	return false, false
+}
+
+// parseDouble interprets the b as a RESP double.
+func parseDouble(b []byte) (float64, error) {
+	switch string(b) {
+	case "inf":
+		return math.Inf(1), nil
+	case "-inf":
+		return math.Inf(-1), nil
+	case "nan":
+		return math.NaN(), nil
+	}
+
+	f, err := strconv.ParseFloat(string(b), 64)
+	if err != nil {
+		return 0, ErrDoubleValue
+	}
+	return f, nil
+}
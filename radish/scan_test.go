@@ -0,0 +1,379 @@
+package radish
+
+import (
+	"bytes"
+	"io"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type testScanner struct {
+	dt DataType
+	b  string
+}
+
+func (s *testScanner) ScanRESP(dt DataType, b []byte) error {
+	s.dt = dt
+	s.b = string(b)
+	return nil
+}
+
+func TestReader_Scan(t *testing.T) {
+	tt := []struct {
+		name  string
+		input string
+		dst   interface{} // A pointer to a zero value of the destination.
+		want  interface{}
+	}{
+		{"simple string to string", "+OK\r\n", new(string), "OK"},
+		{"bulk string to string", "$5\r\nhe\r\no\r\n", new(string), "he\r\no"},
+		{"integer to string", ":-42\r\n", new(string), "-42"},
+		{"verbatim string to string", "=8\r\ntxt:test\r\n", new(string), "test"},
+		{"null bulk string to string", "$-1\r\n", new(string), ""},
+		{"bulk string to bytes", "$4\r\ntest\r\n", new([]byte), []byte("test")},
+		{"double to bytes", ",3.14\r\n", new([]byte), []byte("3.14")},
+		{"null to bytes", "_\r\n", new([]byte), []byte(nil)},
+		{"integer to int64", ":-1337\r\n", new(int64), int64(-1337)},
+		{"bulk string to int64", "$3\r\n123\r\n", new(int64), int64(123)},
+		{"big number to int64", "(123\r\n", new(int64), int64(123)},
+		{"null to int64", "_\r\n", new(int64), int64(0)},
+		{"integer to int", ":42\r\n", new(int), 42},
+		{"double to float64", ",-1.5\r\n", new(float64), -1.5},
+		{"inf to float64", ",inf\r\n", new(float64), math.Inf(1)},
+		{"integer to float64", ":2\r\n", new(float64), float64(2)},
+		{"bulk string to float64", "$4\r\n0.25\r\n", new(float64), 0.25},
+		{"boolean to bool", "#t\r\n", new(bool), true},
+		{"integer to bool", ":1\r\n", new(bool), true},
+		{"bulk string to bool", "$1\r\n0\r\n", new(bool), false},
+		{
+			name:  "array to value",
+			input: "*2\r\n:1\r\n$1\r\na\r\n",
+			dst:   new(Value),
+			want: Value{Type: DataTypeArray, Elems: []Value{
+				{Type: DataTypeInteger, Int: 1},
+				{Type: DataTypeBulkString, Str: "a"},
+			}},
+		},
+		{
+			name:  "integer with attribute",
+			input: "|1\r\n+ttl\r\n:3600\r\n:42\r\n",
+			dst:   new(int64),
+			want:  int64(42),
+		},
+		{
+			name:  "verbatim string to scanner",
+			input: "=8\r\ntxt:test\r\n",
+			dst:   new(testScanner),
+			want:  testScanner{DataTypeVerbatimString, "txt:test"},
+		},
+		{
+			name:  "null to scanner",
+			input: "$-1\r\n",
+			dst:   new(testScanner),
+			want:  testScanner{DataTypeNull, ""},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			r := NewReader(strings.NewReader(tc.input + ":1\r\n"))
+
+			if err := r.Scan(tc.dst); err != nil {
+				t.Fatalf("Scan(): %s", err)
+			}
+
+			got := reflect.ValueOf(tc.dst).Elem().Interface()
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("Scan(): got = %#v, want = %#v", got, tc.want)
+			}
+
+			// The Scan must stop at the start of the next value.
+			if n, err := r.ReadInteger(); err != nil || n != 1 {
+				t.Errorf("ReadInteger(): got = %d, %v, want = 1", n, err)
+			}
+		})
+	}
+}
+
+func TestReader_Scan_multiple(t *testing.T) {
+	r := NewReader(strings.NewReader("+OK\r\n:42\r\n$5\r\nhello\r\n"))
+
+	var (
+		s string
+		i int64
+		b []byte
+	)
+	if err := r.Scan(&s, &i, &b); err != nil {
+		t.Fatalf("Scan(): %s", err)
+	}
+
+	if s != "OK" || i != 42 || string(b) != "hello" {
+		t.Errorf("Scan(): got = %q, %d, %q, want = %q, %d, %q", s, i, b, "OK", 42, "hello")
+	}
+}
+
+func TestReader_Scan_errors(t *testing.T) {
+	tt := []struct {
+		name    string
+		input   string
+		dst     []interface{}
+		wantErr error
+		next    bool // The reader stays at the start of the next value.
+	}{
+		{
+			name:    "error",
+			input:   "-ERR unknown command\r\n",
+			dst:     []interface{}{new(string)},
+			wantErr: &Error{"ERR", "unknown command"},
+			next:    true,
+		},
+		{
+			name:    "bulk error",
+			input:   "!18\r\nSYNTAX invalid arg\r\n",
+			dst:     []interface{}{new(int64)},
+			wantErr: &Error{"SYNTAX", "invalid arg"},
+			next:    true,
+		},
+		{
+			name:    "first error",
+			input:   "+OK\r\n-ERR first\r\n-ERR second\r\n",
+			dst:     []interface{}{new(string), new(string), new(string)},
+			wantErr: &Error{"ERR", "first"},
+			next:    true,
+		},
+		{
+			name:    "string to int64",
+			input:   "+OK\r\n",
+			dst:     []interface{}{new(int64)},
+			wantErr: &UnmarshalTypeError{DataTypeSimpleString, reflect.TypeOf(new(int64))},
+			next:    true,
+		},
+		{
+			name:    "array to string",
+			input:   "*2\r\n:1\r\n:2\r\n",
+			dst:     []interface{}{new(string)},
+			wantErr: &UnmarshalTypeError{DataTypeArray, reflect.TypeOf(new(string))},
+			next:    true,
+		},
+		{
+			name:    "overflow of int",
+			input:   "$20\r\n99999999999999999999\r\n",
+			dst:     []interface{}{new(int64)},
+			wantErr: &UnmarshalTypeError{DataTypeBulkString, reflect.TypeOf(new(int64))},
+			next:    true,
+		},
+		{
+			name:    "unsupported type",
+			input:   ":1\r\n",
+			dst:     []interface{}{new(uint8)},
+			wantErr: &UnmarshalTypeError{DataTypeInteger, reflect.TypeOf(new(uint8))},
+			next:    true,
+		},
+		{
+			name:    "no dst",
+			input:   ":1\r\n",
+			dst:     nil,
+			wantErr: errScanNil,
+		},
+		{
+			name:    "invalid bulk length",
+			input:   "$x\r\n",
+			dst:     []interface{}{new(string)},
+			wantErr: ErrBulkLength,
+		},
+		{
+			name:    "eof",
+			input:   "",
+			dst:     []interface{}{new(string)},
+			wantErr: io.EOF,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			input := tc.input
+			if tc.next {
+				input += ":1\r\n"
+			}
+			r := NewReader(strings.NewReader(input))
+
+			err := r.Scan(tc.dst...)
+			if !reflect.DeepEqual(err, tc.wantErr) {
+				t.Fatalf("Scan(): got error = %v, want error = %v", err, tc.wantErr)
+			}
+
+			if tc.next {
+				if n, err := r.ReadInteger(); err != nil || n != 1 {
+					t.Errorf("ReadInteger(): got = %d, %v, want = 1", n, err)
+				}
+			}
+		})
+	}
+}
+
+func TestReader_ScanArray(t *testing.T) {
+	var (
+		key   string
+		score float64
+		count int
+		flag  bool
+	)
+
+	tt := []struct {
+		name    string
+		input   string
+		want    []interface{}
+		wantErr error
+	}{
+		{
+			name:  "array",
+			input: "*4\r\n$3\r\nkey\r\n$3\r\n1.5\r\n:3\r\n#t\r\n",
+			want:  []interface{}{"key", 1.5, 3, true},
+		},
+		{
+			name:  "map",
+			input: "%2\r\n+key\r\n,1.5\r\n:3\r\n:1\r\n",
+			want:  []interface{}{"key", 1.5, 3, true},
+		},
+		{
+			name:  "null array",
+			input: "*-1\r\n",
+			want:  []interface{}{"", float64(0), 0, false},
+		},
+		{
+			name:    "shorter array",
+			input:   "*2\r\n+key\r\n:2\r\n",
+			want:    []interface{}{"key", float64(2), 0, false},
+			wantErr: &ScanArrayLengthError{2, 4},
+		},
+		{
+			name:    "longer array",
+			input:   "*5\r\n+key\r\n:2\r\n:3\r\n:0\r\n*1\r\n+skipped\r\n",
+			want:    []interface{}{"key", float64(2), 3, false},
+			wantErr: &ScanArrayLengthError{5, 4},
+		},
+		{
+			name:    "error",
+			input:   "-ERR no such key\r\n",
+			want:    []interface{}{"", float64(0), 0, false},
+			wantErr: &Error{"ERR", "no such key"},
+		},
+		{
+			name:    "not an array",
+			input:   "+OK\r\n",
+			want:    []interface{}{"", float64(0), 0, false},
+			wantErr: &UnmarshalTypeError{DataTypeSimpleString, reflect.TypeOf([]interface{}{})},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			key, score, count, flag = "", 0, 0, false
+			r := NewReader(strings.NewReader(tc.input + ":1\r\n"))
+
+			err := r.ScanArray(&key, &score, &count, &flag)
+			if !reflect.DeepEqual(err, tc.wantErr) {
+				t.Fatalf("ScanArray(): got error = %v, want error = %v", err, tc.wantErr)
+			}
+
+			got := []interface{}{key, score, count, flag}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("ScanArray(): got = %#v, want = %#v", got, tc.want)
+			}
+
+			if n, err := r.ReadInteger(); err != nil || n != 1 {
+				t.Errorf("ReadInteger(): got = %d, %v, want = 1", n, err)
+			}
+		})
+	}
+}
+
+// The Scan must not allocate for integers and bulk strings into slices with
+// enough capacity, see the BenchmarkReader_Scan.
+func TestReader_Scan_allocs(t *testing.T) {
+	tt := []struct {
+		name  string
+		input []byte
+		dst   interface{}
+	}{
+		{"integer", []byte(":1337\r\n"), new(int64)},
+		{"bulk string", []byte("$10\r\ntest-value\r\n"), &[]byte{15: 0}},
+		{"array", []byte("*2\r\n:1\r\n$1\r\na\r\n"), nil},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			input := bytes.NewReader(tc.input)
+			r := NewReader(input)
+
+			var i int64
+			b := make([]byte, 0, 16)
+
+			allocs := testing.AllocsPerRun(100, func() {
+				input.Reset(tc.input)
+				r.Reset(input)
+
+				var err error
+				if tc.dst == nil {
+					err = r.ScanArray(&i, &b)
+				} else {
+					err = r.Scan(tc.dst)
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+			})
+			if allocs != 0 {
+				t.Errorf("Scan(): got %v allocs, want 0", allocs)
+			}
+		})
+	}
+}
+
+func BenchmarkReader_Scan(b *testing.B) {
+	bt := []struct {
+		name  string
+		input []byte
+		scan  func(r *Reader, i *int64, s *[]byte) error
+	}{
+		{
+			name:  "integer",
+			input: []byte(":1337\r\n"),
+			scan:  func(r *Reader, i *int64, s *[]byte) error { return r.Scan(i) },
+		},
+		{
+			name:  "bulk string",
+			input: []byte("$10\r\ntest-value\r\n"),
+			scan:  func(r *Reader, i *int64, s *[]byte) error { return r.Scan(s) },
+		},
+		{
+			name:  "array",
+			input: []byte("*2\r\n:1337\r\n$10\r\ntest-value\r\n"),
+			scan:  func(r *Reader, i *int64, s *[]byte) error { return r.ScanArray(i, s) },
+		},
+	}
+
+	for _, bc := range bt {
+		b.Run(bc.name, func(b *testing.B) {
+			input := bytes.NewReader(bc.input)
+			reader := NewReader(input)
+
+			var i int64
+			s := make([]byte, 0, 16)
+
+			b.ReportAllocs()
+			b.ResetTimer()
+
+			for n := 0; n < b.N; n++ {
+				if err := bc.scan(reader, &i, &s); err != nil {
+					b.Fatal(err)
+				}
+
+				input.Reset(bc.input)
+				reader.Reset(input)
+			}
+		})
+	}
+}
@@ -0,0 +1,167 @@
+package server
+
+import (
+	"math"
+	"time"
+
+	"github.com/SuperPaintman/mini-redis/radish"
+)
+
+var (
+	errTimeoutNotFloat = &radish.Error{Kind: "ERR", Msg: "timeout is not a float or out of range"}
+	errTimeoutNegative = &radish.Error{Kind: "ERR", Msg: "timeout is negative"}
+	errTimeoutRange    = &radish.Error{Kind: "ERR", Msg: "timeout is out of range"}
+)
+
+// waiter is a client blocked on keys.
+type waiter struct {
+	keys []string
+
+	// serve tries to serve the client when the key may be ready and reports
+	// whether the client was served. It is called with the keyspace lock
+	// held, from the goroutine of the command that made the key ready, so it
+	// must write the reply by itself.
+	serve func(key string) bool
+
+	served bool
+	done   chan struct{}
+}
+
+// parseTimeout parses a timeout of blocking commands in seconds. A zero
+// timeout means to block forever.
+func parseTimeout(arg radish.Arg) (time.Duration, *radish.Error) {
+	seconds, ok := parseFloat(arg)
+	if !ok {
+		return 0, errTimeoutNotFloat
+	}
+	if seconds < 0 {
+		return 0, errTimeoutNegative
+	}
+
+	ms := seconds * 1000
+	if ms > math.MaxInt64/float64(time.Millisecond) {
+		return 0, errTimeoutRange
+	}
+
+	return time.Duration(ms) * time.Millisecond, nil
+}
+
+// block blocks the client on the keys until it is served, the timeout
+// expires, the connection is closed or the server is shutting down, and
+// reports whether the client was served. A zero timeout means to block
+// forever.
+//
+// Clients blocked on the same key are served in FIFO order, before any other
+// client can touch the key.
+//
+// It must be called with the keyspace lock held. The lock is released while
+// the client is blocked and acquired again before block returns.
+func (ks *Keyspace) block(c *Conn, keys []radish.Arg, timeout time.Duration, serve func(key string) bool) bool {
+	wt := &waiter{
+		serve: serve,
+		done:  make(chan struct{}),
+	}
+
+	for _, key := range keys {
+		k := string(key)
+
+		duplicate := false
+		for _, other := range wt.keys {
+			if other == k {
+				duplicate = true
+				break
+			}
+		}
+		if duplicate {
+			continue
+		}
+
+		wt.keys = append(wt.keys, k)
+		if ks.blocked == nil {
+			ks.blocked = make(map[string][]*waiter)
+		}
+		ks.blocked[k] = append(ks.blocked[k], wt)
+	}
+
+	ks.mu.Unlock()
+
+	var expired <-chan time.Time
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		expired = timer.C
+	}
+
+	closed, stop := c.watchClosed()
+
+	select {
+	case <-wt.done:
+	case <-expired:
+	case <-closed:
+	case <-c.ctx.Done():
+	}
+
+	stop()
+
+	ks.mu.Lock()
+
+	if !wt.served {
+		ks.unblock(wt)
+	}
+
+	return wt.served
+}
+
+func (ks *Keyspace) unblock(wt *waiter) {
+	for _, key := range wt.keys {
+		queue := ks.blocked[key]
+		for i, other := range queue {
+			if other == wt {
+				queue = append(queue[:i], queue[i+1:]...)
+				break
+			}
+		}
+
+		if len(queue) == 0 {
+			delete(ks.blocked, key)
+		} else {
+			ks.blocked[key] = queue
+		}
+	}
+}
+
+// signalKeyAsReady serves clients blocked on the key. It must be called with
+// the keyspace lock held after each modification that may make the key ready
+// for them, e.g. a push into a list.
+func (ks *Keyspace) signalKeyAsReady(key []byte) {
+	if _, ok := ks.blocked[string(key)]; !ok {
+		return
+	}
+
+	ks.ready = append(ks.ready, string(key))
+
+	// Serving a client may make other keys ready (e.g. BLMOVE), they are
+	// served by the outermost call.
+	if ks.serving {
+		return
+	}
+	ks.serving = true
+	defer func() { ks.serving = false }()
+
+	for len(ks.ready) > 0 {
+		key := ks.ready[0]
+		ks.ready = ks.ready[1:]
+
+		// Copy the queue, because it is modified while clients are served.
+		queue := append([]*waiter(nil), ks.blocked[key]...)
+		for _, wt := range queue {
+			if wt.served || !wt.serve(key) {
+				continue
+			}
+
+			wt.served = true
+			ks.unblock(wt)
+			close(wt.done)
+		}
+	}
+}
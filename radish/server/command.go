@@ -0,0 +1,270 @@
+package server
+
+import (
+	"strings"
+
+	"github.com/SuperPaintman/mini-redis/radish"
+)
+
+// aclCategories are ACL categories in the same order as Redis reports them.
+var aclCategories = []string{
+	"keyspace",
+	"read",
+	"write",
+	"set",
+	"sortedset",
+	"list",
+	"hash",
+	"string",
+	"bitmap",
+	"hyperloglog",
+	"geo",
+	"stream",
+	"pubsub",
+	"admin",
+	"fast",
+	"slow",
+	"blocking",
+	"dangerous",
+	"connection",
+	"transaction",
+	"scripting",
+}
+
+// groupCategories maps command groups to their ACL categories.
+var groupCategories = map[string]string{
+	"generic":     "keyspace",
+	"string":      "string",
+	"list":        "list",
+	"hash":        "hash",
+	"set":         "set",
+	"sorted-set":  "sortedset",
+	"stream":      "stream",
+	"pubsub":      "pubsub",
+	"connection":  "connection",
+	"transaction": "transaction",
+	"scripting":   "scripting",
+}
+
+// categories returns ACL categories of the command implied by its flags and
+// group.
+func (info *CommandInfo) categories() []string {
+	set := make(map[string]bool)
+
+	if info.Flags.Has(FlagWrite) {
+		set["write"] = true
+	}
+	if info.Flags.Has(FlagReadonly) {
+		set["read"] = true
+	}
+	if info.Flags.Has(FlagAdmin) {
+		set["admin"] = true
+		set["dangerous"] = true
+	}
+	if info.Flags.Has(FlagPubSub) {
+		set["pubsub"] = true
+	}
+	if info.Flags.Has(FlagBlocking) {
+		set["blocking"] = true
+	}
+	if info.Flags.Has(FlagFast) {
+		set["fast"] = true
+	} else {
+		set["slow"] = true
+	}
+	if category, ok := groupCategories[info.Group]; ok {
+		set[category] = true
+	}
+
+	var categories []string
+	for _, category := range aclCategories {
+		if set[category] {
+			categories = append(categories, "@"+category)
+		}
+	}
+	return categories
+}
+
+var commandHelp = []string{
+	"COMMAND <subcommand> [<arg> [value] [opt] ...]. Subcommands are:",
+	"(no subcommand)",
+	"    Return details about all Redis commands.",
+	"COUNT",
+	"    Return the total number of commands in this Redis server.",
+	"LIST",
+	"    Return a list of all commands in this Redis server.",
+	"INFO [<command-name> ...]",
+	"    Return details about multiple Redis commands.",
+	"    If no command names are given, documentation details for all",
+	"    commands are returned.",
+	"DOCS [<command-name> ...]",
+	"    Return documentation details about multiple Redis commands.",
+	"    If no command names are given, documentation details for all",
+	"    commands are returned.",
+	"HELP",
+	"    Print this help.",
+}
+
+func (m *Mux) registerCommandCommand() {
+	m.HandleFunc(CommandInfo{
+		Name:       "command",
+		Arity:      -1,
+		Flags:      FlagLoading | FlagStale,
+		Group:      "server",
+		Summary:    "Returns detailed information about all commands.",
+		Since:      "2.8.13",
+		Complexity: "O(N) where N is the total number of Redis commands",
+	}, m.command)
+}
+
+func (m *Mux) command(c *Conn, cmd *radish.Command) {
+	w := c.Writer()
+
+	if len(cmd.Args) == 1 {
+		writeCommandInfos(w, m.Commands())
+		return
+	}
+
+	sub := strings.ToLower(string(cmd.Args[1]))
+	switch sub {
+	case "count":
+		if len(cmd.Args) != 2 {
+			_ = writeWrongArity(w, "command|"+sub)
+			return
+		}
+
+		m.mu.RLock()
+		n := len(m.commands)
+		m.mu.RUnlock()
+
+		_ = w.WriteInt(n)
+
+	case "list":
+		if len(cmd.Args) != 2 {
+			_ = writeWrongArity(w, "command|"+sub)
+			return
+		}
+
+		infos := m.Commands()
+		_ = w.WriteArray(len(infos))
+		for i := range infos {
+			_ = w.WriteString(infos[i].Name)
+		}
+
+	case "info":
+		if len(cmd.Args) == 2 {
+			writeCommandInfos(w, m.Commands())
+			return
+		}
+
+		_ = w.WriteArray(len(cmd.Args) - 2)
+		for _, name := range cmd.Args[2:] {
+			e := m.lookup(name)
+			if e == nil {
+				_ = w.WriteArray(-1)
+				continue
+			}
+			writeCommandInfo(w, &e.info)
+		}
+
+	case "docs":
+		var infos []CommandInfo
+		if len(cmd.Args) == 2 {
+			infos = m.Commands()
+		} else {
+			// Unknown commands are omitted.
+			for _, name := range cmd.Args[2:] {
+				if e := m.lookup(name); e != nil {
+					infos = append(infos, e.info)
+				}
+			}
+		}
+
+		_ = w.WriteMap(len(infos))
+		for i := range infos {
+			writeCommandDocs(w, &infos[i])
+		}
+
+	case "help":
+		if len(cmd.Args) != 2 {
+			_ = writeWrongArity(w, "command|"+sub)
+			return
+		}
+
+		_ = w.WriteArray(len(commandHelp))
+		for _, line := range commandHelp {
+			_ = w.WriteSimpleString(line)
+		}
+
+	default:
+		_ = w.WriteError(unknownSubcommand(cmd.Args[1], "COMMAND"))
+	}
+}
+
+func writeCommandInfos(w *radish.Writer, infos []CommandInfo) {
+	_ = w.WriteArray(len(infos))
+	for i := range infos {
+		writeCommandInfo(w, &infos[i])
+	}
+}
+
+func writeCommandInfo(w *radish.Writer, info *CommandInfo) {
+	_ = w.WriteArray(10)
+	_ = w.WriteString(info.Name)
+	_ = w.WriteInt(info.Arity)
+
+	flags := info.Flags.Names()
+	_ = w.WriteSet(len(flags))
+	for _, flag := range flags {
+		_ = w.WriteSimpleString(flag)
+	}
+
+	_ = w.WriteInt(info.FirstKey)
+	_ = w.WriteInt(info.LastKey)
+	_ = w.WriteInt(info.KeyStep)
+
+	categories := info.categories()
+	_ = w.WriteSet(len(categories))
+	for _, category := range categories {
+		_ = w.WriteSimpleString(category)
+	}
+
+	_ = w.WriteArray(0) // Tips.
+	_ = w.WriteArray(0) // Key specs.
+	_ = w.WriteArray(0) // Subcommands.
+}
+
+func writeCommandDocs(w *radish.Writer, info *CommandInfo) {
+	fields := [...]struct {
+		name  string
+		value string
+	}{
+		{"summary", info.Summary},
+		{"since", info.Since},
+		{"group", info.Group},
+		{"complexity", info.Complexity},
+	}
+
+	n := 0
+	for _, f := range fields {
+		if f.value != "" {
+			n++
+		}
+	}
+
+	_ = w.WriteString(info.Name)
+	_ = w.WriteMap(n)
+	for _, f := range fields {
+		if f.value != "" {
+			_ = w.WriteString(f.name)
+			_ = w.WriteString(f.value)
+		}
+	}
+}
+
+func unknownSubcommand(sub radish.Arg, command string) *radish.Error {
+	return &radish.Error{
+		Kind: "ERR",
+		Msg:  "unknown subcommand '" + string(sub) + "'. Try " + command + " HELP.",
+	}
+}
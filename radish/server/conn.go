@@ -0,0 +1,216 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"runtime"
+	"sync/atomic"
+	"time"
+
+	"github.com/SuperPaintman/mini-redis/radish"
+)
+
+// aLongTimeAgo is a non-zero time, far in the past, used for immediate
+// cancellation of network operations.
+var aLongTimeAgo = time.Unix(1, 0)
+
+// maxPendingOutput is the size of the output buffer after which replies are
+// written to the client even while there are pipelined commands, so a client
+// that does not read its replies cannot grow the buffer without bound.
+const maxPendingOutput = 64 * 1024
+
+// lastConnID is the ID of the last accepted connection. IDs are unique across
+// all servers in the process, like Redis client IDs are.
+var lastConnID int64
+
+// Conn represents the server side of a client connection.
+type Conn struct {
+	server *Server
+	rwc    net.Conn
+	id     int64
+	name   string
+
+	r *radish.Reader
+	w *radish.Writer
+
+	// out is the output buffer of the connection. Replies are accumulated in
+	// it and written to the rwc only after the handler returns, so handlers
+	// never block on the network. See the maxPendingOutput.
+	out bytes.Buffer
+
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	closing bool
+}
+
+func (s *Server) newConn(ctx context.Context, rwc net.Conn) *Conn {
+	c := &Conn{
+		server: s,
+		rwc:    rwc,
+		id:     atomic.AddInt64(&lastConnID, 1),
+		r:      radish.NewReaderOptions(rwc, s.ReaderOptions),
+	}
+	c.w = radish.NewWriter(&c.out)
+	c.ctx, c.cancel = context.WithCancel(ctx)
+	return c
+}
+
+// Writer returns the Writer for replies to the connection.
+//
+// It must be used only by the handler of the current command.
+func (c *Conn) Writer() *radish.Writer {
+	return c.w
+}
+
+// ID returns the unique ID of the connection.
+func (c *Conn) ID() int64 {
+	return c.id
+}
+
+// Name returns the name of the connection set by the client, or an empty
+// string.
+func (c *Conn) Name() string {
+	return c.name
+}
+
+// SetName sets the name of the connection.
+func (c *Conn) SetName(name string) {
+	c.name = name
+}
+
+// Protocol returns the version of the RESP protocol negotiated by the client
+// via HELLO: radish.RESP2 (default) or radish.RESP3.
+func (c *Conn) Protocol() int {
+	return c.w.Protocol()
+}
+
+// SetProtocol switches the connection to the version of the RESP protocol.
+// Replies written after the call use the new version.
+func (c *Conn) SetProtocol(protover int) {
+	c.w.SetProtocol(protover)
+}
+
+// Context returns the context of the connection. It is canceled when the
+// connection is closed or the server is shutting down.
+func (c *Conn) Context() context.Context {
+	return c.ctx
+}
+
+// RemoteAddr returns the remote network address of the client.
+func (c *Conn) RemoteAddr() net.Addr {
+	return c.rwc.RemoteAddr()
+}
+
+// Close closes the connection after the reply for the current command is
+// written.
+func (c *Conn) Close() {
+	c.closing = true
+}
+
+// watchClosed watches for the peer closing the connection while the handler
+// is blocked and does not read commands. The returned channel is closed if it
+// happens.
+//
+// The stop must be called before the handler returns.
+func (c *Conn) watchClosed() (closed <-chan struct{}, stop func()) {
+	// The peer has already sent the next commands, they will be read after
+	// the handler returns.
+	if c.r.Buffered() > 0 {
+		return nil, func() {}
+	}
+
+	ch := make(chan struct{})
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		if _, err := c.r.Peek(1); err != nil {
+			if ne, ok := err.(net.Error); ok && ne.Timeout() {
+				return
+			}
+			close(ch)
+		}
+	}()
+
+	return ch, func() {
+		// Interrupt the Peek and restore the deadline.
+		_ = c.rwc.SetReadDeadline(aLongTimeAgo)
+		<-done
+		_ = c.rwc.SetReadDeadline(time.Time{})
+	}
+}
+
+func (c *Conn) serve() {
+	defer c.close()
+
+	defer func() {
+		if err := recover(); err != nil {
+			const size = 64 << 10
+			buf := make([]byte, size)
+			buf = buf[:runtime.Stack(buf, false)]
+			c.server.logf("radish: panic serving %v: %v\n%s", c.RemoteAddr(), err, buf)
+		}
+	}()
+
+	// Interrupt the reading of the next command on shutdown.
+	go func() {
+		<-c.ctx.Done()
+		_ = c.rwc.SetReadDeadline(aLongTimeAgo)
+	}()
+
+	handler := c.server.handler()
+
+	for {
+		if c.ctx.Err() != nil {
+			return
+		}
+
+		cmd, err := c.r.ReadCommand()
+		if err != nil {
+			// Report protocol errors to the client before closing the
+			// connection.
+			if e, ok := err.(*radish.ProtocolError); ok {
+				_ = c.w.WriteError(e.RESPError())
+				_ = c.flush()
+			}
+			return
+		}
+
+		handler.ServeRESP(c, cmd)
+		cmd.Release()
+
+		// Move the replies into the out, it never blocks.
+		if err := c.w.Flush(); err != nil {
+			return
+		}
+
+		// Do not flush the replies while there are pipelined commands,
+		// unless too many of them are pending.
+		if c.r.Buffered() == 0 || c.closing || c.out.Len() >= maxPendingOutput {
+			if err := c.flush(); err != nil {
+				return
+			}
+		}
+
+		if c.closing {
+			return
+		}
+	}
+}
+
+func (c *Conn) flush() error {
+	if err := c.w.Flush(); err != nil {
+		return err
+	}
+
+	_, err := c.out.WriteTo(c.rwc)
+	return err
+}
+
+func (c *Conn) close() {
+	c.cancel()
+	_ = c.rwc.Close()
+}
@@ -0,0 +1,176 @@
+package server
+
+import (
+	"strconv"
+
+	"github.com/SuperPaintman/mini-redis/radish"
+)
+
+func registerConnectionCommands(m *Mux) {
+	m.HandleFunc(CommandInfo{
+		Name:       "ping",
+		Arity:      -1,
+		Flags:      FlagFast,
+		Group:      "connection",
+		Summary:    "Returns the server's liveliness response.",
+		Since:      "1.0.0",
+		Complexity: "O(1)",
+	}, ping)
+
+	m.HandleFunc(CommandInfo{
+		Name:       "echo",
+		Arity:      2,
+		Flags:      FlagFast,
+		Group:      "connection",
+		Summary:    "Returns the given string.",
+		Since:      "1.0.0",
+		Complexity: "O(1)",
+	}, echo)
+
+	m.HandleFunc(CommandInfo{
+		Name:       "hello",
+		Arity:      -1,
+		Flags:      FlagNoScript | FlagLoading | FlagStale | FlagFast | FlagNoAuth,
+		Group:      "connection",
+		Summary:    "Handshakes with the Redis server.",
+		Since:      "6.0.0",
+		Complexity: "O(1)",
+	}, hello)
+
+	m.HandleFunc(CommandInfo{
+		Name:       "quit",
+		Arity:      -1,
+		Flags:      FlagNoScript | FlagLoading | FlagStale | FlagFast | FlagNoAuth,
+		Group:      "connection",
+		Summary:    "Closes the connection.",
+		Since:      "1.0.0",
+		Complexity: "O(1)",
+	}, quit)
+}
+
+func ping(c *Conn, cmd *radish.Command) {
+	w := c.Writer()
+
+	switch len(cmd.Args) {
+	case 1:
+		_ = w.WriteSimpleString("PONG")
+	case 2:
+		_ = w.WriteBytes(cmd.Args[1])
+	default:
+		_ = writeWrongArity(w, "ping")
+	}
+}
+
+func echo(c *Conn, cmd *radish.Command) {
+	_ = c.Writer().WriteBytes(cmd.Args[1])
+}
+
+func quit(c *Conn, cmd *radish.Command) {
+	_ = c.Writer().WriteSimpleString("OK")
+	c.Close()
+}
+
+// defaultUser is the only user of the server. Like the default user of Redis
+// without the requirepass, it accepts any password.
+const defaultUser = "default"
+
+var errWrongPass = &radish.Error{
+	Kind: "WRONGPASS",
+	Msg:  "invalid username-password pair or user is disabled.",
+}
+
+func hello(c *Conn, cmd *radish.Command) {
+	w := c.Writer()
+
+	protover := c.Protocol()
+	if len(cmd.Args) >= 2 {
+		ver, err := strconv.ParseInt(string(cmd.Args[1]), 10, 64)
+		if err != nil {
+			_ = w.WriteError(&radish.Error{
+				Kind: "ERR",
+				Msg:  "Protocol version is not an integer or out of range",
+			})
+			return
+		}
+		if ver < radish.RESP2 || ver > radish.RESP3 {
+			_ = w.WriteError(&radish.Error{
+				Kind: "NOPROTO",
+				Msg:  "unsupported protocol version",
+			})
+			return
+		}
+		protover = int(ver)
+	}
+
+	var (
+		name    string
+		setName bool
+	)
+	for i := 2; i < len(cmd.Args); i++ {
+		opt := cmd.Args[i]
+		more := len(cmd.Args) - 1 - i
+
+		switch {
+		case equalFold(opt, "AUTH") && more >= 2:
+			if string(cmd.Args[i+1]) != defaultUser {
+				_ = w.WriteError(errWrongPass)
+				return
+			}
+			i += 2
+
+		case equalFold(opt, "SETNAME") && more >= 1:
+			name = string(cmd.Args[i+1])
+			setName = true
+			i++
+
+		default:
+			_ = w.WriteError(&radish.Error{
+				Kind: "ERR",
+				Msg:  "Syntax error in HELLO option '" + string(opt) + "'",
+			})
+			return
+		}
+	}
+
+	if setName {
+		if !validClientName(name) {
+			_ = w.WriteError(errClientName)
+			return
+		}
+		c.SetName(name)
+	}
+
+	c.SetProtocol(protover)
+
+	_ = w.WriteMap(7)
+	_ = w.WriteString("server")
+	_ = w.WriteString("redis")
+	_ = w.WriteString("version")
+	_ = w.WriteString(Version)
+	_ = w.WriteString("proto")
+	_ = w.WriteInt(protover)
+	_ = w.WriteString("id")
+	_ = w.WriteInt64(c.ID())
+	_ = w.WriteString("mode")
+	_ = w.WriteString("standalone")
+	_ = w.WriteString("role")
+	_ = w.WriteString("master")
+	_ = w.WriteString("modules")
+	_ = w.WriteArray(0)
+}
+
+var errClientName = &radish.Error{
+	Kind: "ERR",
+	Msg:  "Client names cannot contain spaces, newlines or special characters.",
+}
+
+// validClientName reports whether the name contains only printable ASCII
+// characters without spaces. An empty name is valid and resets the name.
+func validClientName(name string) bool {
+	for i := 0; i < len(name); i++ {
+		if name[i] < '!' || name[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,359 @@
+package server
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"github.com/SuperPaintman/mini-redis/radish"
+)
+
+// Clock provides the current time to a Keyspace.
+type Clock interface {
+	Now() time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time { return time.Now() }
+
+// Parameters of the active expiration, the same as Redis uses.
+const (
+	activeExpireInterval        = 100 * time.Millisecond // 10 Hz.
+	activeExpireKeysPerLoop     = 20
+	activeExpireAcceptableStale = 10 // Percent of expired keys in a sample.
+	activeExpireTimeBudget      = activeExpireInterval * 25 / 100
+)
+
+// ActiveExpire runs the ActiveExpireCycle every 100ms until the ctx is done.
+func (ks *Keyspace) ActiveExpire(ctx context.Context) {
+	ticker := time.NewTicker(activeExpireInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			ks.ActiveExpireCycle()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// ActiveExpireCycle deletes expired keys, which would otherwise stay in memory
+// until they are accessed, and returns the number of deleted keys.
+//
+// Like Redis, it samples random keys with an expire and deletes the expired
+// ones. The sampling repeats while more than 10% of the sampled keys were
+// expired, but no longer than 25ms of the real time.
+func (ks *Keyspace) ActiveExpireCycle() int {
+	start := time.Now()
+
+	total := 0
+	for {
+		ks.mu.Lock()
+		now := ks.now()
+		sampled, expired := 0, 0
+		// The iteration order over maps is random, so it is a random sample.
+		for key, when := range ks.expires {
+			if sampled == activeExpireKeysPerLoop {
+				break
+			}
+			sampled++
+
+			if when <= now {
+				delete(ks.dict, key)
+				delete(ks.expires, key)
+				expired++
+			}
+		}
+		ks.mu.Unlock()
+
+		total += expired
+
+		if sampled == 0 ||
+			expired*100/sampled <= activeExpireAcceptableStale ||
+			time.Since(start) > activeExpireTimeBudget {
+			return total
+		}
+	}
+}
+
+// Options of the EXPIRE family commands.
+const (
+	expireNX = 1 << iota
+	expireXX
+	expireGT
+	expireLT
+)
+
+func (ks *Keyspace) registerExpireCommands(m *Mux) {
+	m.HandleFunc(CommandInfo{
+		Name:       "expire",
+		Arity:      -3,
+		Flags:      FlagWrite | FlagFast,
+		FirstKey:   1,
+		LastKey:    1,
+		KeyStep:    1,
+		Group:      "generic",
+		Summary:    "Sets the expiration time of a key in seconds.",
+		Since:      "1.0.0",
+		Complexity: "O(1)",
+	}, func(c *Conn, cmd *radish.Command) {
+		ks.expireGeneric(c, cmd, "expire", true, time.Second)
+	})
+
+	m.HandleFunc(CommandInfo{
+		Name:       "pexpire",
+		Arity:      -3,
+		Flags:      FlagWrite | FlagFast,
+		FirstKey:   1,
+		LastKey:    1,
+		KeyStep:    1,
+		Group:      "generic",
+		Summary:    "Sets the expiration time of a key in milliseconds.",
+		Since:      "2.6.0",
+		Complexity: "O(1)",
+	}, func(c *Conn, cmd *radish.Command) {
+		ks.expireGeneric(c, cmd, "pexpire", true, time.Millisecond)
+	})
+
+	m.HandleFunc(CommandInfo{
+		Name:       "expireat",
+		Arity:      -3,
+		Flags:      FlagWrite | FlagFast,
+		FirstKey:   1,
+		LastKey:    1,
+		KeyStep:    1,
+		Group:      "generic",
+		Summary:    "Sets the expiration time of a key to a Unix timestamp.",
+		Since:      "1.2.0",
+		Complexity: "O(1)",
+	}, func(c *Conn, cmd *radish.Command) {
+		ks.expireGeneric(c, cmd, "expireat", false, time.Second)
+	})
+
+	m.HandleFunc(CommandInfo{
+		Name:       "pexpireat",
+		Arity:      -3,
+		Flags:      FlagWrite | FlagFast,
+		FirstKey:   1,
+		LastKey:    1,
+		KeyStep:    1,
+		Group:      "generic",
+		Summary:    "Sets the expiration time of a key to a Unix milliseconds timestamp.",
+		Since:      "2.6.0",
+		Complexity: "O(1)",
+	}, func(c *Conn, cmd *radish.Command) {
+		ks.expireGeneric(c, cmd, "pexpireat", false, time.Millisecond)
+	})
+
+	m.HandleFunc(CommandInfo{
+		Name:       "ttl",
+		Arity:      2,
+		Flags:      FlagReadonly | FlagFast,
+		FirstKey:   1,
+		LastKey:    1,
+		KeyStep:    1,
+		Group:      "generic",
+		Summary:    "Returns the expiration time in seconds of a key.",
+		Since:      "1.0.0",
+		Complexity: "O(1)",
+	}, func(c *Conn, cmd *radish.Command) {
+		ks.ttlGeneric(c, cmd, false, time.Second)
+	})
+
+	m.HandleFunc(CommandInfo{
+		Name:       "pttl",
+		Arity:      2,
+		Flags:      FlagReadonly | FlagFast,
+		FirstKey:   1,
+		LastKey:    1,
+		KeyStep:    1,
+		Group:      "generic",
+		Summary:    "Returns the expiration time in milliseconds of a key.",
+		Since:      "2.6.0",
+		Complexity: "O(1)",
+	}, func(c *Conn, cmd *radish.Command) {
+		ks.ttlGeneric(c, cmd, false, time.Millisecond)
+	})
+
+	m.HandleFunc(CommandInfo{
+		Name:       "expiretime",
+		Arity:      2,
+		Flags:      FlagReadonly | FlagFast,
+		FirstKey:   1,
+		LastKey:    1,
+		KeyStep:    1,
+		Group:      "generic",
+		Summary:    "Returns the expiration time of a key as a Unix timestamp.",
+		Since:      "7.0.0",
+		Complexity: "O(1)",
+	}, func(c *Conn, cmd *radish.Command) {
+		ks.ttlGeneric(c, cmd, true, time.Second)
+	})
+
+	m.HandleFunc(CommandInfo{
+		Name:       "pexpiretime",
+		Arity:      2,
+		Flags:      FlagReadonly | FlagFast,
+		FirstKey:   1,
+		LastKey:    1,
+		KeyStep:    1,
+		Group:      "generic",
+		Summary:    "Returns the expiration time of a key as a Unix milliseconds timestamp.",
+		Since:      "7.0.0",
+		Complexity: "O(1)",
+	}, func(c *Conn, cmd *radish.Command) {
+		ks.ttlGeneric(c, cmd, true, time.Millisecond)
+	})
+
+	m.HandleFunc(CommandInfo{
+		Name:       "persist",
+		Arity:      2,
+		Flags:      FlagWrite | FlagFast,
+		FirstKey:   1,
+		LastKey:    1,
+		KeyStep:    1,
+		Group:      "generic",
+		Summary:    "Removes the expiration time of a key.",
+		Since:      "2.2.0",
+		Complexity: "O(1)",
+	}, ks.persist)
+}
+
+// expireGeneric implements EXPIRE, PEXPIRE, EXPIREAT and PEXPIREAT.
+func (ks *Keyspace) expireGeneric(c *Conn, cmd *radish.Command, command string, relative bool, unit time.Duration) {
+	w := c.Writer()
+
+	var flags int
+	for _, arg := range cmd.Args[3:] {
+		switch {
+		case equalFold(arg, "NX"):
+			flags |= expireNX
+		case equalFold(arg, "XX"):
+			flags |= expireXX
+		case equalFold(arg, "GT"):
+			flags |= expireGT
+		case equalFold(arg, "LT"):
+			flags |= expireLT
+		default:
+			_ = w.WriteError(&radish.Error{Kind: "ERR", Msg: "Unsupported option " + string(arg)})
+			return
+		}
+	}
+
+	if flags&expireNX != 0 && flags&(expireXX|expireGT|expireLT) != 0 {
+		_ = w.WriteError(&radish.Error{Kind: "ERR", Msg: "NX and XX, GT or LT options at the same time are not compatible"})
+		return
+	}
+	if flags&expireGT != 0 && flags&expireLT != 0 {
+		_ = w.WriteError(&radish.Error{Kind: "ERR", Msg: "GT and LT options at the same time are not compatible"})
+		return
+	}
+
+	when, ok := parseInt(cmd.Args[2])
+	if !ok {
+		_ = w.WriteError(errNotInteger)
+		return
+	}
+
+	if unit == time.Second {
+		if when > math.MaxInt64/1000 || when < math.MinInt64/1000 {
+			_ = w.WriteError(invalidExpireTime(command))
+			return
+		}
+		when *= 1000
+	}
+
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	if relative {
+		now := ks.now()
+		if when > math.MaxInt64-now {
+			_ = w.WriteError(invalidExpireTime(command))
+			return
+		}
+		when += now
+	}
+
+	key := cmd.Args[1]
+	if ks.lookup(key) == nil {
+		_ = w.WriteInt(0)
+		return
+	}
+
+	if flags != 0 {
+		// A key without an expire has an infinite TTL.
+		current, volatile := ks.expires[string(key)]
+
+		if (flags&expireNX != 0 && volatile) ||
+			(flags&expireXX != 0 && !volatile) ||
+			(flags&expireGT != 0 && (!volatile || when <= current)) ||
+			(flags&expireLT != 0 && volatile && when >= current) {
+			_ = w.WriteInt(0)
+			return
+		}
+	}
+
+	ks.setExpire(key, when)
+
+	_ = w.WriteInt(1)
+}
+
+// ttlGeneric implements TTL, PTTL, EXPIRETIME and PEXPIRETIME.
+func (ks *Keyspace) ttlGeneric(c *Conn, cmd *radish.Command, absolute bool, unit time.Duration) {
+	w := c.Writer()
+
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	key := cmd.Args[1]
+	if ks.lookup(key) == nil {
+		_ = w.WriteInt(-2)
+		return
+	}
+
+	when, ok := ks.expires[string(key)]
+	if !ok {
+		_ = w.WriteInt(-1)
+		return
+	}
+
+	if absolute {
+		if unit == time.Second {
+			when /= 1000
+		}
+		_ = w.WriteInt64(when)
+		return
+	}
+
+	ttl := when - ks.now()
+	if ttl < 0 {
+		ttl = 0
+	}
+	if unit == time.Second {
+		ttl = (ttl + 500) / 1000
+	}
+	_ = w.WriteInt64(ttl)
+}
+
+func (ks *Keyspace) persist(c *Conn, cmd *radish.Command) {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	key := cmd.Args[1]
+	if ks.lookup(key) == nil {
+		_ = c.Writer().WriteInt(0)
+		return
+	}
+
+	if _, ok := ks.expires[string(key)]; !ok {
+		_ = c.Writer().WriteInt(0)
+		return
+	}
+
+	delete(ks.expires, string(key))
+	_ = c.Writer().WriteInt(1)
+}
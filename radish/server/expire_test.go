@@ -0,0 +1,187 @@
+package server
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+type testClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func newTestClock() *testClock {
+	return &testClock{now: time.Unix(1700000000, 0)}
+}
+
+func (c *testClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.now
+}
+
+func (c *testClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.now = c.now.Add(d)
+}
+
+func TestKeyspace_expire(t *testing.T) {
+	clock := newTestClock()
+	ks := NewKeyspace()
+	ks.Clock = clock
+	c := dialTestClient(t, startTestKeyspaceServer(t, ks))
+
+	runTestSteps(t, c, []testStep{
+		{"TTL foo", "(integer) -2"},
+		{"EXPIRE foo 10", "(integer) 0"},
+		{"SET foo bar", "OK"},
+		{"TTL foo", "(integer) -1"},
+		{"EXPIRETIME foo", "(integer) -1"},
+		{"EXPIRE foo 10", "(integer) 1"},
+		{"TTL foo", "(integer) 10"},
+		{"PTTL foo", "(integer) 10000"},
+		{"EXPIRETIME foo", "(integer) 1700000010"},
+		{"PEXPIRETIME foo", "(integer) 1700000010000"},
+	})
+
+	clock.Advance(4500 * time.Millisecond)
+
+	runTestSteps(t, c, []testStep{
+		{"TTL foo", "(integer) 6"},
+		{"PTTL foo", "(integer) 5500"},
+		{"GET foo", `"bar"`},
+	})
+
+	clock.Advance(5500 * time.Millisecond)
+
+	runTestSteps(t, c, []testStep{
+		{"GET foo", "(nil)"},
+		{"TTL foo", "(integer) -2"},
+	})
+}
+
+func TestKeyspace_expireOptions(t *testing.T) {
+	clock := newTestClock()
+	ks := NewKeyspace()
+	ks.Clock = clock
+	c := dialTestClient(t, startTestKeyspaceServer(t, ks))
+
+	runTestSteps(t, c, []testStep{
+		{"SET foo bar", "OK"},
+		{"EXPIRE foo 100 XX", "(integer) 0"},
+		{"EXPIRE foo 100 GT", "(integer) 0"},
+		{"EXPIRE foo 100 NX", "(integer) 1"},
+		{"EXPIRE foo 200 NX", "(integer) 0"},
+		{"EXPIRE foo 50 GT", "(integer) 0"},
+		{"EXPIRE foo 200 GT", "(integer) 1"},
+		{"EXPIRE foo 300 LT", "(integer) 0"},
+		{"EXPIRE foo 150 LT", "(integer) 1"},
+		{"EXPIRE foo 120 XX", "(integer) 1"},
+		{"TTL foo", "(integer) 120"},
+		{"PERSIST foo", "(integer) 1"},
+		{"PERSIST foo", "(integer) 0"},
+		{"EXPIRE foo 100 LT", "(integer) 1"},
+		{"EXPIRE foo 100 NX XX", "(error) ERR NX and XX, GT or LT options at the same time are not compatible"},
+		{"EXPIRE foo 100 GT LT", "(error) ERR GT and LT options at the same time are not compatible"},
+		{"EXPIRE foo 100 YY", "(error) ERR Unsupported option YY"},
+		{"EXPIRE foo abc", "(error) ERR value is not an integer or out of range"},
+		{"EXPIRE foo 9223372036854775807", "(error) ERR invalid expire time in 'expire' command"},
+		{"PEXPIRE foo 9223372036854775807", "(error) ERR invalid expire time in 'pexpire' command"},
+	})
+}
+
+func TestKeyspace_expireat(t *testing.T) {
+	clock := newTestClock()
+	ks := NewKeyspace()
+	ks.Clock = clock
+	c := dialTestClient(t, startTestKeyspaceServer(t, ks))
+
+	runTestSteps(t, c, []testStep{
+		{"SET foo bar", "OK"},
+		{"EXPIREAT foo 1700000100", "(integer) 1"},
+		{"TTL foo", "(integer) 100"},
+		{"PEXPIREAT foo 1700000000500", "(integer) 1"},
+		{"PTTL foo", "(integer) 500"},
+		{"PEXPIRE foo 1500", "(integer) 1"},
+		{"PEXPIRETIME foo", "(integer) 1700000001500"},
+		// Expire times in the past delete keys.
+		{"EXPIREAT foo 1", "(integer) 1"},
+		{"EXISTS foo", "(integer) 0"},
+		{"SET foo bar", "OK"},
+		{"EXPIRE foo -1", "(integer) 1"},
+		{"EXISTS foo", "(integer) 0"},
+	})
+}
+
+func TestKeyspace_setKeepTTL(t *testing.T) {
+	clock := newTestClock()
+	ks := NewKeyspace()
+	ks.Clock = clock
+	c := dialTestClient(t, startTestKeyspaceServer(t, ks))
+
+	runTestSteps(t, c, []testStep{
+		{"SET foo bar EX 100", "OK"},
+		{"SET foo baz KEEPTTL", "OK"},
+		{"TTL foo", "(integer) 100"},
+		{"APPEND foo !", "(integer) 4"},
+		{"TTL foo", "(integer) 100"},
+		{"SET foo bar", "OK"},
+		{"TTL foo", "(integer) -1"},
+		{"SET foo bar PX 1500", "OK"},
+		{"PTTL foo", "(integer) 1500"},
+		{"GETEX foo EXAT 1700000200", `"bar"`},
+		{"TTL foo", "(integer) 200"},
+		{"GETSET foo baz", `"bar"`},
+		{"TTL foo", "(integer) -1"},
+	})
+}
+
+func TestKeyspace_ActiveExpireCycle(t *testing.T) {
+	clock := newTestClock()
+	ks := NewKeyspace()
+	ks.Clock = clock
+	c := dialTestClient(t, startTestKeyspaceServer(t, ks))
+
+	const n = 1000
+	for i := 0; i < n; i++ {
+		ttl := "10"
+		if i%2 == 0 {
+			ttl = "20"
+		}
+		c.send("SET", "key-"+strconv.Itoa(i), "value", "EX", ttl)
+	}
+	c.send("SET", "persistent", "value")
+	c.flush()
+	for i := 0; i < n+1; i++ {
+		if got := c.read(); got != "OK" {
+			t.Fatalf("SET #%d = %s, want OK", i, got)
+		}
+	}
+
+	if expired := ks.ActiveExpireCycle(); expired != 0 {
+		t.Errorf("ActiveExpireCycle() before the expiration = %d, want 0", expired)
+	}
+
+	clock.Advance(30 * time.Second)
+
+	expired := 0
+	for i := 0; i < n && expired < n; i++ {
+		expired += ks.ActiveExpireCycle()
+	}
+	if expired != n {
+		t.Errorf("ActiveExpireCycle() expired %d keys, want %d", expired, n)
+	}
+
+	ks.mu.Lock()
+	keys, expires := len(ks.dict), len(ks.expires)
+	ks.mu.Unlock()
+
+	if keys != 1 || expires != 0 {
+		t.Errorf("keyspace has %d keys and %d expires, want 1 and 0", keys, expires)
+	}
+}
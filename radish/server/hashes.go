@@ -0,0 +1,911 @@
+package server
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"math/rand"
+	"strconv"
+
+	"github.com/SuperPaintman/mini-redis/radish"
+)
+
+var (
+	errHashNotInteger = &radish.Error{Kind: "ERR", Msg: "hash value is not an integer"}
+	errHashNotFloat   = &radish.Error{Kind: "ERR", Msg: "hash value is not a float"}
+	errValueRange     = &radish.Error{Kind: "ERR", Msg: "value is out of range"}
+	errNaNOrInfinity  = &radish.Error{Kind: "ERR", Msg: "value is NaN or Infinity"}
+)
+
+// Default thresholds of the listpack encoding of hashes, the same as Redis
+// uses.
+const (
+	defaultHashMaxListpackEntries = 128
+	defaultHashMaxListpackValue   = 64
+)
+
+// hash is a map of fields to values.
+//
+// Small hashes are encoded as a listpack: a contiguous sequence of fields and
+// values, each prefixed with its uvarint length. It takes much less memory
+// than a map, and a linear search over a few entries is as fast as a map
+// lookup. The hash is converted to a map once it grows past the thresholds of
+// the Keyspace and it is never converted back.
+type hash struct {
+	lp []byte // Nil if the hash is converted to the m.
+	n  int    // Number of fields in the lp.
+
+	m map[string][]byte
+}
+
+func newHash() *hash {
+	return &hash{lp: []byte{}}
+}
+
+// lpNext returns the first element of the lp and the rest of the lp.
+func lpNext(lp []byte) (elem, rest []byte) {
+	n, size := binary.Uvarint(lp)
+	lp = lp[size:]
+	return lp[:n], lp[n:]
+}
+
+func lpAppend(lp, elem []byte) []byte {
+	var buf [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(buf[:], uint64(len(elem)))
+	lp = append(lp, buf[:n]...)
+	return append(lp, elem...)
+}
+
+// lpFind returns the offsets of the entry of the field and its value in the
+// lp, or -1 if the field is not found.
+func (h *hash) lpFind(field []byte) (entry, value int) {
+	lp := h.lp
+	for len(lp) > 0 {
+		start := len(h.lp) - len(lp)
+
+		var f []byte
+		f, lp = lpNext(lp)
+		if bytes.Equal(f, field) {
+			return start, len(h.lp) - len(lp)
+		}
+
+		_, lp = lpNext(lp)
+	}
+
+	return -1, -1
+}
+
+func (h *hash) Len() int {
+	if h.m != nil {
+		return len(h.m)
+	}
+	return h.n
+}
+
+// Get returns the value of the field.
+func (h *hash) Get(field []byte) ([]byte, bool) {
+	if h.m != nil {
+		v, ok := h.m[string(field)]
+		return v, ok
+	}
+
+	_, value := h.lpFind(field)
+	if value < 0 {
+		return nil, false
+	}
+
+	v, _ := lpNext(h.lp[value:])
+	return v, true
+}
+
+// Set sets the value of the field and reports whether the field is new. The
+// field and the value are copied.
+func (h *hash) Set(field, value []byte) bool {
+	if h.m != nil {
+		_, exists := h.m[string(field)]
+		v := make([]byte, len(value))
+		copy(v, value)
+		h.m[string(field)] = v
+		return !exists
+	}
+
+	entry, start := h.lpFind(field)
+	if entry < 0 {
+		h.lp = lpAppend(h.lp, field)
+		h.lp = lpAppend(h.lp, value)
+		h.n++
+		return true
+	}
+
+	_, rest := lpNext(h.lp[start:])
+	tail := append([]byte(nil), rest...)
+	h.lp = lpAppend(h.lp[:start], value)
+	h.lp = append(h.lp, tail...)
+	return false
+}
+
+// Delete deletes the field and reports whether it existed.
+func (h *hash) Delete(field []byte) bool {
+	if h.m != nil {
+		if _, ok := h.m[string(field)]; !ok {
+			return false
+		}
+		delete(h.m, string(field))
+		return true
+	}
+
+	entry, start := h.lpFind(field)
+	if entry < 0 {
+		return false
+	}
+
+	_, rest := lpNext(h.lp[start:])
+	h.lp = append(h.lp[:entry], rest...)
+	h.n--
+	return true
+}
+
+// Range calls the fn for each field and value of the hash until it returns
+// false. The hash must not be modified during the iteration.
+func (h *hash) Range(fn func(field, value []byte) bool) {
+	if h.m != nil {
+		for f, v := range h.m {
+			if !fn([]byte(f), v) {
+				return
+			}
+		}
+		return
+	}
+
+	lp := h.lp
+	for len(lp) > 0 {
+		var f, v []byte
+		f, lp = lpNext(lp)
+		v, lp = lpNext(lp)
+		if !fn(f, v) {
+			return
+		}
+	}
+}
+
+// convert converts the listpack to the map encoding.
+func (h *hash) convert() {
+	m := make(map[string][]byte, h.n)
+	h.Range(func(field, value []byte) bool {
+		m[string(field)] = append([]byte(nil), value...)
+		return true
+	})
+
+	h.m = m
+	h.lp = nil
+	h.n = 0
+}
+
+func (h *hash) encoding() string {
+	if h.m != nil {
+		return "hashtable"
+	}
+	return "listpack"
+}
+
+// lookupHash returns the hash value of the key or nil if the key does not
+// exist.
+func (ks *Keyspace) lookupHash(key []byte) (*hash, error) {
+	v := ks.lookup(key)
+	if v == nil {
+		return nil, nil
+	}
+
+	h, ok := v.(*hash)
+	if !ok {
+		return nil, errWrongType
+	}
+	return h, nil
+}
+
+// lookupHashOrCreate returns the hash value of the key and creates it if the
+// key does not exist.
+func (ks *Keyspace) lookupHashOrCreate(key []byte) (*hash, error) {
+	h, err := ks.lookupHash(key)
+	if err != nil || h != nil {
+		return h, err
+	}
+
+	h = newHash()
+	ks.setKey(key, h, false)
+	return h, nil
+}
+
+// hashSet sets the field of the h and converts the h to the map encoding if
+// it grows past the thresholds.
+func (ks *Keyspace) hashSet(h *hash, field, value []byte) bool {
+	if h.m == nil && (len(field) > ks.HashMaxListpackValue || len(value) > ks.HashMaxListpackValue) {
+		h.convert()
+	}
+
+	created := h.Set(field, value)
+
+	if h.m == nil && h.n > ks.HashMaxListpackEntries {
+		h.convert()
+	}
+
+	return created
+}
+
+func (ks *Keyspace) registerHashCommands(m *Mux) {
+	m.HandleFunc(CommandInfo{
+		Name:       "hset",
+		Arity:      -4,
+		Flags:      FlagWrite | FlagDenyOOM | FlagFast,
+		FirstKey:   1,
+		LastKey:    1,
+		KeyStep:    1,
+		Group:      "hash",
+		Summary:    "Creates or modifies the value of a field in a hash.",
+		Since:      "2.0.0",
+		Complexity: "O(1) for each field/value pair added, so O(N) to add N field/value pairs when the command is called with multiple field/value pairs.",
+	}, ks.hset)
+
+	m.HandleFunc(CommandInfo{
+		Name:       "hsetnx",
+		Arity:      4,
+		Flags:      FlagWrite | FlagDenyOOM | FlagFast,
+		FirstKey:   1,
+		LastKey:    1,
+		KeyStep:    1,
+		Group:      "hash",
+		Summary:    "Sets the value of a field in a hash only when the field doesn't exist.",
+		Since:      "2.0.0",
+		Complexity: "O(1)",
+	}, ks.hsetnx)
+
+	m.HandleFunc(CommandInfo{
+		Name:       "hget",
+		Arity:      3,
+		Flags:      FlagReadonly | FlagFast,
+		FirstKey:   1,
+		LastKey:    1,
+		KeyStep:    1,
+		Group:      "hash",
+		Summary:    "Returns the value of a field in a hash.",
+		Since:      "2.0.0",
+		Complexity: "O(1)",
+	}, ks.hget)
+
+	m.HandleFunc(CommandInfo{
+		Name:       "hmget",
+		Arity:      -3,
+		Flags:      FlagReadonly | FlagFast,
+		FirstKey:   1,
+		LastKey:    1,
+		KeyStep:    1,
+		Group:      "hash",
+		Summary:    "Returns the values of all fields in a hash.",
+		Since:      "2.0.0",
+		Complexity: "O(N) where N is the number of fields being requested.",
+	}, ks.hmget)
+
+	m.HandleFunc(CommandInfo{
+		Name:       "hdel",
+		Arity:      -3,
+		Flags:      FlagWrite | FlagFast,
+		FirstKey:   1,
+		LastKey:    1,
+		KeyStep:    1,
+		Group:      "hash",
+		Summary:    "Deletes one or more fields and their values from a hash. Deletes the hash if no fields remain.",
+		Since:      "2.0.0",
+		Complexity: "O(N) where N is the number of fields to be removed.",
+	}, ks.hdel)
+
+	m.HandleFunc(CommandInfo{
+		Name:       "hexists",
+		Arity:      3,
+		Flags:      FlagReadonly | FlagFast,
+		FirstKey:   1,
+		LastKey:    1,
+		KeyStep:    1,
+		Group:      "hash",
+		Summary:    "Determines whether a field exists in a hash.",
+		Since:      "2.0.0",
+		Complexity: "O(1)",
+	}, ks.hexists)
+
+	m.HandleFunc(CommandInfo{
+		Name:       "hlen",
+		Arity:      2,
+		Flags:      FlagReadonly | FlagFast,
+		FirstKey:   1,
+		LastKey:    1,
+		KeyStep:    1,
+		Group:      "hash",
+		Summary:    "Returns the number of fields in a hash.",
+		Since:      "2.0.0",
+		Complexity: "O(1)",
+	}, ks.hlen)
+
+	m.HandleFunc(CommandInfo{
+		Name:       "hstrlen",
+		Arity:      3,
+		Flags:      FlagReadonly | FlagFast,
+		FirstKey:   1,
+		LastKey:    1,
+		KeyStep:    1,
+		Group:      "hash",
+		Summary:    "Returns the length of the value of a field.",
+		Since:      "3.2.0",
+		Complexity: "O(1)",
+	}, ks.hstrlen)
+
+	m.HandleFunc(CommandInfo{
+		Name:       "hkeys",
+		Arity:      2,
+		Flags:      FlagReadonly,
+		FirstKey:   1,
+		LastKey:    1,
+		KeyStep:    1,
+		Group:      "hash",
+		Summary:    "Returns all fields in a hash.",
+		Since:      "2.0.0",
+		Complexity: "O(N) where N is the size of the hash.",
+	}, func(c *Conn, cmd *radish.Command) {
+		ks.hgetallGeneric(c, cmd, true, false)
+	})
+
+	m.HandleFunc(CommandInfo{
+		Name:       "hvals",
+		Arity:      2,
+		Flags:      FlagReadonly,
+		FirstKey:   1,
+		LastKey:    1,
+		KeyStep:    1,
+		Group:      "hash",
+		Summary:    "Returns all values in a hash.",
+		Since:      "2.0.0",
+		Complexity: "O(N) where N is the size of the hash.",
+	}, func(c *Conn, cmd *radish.Command) {
+		ks.hgetallGeneric(c, cmd, false, true)
+	})
+
+	m.HandleFunc(CommandInfo{
+		Name:       "hgetall",
+		Arity:      2,
+		Flags:      FlagReadonly,
+		FirstKey:   1,
+		LastKey:    1,
+		KeyStep:    1,
+		Group:      "hash",
+		Summary:    "Returns all fields and values in a hash.",
+		Since:      "2.0.0",
+		Complexity: "O(N) where N is the size of the hash.",
+	}, func(c *Conn, cmd *radish.Command) {
+		ks.hgetallGeneric(c, cmd, true, true)
+	})
+
+	m.HandleFunc(CommandInfo{
+		Name:       "hincrby",
+		Arity:      4,
+		Flags:      FlagWrite | FlagDenyOOM | FlagFast,
+		FirstKey:   1,
+		LastKey:    1,
+		KeyStep:    1,
+		Group:      "hash",
+		Summary:    "Increments the integer value of a field in a hash by a number. Uses 0 as initial value if the field doesn't exist.",
+		Since:      "2.0.0",
+		Complexity: "O(1)",
+	}, ks.hincrby)
+
+	m.HandleFunc(CommandInfo{
+		Name:       "hincrbyfloat",
+		Arity:      4,
+		Flags:      FlagWrite | FlagDenyOOM | FlagFast,
+		FirstKey:   1,
+		LastKey:    1,
+		KeyStep:    1,
+		Group:      "hash",
+		Summary:    "Increments the floating point value of a field by a number. Uses 0 as initial value if the field doesn't exist.",
+		Since:      "2.6.0",
+		Complexity: "O(1)",
+	}, ks.hincrbyfloat)
+
+	m.HandleFunc(CommandInfo{
+		Name:       "hrandfield",
+		Arity:      -2,
+		Flags:      FlagReadonly,
+		FirstKey:   1,
+		LastKey:    1,
+		KeyStep:    1,
+		Group:      "hash",
+		Summary:    "Returns one or more random fields from a hash.",
+		Since:      "6.2.0",
+		Complexity: "O(N) where N is the number of fields returned",
+	}, ks.hrandfield)
+
+	m.HandleFunc(CommandInfo{
+		Name:       "hscan",
+		Arity:      -3,
+		Flags:      FlagReadonly,
+		FirstKey:   1,
+		LastKey:    1,
+		KeyStep:    1,
+		Group:      "hash",
+		Summary:    "Iterates over fields and values of a hash.",
+		Since:      "2.8.0",
+		Complexity: "O(1) for every call. O(N) for a complete iteration, including enough command calls for the cursor to return back to 0. N is the number of elements inside the collection.",
+	}, ks.hscan)
+}
+
+func (ks *Keyspace) hset(c *Conn, cmd *radish.Command) {
+	w := c.Writer()
+
+	if len(cmd.Args)%2 != 0 {
+		_ = writeWrongArity(w, "hset")
+		return
+	}
+
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	h, err := ks.lookupHashOrCreate(cmd.Args[1])
+	if err != nil {
+		_ = w.WriteError(errWrongType)
+		return
+	}
+
+	created := 0
+	for i := 2; i < len(cmd.Args); i += 2 {
+		if ks.hashSet(h, cmd.Args[i], cmd.Args[i+1]) {
+			created++
+		}
+	}
+
+	_ = w.WriteInt(created)
+}
+
+func (ks *Keyspace) hsetnx(c *Conn, cmd *radish.Command) {
+	w := c.Writer()
+
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	h, err := ks.lookupHashOrCreate(cmd.Args[1])
+	if err != nil {
+		_ = w.WriteError(errWrongType)
+		return
+	}
+
+	if _, ok := h.Get(cmd.Args[2]); ok {
+		_ = w.WriteInt(0)
+		return
+	}
+
+	ks.hashSet(h, cmd.Args[2], cmd.Args[3])
+
+	_ = w.WriteInt(1)
+}
+
+func (ks *Keyspace) hget(c *Conn, cmd *radish.Command) {
+	w := c.Writer()
+
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	h, err := ks.lookupHash(cmd.Args[1])
+	if err != nil {
+		_ = w.WriteError(errWrongType)
+		return
+	}
+	if h == nil {
+		_ = w.WriteNull()
+		return
+	}
+
+	v, ok := h.Get(cmd.Args[2])
+	if !ok {
+		_ = w.WriteNull()
+		return
+	}
+
+	_ = w.WriteBytes(v)
+}
+
+func (ks *Keyspace) hmget(c *Conn, cmd *radish.Command) {
+	w := c.Writer()
+
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	h, err := ks.lookupHash(cmd.Args[1])
+	if err != nil {
+		_ = w.WriteError(errWrongType)
+		return
+	}
+
+	fields := cmd.Args[2:]
+	_ = w.WriteArray(len(fields))
+	for _, field := range fields {
+		if h == nil {
+			_ = w.WriteNull()
+			continue
+		}
+
+		if v, ok := h.Get(field); ok {
+			_ = w.WriteBytes(v)
+		} else {
+			_ = w.WriteNull()
+		}
+	}
+}
+
+func (ks *Keyspace) hdel(c *Conn, cmd *radish.Command) {
+	w := c.Writer()
+
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	key := cmd.Args[1]
+	h, err := ks.lookupHash(key)
+	if err != nil {
+		_ = w.WriteError(errWrongType)
+		return
+	}
+	if h == nil {
+		_ = w.WriteInt(0)
+		return
+	}
+
+	deleted := 0
+	for _, field := range cmd.Args[2:] {
+		if h.Delete(field) {
+			deleted++
+		}
+	}
+
+	if h.Len() == 0 {
+		ks.delete(key)
+	}
+
+	_ = w.WriteInt(deleted)
+}
+
+func (ks *Keyspace) hexists(c *Conn, cmd *radish.Command) {
+	w := c.Writer()
+
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	h, err := ks.lookupHash(cmd.Args[1])
+	if err != nil {
+		_ = w.WriteError(errWrongType)
+		return
+	}
+	if h == nil {
+		_ = w.WriteInt(0)
+		return
+	}
+
+	if _, ok := h.Get(cmd.Args[2]); ok {
+		_ = w.WriteInt(1)
+	} else {
+		_ = w.WriteInt(0)
+	}
+}
+
+func (ks *Keyspace) hlen(c *Conn, cmd *radish.Command) {
+	w := c.Writer()
+
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	h, err := ks.lookupHash(cmd.Args[1])
+	if err != nil {
+		_ = w.WriteError(errWrongType)
+		return
+	}
+	if h == nil {
+		_ = w.WriteInt(0)
+		return
+	}
+
+	_ = w.WriteInt(h.Len())
+}
+
+func (ks *Keyspace) hstrlen(c *Conn, cmd *radish.Command) {
+	w := c.Writer()
+
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	h, err := ks.lookupHash(cmd.Args[1])
+	if err != nil {
+		_ = w.WriteError(errWrongType)
+		return
+	}
+	if h == nil {
+		_ = w.WriteInt(0)
+		return
+	}
+
+	v, _ := h.Get(cmd.Args[2])
+	_ = w.WriteInt(len(v))
+}
+
+// hgetallGeneric implements HKEYS, HVALS and HGETALL.
+//
+// The reply is written directly from the hash, without building intermediate
+// slices.
+func (ks *Keyspace) hgetallGeneric(c *Conn, cmd *radish.Command, fields, values bool) {
+	w := c.Writer()
+
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	h, err := ks.lookupHash(cmd.Args[1])
+	if err != nil {
+		_ = w.WriteError(errWrongType)
+		return
+	}
+	if h == nil {
+		if fields && values {
+			_ = w.WriteMap(0)
+		} else {
+			_ = w.WriteArray(0)
+		}
+		return
+	}
+
+	if fields && values {
+		_ = w.WriteMap(h.Len())
+	} else {
+		_ = w.WriteArray(h.Len())
+	}
+
+	// Do not convert fields of the map to byte slices.
+	if h.m != nil {
+		for field, value := range h.m {
+			if fields {
+				_ = w.WriteString(field)
+			}
+			if values {
+				_ = w.WriteBytes(value)
+			}
+		}
+		return
+	}
+
+	h.Range(func(field, value []byte) bool {
+		if fields {
+			_ = w.WriteBytes(field)
+		}
+		if values {
+			_ = w.WriteBytes(value)
+		}
+		return true
+	})
+}
+
+func (ks *Keyspace) hincrby(c *Conn, cmd *radish.Command) {
+	w := c.Writer()
+
+	incr, ok := parseInt(cmd.Args[3])
+	if !ok {
+		_ = w.WriteError(errNotInteger)
+		return
+	}
+
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	h, err := ks.lookupHashOrCreate(cmd.Args[1])
+	if err != nil {
+		_ = w.WriteError(errWrongType)
+		return
+	}
+
+	field := cmd.Args[2]
+
+	var value int64
+	if v, exists := h.Get(field); exists {
+		value, ok = parseInt(v)
+		if !ok {
+			_ = w.WriteError(errHashNotInteger)
+			return
+		}
+	}
+
+	if (incr < 0 && value < 0 && incr < math.MinInt64-value) ||
+		(incr > 0 && value > 0 && incr > math.MaxInt64-value) {
+		_ = w.WriteError(errIncrOverflow)
+		return
+	}
+	value += incr
+
+	ks.hashSet(h, field, strconv.AppendInt(nil, value, 10))
+
+	_ = w.WriteInt64(value)
+}
+
+func (ks *Keyspace) hincrbyfloat(c *Conn, cmd *radish.Command) {
+	w := c.Writer()
+
+	incr, ok := parseFloat(cmd.Args[3])
+	if !ok {
+		_ = w.WriteError(errNotFloat)
+		return
+	}
+	if math.IsInf(incr, 0) {
+		_ = w.WriteError(errNaNOrInfinity)
+		return
+	}
+
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	h, err := ks.lookupHashOrCreate(cmd.Args[1])
+	if err != nil {
+		_ = w.WriteError(errWrongType)
+		return
+	}
+
+	field := cmd.Args[2]
+
+	var value float64
+	if v, exists := h.Get(field); exists {
+		value, ok = parseFloat(v)
+		if !ok {
+			_ = w.WriteError(errHashNotFloat)
+			return
+		}
+	}
+
+	value += incr
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		_ = w.WriteError(errIncrNaNOrInfinity)
+		return
+	}
+
+	s := strconv.AppendFloat(nil, value, 'f', -1, 64)
+	ks.hashSet(h, field, s)
+
+	_ = w.WriteBytes(s)
+}
+
+func (ks *Keyspace) hrandfield(c *Conn, cmd *radish.Command) {
+	w := c.Writer()
+
+	var (
+		count      int64
+		hasCount   bool
+		withValues bool
+	)
+	switch len(cmd.Args) {
+	case 2:
+	case 4:
+		if !equalFold(cmd.Args[3], "WITHVALUES") {
+			_ = w.WriteError(errSyntax)
+			return
+		}
+		withValues = true
+		fallthrough
+	case 3:
+		var ok bool
+		count, ok = parseInt(cmd.Args[2])
+		if !ok {
+			_ = w.WriteError(errNotInteger)
+			return
+		}
+		hasCount = true
+	default:
+		_ = w.WriteError(errSyntax)
+		return
+	}
+
+	// The reply of a negative count with values would overflow.
+	if withValues && count < -math.MaxInt64/2 {
+		_ = w.WriteError(errValueRange)
+		return
+	}
+
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	h, err := ks.lookupHash(cmd.Args[1])
+	if err != nil {
+		_ = w.WriteError(errWrongType)
+		return
+	}
+	if h == nil {
+		if hasCount {
+			_ = w.WriteArray(0)
+		} else {
+			_ = w.WriteNull()
+		}
+		return
+	}
+
+	var entries [][2][]byte
+	h.Range(func(field, value []byte) bool {
+		entries = append(entries, [2][]byte{field, value})
+		return true
+	})
+
+	if !hasCount {
+		_ = w.WriteBytes(entries[rand.Intn(len(entries))][0])
+		return
+	}
+
+	// A negative count allows the same field to be returned multiple times,
+	// a positive one returns distinct fields.
+	n := int(count)
+	switch {
+	case count < 0:
+		if count == math.MinInt64 {
+			_ = w.WriteError(errValueRange)
+			return
+		}
+		n = int(-count)
+	case count >= int64(len(entries)):
+		n = len(entries)
+	default:
+		rand.Shuffle(len(entries), func(i, j int) {
+			entries[i], entries[j] = entries[j], entries[i]
+		})
+	}
+
+	if withValues {
+		_ = w.WriteArray(n * 2)
+	} else {
+		_ = w.WriteArray(n)
+	}
+	for i := 0; i < n; i++ {
+		e := entries[i%len(entries)]
+		if count < 0 {
+			e = entries[rand.Intn(len(entries))]
+		}
+
+		_ = w.WriteBytes(e[0])
+		if withValues {
+			_ = w.WriteBytes(e[1])
+		}
+	}
+}
+
+func (ks *Keyspace) hscan(c *Conn, cmd *radish.Command) {
+	w := c.Writer()
+
+	opts, e := parseScanOptions(cmd.Args[2:], true)
+	if e != nil {
+		_ = w.WriteError(e)
+		return
+	}
+
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	h, err := ks.lookupHash(cmd.Args[1])
+	if err != nil {
+		_ = w.WriteError(errWrongType)
+		return
+	}
+	if h == nil {
+		writeScanReply(w, 0, nil, nil, false)
+		return
+	}
+
+	entries := make([]scanEntry, 0, h.Len())
+	h.Range(func(field, value []byte) bool {
+		entries = append(entries, scanEntry{key: field, value: value})
+		return true
+	})
+
+	// Like Redis, small encodings are returned at once.
+	var next uint64
+	if h.m != nil {
+		for i := range entries {
+			entries[i].hash = scanHash(entries[i].key)
+		}
+		entries, next = scanPage(entries, opts.cursor, opts.count)
+	}
+
+	writeScanReply(w, next, entries, opts.match, !opts.noValues)
+}
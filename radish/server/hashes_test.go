@@ -0,0 +1,248 @@
+package server
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestKeyspace_hashes(t *testing.T) {
+	tt := []struct {
+		name  string
+		steps []testStep
+	}{
+		{
+			name: "set and get",
+			steps: []testStep{
+				{"HSET h a 1 b 2", "(integer) 2"},
+				{"HSET h b 3 c 4", "(integer) 1"},
+				{"HGET h b", `"3"`},
+				{"HGET h missing", "(nil)"},
+				{"HGET missing a", "(nil)"},
+				{"HMGET h a missing c", `["1", (nil), "4"]`},
+				{"HMGET missing a", "[(nil)]"},
+				{"HLEN h", "(integer) 3"},
+				{"HLEN missing", "(integer) 0"},
+				{"HGETALL h", `["a", "1", "b", "3", "c", "4"]`},
+				{"HKEYS h", `["a", "b", "c"]`},
+				{"HVALS h", `["1", "3", "4"]`},
+				{"HGETALL missing", "[]"},
+				{"TYPE h", "hash"},
+				{"OBJECT ENCODING h", `"listpack"`},
+				{"HSET h a", "(error) ERR wrong number of arguments for 'hset' command"},
+			},
+		},
+		{
+			name: "setnx exists and strlen",
+			steps: []testStep{
+				{"HSETNX h a hello", "(integer) 1"},
+				{"HSETNX h a world", "(integer) 0"},
+				{"HGET h a", `"hello"`},
+				{"HEXISTS h a", "(integer) 1"},
+				{"HEXISTS h b", "(integer) 0"},
+				{"HEXISTS missing a", "(integer) 0"},
+				{"HSTRLEN h a", "(integer) 5"},
+				{"HSTRLEN h b", "(integer) 0"},
+			},
+		},
+		{
+			name: "del",
+			steps: []testStep{
+				{"HSET h a 1 b 2 c 3", "(integer) 3"},
+				{"HDEL h b missing", "(integer) 1"},
+				{"HGETALL h", `["a", "1", "c", "3"]`},
+				{"HSET h a 10", "(integer) 0"},
+				{"HGETALL h", `["a", "10", "c", "3"]`},
+				{"HDEL h a c", "(integer) 2"},
+				{"EXISTS h", "(integer) 0"},
+				{"HDEL missing a", "(integer) 0"},
+			},
+		},
+		{
+			name: "incrby",
+			steps: []testStep{
+				{"HINCRBY h n 5", "(integer) 5"},
+				{"HINCRBY h n -10", "(integer) -5"},
+				{"HINCRBY h n x", "(error) ERR value is not an integer or out of range"},
+				{"HSET h s abc max 9223372036854775807", "(integer) 2"},
+				{"HINCRBY h s 1", "(error) ERR hash value is not an integer"},
+				{"HINCRBY h max 1", "(error) ERR increment or decrement would overflow"},
+				{"HINCRBYFLOAT h f 10.5", `"10.5"`},
+				{"HINCRBYFLOAT h f 0.1", `"10.6"`},
+				{"HINCRBYFLOAT h n 1.5", `"-3.5"`},
+				{"HINCRBYFLOAT h s 1", "(error) ERR hash value is not a float"},
+				{"HINCRBYFLOAT h f x", "(error) ERR value is not a valid float"},
+				{"HINCRBYFLOAT h f inf", "(error) ERR value is NaN or Infinity"},
+			},
+		},
+		{
+			name: "randfield",
+			steps: []testStep{
+				{"HRANDFIELD missing", "(nil)"},
+				{"HRANDFIELD missing 1", "[]"},
+				{"HSET h a 1", "(integer) 1"},
+				{"HRANDFIELD h", `"a"`},
+				{"HRANDFIELD h 0", "[]"},
+				{"HRANDFIELD h 5", `["a"]`},
+				{"HRANDFIELD h -3", `["a", "a", "a"]`},
+				{"HRANDFIELD h -2 WITHVALUES", `["a", "1", "a", "1"]`},
+				{"HRANDFIELD h 1 WITHSCORES", "(error) ERR syntax error"},
+				{"HRANDFIELD h x", "(error) ERR value is not an integer or out of range"},
+				{"HRANDFIELD h -9223372036854775807 WITHVALUES", "(error) ERR value is out of range"},
+				{"HRANDFIELD h -9223372036854775808", "(error) ERR value is out of range"},
+			},
+		},
+		{
+			name: "scan",
+			steps: []testStep{
+				{"HSCAN missing 0", `["0", []]`},
+				{"HSET h a 1 b 2 ab 3", "(integer) 3"},
+				{"HSCAN h 0", `["0", ["a", "1", "b", "2", "ab", "3"]]`},
+				{"HSCAN h 0 MATCH a*", `["0", ["a", "1", "ab", "3"]]`},
+				{"HSCAN h 0 NOVALUES", `["0", ["a", "b", "ab"]]`},
+				{"HSCAN h x", "(error) ERR invalid cursor"},
+				{"HSCAN h 0 COUNT 0", "(error) ERR syntax error"},
+				{"HSCAN h 0 COUNT x", "(error) ERR value is not an integer or out of range"},
+				{"HSCAN h 0 MATCH", "(error) ERR syntax error"},
+			},
+		},
+		{
+			name: "wrong type",
+			steps: []testStep{
+				{"SET s v", "OK"},
+				{"HSET s a 1", "(error) WRONGTYPE Operation against a key holding the wrong kind of value"},
+				{"HGET s a", "(error) WRONGTYPE Operation against a key holding the wrong kind of value"},
+				{"HGETALL s", "(error) WRONGTYPE Operation against a key holding the wrong kind of value"},
+				{"HSET h a 1", "(integer) 1"},
+				{"GET h", "(error) WRONGTYPE Operation against a key holding the wrong kind of value"},
+				{"LPUSH h a", "(error) WRONGTYPE Operation against a key holding the wrong kind of value"},
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			c := dialTestClient(t, startTestKeyspaceServer(t, NewKeyspace()))
+
+			runTestSteps(t, c, tc.steps)
+		})
+	}
+}
+
+func TestKeyspace_hashEncoding(t *testing.T) {
+	tt := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{"small", []string{"HSET", "h", "a", "1", "b", "2"}, "listpack"},
+		{"too many entries", []string{"HSET", "h", "a", "1", "b", "2", "c", "3", "d", "4"}, "hashtable"},
+		{"long value", []string{"HSET", "h", "a", strings.Repeat("x", 9)}, "hashtable"},
+		{"long field", []string{"HSET", "h", strings.Repeat("x", 9), "1"}, "hashtable"},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			ks := NewKeyspace()
+			ks.HashMaxListpackEntries = 3
+			ks.HashMaxListpackValue = 8
+			c := dialTestClient(t, startTestKeyspaceServer(t, ks))
+
+			c.do(tc.args...)
+
+			if got, want := c.do("OBJECT", "ENCODING", "h"), strconv.Quote(tc.want); got != want {
+				t.Errorf("OBJECT ENCODING h = %s, want %s", got, want)
+			}
+
+			// The content must be the same in all encodings.
+			var want []string
+			for i := 2; i < len(tc.args); i += 2 {
+				want = append(want, tc.args[i]+"="+tc.args[i+1])
+			}
+			sort.Strings(want)
+
+			var got []string
+			fields := strings.Split(strings.Trim(c.do("HGETALL", "h"), "[]"), ", ")
+			for i := 0; i < len(fields); i += 2 {
+				got = append(got, unquote(t, fields[i])+"="+unquote(t, fields[i+1]))
+			}
+			sort.Strings(got)
+
+			if strings.Join(got, " ") != strings.Join(want, " ") {
+				t.Errorf("HGETALL h = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestKeyspace_hscan(t *testing.T) {
+	ks := NewKeyspace()
+	ks.HashMaxListpackEntries = 0
+	c := dialTestClient(t, startTestKeyspaceServer(t, ks))
+
+	const n = 100
+	args := []string{"HSET", "h"}
+	for i := 0; i < n; i++ {
+		args = append(args, "f"+strconv.Itoa(i), strconv.Itoa(i))
+	}
+	c.do(args...)
+
+	seen := make(map[string]bool)
+	cursor := "0"
+	for calls := 0; ; calls++ {
+		if calls > n {
+			t.Fatalf("HSCAN did not finish after %d calls", calls)
+		}
+
+		c.send("HSCAN", "h", cursor, "COUNT", "7")
+		c.flush()
+
+		// ["cursor", [fields and values]]
+		if _, err := c.r.ReadArray(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		next, _, err := c.r.ReadString()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		length, err := c.r.ReadArray()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		for i := 0; i < length; i += 2 {
+			field, _, _ := c.r.ReadString()
+			value, _, _ := c.r.ReadString()
+			if "f"+value != field {
+				t.Errorf("HSCAN returned %q = %q", field, value)
+			}
+			seen[field] = true
+		}
+
+		// Fields deleted during the iteration must not break it.
+		if calls == 2 {
+			c.do("HDEL", "h", "f0", "f1", "f2")
+		}
+
+		cursor = next
+		if cursor == "0" {
+			break
+		}
+	}
+
+	for i := 3; i < n; i++ {
+		if field := "f" + strconv.Itoa(i); !seen[field] {
+			t.Errorf("HSCAN did not return %q", field)
+		}
+	}
+}
+
+func unquote(t *testing.T, s string) string {
+	t.Helper()
+
+	u, err := strconv.Unquote(s)
+	if err != nil {
+		t.Fatalf("unexpected error: failed to unquote %s: %v", s, err)
+	}
+	return u
+}
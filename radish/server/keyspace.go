@@ -0,0 +1,374 @@
+package server
+
+import (
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/SuperPaintman/mini-redis/radish"
+)
+
+var (
+	errWrongType  = &radish.Error{Kind: "WRONGTYPE", Msg: "Operation against a key holding the wrong kind of value"}
+	errSyntax     = &radish.Error{Kind: "ERR", Msg: "syntax error"}
+	errNotInteger = &radish.Error{Kind: "ERR", Msg: "value is not an integer or out of range"}
+	errNotFloat   = &radish.Error{Kind: "ERR", Msg: "value is not a valid float"}
+)
+
+// maxFloatLength is the maximum length of a string representation of a
+// float.
+const maxFloatLength = 5 * 1024
+
+// embstrMaxLength is the maximum length of strings that Redis encodes as
+// embstr. It is used only to report the encoding.
+const embstrMaxLength = 44
+
+// Keyspace is a concurrent in-memory keyspace.
+//
+// Keyspace commands become available after the Keyspace is registered in a
+// Mux.
+//
+// Values stored in the keyspace never alias Args of commands, because Args
+// are slices of the pooled Command buffers and they are reused for the next
+// commands.
+//
+// Keys with an expire are deleted lazily when they are accessed, and actively
+// by the ActiveExpire.
+type Keyspace struct {
+	// Clock provides the current time for expiration of keys. If nil, the
+	// system clock is used.
+	//
+	// It must not be changed after the Keyspace is registered.
+	Clock Clock
+
+	// HashMaxListpackEntries and HashMaxListpackValue are the thresholds of
+	// the compact encoding of hashes. A hash is converted to a hash table once
+	// it has more fields than HashMaxListpackEntries, or a field or a value
+	// longer than HashMaxListpackValue bytes.
+	//
+	// NewKeyspace sets them to 128 and 64, like Redis does.
+	HashMaxListpackEntries int
+	HashMaxListpackValue   int
+
+	// SetMaxIntsetEntries is the threshold of the compact encoding of sets of
+	// integers. A set is converted to a hash table once it has more members
+	// than SetMaxIntsetEntries or a non-integer member.
+	//
+	// NewKeyspace sets it to 512, like Redis does.
+	SetMaxIntsetEntries int
+
+	mu      sync.Mutex
+	dict    map[string]interface{}
+	expires map[string]int64 // Unix time in milliseconds.
+
+	// Clients blocked on keys, see block.
+	blocked map[string][]*waiter
+	ready   []string
+	serving bool
+}
+
+// NewKeyspace returns a new empty Keyspace.
+func NewKeyspace() *Keyspace {
+	return &Keyspace{
+		HashMaxListpackEntries: defaultHashMaxListpackEntries,
+		HashMaxListpackValue:   defaultHashMaxListpackValue,
+		SetMaxIntsetEntries:    defaultSetMaxIntsetEntries,
+
+		dict:    make(map[string]interface{}),
+		expires: make(map[string]int64),
+	}
+}
+
+// Register registers all keyspace commands in the m.
+func (ks *Keyspace) Register(m *Mux) {
+	ks.registerGenericCommands(m)
+	ks.registerExpireCommands(m)
+	ks.registerStringCommands(m)
+	ks.registerListCommands(m)
+	ks.registerHashCommands(m)
+	ks.registerSetCommands(m)
+	ks.registerZsetCommands(m)
+	ks.registerStreamCommands(m)
+}
+
+// now returns the current Unix time in milliseconds.
+func (ks *Keyspace) now() int64 {
+	clock := ks.Clock
+	if clock == nil {
+		clock = systemClock{}
+	}
+	return clock.Now().UnixNano() / int64(time.Millisecond)
+}
+
+// lookup returns the value of the key or nil if the key does not exist.
+//
+// Expired keys are deleted on access.
+func (ks *Keyspace) lookup(key []byte) interface{} {
+	v, ok := ks.dict[string(key)]
+	if !ok {
+		return nil
+	}
+
+	if ks.expireIfNeeded(key) {
+		return nil
+	}
+
+	return v
+}
+
+// lookupString returns the string value of the key or nil if the key does not
+// exist.
+func (ks *Keyspace) lookupString(key []byte) ([]byte, error) {
+	v := ks.lookup(key)
+	if v == nil {
+		return nil, nil
+	}
+
+	s, ok := v.([]byte)
+	if !ok {
+		return nil, errWrongType
+	}
+	return s, nil
+}
+
+// expireIfNeeded deletes the key if it is expired.
+func (ks *Keyspace) expireIfNeeded(key []byte) bool {
+	when, ok := ks.expires[string(key)]
+	if !ok || when > ks.now() {
+		return false
+	}
+
+	ks.delete(key)
+	return true
+}
+
+// setKey sets the value of the key and discards its TTL unless keepTTL is
+// set.
+//
+// The key is copied, but the v is stored as is.
+func (ks *Keyspace) setKey(key []byte, v interface{}, keepTTL bool) {
+	ks.dict[string(key)] = v
+	if !keepTTL {
+		delete(ks.expires, string(key))
+	}
+}
+
+// setExpire sets the expiration time of an existing key. The key is deleted
+// if the time is already in the past.
+func (ks *Keyspace) setExpire(key []byte, when int64) {
+	if when <= ks.now() {
+		ks.delete(key)
+		return
+	}
+
+	ks.expires[string(key)] = when
+}
+
+// delete deletes the key and reports whether it existed.
+func (ks *Keyspace) delete(key []byte) bool {
+	if _, ok := ks.dict[string(key)]; !ok {
+		return false
+	}
+
+	delete(ks.dict, string(key))
+	delete(ks.expires, string(key))
+	return true
+}
+
+func typeName(v interface{}) string {
+	switch v.(type) {
+	case nil:
+		return "none"
+	case []byte:
+		return "string"
+	case *list:
+		return "list"
+	case *hash:
+		return "hash"
+	case *set:
+		return "set"
+	case *zset:
+		return "zset"
+	case *stream:
+		return "stream"
+	default:
+		return "unknown"
+	}
+}
+
+// encodingName returns the name of the internal encoding of the v, as it is
+// reported by the OBJECT ENCODING.
+func encodingName(v interface{}) string {
+	switch v := v.(type) {
+	case []byte:
+		if _, ok := parseInt(v); ok {
+			return "int"
+		}
+		if len(v) <= embstrMaxLength {
+			return "embstr"
+		}
+		return "raw"
+	case *list:
+		return "quicklist"
+	case *hash:
+		return v.encoding()
+	case *set:
+		return v.encoding()
+	case *zset:
+		return "skiplist"
+	case *stream:
+		return "stream"
+	default:
+		return "unknown"
+	}
+}
+
+// equalFold reports whether the arg is equal to the upper-case ASCII s under
+// case-folding.
+func equalFold(arg radish.Arg, s string) bool {
+	if len(arg) != len(s) {
+		return false
+	}
+
+	for i, ch := range arg {
+		if 'a' <= ch && ch <= 'z' {
+			ch -= 'a' - 'A'
+		}
+		if ch != s[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+func parseInt(b []byte) (int64, bool) {
+	n, err := radish.ParseInt(b)
+	return n, err == nil
+}
+
+// parseFloat interprets the b as a float like the Redis string2ld does: it
+// must not have spaces, must not be a NaN and must be in range.
+func parseFloat(b []byte) (float64, bool) {
+	if len(b) == 0 || len(b) > maxFloatLength {
+		return 0, false
+	}
+
+	f, err := strconv.ParseFloat(string(b), 64)
+	if err != nil || math.IsNaN(f) {
+		return 0, false
+	}
+	return f, true
+}
+
+func (ks *Keyspace) registerGenericCommands(m *Mux) {
+	m.HandleFunc(CommandInfo{
+		Name:       "del",
+		Arity:      -2,
+		Flags:      FlagWrite,
+		FirstKey:   1,
+		LastKey:    -1,
+		KeyStep:    1,
+		Group:      "generic",
+		Summary:    "Deletes one or more keys.",
+		Since:      "1.0.0",
+		Complexity: "O(N) where N is the number of keys that will be removed.",
+	}, ks.del)
+
+	m.HandleFunc(CommandInfo{
+		Name:       "exists",
+		Arity:      -2,
+		Flags:      FlagReadonly | FlagFast,
+		FirstKey:   1,
+		LastKey:    -1,
+		KeyStep:    1,
+		Group:      "generic",
+		Summary:    "Determines whether one or more keys exist.",
+		Since:      "1.0.0",
+		Complexity: "O(N) where N is the number of keys to check.",
+	}, ks.exists)
+
+	m.HandleFunc(CommandInfo{
+		Name:       "type",
+		Arity:      2,
+		Flags:      FlagReadonly | FlagFast,
+		FirstKey:   1,
+		LastKey:    1,
+		KeyStep:    1,
+		Group:      "generic",
+		Summary:    "Determines the type of value stored at a key.",
+		Since:      "1.0.0",
+		Complexity: "O(1)",
+	}, ks.typ)
+
+	m.HandleFunc(CommandInfo{
+		Name:       "object",
+		Arity:      -2,
+		Flags:      FlagReadonly,
+		FirstKey:   2,
+		LastKey:    2,
+		KeyStep:    1,
+		Group:      "generic",
+		Summary:    "A container for object introspection commands.",
+		Since:      "2.2.3",
+		Complexity: "Depends on subcommand.",
+	}, ks.object)
+}
+
+func (ks *Keyspace) del(c *Conn, cmd *radish.Command) {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	deleted := 0
+	for _, key := range cmd.Args[1:] {
+		ks.expireIfNeeded(key)
+		if ks.delete(key) {
+			deleted++
+		}
+	}
+
+	_ = c.Writer().WriteInt(deleted)
+}
+
+func (ks *Keyspace) exists(c *Conn, cmd *radish.Command) {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	count := 0
+	for _, key := range cmd.Args[1:] {
+		if ks.lookup(key) != nil {
+			count++
+		}
+	}
+
+	_ = c.Writer().WriteInt(count)
+}
+
+func (ks *Keyspace) typ(c *Conn, cmd *radish.Command) {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	_ = c.Writer().WriteSimpleString(typeName(ks.lookup(cmd.Args[1])))
+}
+
+func (ks *Keyspace) object(c *Conn, cmd *radish.Command) {
+	w := c.Writer()
+
+	sub := cmd.Args[1]
+	if !equalFold(sub, "ENCODING") || len(cmd.Args) != 3 {
+		_ = w.WriteError(unknownSubcommand(sub, "OBJECT"))
+		return
+	}
+
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	v := ks.lookup(cmd.Args[2])
+	if v == nil {
+		_ = w.WriteNull()
+		return
+	}
+
+	_ = w.WriteString(encodingName(v))
+}
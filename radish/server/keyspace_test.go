@@ -0,0 +1,45 @@
+package server
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestKeyspace_resp3(t *testing.T) {
+	c := dialTestClient(t, startTestKeyspaceServer(t, NewKeyspace()))
+
+	runTestSteps(t, c, []testStep{
+		{"HSET h a 1", "(integer) 1"},
+		{"SADD s x", "(integer) 1"},
+		{"ZADD z 1.5 m", "(integer) 1"},
+		{"XADD x 1-1 f v", `"1-1"`},
+
+		{"GET missing", "(nil)"},
+		{"HGETALL h", `["a", "1"]`},
+		{"SMEMBERS s", `["x"]`},
+		{"ZSCORE z m", `"1.5"`},
+		{"XREAD STREAMS x 0", `[["x", [["1-1", ["f", "v"]]]]]`},
+		{"XREAD STREAMS x 1-1", "(nil)"},
+	})
+
+	if got := c.do("HELLO", "3"); !strings.HasPrefix(got, "{") {
+		t.Fatalf("HELLO 3 = %s, want a map", got)
+	}
+
+	runTestSteps(t, c, []testStep{
+		{"GET missing", "(nil)"},
+		{"HGETALL h", `{"a" => "1"}`},
+		{"HGETALL missing", "{}"},
+		{"HKEYS h", `["a"]`},
+		{"SMEMBERS s", `{"x"}`},
+		{"SUNION s missing", `{"x"}`},
+		{"ZSCORE z m", "(double) 1.5"},
+		{"ZINCRBY z 1 m", "(double) 2.5"},
+		{"ZRANGE z 0 -1 WITHSCORES", `["m", (double) 2.5]`},
+		{"XREAD STREAMS x 0", `{"x" => [["1-1", ["f", "v"]]]}`},
+		{"XREAD STREAMS x 1-1", "(nil)"},
+		{"XINFO GROUPS x", "[]"},
+		{"XGROUP CREATE x g $", "OK"},
+		{"XINFO GROUPS x", `[{"name" => "g", "consumers" => (integer) 0, "pending" => (integer) 0, "last-delivered-id" => "1-1"}]`},
+	})
+}
@@ -0,0 +1,1193 @@
+package server
+
+import (
+	"bytes"
+	"math"
+	"time"
+
+	"github.com/SuperPaintman/mini-redis/radish"
+)
+
+var (
+	errNoSuchKey      = &radish.Error{Kind: "ERR", Msg: "no such key"}
+	errIndexRange     = &radish.Error{Kind: "ERR", Msg: "index out of range"}
+	errMustBePositive = &radish.Error{Kind: "ERR", Msg: "value is out of range, must be positive"}
+	errNumkeysZero    = &radish.Error{Kind: "ERR", Msg: "numkeys should be greater than 0"}
+	errCountZero      = &radish.Error{Kind: "ERR", Msg: "count should be greater than 0"}
+)
+
+// list is a double-ended queue of elements backed by a ring buffer.
+type list struct {
+	buf  [][]byte
+	head int
+	n    int
+}
+
+func newList() *list {
+	return &list{buf: make([][]byte, 4)}
+}
+
+func (l *list) Len() int { return l.n }
+
+// At returns the i-th element.
+func (l *list) At(i int) []byte {
+	return l.buf[(l.head+i)%len(l.buf)]
+}
+
+// Set sets the i-th element.
+func (l *list) Set(i int, v []byte) {
+	l.buf[(l.head+i)%len(l.buf)] = v
+}
+
+func (l *list) PushFront(v []byte) {
+	l.grow()
+	l.head = (l.head - 1 + len(l.buf)) % len(l.buf)
+	l.buf[l.head] = v
+	l.n++
+}
+
+func (l *list) PushBack(v []byte) {
+	l.grow()
+	l.buf[(l.head+l.n)%len(l.buf)] = v
+	l.n++
+}
+
+func (l *list) PopFront() []byte {
+	v := l.buf[l.head]
+	l.buf[l.head] = nil
+	l.head = (l.head + 1) % len(l.buf)
+	l.n--
+	return v
+}
+
+func (l *list) PopBack() []byte {
+	i := (l.head + l.n - 1) % len(l.buf)
+	v := l.buf[i]
+	l.buf[i] = nil
+	l.n--
+	return v
+}
+
+// Elements returns a copy of all elements in order.
+func (l *list) Elements() [][]byte {
+	elems := make([][]byte, l.n)
+	for i := range elems {
+		elems[i] = l.At(i)
+	}
+	return elems
+}
+
+// Reset replaces all elements with the elems.
+func (l *list) Reset(elems [][]byte) {
+	size := 4
+	for size < len(elems) {
+		size *= 2
+	}
+
+	l.buf = make([][]byte, size)
+	copy(l.buf, elems)
+	l.head = 0
+	l.n = len(elems)
+}
+
+func (l *list) grow() {
+	if l.n < len(l.buf) {
+		return
+	}
+
+	buf := make([][]byte, len(l.buf)*2)
+	for i := 0; i < l.n; i++ {
+		buf[i] = l.At(i)
+	}
+	l.buf = buf
+	l.head = 0
+}
+
+// Sides of a list.
+const (
+	listHead = iota
+	listTail
+)
+
+func (l *list) push(v []byte, where int) {
+	if where == listHead {
+		l.PushFront(v)
+	} else {
+		l.PushBack(v)
+	}
+}
+
+func (l *list) pop(where int) []byte {
+	if where == listHead {
+		return l.PopFront()
+	}
+	return l.PopBack()
+}
+
+func parseListSide(arg radish.Arg) (int, bool) {
+	switch {
+	case equalFold(arg, "LEFT"):
+		return listHead, true
+	case equalFold(arg, "RIGHT"):
+		return listTail, true
+	default:
+		return 0, false
+	}
+}
+
+// lookupList returns the list value of the key or nil if the key does not
+// exist.
+func (ks *Keyspace) lookupList(key []byte) (*list, error) {
+	v := ks.lookup(key)
+	if v == nil {
+		return nil, nil
+	}
+
+	l, ok := v.(*list)
+	if !ok {
+		return nil, errWrongType
+	}
+	return l, nil
+}
+
+// removeListIfEmpty deletes the key of the l if the l is empty, since Redis
+// does not keep empty lists.
+func (ks *Keyspace) removeListIfEmpty(key []byte, l *list) {
+	if l.Len() == 0 {
+		ks.delete(key)
+	}
+}
+
+// normalizeRange converts start and stop indexes (inclusive and possibly
+// negative) of the Redis range commands to the [start, end) range of a
+// sequence of n elements.
+func normalizeRange(start, stop int64, n int) (int, int) {
+	length := int64(n)
+
+	if start < 0 {
+		start += length
+	}
+	if stop < 0 {
+		stop += length
+	}
+	if start < 0 {
+		start = 0
+	}
+	if start > stop || start >= length {
+		return 0, 0
+	}
+	if stop >= length {
+		stop = length - 1
+	}
+
+	return int(start), int(stop) + 1
+}
+
+func (ks *Keyspace) registerListCommands(m *Mux) {
+	m.HandleFunc(CommandInfo{
+		Name:       "lpush",
+		Arity:      -3,
+		Flags:      FlagWrite | FlagDenyOOM | FlagFast,
+		FirstKey:   1,
+		LastKey:    1,
+		KeyStep:    1,
+		Group:      "list",
+		Summary:    "Prepends one or more elements to a list. Creates the key if it doesn't exist.",
+		Since:      "1.0.0",
+		Complexity: "O(1) for each element added, so O(N) to add N elements when the command is called with multiple arguments.",
+	}, func(c *Conn, cmd *radish.Command) {
+		ks.pushGeneric(c, cmd, listHead, false)
+	})
+
+	m.HandleFunc(CommandInfo{
+		Name:       "rpush",
+		Arity:      -3,
+		Flags:      FlagWrite | FlagDenyOOM | FlagFast,
+		FirstKey:   1,
+		LastKey:    1,
+		KeyStep:    1,
+		Group:      "list",
+		Summary:    "Appends one or more elements to a list. Creates the key if it doesn't exist.",
+		Since:      "1.0.0",
+		Complexity: "O(1) for each element added, so O(N) to add N elements when the command is called with multiple arguments.",
+	}, func(c *Conn, cmd *radish.Command) {
+		ks.pushGeneric(c, cmd, listTail, false)
+	})
+
+	m.HandleFunc(CommandInfo{
+		Name:       "lpushx",
+		Arity:      -3,
+		Flags:      FlagWrite | FlagDenyOOM | FlagFast,
+		FirstKey:   1,
+		LastKey:    1,
+		KeyStep:    1,
+		Group:      "list",
+		Summary:    "Prepends one or more elements to a list only when the list exists.",
+		Since:      "2.2.0",
+		Complexity: "O(1) for each element added, so O(N) to add N elements when the command is called with multiple arguments.",
+	}, func(c *Conn, cmd *radish.Command) {
+		ks.pushGeneric(c, cmd, listHead, true)
+	})
+
+	m.HandleFunc(CommandInfo{
+		Name:       "rpushx",
+		Arity:      -3,
+		Flags:      FlagWrite | FlagDenyOOM | FlagFast,
+		FirstKey:   1,
+		LastKey:    1,
+		KeyStep:    1,
+		Group:      "list",
+		Summary:    "Appends an element to a list only when the list exists.",
+		Since:      "2.2.0",
+		Complexity: "O(1) for each element added, so O(N) to add N elements when the command is called with multiple arguments.",
+	}, func(c *Conn, cmd *radish.Command) {
+		ks.pushGeneric(c, cmd, listTail, true)
+	})
+
+	m.HandleFunc(CommandInfo{
+		Name:       "lpop",
+		Arity:      -2,
+		Flags:      FlagWrite | FlagFast,
+		FirstKey:   1,
+		LastKey:    1,
+		KeyStep:    1,
+		Group:      "list",
+		Summary:    "Returns the first elements in a list after removing it. Deletes the list if the last element was popped.",
+		Since:      "1.0.0",
+		Complexity: "O(N) where N is the number of elements returned",
+	}, func(c *Conn, cmd *radish.Command) {
+		ks.popGeneric(c, cmd, listHead)
+	})
+
+	m.HandleFunc(CommandInfo{
+		Name:       "rpop",
+		Arity:      -2,
+		Flags:      FlagWrite | FlagFast,
+		FirstKey:   1,
+		LastKey:    1,
+		KeyStep:    1,
+		Group:      "list",
+		Summary:    "Returns and removes the last elements of a list. Deletes the list if the last element was popped.",
+		Since:      "1.0.0",
+		Complexity: "O(N) where N is the number of elements returned",
+	}, func(c *Conn, cmd *radish.Command) {
+		ks.popGeneric(c, cmd, listTail)
+	})
+
+	m.HandleFunc(CommandInfo{
+		Name:       "lrange",
+		Arity:      4,
+		Flags:      FlagReadonly,
+		FirstKey:   1,
+		LastKey:    1,
+		KeyStep:    1,
+		Group:      "list",
+		Summary:    "Returns a range of elements from a list.",
+		Since:      "1.0.0",
+		Complexity: "O(S+N) where S is the distance of start offset from HEAD for small lists, from nearest end (HEAD or TAIL) for large lists; and N is the number of elements in the specified range.",
+	}, ks.lrange)
+
+	m.HandleFunc(CommandInfo{
+		Name:       "llen",
+		Arity:      2,
+		Flags:      FlagReadonly | FlagFast,
+		FirstKey:   1,
+		LastKey:    1,
+		KeyStep:    1,
+		Group:      "list",
+		Summary:    "Returns the length of a list.",
+		Since:      "1.0.0",
+		Complexity: "O(1)",
+	}, ks.llen)
+
+	m.HandleFunc(CommandInfo{
+		Name:       "lindex",
+		Arity:      3,
+		Flags:      FlagReadonly,
+		FirstKey:   1,
+		LastKey:    1,
+		KeyStep:    1,
+		Group:      "list",
+		Summary:    "Returns an element from a list by its index.",
+		Since:      "1.0.0",
+		Complexity: "O(N) where N is the number of elements to traverse to get to the element at index. This makes asking for the first or the last element of the list O(1).",
+	}, ks.lindex)
+
+	m.HandleFunc(CommandInfo{
+		Name:       "lset",
+		Arity:      4,
+		Flags:      FlagWrite | FlagDenyOOM,
+		FirstKey:   1,
+		LastKey:    1,
+		KeyStep:    1,
+		Group:      "list",
+		Summary:    "Sets the value of an element in a list by its index.",
+		Since:      "1.0.0",
+		Complexity: "O(N) where N is the length of the list. Setting either the first or the last element of the list is O(1).",
+	}, ks.lset)
+
+	m.HandleFunc(CommandInfo{
+		Name:       "linsert",
+		Arity:      5,
+		Flags:      FlagWrite | FlagDenyOOM,
+		FirstKey:   1,
+		LastKey:    1,
+		KeyStep:    1,
+		Group:      "list",
+		Summary:    "Inserts an element before or after another element in a list.",
+		Since:      "2.2.0",
+		Complexity: "O(N) where N is the number of elements to traverse before seeing the value pivot.",
+	}, ks.linsert)
+
+	m.HandleFunc(CommandInfo{
+		Name:       "lrem",
+		Arity:      4,
+		Flags:      FlagWrite,
+		FirstKey:   1,
+		LastKey:    1,
+		KeyStep:    1,
+		Group:      "list",
+		Summary:    "Removes elements from a list. Deletes the list if the last element was removed.",
+		Since:      "1.0.0",
+		Complexity: "O(N+M) where N is the length of the list and M is the number of elements removed.",
+	}, ks.lrem)
+
+	m.HandleFunc(CommandInfo{
+		Name:       "ltrim",
+		Arity:      4,
+		Flags:      FlagWrite,
+		FirstKey:   1,
+		LastKey:    1,
+		KeyStep:    1,
+		Group:      "list",
+		Summary:    "Removes elements from both ends a list. Deletes the list if all elements were trimmed.",
+		Since:      "1.0.0",
+		Complexity: "O(N) where N is the number of elements to be removed by the operation.",
+	}, ks.ltrim)
+
+	m.HandleFunc(CommandInfo{
+		Name:       "lpos",
+		Arity:      -3,
+		Flags:      FlagReadonly,
+		FirstKey:   1,
+		LastKey:    1,
+		KeyStep:    1,
+		Group:      "list",
+		Summary:    "Returns the index of matching elements in a list.",
+		Since:      "6.0.6",
+		Complexity: "O(N) where N is the number of elements in the list, for the average case. When searching for elements near the head or the tail of the list, or when the MAXLEN option is provided, the command may run in constant time.",
+	}, ks.lpos)
+
+	m.HandleFunc(CommandInfo{
+		Name:       "lmove",
+		Arity:      5,
+		Flags:      FlagWrite | FlagDenyOOM,
+		FirstKey:   1,
+		LastKey:    2,
+		KeyStep:    1,
+		Group:      "list",
+		Summary:    "Returns an element after popping it from one list and pushing it to another. Deletes the list if the last element was moved.",
+		Since:      "6.2.0",
+		Complexity: "O(1)",
+	}, func(c *Conn, cmd *radish.Command) {
+		ks.lmoveGeneric(c, cmd, cmd.Args[3], cmd.Args[4], nil)
+	})
+
+	m.HandleFunc(CommandInfo{
+		Name:       "rpoplpush",
+		Arity:      3,
+		Flags:      FlagWrite | FlagDenyOOM,
+		FirstKey:   1,
+		LastKey:    2,
+		KeyStep:    1,
+		Group:      "list",
+		Summary:    "Returns the last element of a list after removing and pushing it to another list. Deletes the list if the last element was popped.",
+		Since:      "1.2.0",
+		Complexity: "O(1)",
+	}, func(c *Conn, cmd *radish.Command) {
+		ks.lmoveGeneric(c, cmd, radish.Arg("RIGHT"), radish.Arg("LEFT"), nil)
+	})
+
+	m.HandleFunc(CommandInfo{
+		Name:       "lmpop",
+		Arity:      -4,
+		Flags:      FlagWrite,
+		FirstKey:   0,
+		LastKey:    0,
+		KeyStep:    0,
+		Group:      "list",
+		Summary:    "Returns multiple elements from a list after removing them. Deletes the list if the last element was popped.",
+		Since:      "7.0.0",
+		Complexity: "O(N+M) where N is the number of provided keys and M is the number of elements returned.",
+	}, func(c *Conn, cmd *radish.Command) {
+		ks.lmpopGeneric(c, cmd.Args[1:], nil)
+	})
+
+	m.HandleFunc(CommandInfo{
+		Name:       "blpop",
+		Arity:      -3,
+		Flags:      FlagWrite | FlagNoScript | FlagBlocking,
+		FirstKey:   1,
+		LastKey:    -2,
+		KeyStep:    1,
+		Group:      "list",
+		Summary:    "Removes and returns the first element in a list. Blocks until an element is available otherwise. Deletes the list if the last element was popped.",
+		Since:      "2.0.0",
+		Complexity: "O(N) where N is the number of provided keys.",
+	}, func(c *Conn, cmd *radish.Command) {
+		ks.bpopGeneric(c, cmd, listHead)
+	})
+
+	m.HandleFunc(CommandInfo{
+		Name:       "brpop",
+		Arity:      -3,
+		Flags:      FlagWrite | FlagNoScript | FlagBlocking,
+		FirstKey:   1,
+		LastKey:    -2,
+		KeyStep:    1,
+		Group:      "list",
+		Summary:    "Removes and returns the last element in a list. Blocks until an element is available otherwise. Deletes the list if the last element was popped.",
+		Since:      "2.0.0",
+		Complexity: "O(N) where N is the number of provided keys.",
+	}, func(c *Conn, cmd *radish.Command) {
+		ks.bpopGeneric(c, cmd, listTail)
+	})
+
+	m.HandleFunc(CommandInfo{
+		Name:       "blmove",
+		Arity:      6,
+		Flags:      FlagWrite | FlagDenyOOM | FlagNoScript | FlagBlocking,
+		FirstKey:   1,
+		LastKey:    2,
+		KeyStep:    1,
+		Group:      "list",
+		Summary:    "Pops an element from a list, pushes it to another list and returns it. Blocks until an element is available otherwise. Deletes the list if the last element was moved.",
+		Since:      "6.2.0",
+		Complexity: "O(1)",
+	}, func(c *Conn, cmd *radish.Command) {
+		ks.lmoveGeneric(c, cmd, cmd.Args[3], cmd.Args[4], cmd.Args[5])
+	})
+
+	m.HandleFunc(CommandInfo{
+		Name:       "brpoplpush",
+		Arity:      4,
+		Flags:      FlagWrite | FlagDenyOOM | FlagNoScript | FlagBlocking,
+		FirstKey:   1,
+		LastKey:    2,
+		KeyStep:    1,
+		Group:      "list",
+		Summary:    "Pops an element from a list, pushes it to another list and returns it. Block until an element is available otherwise. Deletes the list if the last element was popped.",
+		Since:      "2.2.0",
+		Complexity: "O(1)",
+	}, func(c *Conn, cmd *radish.Command) {
+		ks.lmoveGeneric(c, cmd, radish.Arg("RIGHT"), radish.Arg("LEFT"), cmd.Args[3])
+	})
+
+	m.HandleFunc(CommandInfo{
+		Name:       "blmpop",
+		Arity:      -5,
+		Flags:      FlagWrite | FlagBlocking,
+		FirstKey:   0,
+		LastKey:    0,
+		KeyStep:    0,
+		Group:      "list",
+		Summary:    "Pops the first element from one of multiple lists. Blocks until an element is available otherwise. Deletes the list if the last element was popped.",
+		Since:      "7.0.0",
+		Complexity: "O(N+M) where N is the number of provided keys and M is the number of elements returned.",
+	}, func(c *Conn, cmd *radish.Command) {
+		ks.lmpopGeneric(c, cmd.Args[2:], cmd.Args[1])
+	})
+}
+
+func (ks *Keyspace) pushGeneric(c *Conn, cmd *radish.Command, where int, onlyExisting bool) {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	key := cmd.Args[1]
+	l, err := ks.lookupList(key)
+	if err != nil {
+		_ = c.Writer().WriteError(errWrongType)
+		return
+	}
+
+	if l == nil {
+		if onlyExisting {
+			_ = c.Writer().WriteInt(0)
+			return
+		}
+
+		l = newList()
+		ks.setKey(key, l, false)
+	}
+
+	for _, elem := range cmd.Args[2:] {
+		l.push(elem.Bytes(), where)
+	}
+	n := l.Len()
+
+	ks.signalKeyAsReady(key)
+
+	_ = c.Writer().WriteInt(n)
+}
+
+func (ks *Keyspace) popGeneric(c *Conn, cmd *radish.Command, where int) {
+	w := c.Writer()
+
+	if len(cmd.Args) > 3 {
+		_ = writeWrongArity(w, string(bytes.ToLower(cmd.Args[0])))
+		return
+	}
+
+	hasCount := len(cmd.Args) == 3
+	var count int64
+	if hasCount {
+		var ok bool
+		count, ok = parseInt(cmd.Args[2])
+		if !ok || count < 0 {
+			_ = w.WriteError(errMustBePositive)
+			return
+		}
+	}
+
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	key := cmd.Args[1]
+	l, err := ks.lookupList(key)
+	if err != nil {
+		_ = w.WriteError(errWrongType)
+		return
+	}
+
+	if l == nil {
+		if hasCount {
+			_ = w.WriteArray(-1)
+		} else {
+			_ = w.WriteNull()
+		}
+		return
+	}
+
+	if !hasCount {
+		_ = w.WriteBytes(l.pop(where))
+		ks.removeListIfEmpty(key, l)
+		return
+	}
+
+	if count > int64(l.Len()) {
+		count = int64(l.Len())
+	}
+
+	_ = w.WriteArray(int(count))
+	for i := int64(0); i < count; i++ {
+		_ = w.WriteBytes(l.pop(where))
+	}
+	ks.removeListIfEmpty(key, l)
+}
+
+func (ks *Keyspace) lrange(c *Conn, cmd *radish.Command) {
+	w := c.Writer()
+
+	start, ok1 := parseInt(cmd.Args[2])
+	stop, ok2 := parseInt(cmd.Args[3])
+	if !ok1 || !ok2 {
+		_ = w.WriteError(errNotInteger)
+		return
+	}
+
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	l, err := ks.lookupList(cmd.Args[1])
+	if err != nil {
+		_ = w.WriteError(errWrongType)
+		return
+	}
+	if l == nil {
+		_ = w.WriteArray(0)
+		return
+	}
+
+	from, to := normalizeRange(start, stop, l.Len())
+	_ = w.WriteArray(to - from)
+	for i := from; i < to; i++ {
+		_ = w.WriteBytes(l.At(i))
+	}
+}
+
+func (ks *Keyspace) llen(c *Conn, cmd *radish.Command) {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	l, err := ks.lookupList(cmd.Args[1])
+	if err != nil {
+		_ = c.Writer().WriteError(errWrongType)
+		return
+	}
+	if l == nil {
+		_ = c.Writer().WriteInt(0)
+		return
+	}
+
+	_ = c.Writer().WriteInt(l.Len())
+}
+
+func (ks *Keyspace) lindex(c *Conn, cmd *radish.Command) {
+	w := c.Writer()
+
+	index, ok := parseInt(cmd.Args[2])
+	if !ok {
+		_ = w.WriteError(errNotInteger)
+		return
+	}
+
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	l, err := ks.lookupList(cmd.Args[1])
+	if err != nil {
+		_ = w.WriteError(errWrongType)
+		return
+	}
+	if l == nil {
+		_ = w.WriteNull()
+		return
+	}
+
+	if index < 0 {
+		index += int64(l.Len())
+	}
+	if index < 0 || index >= int64(l.Len()) {
+		_ = w.WriteNull()
+		return
+	}
+
+	_ = w.WriteBytes(l.At(int(index)))
+}
+
+func (ks *Keyspace) lset(c *Conn, cmd *radish.Command) {
+	w := c.Writer()
+
+	index, ok := parseInt(cmd.Args[2])
+	if !ok {
+		_ = w.WriteError(errNotInteger)
+		return
+	}
+
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	l, err := ks.lookupList(cmd.Args[1])
+	if err != nil {
+		_ = w.WriteError(errWrongType)
+		return
+	}
+	if l == nil {
+		_ = w.WriteError(errNoSuchKey)
+		return
+	}
+
+	if index < 0 {
+		index += int64(l.Len())
+	}
+	if index < 0 || index >= int64(l.Len()) {
+		_ = w.WriteError(errIndexRange)
+		return
+	}
+
+	l.Set(int(index), cmd.Args[3].Bytes())
+
+	_ = w.WriteSimpleString("OK")
+}
+
+func (ks *Keyspace) linsert(c *Conn, cmd *radish.Command) {
+	w := c.Writer()
+
+	var after bool
+	switch {
+	case equalFold(cmd.Args[2], "BEFORE"):
+		after = false
+	case equalFold(cmd.Args[2], "AFTER"):
+		after = true
+	default:
+		_ = w.WriteError(errSyntax)
+		return
+	}
+
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	l, err := ks.lookupList(cmd.Args[1])
+	if err != nil {
+		_ = w.WriteError(errWrongType)
+		return
+	}
+	if l == nil {
+		_ = w.WriteInt(0)
+		return
+	}
+
+	pivot := cmd.Args[3]
+	for i := 0; i < l.Len(); i++ {
+		if !bytes.Equal(l.At(i), pivot) {
+			continue
+		}
+
+		if after {
+			i++
+		}
+
+		elems := l.Elements()
+		elems = append(elems, nil)
+		copy(elems[i+1:], elems[i:])
+		elems[i] = cmd.Args[4].Bytes()
+		l.Reset(elems)
+
+		_ = w.WriteInt(l.Len())
+		return
+	}
+
+	_ = w.WriteInt(-1)
+}
+
+func (ks *Keyspace) lrem(c *Conn, cmd *radish.Command) {
+	w := c.Writer()
+
+	count, ok := parseInt(cmd.Args[2])
+	if !ok {
+		_ = w.WriteError(errNotInteger)
+		return
+	}
+
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	key := cmd.Args[1]
+	l, err := ks.lookupList(key)
+	if err != nil {
+		_ = w.WriteError(errWrongType)
+		return
+	}
+	if l == nil {
+		_ = w.WriteInt(0)
+		return
+	}
+
+	elems := l.Elements()
+	elem := cmd.Args[3]
+	removed := int64(0)
+
+	// A negative count removes elements moving from tail to head.
+	limit := count
+	if limit < 0 {
+		limit = -limit
+		for i := len(elems) - 1; i >= 0 && (limit == 0 || removed < limit); i-- {
+			if bytes.Equal(elems[i], elem) {
+				elems[i] = nil
+				removed++
+			}
+		}
+	} else {
+		for i := 0; i < len(elems) && (limit == 0 || removed < limit); i++ {
+			if bytes.Equal(elems[i], elem) {
+				elems[i] = nil
+				removed++
+			}
+		}
+	}
+
+	if removed > 0 {
+		kept := elems[:0]
+		for _, e := range elems {
+			if e != nil {
+				kept = append(kept, e)
+			}
+		}
+		l.Reset(kept)
+		ks.removeListIfEmpty(key, l)
+	}
+
+	_ = w.WriteInt64(removed)
+}
+
+func (ks *Keyspace) ltrim(c *Conn, cmd *radish.Command) {
+	w := c.Writer()
+
+	start, ok1 := parseInt(cmd.Args[2])
+	stop, ok2 := parseInt(cmd.Args[3])
+	if !ok1 || !ok2 {
+		_ = w.WriteError(errNotInteger)
+		return
+	}
+
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	key := cmd.Args[1]
+	l, err := ks.lookupList(key)
+	if err != nil {
+		_ = w.WriteError(errWrongType)
+		return
+	}
+	if l == nil {
+		_ = w.WriteSimpleString("OK")
+		return
+	}
+
+	from, to := normalizeRange(start, stop, l.Len())
+	l.Reset(l.Elements()[from:to])
+	ks.removeListIfEmpty(key, l)
+
+	_ = w.WriteSimpleString("OK")
+}
+
+func (ks *Keyspace) lpos(c *Conn, cmd *radish.Command) {
+	w := c.Writer()
+
+	var (
+		rank     int64 = 1
+		count    int64
+		hasCount bool
+		maxlen   int64
+	)
+
+	args := cmd.Args[3:]
+	for i := 0; i < len(args); i += 2 {
+		if i+1 >= len(args) {
+			_ = w.WriteError(errSyntax)
+			return
+		}
+
+		opt, arg := args[i], args[i+1]
+		if !equalFold(opt, "RANK") && !equalFold(opt, "COUNT") && !equalFold(opt, "MAXLEN") {
+			_ = w.WriteError(errSyntax)
+			return
+		}
+
+		n, ok := parseInt(arg)
+		if !ok {
+			_ = w.WriteError(errNotInteger)
+			return
+		}
+
+		switch {
+		case equalFold(opt, "RANK"):
+			if n == 0 {
+				_ = w.WriteError(&radish.Error{Kind: "ERR", Msg: "RANK can't be zero: use 1 to start from the first match, 2 from the second ... or use negative to start from the end of the list"})
+				return
+			}
+			if n == math.MinInt64 {
+				_ = w.WriteError(&radish.Error{Kind: "ERR", Msg: "value is out of range"})
+				return
+			}
+			rank = n
+
+		case equalFold(opt, "COUNT"):
+			if n < 0 {
+				_ = w.WriteError(&radish.Error{Kind: "ERR", Msg: "COUNT can't be negative"})
+				return
+			}
+			count = n
+			hasCount = true
+
+		case equalFold(opt, "MAXLEN"):
+			if n < 0 {
+				_ = w.WriteError(&radish.Error{Kind: "ERR", Msg: "MAXLEN can't be negative"})
+				return
+			}
+			maxlen = n
+		}
+	}
+
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	l, err := ks.lookupList(cmd.Args[1])
+	if err != nil {
+		_ = w.WriteError(errWrongType)
+		return
+	}
+	if l == nil {
+		if hasCount {
+			_ = w.WriteArray(0)
+		} else {
+			_ = w.WriteNull()
+		}
+		return
+	}
+
+	// A negative rank searches from the tail.
+	step, i := 1, 0
+	if rank < 0 {
+		rank = -rank
+		step, i = -1, l.Len()-1
+	}
+
+	elem := cmd.Args[2]
+	var matches []int
+	for compared := int64(0); i >= 0 && i < l.Len(); i += step {
+		if maxlen != 0 && compared == maxlen {
+			break
+		}
+		compared++
+
+		if !bytes.Equal(l.At(i), elem) {
+			continue
+		}
+
+		if rank > 1 {
+			rank--
+			continue
+		}
+
+		matches = append(matches, i)
+		if !hasCount || (count != 0 && int64(len(matches)) == count) {
+			break
+		}
+	}
+
+	if !hasCount {
+		if len(matches) == 0 {
+			_ = w.WriteNull()
+		} else {
+			_ = w.WriteInt(matches[0])
+		}
+		return
+	}
+
+	_ = w.WriteArray(len(matches))
+	for _, index := range matches {
+		_ = w.WriteInt(index)
+	}
+}
+
+// lmoveGeneric implements LMOVE, RPOPLPUSH, BLMOVE and BRPOPLPUSH. The
+// timeout is nil for non-blocking commands.
+func (ks *Keyspace) lmoveGeneric(c *Conn, cmd *radish.Command, wherefrom, whereto, timeoutArg radish.Arg) {
+	w := c.Writer()
+
+	from, ok1 := parseListSide(wherefrom)
+	to, ok2 := parseListSide(whereto)
+	if !ok1 || !ok2 {
+		_ = w.WriteError(errSyntax)
+		return
+	}
+
+	var timeout time.Duration
+	if timeoutArg != nil {
+		var e *radish.Error
+		timeout, e = parseTimeout(timeoutArg)
+		if e != nil {
+			_ = w.WriteError(e)
+			return
+		}
+	}
+
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	src, dst := cmd.Args[1], cmd.Args[2]
+
+	// move moves an element if both lists are available, otherwise it reports
+	// false.
+	move := func(src, dst []byte) (bool, error) {
+		srcList, err := ks.lookupList(src)
+		if err != nil {
+			return false, err
+		}
+		if srcList == nil {
+			return false, nil
+		}
+
+		dstList, err := ks.lookupList(dst)
+		if err != nil {
+			return false, err
+		}
+
+		elem := srcList.pop(from)
+		if dstList == nil {
+			dstList = newList()
+			ks.setKey(dst, dstList, false)
+		}
+		dstList.push(elem, to)
+
+		// The src and dst may be the same list, so it is checked after the
+		// push.
+		ks.removeListIfEmpty(src, srcList)
+
+		_ = w.WriteBytes(elem)
+
+		ks.signalKeyAsReady(dst)
+		return true, nil
+	}
+
+	moved, err := move(src, dst)
+	if err != nil {
+		_ = w.WriteError(errWrongType)
+		return
+	}
+	if moved {
+		return
+	}
+
+	if timeoutArg == nil {
+		_ = w.WriteNull()
+		return
+	}
+
+	dstKey := dst.Bytes()
+	served := ks.block(c, cmd.Args[1:2], timeout, func(key string) bool {
+		// The client stays blocked if the destination has a wrong type.
+		moved, _ := move([]byte(key), dstKey)
+		return moved
+	})
+	if !served {
+		_ = w.WriteNull()
+	}
+}
+
+// lmpopGeneric implements LMPOP and BLMPOP. The args start with numkeys. The
+// timeout is nil for non-blocking commands.
+func (ks *Keyspace) lmpopGeneric(c *Conn, args []radish.Arg, timeoutArg radish.Arg) {
+	w := c.Writer()
+
+	var timeout time.Duration
+	if timeoutArg != nil {
+		var e *radish.Error
+		timeout, e = parseTimeout(timeoutArg)
+		if e != nil {
+			_ = w.WriteError(e)
+			return
+		}
+	}
+
+	numkeys, ok := parseInt(args[0])
+	if !ok {
+		_ = w.WriteError(errNotInteger)
+		return
+	}
+	if numkeys <= 0 {
+		_ = w.WriteError(errNumkeysZero)
+		return
+	}
+	if numkeys > int64(len(args)-2) {
+		_ = w.WriteError(errSyntax)
+		return
+	}
+
+	keys := args[1 : numkeys+1]
+	args = args[numkeys+1:]
+
+	where, ok := parseListSide(args[0])
+	if !ok {
+		_ = w.WriteError(errSyntax)
+		return
+	}
+
+	count := int64(1)
+	switch {
+	case len(args) == 1:
+	case len(args) == 3 && equalFold(args[1], "COUNT"):
+		count, ok = parseInt(args[2])
+		if !ok || count <= 0 {
+			_ = w.WriteError(errCountZero)
+			return
+		}
+	default:
+		_ = w.WriteError(errSyntax)
+		return
+	}
+
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	pop := func(key []byte, l *list) {
+		n := count
+		if n > int64(l.Len()) {
+			n = int64(l.Len())
+		}
+
+		_ = w.WriteArray(2)
+		_ = w.WriteBytes(key)
+		_ = w.WriteArray(int(n))
+		for i := int64(0); i < n; i++ {
+			_ = w.WriteBytes(l.pop(where))
+		}
+		ks.removeListIfEmpty(key, l)
+	}
+
+	for _, key := range keys {
+		l, err := ks.lookupList(key)
+		if err != nil {
+			_ = w.WriteError(errWrongType)
+			return
+		}
+		if l != nil {
+			pop(key, l)
+			return
+		}
+	}
+
+	if timeoutArg == nil {
+		_ = w.WriteArray(-1)
+		return
+	}
+
+	served := ks.block(c, keys, timeout, func(key string) bool {
+		l, _ := ks.lookupList([]byte(key))
+		if l == nil {
+			return false
+		}
+		pop([]byte(key), l)
+		return true
+	})
+	if !served {
+		_ = w.WriteArray(-1)
+	}
+}
+
+// bpopGeneric implements BLPOP and BRPOP.
+func (ks *Keyspace) bpopGeneric(c *Conn, cmd *radish.Command, where int) {
+	w := c.Writer()
+
+	timeout, e := parseTimeout(cmd.Args[len(cmd.Args)-1])
+	if e != nil {
+		_ = w.WriteError(e)
+		return
+	}
+
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	pop := func(key []byte, l *list) {
+		_ = w.WriteArray(2)
+		_ = w.WriteBytes(key)
+		_ = w.WriteBytes(l.pop(where))
+		ks.removeListIfEmpty(key, l)
+	}
+
+	keys := cmd.Args[1 : len(cmd.Args)-1]
+	for _, key := range keys {
+		l, err := ks.lookupList(key)
+		if err != nil {
+			_ = w.WriteError(errWrongType)
+			return
+		}
+		if l != nil {
+			pop(key, l)
+			return
+		}
+	}
+
+	served := ks.block(c, keys, timeout, func(key string) bool {
+		l, _ := ks.lookupList([]byte(key))
+		if l == nil {
+			return false
+		}
+		pop([]byte(key), l)
+		return true
+	})
+	if !served {
+		_ = w.WriteArray(-1)
+	}
+}
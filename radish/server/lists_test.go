@@ -0,0 +1,318 @@
+package server
+
+import (
+	"testing"
+	"time"
+)
+
+func TestKeyspace_lists(t *testing.T) {
+	tt := []struct {
+		name  string
+		steps []testStep
+	}{
+		{
+			name: "push and range",
+			steps: []testStep{
+				{"RPUSH l b c", "(integer) 2"},
+				{"LPUSH l a z", "(integer) 4"},
+				{"LRANGE l 0 -1", `["z", "a", "b", "c"]`},
+				{"LRANGE l 1 2", `["a", "b"]`},
+				{"LRANGE l -2 100", `["b", "c"]`},
+				{"LRANGE l 3 1", "[]"},
+				{"LRANGE missing 0 -1", "[]"},
+				{"LLEN l", "(integer) 4"},
+				{"LLEN missing", "(integer) 0"},
+				{"TYPE l", "list"},
+			},
+		},
+		{
+			name: "pushx",
+			steps: []testStep{
+				{"LPUSHX l a", "(integer) 0"},
+				{"RPUSHX l a", "(integer) 0"},
+				{"EXISTS l", "(integer) 0"},
+				{"RPUSH l b", "(integer) 1"},
+				{"LPUSHX l a", "(integer) 2"},
+				{"RPUSHX l c d", "(integer) 4"},
+				{"LRANGE l 0 -1", `["a", "b", "c", "d"]`},
+			},
+		},
+		{
+			name: "pop",
+			steps: []testStep{
+				{"RPUSH l a b c d e", "(integer) 5"},
+				{"LPOP l", `"a"`},
+				{"RPOP l", `"e"`},
+				{"LPOP l 2", `["b", "c"]`},
+				{"RPOP l 0", "[]"},
+				{"RPOP l 10", `["d"]`},
+				{"EXISTS l", "(integer) 0"},
+				{"LPOP l", "(nil)"},
+				{"LPOP l 1", "(nil)"},
+				{"LPOP l -1", "(error) ERR value is out of range, must be positive"},
+				{"LPOP l 1 2", "(error) ERR wrong number of arguments for 'lpop' command"},
+			},
+		},
+		{
+			name: "index and set",
+			steps: []testStep{
+				{"RPUSH l a b c", "(integer) 3"},
+				{"LINDEX l 0", `"a"`},
+				{"LINDEX l -1", `"c"`},
+				{"LINDEX l 3", "(nil)"},
+				{"LINDEX l x", "(error) ERR value is not an integer or out of range"},
+				{"LSET l 1 B", "OK"},
+				{"LSET l -1 C", "OK"},
+				{"LRANGE l 0 -1", `["a", "B", "C"]`},
+				{"LSET l 3 x", "(error) ERR index out of range"},
+				{"LSET missing 0 x", "(error) ERR no such key"},
+			},
+		},
+		{
+			name: "insert",
+			steps: []testStep{
+				{"RPUSH l a c", "(integer) 2"},
+				{"LINSERT l BEFORE c b", "(integer) 3"},
+				{"LINSERT l after c d", "(integer) 4"},
+				{"LINSERT l BEFORE x y", "(integer) -1"},
+				{"LINSERT missing BEFORE a b", "(integer) 0"},
+				{"LINSERT l MIDDLE a b", "(error) ERR syntax error"},
+				{"LRANGE l 0 -1", `["a", "b", "c", "d"]`},
+			},
+		},
+		{
+			name: "rem",
+			steps: []testStep{
+				{"RPUSH l a x b x c x", "(integer) 6"},
+				{"LREM l 1 x", "(integer) 1"},
+				{"LRANGE l 0 -1", `["a", "b", "x", "c", "x"]`},
+				{"LREM l -1 x", "(integer) 1"},
+				{"LRANGE l 0 -1", `["a", "b", "x", "c"]`},
+				{"LREM l 0 x", "(integer) 1"},
+				{"LREM l 0 missing", "(integer) 0"},
+				{"LRANGE l 0 -1", `["a", "b", "c"]`},
+			},
+		},
+		{
+			name: "trim",
+			steps: []testStep{
+				{"RPUSH l a b c d e", "(integer) 5"},
+				{"LTRIM l 1 -2", "OK"},
+				{"LRANGE l 0 -1", `["b", "c", "d"]`},
+				{"LTRIM l 5 10", "OK"},
+				{"EXISTS l", "(integer) 0"},
+				{"LTRIM missing 0 1", "OK"},
+			},
+		},
+		{
+			name: "pos",
+			steps: []testStep{
+				{"RPUSH l a b c 1 2 3 c c", "(integer) 8"},
+				{"LPOS l c", "(integer) 2"},
+				{"LPOS l c RANK 2", "(integer) 6"},
+				{"LPOS l c RANK -1", "(integer) 7"},
+				{"LPOS l c COUNT 2", "[(integer) 2, (integer) 6]"},
+				{"LPOS l c COUNT 0", "[(integer) 2, (integer) 6, (integer) 7]"},
+				{"LPOS l c RANK -1 COUNT 2", "[(integer) 7, (integer) 6]"},
+				{"LPOS l c COUNT 0 MAXLEN 3", "[(integer) 2]"},
+				{"LPOS l x", "(nil)"},
+				{"LPOS l x COUNT 1", "[]"},
+				{"LPOS l c RANK 0", "(error) ERR RANK can't be zero: use 1 to start from the first match, 2 from the second ... or use negative to start from the end of the list"},
+				{"LPOS l c COUNT -1", "(error) ERR COUNT can't be negative"},
+				{"LPOS l c MAXLEN -1", "(error) ERR MAXLEN can't be negative"},
+				{"LPOS l c FOO 1", "(error) ERR syntax error"},
+				{"LPOS l c RANK", "(error) ERR syntax error"},
+			},
+		},
+		{
+			name: "move",
+			steps: []testStep{
+				{"RPUSH src a b c", "(integer) 3"},
+				{"LMOVE src dst LEFT RIGHT", `"a"`},
+				{"LMOVE src dst RIGHT LEFT", `"c"`},
+				{"RPOPLPUSH src dst", `"b"`},
+				{"EXISTS src", "(integer) 0"},
+				{"LRANGE dst 0 -1", `["b", "c", "a"]`},
+				{"LMOVE dst dst LEFT RIGHT", `"b"`},
+				{"LRANGE dst 0 -1", `["c", "a", "b"]`},
+				{"LMOVE missing dst LEFT RIGHT", "(nil)"},
+				{"LMOVE dst dst UP DOWN", "(error) ERR syntax error"},
+				{"RPUSH one x", "(integer) 1"},
+				{"LMOVE one one LEFT LEFT", `"x"`},
+				{"LRANGE one 0 -1", `["x"]`},
+			},
+		},
+		{
+			name: "mpop",
+			steps: []testStep{
+				{"RPUSH b 1 2 3", "(integer) 3"},
+				{"LMPOP 2 a b LEFT", `["b", ["1"]]`},
+				{"LMPOP 2 a b RIGHT COUNT 5", `["b", ["3", "2"]]`},
+				{"LMPOP 2 a b LEFT", "(nil)"},
+				{"LMPOP 0 a LEFT", "(error) ERR numkeys should be greater than 0"},
+				{"LMPOP 1 a LEFT COUNT 0", "(error) ERR count should be greater than 0"},
+				{"LMPOP 3 a b LEFT", "(error) ERR syntax error"},
+				{"LMPOP 1 a UP", "(error) ERR syntax error"},
+			},
+		},
+		{
+			name: "wrong type",
+			steps: []testStep{
+				{"SET s v", "OK"},
+				{"LPUSH s x", "(error) WRONGTYPE Operation against a key holding the wrong kind of value"},
+				{"LRANGE s 0 -1", "(error) WRONGTYPE Operation against a key holding the wrong kind of value"},
+				{"LPOP s", "(error) WRONGTYPE Operation against a key holding the wrong kind of value"},
+				{"RPUSH l x", "(integer) 1"},
+				{"GET l", "(error) WRONGTYPE Operation against a key holding the wrong kind of value"},
+				{"LMOVE l s LEFT LEFT", "(error) WRONGTYPE Operation against a key holding the wrong kind of value"},
+				{"LLEN l", "(integer) 1"},
+				{"BLPOP s 0", "(error) WRONGTYPE Operation against a key holding the wrong kind of value"},
+			},
+		},
+		{
+			name: "blocking without blocking",
+			steps: []testStep{
+				{"RPUSH b x y", "(integer) 2"},
+				{"BLPOP a b 0", `["b", "x"]`},
+				{"BRPOP a b 0", `["b", "y"]`},
+				{"BLPOP a 0.01", "(nil)"},
+				{"BLMPOP 0.01 1 a LEFT", "(nil)"},
+				{"BLMOVE a b LEFT LEFT 0.01", "(nil)"},
+				{"BLPOP a -1", "(error) ERR timeout is negative"},
+				{"BLPOP a x", "(error) ERR timeout is not a float or out of range"},
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			c := dialTestClient(t, startTestKeyspaceServer(t, NewKeyspace()))
+
+			runTestSteps(t, c, tc.steps)
+		})
+	}
+}
+
+// waitBlocked waits until n clients are blocked on the key.
+func waitBlocked(t *testing.T, ks *Keyspace, key string, n int) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		ks.mu.Lock()
+		blocked := len(ks.blocked[key])
+		ks.mu.Unlock()
+
+		if blocked == n {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%d clients are blocked on %q, want %d", blocked, key, n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestKeyspace_blpop(t *testing.T) {
+	ks := NewKeyspace()
+	addr := startTestKeyspaceServer(t, ks)
+
+	blocked := dialTestClient(t, addr)
+	c := dialTestClient(t, addr)
+
+	blocked.send("BLPOP", "a", "b", "0")
+	blocked.flush()
+	waitBlocked(t, ks, "b", 1)
+
+	if got, want := c.do("RPUSH", "b", "x", "y"), "(integer) 2"; got != want {
+		t.Fatalf("RPUSH = %s, want %s", got, want)
+	}
+
+	if got, want := blocked.read(), `["b", "x"]`; got != want {
+		t.Errorf("BLPOP = %s, want %s", got, want)
+	}
+	if got, want := c.do("LRANGE", "b", "0", "-1"), `["y"]`; got != want {
+		t.Errorf("LRANGE = %s, want %s", got, want)
+	}
+	waitBlocked(t, ks, "a", 0)
+}
+
+func TestKeyspace_blpop_fifo(t *testing.T) {
+	ks := NewKeyspace()
+	addr := startTestKeyspaceServer(t, ks)
+
+	first := dialTestClient(t, addr)
+	second := dialTestClient(t, addr)
+	c := dialTestClient(t, addr)
+
+	first.send("BLPOP", "k", "0")
+	first.flush()
+	waitBlocked(t, ks, "k", 1)
+
+	second.send("BLPOP", "k", "0")
+	second.flush()
+	waitBlocked(t, ks, "k", 2)
+
+	if got, want := c.do("RPUSH", "k", "x", "y"), "(integer) 2"; got != want {
+		t.Fatalf("RPUSH = %s, want %s", got, want)
+	}
+
+	if got, want := first.read(), `["k", "x"]`; got != want {
+		t.Errorf("first BLPOP = %s, want %s", got, want)
+	}
+	if got, want := second.read(), `["k", "y"]`; got != want {
+		t.Errorf("second BLPOP = %s, want %s", got, want)
+	}
+	if got, want := c.do("EXISTS", "k"), "(integer) 0"; got != want {
+		t.Errorf("EXISTS = %s, want %s", got, want)
+	}
+}
+
+func TestKeyspace_blmove(t *testing.T) {
+	ks := NewKeyspace()
+	addr := startTestKeyspaceServer(t, ks)
+
+	blocked := dialTestClient(t, addr)
+	chained := dialTestClient(t, addr)
+	c := dialTestClient(t, addr)
+
+	blocked.send("BLMOVE", "src", "dst", "LEFT", "RIGHT", "0")
+	blocked.flush()
+	waitBlocked(t, ks, "src", 1)
+
+	// The element moved to dst must serve the client blocked on it.
+	chained.send("BRPOP", "dst", "0")
+	chained.flush()
+	waitBlocked(t, ks, "dst", 1)
+
+	if got, want := c.do("RPUSH", "src", "x"), "(integer) 1"; got != want {
+		t.Fatalf("RPUSH = %s, want %s", got, want)
+	}
+
+	if got, want := blocked.read(), `"x"`; got != want {
+		t.Errorf("BLMOVE = %s, want %s", got, want)
+	}
+	if got, want := chained.read(), `["dst", "x"]`; got != want {
+		t.Errorf("BRPOP = %s, want %s", got, want)
+	}
+}
+
+func TestKeyspace_blpop_disconnected(t *testing.T) {
+	ks := NewKeyspace()
+	addr := startTestKeyspaceServer(t, ks)
+
+	blocked := dialTestClient(t, addr)
+	c := dialTestClient(t, addr)
+
+	blocked.send("BLPOP", "k", "0")
+	blocked.flush()
+	waitBlocked(t, ks, "k", 1)
+
+	_ = blocked.conn.Close()
+	waitBlocked(t, ks, "k", 0)
+
+	runTestSteps(t, c, []testStep{
+		{"RPUSH k x", "(integer) 1"},
+		{"LLEN k", "(integer) 1"},
+	})
+}
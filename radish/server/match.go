@@ -0,0 +1,119 @@
+package server
+
+// matchGlob reports whether the s matches the glob-style pattern, like the
+// Redis stringmatchlen does.
+//
+// Supported patterns:
+//
+//	?       matches any single character
+//	*       matches any sequence of characters
+//	[abc]   matches one of the characters
+//	[^abc]  matches any character except the listed ones
+//	[a-z]   matches a character in the range
+//	\x      matches the character x literally
+func matchGlob(pattern, s []byte) bool {
+	for len(pattern) > 0 {
+		switch pattern[0] {
+		case '*':
+			// Collapse sequential stars.
+			for len(pattern) > 1 && pattern[1] == '*' {
+				pattern = pattern[1:]
+			}
+			if len(pattern) == 1 {
+				return true
+			}
+
+			for i := 0; i <= len(s); i++ {
+				if matchGlob(pattern[1:], s[i:]) {
+					return true
+				}
+			}
+			return false
+
+		case '?':
+			if len(s) == 0 {
+				return false
+			}
+			s = s[1:]
+
+		case '[':
+			if len(s) == 0 {
+				return false
+			}
+
+			var ok bool
+			pattern, ok = matchClass(pattern[1:], s[0])
+			if !ok {
+				return false
+			}
+			s = s[1:]
+
+			// The pattern is already advanced past the class.
+			continue
+
+		case '\\':
+			if len(pattern) >= 2 {
+				pattern = pattern[1:]
+			}
+			fallthrough
+
+		default:
+			if len(s) == 0 || pattern[0] != s[0] {
+				return false
+			}
+			s = s[1:]
+		}
+
+		pattern = pattern[1:]
+	}
+
+	return len(s) == 0
+}
+
+// matchClass matches the ch against a character class. The pattern starts
+// after the opening bracket. It returns the rest of the pattern after the
+// closing bracket.
+func matchClass(pattern []byte, ch byte) ([]byte, bool) {
+	not := len(pattern) > 0 && pattern[0] == '^'
+	if not {
+		pattern = pattern[1:]
+	}
+
+	match := false
+	for len(pattern) > 0 && pattern[0] != ']' {
+		switch {
+		case pattern[0] == '\\' && len(pattern) >= 2:
+			pattern = pattern[1:]
+			if pattern[0] == ch {
+				match = true
+			}
+
+		case len(pattern) >= 3 && pattern[1] == '-':
+			start, end := pattern[0], pattern[2]
+			if start > end {
+				start, end = end, start
+			}
+			if start <= ch && ch <= end {
+				match = true
+			}
+			pattern = pattern[2:]
+
+		default:
+			if pattern[0] == ch {
+				match = true
+			}
+		}
+
+		pattern = pattern[1:]
+	}
+
+	// Skip the closing bracket, an unterminated class ends with the pattern.
+	if len(pattern) > 0 {
+		pattern = pattern[1:]
+	}
+
+	if not {
+		match = !match
+	}
+	return pattern, match
+}
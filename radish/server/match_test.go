@@ -0,0 +1,45 @@
+package server
+
+import (
+	"testing"
+)
+
+func TestMatchGlob(t *testing.T) {
+	tt := []struct {
+		pattern string
+		s       string
+		want    bool
+	}{
+		{"", "", true},
+		{"", "a", false},
+		{"*", "", true},
+		{"*", "anything", true},
+		{"h?llo", "hello", true},
+		{"h?llo", "hllo", false},
+		{"h*llo", "hllo", true},
+		{"h*llo", "heeeello", true},
+		{"h*llo", "hello!", false},
+		{"h**o", "hello", true},
+		{"*b*", "abc", true},
+		{"*b*", "acd", false},
+		{"h[ae]llo", "hello", true},
+		{"h[ae]llo", "hallo", true},
+		{"h[ae]llo", "hillo", false},
+		{"h[^e]llo", "hallo", true},
+		{"h[^e]llo", "hello", false},
+		{"h[a-b]llo", "hbllo", true},
+		{"h[b-a]llo", "hallo", true},
+		{"h[a-b]llo", "hcllo", false},
+		{`h\*llo`, "h*llo", true},
+		{`h\*llo`, "hello", false},
+		{`h[\]]llo`, "h]llo", true},
+		{"h[ae", "ha", true},
+		{`abc\`, `abc\`, true},
+	}
+
+	for _, tc := range tt {
+		if got := matchGlob([]byte(tc.pattern), []byte(tc.s)); got != tc.want {
+			t.Errorf("matchGlob(%q, %q) = %v, want %v", tc.pattern, tc.s, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,226 @@
+package server
+
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/SuperPaintman/mini-redis/radish"
+)
+
+// Flags describes the behavior of a command.
+type Flags uint32
+
+const (
+	FlagWrite    Flags = 1 << iota // The command may modify the keyspace.
+	FlagReadonly                   // The command only reads from the keyspace.
+	FlagDenyOOM                    // The command may increase memory usage.
+	FlagAdmin                      // The command is an administrative command.
+	FlagPubSub                     // The command is related to Pub/Sub.
+	FlagNoScript                   // The command is not allowed in scripts.
+	FlagBlocking                   // The command may block the client.
+	FlagLoading                    // The command is allowed while loading the database.
+	FlagStale                      // The command is allowed while a replica has stale data.
+	FlagFast                       // The command runs in O(1) or O(log(N)) time.
+	FlagNoAuth                     // The command does not require authentication.
+)
+
+var flagNames = []struct {
+	flag Flags
+	name string
+}{
+	{FlagWrite, "write"},
+	{FlagReadonly, "readonly"},
+	{FlagDenyOOM, "denyoom"},
+	{FlagAdmin, "admin"},
+	{FlagPubSub, "pubsub"},
+	{FlagNoScript, "noscript"},
+	{FlagBlocking, "blocking"},
+	{FlagLoading, "loading"},
+	{FlagStale, "stale"},
+	{FlagFast, "fast"},
+	{FlagNoAuth, "no_auth"},
+}
+
+// Has reports whether all of the flag bits are set.
+func (f Flags) Has(flag Flags) bool {
+	return f&flag == flag
+}
+
+// Names returns the Redis names of the set flags.
+func (f Flags) Names() []string {
+	var names []string
+	for _, fn := range flagNames {
+		if f.Has(fn.flag) {
+			names = append(names, fn.name)
+		}
+	}
+	return names
+}
+
+// CommandInfo describes a command registered in a Mux.
+//
+// The meaning of the fields is the same as in the Redis command table.
+type CommandInfo struct {
+	// Name of the command. It is case-insensitive.
+	Name string
+
+	// Arity is the number of arguments, including the command name. A
+	// negative arity means that the command takes at least -Arity arguments.
+	Arity int
+
+	Flags Flags
+
+	// Positions of the first and the last keys and the step between them. A
+	// negative LastKey counts from the end of arguments. All of them are
+	// zero for commands without keys.
+	FirstKey int
+	LastKey  int
+	KeyStep  int
+
+	// Documentation of the command, used by COMMAND DOCS.
+	Group      string // e.g. "string", "list", "connection", etc.
+	Summary    string
+	Since      string
+	Complexity string
+}
+
+// checkArity reports whether the argc number of arguments satisfies the
+// arity of the command.
+func (info *CommandInfo) checkArity(argc int) bool {
+	if info.Arity > 0 {
+		return argc == info.Arity
+	}
+	return argc >= -info.Arity
+}
+
+// Mux is a RESP command multiplexer.
+//
+// It matches the name of each incoming command against registered commands,
+// validates the number of arguments and calls the handler of the command.
+//
+// The zero value is an empty Mux ready to use.
+type Mux struct {
+	mu       sync.RWMutex
+	commands map[string]*muxEntry
+}
+
+type muxEntry struct {
+	info CommandInfo
+	h    Handler
+}
+
+// NewMux allocates and returns a new Mux with connection commands (PING,
+// ECHO, QUIT) and the COMMAND command registered.
+func NewMux() *Mux {
+	m := &Mux{}
+	registerConnectionCommands(m)
+	m.registerCommandCommand()
+	return m
+}
+
+// DefaultMux is the default Mux used by Server.
+var DefaultMux = NewMux()
+
+// Handle registers the handler for the given command.
+//
+// If a handler already exists for the command, or the info is invalid, Handle
+// panics.
+func (m *Mux) Handle(info CommandInfo, h Handler) {
+	if info.Name == "" {
+		panic("radish: invalid command name")
+	}
+	if info.Arity == 0 {
+		panic("radish: invalid arity for command " + info.Name)
+	}
+	if h == nil {
+		panic("radish: nil handler for command " + info.Name)
+	}
+
+	info.Name = strings.ToLower(info.Name)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, exist := m.commands[info.Name]; exist {
+		panic("radish: multiple registrations for command " + info.Name)
+	}
+
+	if m.commands == nil {
+		m.commands = make(map[string]*muxEntry)
+	}
+	m.commands[info.Name] = &muxEntry{info: info, h: h}
+}
+
+// HandleFunc registers the handler function for the given command.
+func (m *Mux) HandleFunc(info CommandInfo, handler func(c *Conn, cmd *radish.Command)) {
+	if handler == nil {
+		panic("radish: nil handler for command " + info.Name)
+	}
+	m.Handle(info, HandlerFunc(handler))
+}
+
+// Lookup returns the info of the command with the given case-insensitive
+// name.
+func (m *Mux) Lookup(name string) (info CommandInfo, ok bool) {
+	e := m.lookup([]byte(name))
+	if e == nil {
+		return CommandInfo{}, false
+	}
+	return e.info, true
+}
+
+// Commands returns infos of all registered commands sorted by name.
+func (m *Mux) Commands() []CommandInfo {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	infos := make([]CommandInfo, 0, len(m.commands))
+	for _, e := range m.commands {
+		infos = append(infos, e.info)
+	}
+
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].Name < infos[j].Name
+	})
+
+	return infos
+}
+
+// ServeRESP dispatches the command to the handler of the registered command.
+//
+// It replies with an error if the command is unknown or has a wrong number of
+// arguments.
+func (m *Mux) ServeRESP(c *Conn, cmd *radish.Command) {
+	e := m.lookup(cmd.Args[0])
+	if e == nil {
+		_ = c.Writer().WriteRawError("ERR", unknownCommandMsg(cmd))
+		return
+	}
+
+	if !e.info.checkArity(len(cmd.Args)) {
+		_ = writeWrongArity(c.Writer(), e.info.Name)
+		return
+	}
+
+	e.h.ServeRESP(c, cmd)
+}
+
+func (m *Mux) lookup(name []byte) *muxEntry {
+	// Most of the command names are short enough to fit in the buffer, so
+	// lowercasing does not allocate.
+	var buf [32]byte
+	lower := buf[:0]
+	for _, ch := range name {
+		if 'A' <= ch && ch <= 'Z' {
+			ch += 'a' - 'A'
+		}
+		lower = append(lower, ch)
+	}
+
+	m.mu.RLock()
+	e := m.commands[string(lower)]
+	m.mu.RUnlock()
+
+	return e
+}
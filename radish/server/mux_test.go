@@ -0,0 +1,127 @@
+package server
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+
+	"github.com/SuperPaintman/mini-redis/radish"
+)
+
+func newTestMux() *Mux {
+	m := NewMux()
+
+	m.HandleFunc(CommandInfo{
+		Name:     "Greet",
+		Arity:    2,
+		Flags:    FlagReadonly | FlagFast,
+		FirstKey: 1,
+		LastKey:  1,
+		KeyStep:  1,
+		Group:    "string",
+		Summary:  "Says hello.",
+		Since:    "1.0.0",
+	}, func(c *Conn, cmd *radish.Command) {
+		_ = c.Writer().WriteString("hello " + string(cmd.Args[1]))
+	})
+
+	m.HandleFunc(CommandInfo{
+		Name:  "count",
+		Arity: -2,
+		Flags: FlagWrite,
+	}, func(c *Conn, cmd *radish.Command) {
+		_ = c.Writer().WriteInt(len(cmd.Args) - 1)
+	})
+
+	return m
+}
+
+func TestMux(t *testing.T) {
+	c := newTestClient(t, newTestMux())
+
+	runTestSteps(t, c, []testStep{
+		{"greet world", `"hello world"`},
+		{"GREET world", `"hello world"`},
+		{"GrEeT world", `"hello world"`},
+		{"greet", "(error) ERR wrong number of arguments for 'greet' command"},
+		{"greet a b", "(error) ERR wrong number of arguments for 'greet' command"},
+		{"count a", "(integer) 1"},
+		{"count a b c", "(integer) 3"},
+		{"COUNT", "(error) ERR wrong number of arguments for 'count' command"},
+		{"unknown a", "(error) ERR unknown command 'unknown', with args beginning with: 'a'"},
+		{"PING", "PONG"},
+	})
+}
+
+func TestMux_command(t *testing.T) {
+	c := newTestClient(t, newTestMux())
+
+	runTestSteps(t, c, []testStep{
+		{"COMMAND COUNT", "(integer) 7"},
+		{"COMMAND LIST", `["command", "count", "echo", "greet", "hello", "ping", "quit"]`},
+		{"COMMAND INFO greet unknown", `[["greet", (integer) 2, [readonly, fast], (integer) 1, (integer) 1, (integer) 1, [@read, @string, @fast], [], [], []], (nil)]`},
+		{"COMMAND INFO count", `[["count", (integer) -2, [write], (integer) 0, (integer) 0, (integer) 0, [@write, @slow], [], [], []]]`},
+		{"COMMAND DOCS greet unknown", `["greet", ["summary", "Says hello.", "since", "1.0.0", "group", "string"]]`},
+		{"COMMAND DOCS count", `["count", []]`},
+		{"COMMAND COUNT extra", "(error) ERR wrong number of arguments for 'command|count' command"},
+		{"COMMAND UNKNOWN", "(error) ERR unknown subcommand 'UNKNOWN'. Try COMMAND HELP."},
+	})
+}
+
+func TestMux_Handle_duplicate(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("Handle() did not panic on a duplicate command")
+		}
+	}()
+
+	m := NewMux()
+	m.HandleFunc(CommandInfo{Name: "PING", Arity: 1}, ping)
+}
+
+func TestMux_Lookup(t *testing.T) {
+	m := newTestMux()
+
+	info, ok := m.Lookup("GREET")
+	if !ok {
+		t.Fatal("Lookup() did not find the command")
+	}
+	if info.Name != "greet" || info.Arity != 2 {
+		t.Errorf("Lookup() = %+v, want greet with arity 2", info)
+	}
+
+	if _, ok := m.Lookup("unknown"); ok {
+		t.Error("Lookup() found an unknown command")
+	}
+}
+
+func TestMux_specArities(t *testing.T) {
+	data, err := os.ReadFile("../commands.json")
+	if err != nil {
+		t.Fatalf("ReadFile(): unexpected error: %v", err)
+	}
+
+	var spec struct {
+		Commands []struct {
+			Name  string `json:"name"`
+			Arity int    `json:"arity"`
+		} `json:"commands"`
+	}
+	if err := json.Unmarshal(data, &spec); err != nil {
+		t.Fatalf("Unmarshal(): unexpected error: %v", err)
+	}
+
+	m := NewMux()
+	NewKeyspace().Register(m)
+
+	for _, cmd := range spec.Commands {
+		info, ok := m.Lookup(cmd.Name)
+		if !ok {
+			t.Errorf("%s: the command is not registered", cmd.Name)
+			continue
+		}
+		if info.Arity != cmd.Arity {
+			t.Errorf("%s: Arity = %d, want %d from the command spec", cmd.Name, info.Arity, cmd.Arity)
+		}
+	}
+}
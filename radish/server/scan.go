@@ -0,0 +1,152 @@
+package server
+
+import (
+	"hash/fnv"
+	"math"
+	"sort"
+	"strconv"
+
+	"github.com/SuperPaintman/mini-redis/radish"
+)
+
+var errInvalidCursor = &radish.Error{Kind: "ERR", Msg: "invalid cursor"}
+
+// defaultScanCount is the default number of elements visited by a single
+// call of the SCAN family commands.
+const defaultScanCount = 10
+
+// scanOptions are the options of the SCAN family commands.
+type scanOptions struct {
+	cursor   uint64
+	match    []byte // Nil if all elements match.
+	count    int
+	noValues bool
+}
+
+// parseScanOptions parses the cursor and the options of the SCAN family
+// commands. The NOVALUES option is allowed only if noValues is set.
+func parseScanOptions(args []radish.Arg, noValues bool) (opts scanOptions, err *radish.Error) {
+	cursor, perr := strconv.ParseUint(string(args[0]), 10, 64)
+	if perr != nil {
+		return opts, errInvalidCursor
+	}
+	opts.cursor = cursor
+	opts.count = defaultScanCount
+
+	for i := 1; i < len(args); i++ {
+		arg := args[i]
+		hasNext := i+1 < len(args)
+
+		switch {
+		case equalFold(arg, "COUNT") && hasNext:
+			i++
+			n, ok := parseInt(args[i])
+			if !ok {
+				return opts, errNotInteger
+			}
+			if n < 1 {
+				return opts, errSyntax
+			}
+			if n > math.MaxInt32 {
+				n = math.MaxInt32
+			}
+			opts.count = int(n)
+
+		case equalFold(arg, "MATCH") && hasNext:
+			i++
+			// A single star matches all elements, so it is not worth matching.
+			if len(args[i]) == 1 && args[i][0] == '*' {
+				opts.match = nil
+			} else {
+				opts.match = args[i]
+			}
+
+		case equalFold(arg, "NOVALUES") && noValues:
+			opts.noValues = true
+
+		default:
+			return opts, errSyntax
+		}
+	}
+
+	return opts, nil
+}
+
+// scanEntry is an element of a collection iterated by a SCAN family command.
+type scanEntry struct {
+	hash  uint64
+	key   []byte
+	value []byte
+}
+
+// scanHash returns the position of the key in the iteration order.
+func scanHash(key []byte) uint64 {
+	h := fnv.New64a()
+	_, _ = h.Write(key)
+	return h.Sum64()
+}
+
+// scanPage returns the page of the entries for the cursor and the cursor of
+// the next page, zero if it is the last one.
+//
+// Entries are iterated in the order of their hashes, and the cursor is the
+// hash of the first entry of the page. So the elements that are present
+// during the full iteration are returned at least once even if the collection
+// is modified between the calls, like Redis guarantees.
+//
+// The entries are reordered and must have the hash set.
+func scanPage(entries []scanEntry, cursor uint64, count int) (page []scanEntry, next uint64) {
+	rest := entries[:0]
+	for _, e := range entries {
+		if e.hash >= cursor {
+			rest = append(rest, e)
+		}
+	}
+
+	sort.Slice(rest, func(i, j int) bool {
+		return rest[i].hash < rest[j].hash
+	})
+
+	if len(rest) <= count {
+		return rest, 0
+	}
+
+	// Do not split entries with the same hash between pages, otherwise some of
+	// them would be skipped.
+	end := count
+	for end < len(rest) && rest[end].hash == rest[end-1].hash {
+		end++
+	}
+	if end == len(rest) {
+		return rest, 0
+	}
+
+	return rest[:end], rest[end].hash
+}
+
+// writeScanReply writes the reply of a SCAN family command. Entries that do
+// not match the pattern are filtered out. If withValues is set, the values
+// follow the keys.
+func writeScanReply(w *radish.Writer, next uint64, entries []scanEntry, match []byte, withValues bool) {
+	matched := entries[:0]
+	for _, e := range entries {
+		if match == nil || matchGlob(match, e.key) {
+			matched = append(matched, e)
+		}
+	}
+
+	_ = w.WriteArray(2)
+	_ = w.WriteString(strconv.FormatUint(next, 10))
+
+	if withValues {
+		_ = w.WriteArray(len(matched) * 2)
+	} else {
+		_ = w.WriteArray(len(matched))
+	}
+	for _, e := range matched {
+		_ = w.WriteBytes(e.key)
+		if withValues {
+			_ = w.WriteBytes(e.value)
+		}
+	}
+}
@@ -0,0 +1,154 @@
+// Package server implements a Redis-compatible server on top of the radish
+// RESP reader and writer.
+package server
+
+import (
+	"context"
+	"log"
+	"net"
+	"sync"
+
+	"github.com/SuperPaintman/mini-redis/radish"
+)
+
+// Version is the version of Redis the server is compatible with. It is
+// reported to clients by HELLO.
+const Version = "7.0.0"
+
+// A Handler responds to a RESP command.
+//
+// ServeRESP should write the reply to the Writer of the connection and then
+// return. The Command and its Args are reused after ServeRESP returns, so the
+// handler must copy everything it wants to keep (e.g. via Arg.Bytes).
+type Handler interface {
+	ServeRESP(c *Conn, cmd *radish.Command)
+}
+
+// The HandlerFunc type is an adapter to allow the use of ordinary functions as
+// RESP handlers.
+type HandlerFunc func(c *Conn, cmd *radish.Command)
+
+// ServeRESP calls f(c, cmd).
+func (f HandlerFunc) ServeRESP(c *Conn, cmd *radish.Command) {
+	f(c, cmd)
+}
+
+// Server defines parameters for running a RESP server.
+type Server struct {
+	// Addr optionally specifies the TCP address for the server to listen on,
+	// in the form "host:port". If empty, ":6379" is used.
+	Addr string
+
+	// Handler to invoke, DefaultMux if nil.
+	Handler Handler
+
+	// ReaderOptions specifies limits of commands read from clients, see
+	// radish.ReaderOptions. Zero values mean the Redis defaults. A client that
+	// exceeds a limit gets a protocol error and is disconnected.
+	ReaderOptions radish.ReaderOptions
+
+	// ErrorLog specifies an optional logger for errors accepting connections
+	// and unexpected behavior from handlers. If nil, logging is done via the
+	// log package's standard logger.
+	ErrorLog *log.Logger
+}
+
+// ListenAndServe listens on the TCP network address s.Addr and then calls
+// Serve to handle incoming connections.
+func (s *Server) ListenAndServe(ctx context.Context) error {
+	addr := s.Addr
+	if addr == "" {
+		addr = ":6379"
+	}
+
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+
+	return s.Serve(ctx, l)
+}
+
+// Serve accepts incoming connections on the Listener l, creating a new service
+// goroutine for each.
+//
+// When the ctx is done, Serve stops accepting new connections, lets every
+// connection finish its current command and returns nil after all of them
+// have been closed. Serve always closes the l before returning.
+func (s *Server) Serve(ctx context.Context, l net.Listener) error {
+	ctx, cancel := context.WithCancel(ctx)
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	defer cancel()
+	defer l.Close()
+
+	go func() {
+		<-ctx.Done()
+		_ = l.Close()
+	}()
+
+	for {
+		rwc, err := l.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+
+		c := s.newConn(ctx, rwc)
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c.serve()
+		}()
+	}
+}
+
+func (s *Server) handler() Handler {
+	if s.Handler != nil {
+		return s.Handler
+	}
+	return DefaultMux
+}
+
+func (s *Server) logf(format string, args ...interface{}) {
+	if s.ErrorLog != nil {
+		s.ErrorLog.Printf(format, args...)
+	} else {
+		log.Printf(format, args...)
+	}
+}
+
+// writeWrongArity writes the error of a wrong number of arguments for the
+// command or subcommand with the given name, e.g. "get" or "command|info".
+func writeWrongArity(w *radish.Writer, name string) error {
+	return w.WriteRawError("ERR", "wrong number of arguments for '"+name+"' command")
+}
+
+func unknownCommandMsg(cmd *radish.Command) string {
+	// The same format and limits as Redis uses.
+	const maxLength = 128
+
+	name := cmd.Args[0]
+	if len(name) > maxLength {
+		name = name[:maxLength]
+	}
+
+	var args []byte
+	for _, arg := range cmd.Args[1:] {
+		if len(args) >= maxLength {
+			break
+		}
+		if rest := maxLength - len(args); len(arg) > rest {
+			arg = arg[:rest]
+		}
+		args = append(args, '\'')
+		args = append(args, arg...)
+		args = append(args, '\'', ' ')
+	}
+
+	return "unknown command '" + string(name) + "', with args beginning with: " + string(args)
+}
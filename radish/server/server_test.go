@@ -0,0 +1,416 @@
+package server
+
+import (
+	"context"
+	"io"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SuperPaintman/mini-redis/radish"
+)
+
+func startTestServer(t testing.TB, h Handler) string {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unexpected error: failed to listen: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	srv := &Server{Handler: h}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- srv.Serve(ctx, l)
+	}()
+
+	t.Cleanup(func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Errorf("Serve() returned unexpected error: %v", err)
+		}
+	})
+
+	return l.Addr().String()
+}
+
+// startTestKeyspaceServer starts a server with the commands of the ks and
+// returns its address.
+func startTestKeyspaceServer(t testing.TB, ks *Keyspace) string {
+	t.Helper()
+
+	m := NewMux()
+	ks.Register(m)
+
+	return startTestServer(t, m)
+}
+
+type testClient struct {
+	t    testing.TB
+	conn net.Conn
+	r    *radish.Reader
+	w    *radish.Writer
+}
+
+func dialTestClient(t testing.TB, addr string) *testClient {
+	t.Helper()
+
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("unexpected error: failed to dial: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = conn.Close()
+	})
+
+	return &testClient{
+		t:    t,
+		conn: conn,
+		r:    radish.NewReader(conn),
+		w:    radish.NewWriter(conn),
+	}
+}
+
+func newTestClient(t testing.TB, h Handler) *testClient {
+	t.Helper()
+
+	return dialTestClient(t, startTestServer(t, h))
+}
+
+// send writes a command without flushing it.
+func (c *testClient) send(args ...string) {
+	_ = c.w.WriteArray(len(args))
+	for _, arg := range args {
+		_ = c.w.WriteString(arg)
+	}
+}
+
+func (c *testClient) flush() {
+	c.t.Helper()
+
+	if err := c.w.Flush(); err != nil {
+		c.t.Fatalf("unexpected error: failed to flush the command: %v", err)
+	}
+}
+
+// read reads a reply and formats it in a redis-cli like form.
+func (c *testClient) read() string {
+	c.t.Helper()
+
+	_ = c.conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+	s, err := formatReply(c.r)
+	if err != nil {
+		c.t.Fatalf("unexpected error: failed to read the reply: %v", err)
+	}
+	return s
+}
+
+func (c *testClient) do(args ...string) string {
+	c.t.Helper()
+
+	c.send(args...)
+	c.flush()
+	return c.read()
+}
+
+func formatReply(r *radish.Reader) (string, error) {
+	dt, v, err := r.ReadAny()
+	if err != nil {
+		return "", err
+	}
+
+	switch dt {
+	case radish.DataTypeSimpleString:
+		return v.(string), nil
+
+	case radish.DataTypeError, radish.DataTypeBulkError:
+		e := v.(*radish.Error)
+		return strings.TrimSpace("(error) " + e.Kind + " " + e.Msg), nil
+
+	case radish.DataTypeInteger:
+		return "(integer) " + strconv.Itoa(v.(int)), nil
+
+	case radish.DataTypeBulkString, radish.DataTypeVerbatimString:
+		return strconv.Quote(v.(string)), nil
+
+	case radish.DataTypeBoolean:
+		return "(" + strconv.FormatBool(v.(bool)) + ")", nil
+
+	case radish.DataTypeDouble:
+		return "(double) " + strconv.FormatFloat(v.(float64), 'g', -1, 64), nil
+
+	case radish.DataTypeArray, radish.DataTypePush:
+		length := v.(int)
+		if length < 0 {
+			return "(nil)", nil
+		}
+
+		elems, err := formatReplies(r, length)
+		if err != nil {
+			return "", err
+		}
+		return "[" + strings.Join(elems, ", ") + "]", nil
+
+	case radish.DataTypeSet:
+		elems, err := formatReplies(r, v.(int))
+		if err != nil {
+			return "", err
+		}
+		return "{" + strings.Join(elems, ", ") + "}", nil
+
+	case radish.DataTypeMap:
+		elems, err := formatReplies(r, 2*v.(int))
+		if err != nil {
+			return "", err
+		}
+
+		pairs := make([]string, 0, len(elems)/2)
+		for i := 0; i < len(elems); i += 2 {
+			pairs = append(pairs, elems[i]+" => "+elems[i+1])
+		}
+		return "{" + strings.Join(pairs, ", ") + "}", nil
+
+	default:
+		return "(nil)", nil
+	}
+}
+
+func formatReplies(r *radish.Reader, n int) ([]string, error) {
+	elems := make([]string, n)
+	for i := range elems {
+		var err error
+		elems[i], err = formatReply(r)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return elems, nil
+}
+
+type testStep struct {
+	cmd  string // Space separated arguments.
+	want string
+}
+
+func runTestSteps(t *testing.T, c *testClient, steps []testStep) {
+	t.Helper()
+
+	for i, step := range steps {
+		got := c.do(strings.Fields(step.cmd)...)
+		if got != step.want {
+			t.Errorf("#%d %s = %s, want %s", i, step.cmd, got, step.want)
+		}
+	}
+}
+
+func TestServer_DefaultMux(t *testing.T) {
+	c := newTestClient(t, nil)
+
+	runTestSteps(t, c, []testStep{
+		{"PING", "PONG"},
+		{"ping hello", `"hello"`},
+		{"PING a b", "(error) ERR wrong number of arguments for 'ping' command"},
+		{"ECHO hello", `"hello"`},
+		{"ECHO", "(error) ERR wrong number of arguments for 'echo' command"},
+		{"GO", "(error) ERR unknown command 'GO', with args beginning with:"},
+		{"GO a b", "(error) ERR unknown command 'GO', with args beginning with: 'a' 'b'"},
+	})
+}
+
+func TestServer_pipelining(t *testing.T) {
+	c := newTestClient(t, nil)
+
+	c.send("PING")
+	c.send("ECHO", "first")
+	c.send("ECHO", "second")
+	c.send("PING")
+	c.flush()
+
+	want := []string{"PONG", `"first"`, `"second"`, "PONG"}
+	for i, w := range want {
+		if got := c.read(); got != w {
+			t.Errorf("reply #%d = %s, want %s", i, got, w)
+		}
+	}
+}
+
+func TestServer_pipeliningFlushesLargeOutput(t *testing.T) {
+	m := NewMux()
+	m.HandleFunc(CommandInfo{Name: "big", Arity: 1}, func(c *Conn, cmd *radish.Command) {
+		_ = c.Writer().WriteString(strings.Repeat("x", maxPendingOutput))
+	})
+	c := newTestClient(t, m)
+
+	// The server waits for the rest of the second command, which is never
+	// sent, so the reply must be written before it.
+	if _, err := c.conn.Write([]byte("*1\r\n$3\r\nBIG\r\n*1\r\n$4\r\nPI")); err != nil {
+		t.Fatalf("unexpected error: failed to write the commands: %v", err)
+	}
+
+	if got := c.read(); len(got) != maxPendingOutput+2 {
+		t.Errorf("reply length = %d, want %d", len(got), maxPendingOutput+2)
+	}
+}
+
+func TestServer_quit(t *testing.T) {
+	c := newTestClient(t, nil)
+
+	if got := c.do("QUIT"); got != "OK" {
+		t.Fatalf("QUIT = %s, want OK", got)
+	}
+
+	if _, _, err := c.r.ReadAny(); err != io.EOF {
+		t.Errorf("ReadAny() after QUIT error = %v, want %v", err, io.EOF)
+	}
+}
+
+func TestServer_hello(t *testing.T) {
+	c := newTestClient(t, nil)
+
+	// IDs of connections depend on the order of tests.
+	idRe := regexp.MustCompile(`"id"(,| =>) \(integer\) \d+`)
+
+	steps := []testStep{
+		{"HELLO", `["server", "redis", "version", "7.0.0", "proto", (integer) 2, "id" ID, "mode", "standalone", "role", "master", "modules", []]`},
+		{"HELLO 3", `{"server" => "redis", "version" => "7.0.0", "proto" => (integer) 3, "id" ID, "mode" => "standalone", "role" => "master", "modules" => []}`},
+		{"HELLO", `{"server" => "redis", "version" => "7.0.0", "proto" => (integer) 3, "id" ID, "mode" => "standalone", "role" => "master", "modules" => []}`},
+		{"HELLO 2 AUTH default secret SETNAME app", `["server", "redis", "version", "7.0.0", "proto", (integer) 2, "id" ID, "mode", "standalone", "role", "master", "modules", []]`},
+		{"HELLO 4", "(error) NOPROTO unsupported protocol version"},
+		{"HELLO 1", "(error) NOPROTO unsupported protocol version"},
+		{"HELLO three", "(error) ERR Protocol version is not an integer or out of range"},
+		{"HELLO 3 AUTH alice secret", "(error) WRONGPASS invalid username-password pair or user is disabled."},
+		{"HELLO 3 AUTH default", "(error) ERR Syntax error in HELLO option 'AUTH'"},
+		{"HELLO 3 SETNAME", "(error) ERR Syntax error in HELLO option 'SETNAME'"},
+		{"HELLO 3 SETNAME bad\x01name", "(error) ERR Client names cannot contain spaces, newlines or special characters."},
+		{"HELLO 3 UNKNOWN", "(error) ERR Syntax error in HELLO option 'UNKNOWN'"},
+
+		// Failed HELLO does not switch the protocol.
+		{"HELLO", `["server", "redis", "version", "7.0.0", "proto", (integer) 2, "id" ID, "mode", "standalone", "role", "master", "modules", []]`},
+	}
+
+	for i, step := range steps {
+		args := strings.Fields(step.cmd)
+		for j := range args {
+			args[j] = strings.Replace(args[j], `\x01`, "\x01", -1)
+		}
+
+		got := idRe.ReplaceAllString(c.do(args...), `"id" ID`)
+		if got != step.want {
+			t.Errorf("#%d %s = %s, want %s", i, step.cmd, got, step.want)
+		}
+	}
+}
+
+func TestServer_inline(t *testing.T) {
+	c := newTestClient(t, nil)
+
+	if _, err := c.conn.Write([]byte("PING\r\nECHO \"hello world\"\n\r\nping 'a b'\r\n")); err != nil {
+		t.Fatalf("unexpected error: failed to write: %v", err)
+	}
+
+	want := []string{"PONG", `"hello world"`, `"a b"`}
+	for i, w := range want {
+		if got := c.read(); got != w {
+			t.Errorf("reply #%d = %s, want %s", i, got, w)
+		}
+	}
+
+	if _, err := c.conn.Write([]byte("ECHO \"unbalanced\r\n")); err != nil {
+		t.Fatalf("unexpected error: failed to write: %v", err)
+	}
+
+	wantErr := "(error) ERR Protocol error: unbalanced quotes in request"
+	if got := c.read(); got != wantErr {
+		t.Errorf("reply = %s, want %s", got, wantErr)
+	}
+
+	if _, _, err := c.r.ReadAny(); err != io.EOF {
+		t.Errorf("ReadAny() after a protocol error = %v, want %v", err, io.EOF)
+	}
+}
+
+func TestServer_protocolError(t *testing.T) {
+	c := newTestClient(t, nil)
+
+	if _, err := c.conn.Write([]byte("*1\r\n$abc\r\n")); err != nil {
+		t.Fatalf("unexpected error: failed to write: %v", err)
+	}
+
+	want := "(error) ERR Protocol error: invalid bulk length"
+	if got := c.read(); got != want {
+		t.Errorf("reply = %s, want %s", got, want)
+	}
+
+	if _, _, err := c.r.ReadAny(); err != io.EOF {
+		t.Errorf("ReadAny() after a protocol error = %v, want %v", err, io.EOF)
+	}
+}
+
+func TestServer_ReaderOptions(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unexpected error: failed to listen: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	srv := &Server{
+		ReaderOptions: radish.ReaderOptions{MaxBulkLength: 8},
+	}
+	go func() {
+		_ = srv.Serve(ctx, l)
+	}()
+
+	c := dialTestClient(t, l.Addr().String())
+
+	runTestSteps(t, c, []testStep{
+		{"ECHO 12345678", `"12345678"`},
+		{"ECHO 123456789", "(error) ERR Protocol error: invalid bulk length"},
+	})
+
+	if _, _, err := c.r.ReadAny(); err != io.EOF {
+		t.Errorf("ReadAny() after a protocol error = %v, want %v", err, io.EOF)
+	}
+}
+
+func TestServer_Serve_shutdown(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unexpected error: failed to listen: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	srv := &Server{}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- srv.Serve(ctx, l)
+	}()
+
+	c := dialTestClient(t, l.Addr().String())
+	if got := c.do("PING"); got != "PONG" {
+		t.Fatalf("PING = %s, want PONG", got)
+	}
+
+	cancel()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Serve() error = %v, want nil", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Serve() did not return after the context was canceled")
+	}
+
+	if _, _, err := c.r.ReadAny(); err != io.EOF {
+		t.Errorf("ReadAny() after shutdown error = %v, want %v", err, io.EOF)
+	}
+}
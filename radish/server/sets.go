@@ -0,0 +1,989 @@
+package server
+
+import (
+	"encoding/binary"
+	"math"
+	"math/rand"
+	"sort"
+	"strconv"
+
+	"github.com/SuperPaintman/mini-redis/radish"
+)
+
+var (
+	errKeysGreaterThanArgs = &radish.Error{Kind: "ERR", Msg: "Number of keys can't be greater than number of args"}
+	errLimitNegative       = &radish.Error{Kind: "ERR", Msg: "LIMIT can't be negative"}
+)
+
+// defaultSetMaxIntsetEntries is the default threshold of the intset encoding
+// of sets, the same as Redis uses.
+const defaultSetMaxIntsetEntries = 512
+
+// set is a set of members.
+//
+// Sets that contain only integers are encoded as an intset: a sorted array of
+// integers, all of the same width of 2, 4 or 8 bytes, which is enough for the
+// largest of them. The set is converted to a map once a non-integer member is
+// added or it grows past the threshold of the Keyspace, and it is never
+// converted back.
+type set struct {
+	is  []byte // Nil if the set is converted to the m.
+	enc int    // Width of integers in the is.
+
+	m map[string]struct{}
+}
+
+func newSet() *set {
+	return &set{is: []byte{}, enc: 2}
+}
+
+// intsetWidth returns the width of the smallest intset encoding for the v.
+func intsetWidth(v int64) int {
+	switch {
+	case v >= math.MinInt16 && v <= math.MaxInt16:
+		return 2
+	case v >= math.MinInt32 && v <= math.MaxInt32:
+		return 4
+	default:
+		return 8
+	}
+}
+
+func (s *set) isLen() int {
+	return len(s.is) / s.enc
+}
+
+func (s *set) isAt(i int) int64 {
+	b := s.is[i*s.enc:]
+	switch s.enc {
+	case 2:
+		return int64(int16(binary.LittleEndian.Uint16(b)))
+	case 4:
+		return int64(int32(binary.LittleEndian.Uint32(b)))
+	default:
+		return int64(binary.LittleEndian.Uint64(b))
+	}
+}
+
+func (s *set) isPut(i int, v int64) {
+	b := s.is[i*s.enc:]
+	switch s.enc {
+	case 2:
+		binary.LittleEndian.PutUint16(b, uint16(v))
+	case 4:
+		binary.LittleEndian.PutUint32(b, uint32(v))
+	default:
+		binary.LittleEndian.PutUint64(b, uint64(v))
+	}
+}
+
+// isSearch returns the position of the v in the intset, or the position where
+// it would be inserted.
+func (s *set) isSearch(v int64) (int, bool) {
+	n := s.isLen()
+	i := sort.Search(n, func(i int) bool {
+		return s.isAt(i) >= v
+	})
+	return i, i < n && s.isAt(i) == v
+}
+
+func (s *set) isAdd(v int64) bool {
+	if width := intsetWidth(v); width > s.enc {
+		s.isUpgrade(width)
+	}
+
+	i, ok := s.isSearch(v)
+	if ok {
+		return false
+	}
+
+	s.is = append(s.is, make([]byte, s.enc)...)
+	copy(s.is[(i+1)*s.enc:], s.is[i*s.enc:])
+	s.isPut(i, v)
+	return true
+}
+
+func (s *set) isRemove(v int64) bool {
+	i, ok := s.isSearch(v)
+	if !ok {
+		return false
+	}
+
+	s.is = append(s.is[:i*s.enc], s.is[(i+1)*s.enc:]...)
+	return true
+}
+
+// isUpgrade re-encodes the intset with the wider integers.
+func (s *set) isUpgrade(width int) {
+	old := *s
+
+	s.is = make([]byte, old.isLen()*width)
+	s.enc = width
+	for i := 0; i < old.isLen(); i++ {
+		s.isPut(i, old.isAt(i))
+	}
+}
+
+func (s *set) Len() int {
+	if s.m != nil {
+		return len(s.m)
+	}
+	return s.isLen()
+}
+
+// Has reports whether the member is in the set.
+func (s *set) Has(member []byte) bool {
+	if s.m != nil {
+		_, ok := s.m[string(member)]
+		return ok
+	}
+
+	v, ok := parseInt(member)
+	if !ok {
+		return false
+	}
+
+	_, ok = s.isSearch(v)
+	return ok
+}
+
+// Remove removes the member and reports whether it was in the set.
+func (s *set) Remove(member []byte) bool {
+	if s.m != nil {
+		if _, ok := s.m[string(member)]; !ok {
+			return false
+		}
+		delete(s.m, string(member))
+		return true
+	}
+
+	v, ok := parseInt(member)
+	if !ok {
+		return false
+	}
+	return s.isRemove(v)
+}
+
+// Range calls the fn for each member of the set until it returns false. The
+// member must not be retained by the fn and the set must not be modified
+// during the iteration.
+func (s *set) Range(fn func(member []byte) bool) {
+	if s.m != nil {
+		for member := range s.m {
+			if !fn([]byte(member)) {
+				return
+			}
+		}
+		return
+	}
+
+	var buf [20]byte
+	for i := 0; i < s.isLen(); i++ {
+		if !fn(strconv.AppendInt(buf[:0], s.isAt(i), 10)) {
+			return
+		}
+	}
+}
+
+// Members returns a copy of the members of the set.
+func (s *set) Members() [][]byte {
+	members := make([][]byte, 0, s.Len())
+	s.Range(func(member []byte) bool {
+		members = append(members, append([]byte(nil), member...))
+		return true
+	})
+	return members
+}
+
+// convert converts the intset to the map encoding.
+func (s *set) convert() {
+	m := make(map[string]struct{}, s.isLen())
+	s.Range(func(member []byte) bool {
+		m[string(member)] = struct{}{}
+		return true
+	})
+
+	s.m = m
+	s.is = nil
+}
+
+func (s *set) encoding() string {
+	if s.m != nil {
+		return "hashtable"
+	}
+	return "intset"
+}
+
+// lookupSet returns the set value of the key or nil if the key does not
+// exist.
+func (ks *Keyspace) lookupSet(key []byte) (*set, error) {
+	v := ks.lookup(key)
+	if v == nil {
+		return nil, nil
+	}
+
+	s, ok := v.(*set)
+	if !ok {
+		return nil, errWrongType
+	}
+	return s, nil
+}
+
+// setAdd adds the member to the s and converts the s to the map encoding if
+// the member is not an integer or the s grows past the threshold.
+func (ks *Keyspace) setAdd(s *set, member []byte) bool {
+	if s.m == nil {
+		v, ok := parseInt(member)
+		if ok {
+			added := s.isAdd(v)
+			if s.isLen() > ks.SetMaxIntsetEntries {
+				s.convert()
+			}
+			return added
+		}
+
+		s.convert()
+	}
+
+	if _, ok := s.m[string(member)]; ok {
+		return false
+	}
+	s.m[string(member)] = struct{}{}
+	return true
+}
+
+// storeSet replaces the value of the key with a set of the members, or deletes
+// the key if there are no members. It returns the size of the set.
+func (ks *Keyspace) storeSet(key []byte, members [][]byte) int {
+	if len(members) == 0 {
+		ks.delete(key)
+		return 0
+	}
+
+	s := newSet()
+	for _, member := range members {
+		ks.setAdd(s, member)
+	}
+	ks.setKey(key, s, false)
+	return s.Len()
+}
+
+func (ks *Keyspace) registerSetCommands(m *Mux) {
+	m.HandleFunc(CommandInfo{
+		Name:       "sadd",
+		Arity:      -3,
+		Flags:      FlagWrite | FlagDenyOOM | FlagFast,
+		FirstKey:   1,
+		LastKey:    1,
+		KeyStep:    1,
+		Group:      "set",
+		Summary:    "Adds one or more members to a set. Creates the key if it doesn't exist.",
+		Since:      "1.0.0",
+		Complexity: "O(1) for each element added, so O(N) to add N elements when the command is called with multiple arguments.",
+	}, ks.sadd)
+
+	m.HandleFunc(CommandInfo{
+		Name:       "srem",
+		Arity:      -3,
+		Flags:      FlagWrite | FlagFast,
+		FirstKey:   1,
+		LastKey:    1,
+		KeyStep:    1,
+		Group:      "set",
+		Summary:    "Removes one or more members from a set. Deletes the set if the last member was removed.",
+		Since:      "1.0.0",
+		Complexity: "O(N) where N is the number of members to be removed.",
+	}, ks.srem)
+
+	m.HandleFunc(CommandInfo{
+		Name:       "sismember",
+		Arity:      3,
+		Flags:      FlagReadonly | FlagFast,
+		FirstKey:   1,
+		LastKey:    1,
+		KeyStep:    1,
+		Group:      "set",
+		Summary:    "Determines whether a member belongs to a set.",
+		Since:      "1.0.0",
+		Complexity: "O(1)",
+	}, ks.sismember)
+
+	m.HandleFunc(CommandInfo{
+		Name:       "smismember",
+		Arity:      -3,
+		Flags:      FlagReadonly | FlagFast,
+		FirstKey:   1,
+		LastKey:    1,
+		KeyStep:    1,
+		Group:      "set",
+		Summary:    "Determines whether multiple members belong to a set.",
+		Since:      "6.2.0",
+		Complexity: "O(N) where N is the number of elements being checked for membership",
+	}, ks.smismember)
+
+	m.HandleFunc(CommandInfo{
+		Name:       "smembers",
+		Arity:      2,
+		Flags:      FlagReadonly,
+		FirstKey:   1,
+		LastKey:    1,
+		KeyStep:    1,
+		Group:      "set",
+		Summary:    "Returns all members of a set.",
+		Since:      "1.0.0",
+		Complexity: "O(N) where N is the set cardinality.",
+	}, ks.smembers)
+
+	m.HandleFunc(CommandInfo{
+		Name:       "scard",
+		Arity:      2,
+		Flags:      FlagReadonly | FlagFast,
+		FirstKey:   1,
+		LastKey:    1,
+		KeyStep:    1,
+		Group:      "set",
+		Summary:    "Returns the number of members in a set.",
+		Since:      "1.0.0",
+		Complexity: "O(1)",
+	}, ks.scard)
+
+	m.HandleFunc(CommandInfo{
+		Name:       "spop",
+		Arity:      -2,
+		Flags:      FlagWrite | FlagFast,
+		FirstKey:   1,
+		LastKey:    1,
+		KeyStep:    1,
+		Group:      "set",
+		Summary:    "Returns one or more random members from a set after removing them. Deletes the set if the last member was popped.",
+		Since:      "1.0.0",
+		Complexity: "Without the count argument O(1), otherwise O(N) where N is the value of the passed count.",
+	}, ks.spop)
+
+	m.HandleFunc(CommandInfo{
+		Name:       "srandmember",
+		Arity:      -2,
+		Flags:      FlagReadonly,
+		FirstKey:   1,
+		LastKey:    1,
+		KeyStep:    1,
+		Group:      "set",
+		Summary:    "Get one or multiple random members from a set",
+		Since:      "1.0.0",
+		Complexity: "Without the count argument O(1), otherwise O(N) where N is the absolute value of the passed count.",
+	}, ks.srandmember)
+
+	m.HandleFunc(CommandInfo{
+		Name:       "smove",
+		Arity:      4,
+		Flags:      FlagWrite | FlagFast,
+		FirstKey:   1,
+		LastKey:    2,
+		KeyStep:    1,
+		Group:      "set",
+		Summary:    "Moves a member from one set to another.",
+		Since:      "1.0.0",
+		Complexity: "O(1)",
+	}, ks.smove)
+
+	m.HandleFunc(CommandInfo{
+		Name:       "sinter",
+		Arity:      -2,
+		Flags:      FlagReadonly,
+		FirstKey:   1,
+		LastKey:    -1,
+		KeyStep:    1,
+		Group:      "set",
+		Summary:    "Returns the intersect of multiple sets.",
+		Since:      "1.0.0",
+		Complexity: "O(N*M) worst case where N is the cardinality of the smallest set and M is the number of sets.",
+	}, func(c *Conn, cmd *radish.Command) {
+		ks.setAlgebraGeneric(c, cmd.Args[1:], nil, setInter)
+	})
+
+	m.HandleFunc(CommandInfo{
+		Name:       "sinterstore",
+		Arity:      -3,
+		Flags:      FlagWrite | FlagDenyOOM,
+		FirstKey:   1,
+		LastKey:    -1,
+		KeyStep:    1,
+		Group:      "set",
+		Summary:    "Stores the intersect of multiple sets in a key.",
+		Since:      "1.0.0",
+		Complexity: "O(N*M) worst case where N is the cardinality of the smallest set and M is the number of sets.",
+	}, func(c *Conn, cmd *radish.Command) {
+		ks.setAlgebraGeneric(c, cmd.Args[2:], cmd.Args[1], setInter)
+	})
+
+	m.HandleFunc(CommandInfo{
+		Name:       "sunion",
+		Arity:      -2,
+		Flags:      FlagReadonly,
+		FirstKey:   1,
+		LastKey:    -1,
+		KeyStep:    1,
+		Group:      "set",
+		Summary:    "Returns the union of multiple sets.",
+		Since:      "1.0.0",
+		Complexity: "O(N) where N is the total number of elements in all given sets.",
+	}, func(c *Conn, cmd *radish.Command) {
+		ks.setAlgebraGeneric(c, cmd.Args[1:], nil, setUnion)
+	})
+
+	m.HandleFunc(CommandInfo{
+		Name:       "sunionstore",
+		Arity:      -3,
+		Flags:      FlagWrite | FlagDenyOOM,
+		FirstKey:   1,
+		LastKey:    -1,
+		KeyStep:    1,
+		Group:      "set",
+		Summary:    "Stores the union of multiple sets in a key.",
+		Since:      "1.0.0",
+		Complexity: "O(N) where N is the total number of elements in all given sets.",
+	}, func(c *Conn, cmd *radish.Command) {
+		ks.setAlgebraGeneric(c, cmd.Args[2:], cmd.Args[1], setUnion)
+	})
+
+	m.HandleFunc(CommandInfo{
+		Name:       "sdiff",
+		Arity:      -2,
+		Flags:      FlagReadonly,
+		FirstKey:   1,
+		LastKey:    -1,
+		KeyStep:    1,
+		Group:      "set",
+		Summary:    "Returns the difference of multiple sets.",
+		Since:      "1.0.0",
+		Complexity: "O(N) where N is the total number of elements in all given sets.",
+	}, func(c *Conn, cmd *radish.Command) {
+		ks.setAlgebraGeneric(c, cmd.Args[1:], nil, setDiff)
+	})
+
+	m.HandleFunc(CommandInfo{
+		Name:       "sdiffstore",
+		Arity:      -3,
+		Flags:      FlagWrite | FlagDenyOOM,
+		FirstKey:   1,
+		LastKey:    -1,
+		KeyStep:    1,
+		Group:      "set",
+		Summary:    "Stores the difference of multiple sets in a key.",
+		Since:      "1.0.0",
+		Complexity: "O(N) where N is the total number of elements in all given sets.",
+	}, func(c *Conn, cmd *radish.Command) {
+		ks.setAlgebraGeneric(c, cmd.Args[2:], cmd.Args[1], setDiff)
+	})
+
+	m.HandleFunc(CommandInfo{
+		Name:       "sintercard",
+		Arity:      -3,
+		Flags:      FlagReadonly,
+		FirstKey:   0,
+		LastKey:    0,
+		KeyStep:    0,
+		Group:      "set",
+		Summary:    "Returns the number of members of the intersect of multiple sets.",
+		Since:      "7.0.0",
+		Complexity: "O(N*M) worst case where N is the cardinality of the smallest set and M is the number of sets.",
+	}, ks.sintercard)
+}
+
+func (ks *Keyspace) sadd(c *Conn, cmd *radish.Command) {
+	w := c.Writer()
+
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	key := cmd.Args[1]
+	s, err := ks.lookupSet(key)
+	if err != nil {
+		_ = w.WriteError(errWrongType)
+		return
+	}
+	if s == nil {
+		s = newSet()
+		ks.setKey(key, s, false)
+	}
+
+	added := 0
+	for _, member := range cmd.Args[2:] {
+		if ks.setAdd(s, member) {
+			added++
+		}
+	}
+
+	_ = w.WriteInt(added)
+}
+
+func (ks *Keyspace) srem(c *Conn, cmd *radish.Command) {
+	w := c.Writer()
+
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	key := cmd.Args[1]
+	s, err := ks.lookupSet(key)
+	if err != nil {
+		_ = w.WriteError(errWrongType)
+		return
+	}
+	if s == nil {
+		_ = w.WriteInt(0)
+		return
+	}
+
+	removed := 0
+	for _, member := range cmd.Args[2:] {
+		if s.Remove(member) {
+			removed++
+		}
+	}
+
+	if s.Len() == 0 {
+		ks.delete(key)
+	}
+
+	_ = w.WriteInt(removed)
+}
+
+func (ks *Keyspace) sismember(c *Conn, cmd *radish.Command) {
+	w := c.Writer()
+
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	s, err := ks.lookupSet(cmd.Args[1])
+	if err != nil {
+		_ = w.WriteError(errWrongType)
+		return
+	}
+
+	if s != nil && s.Has(cmd.Args[2]) {
+		_ = w.WriteInt(1)
+	} else {
+		_ = w.WriteInt(0)
+	}
+}
+
+func (ks *Keyspace) smismember(c *Conn, cmd *radish.Command) {
+	w := c.Writer()
+
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	s, err := ks.lookupSet(cmd.Args[1])
+	if err != nil {
+		_ = w.WriteError(errWrongType)
+		return
+	}
+
+	members := cmd.Args[2:]
+	_ = w.WriteArray(len(members))
+	for _, member := range members {
+		if s != nil && s.Has(member) {
+			_ = w.WriteInt(1)
+		} else {
+			_ = w.WriteInt(0)
+		}
+	}
+}
+
+func (ks *Keyspace) smembers(c *Conn, cmd *radish.Command) {
+	w := c.Writer()
+
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	s, err := ks.lookupSet(cmd.Args[1])
+	if err != nil {
+		_ = w.WriteError(errWrongType)
+		return
+	}
+	if s == nil {
+		_ = w.WriteSet(0)
+		return
+	}
+
+	_ = w.WriteSet(s.Len())
+	s.Range(func(member []byte) bool {
+		_ = w.WriteBytes(member)
+		return true
+	})
+}
+
+func (ks *Keyspace) scard(c *Conn, cmd *radish.Command) {
+	w := c.Writer()
+
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	s, err := ks.lookupSet(cmd.Args[1])
+	if err != nil {
+		_ = w.WriteError(errWrongType)
+		return
+	}
+	if s == nil {
+		_ = w.WriteInt(0)
+		return
+	}
+
+	_ = w.WriteInt(s.Len())
+}
+
+// parseSetCount parses the optional count of SPOP and SRANDMEMBER.
+func parseSetCount(cmd *radish.Command) (count int64, hasCount bool, err *radish.Error) {
+	switch len(cmd.Args) {
+	case 2:
+		return 0, false, nil
+	case 3:
+		count, ok := parseInt(cmd.Args[2])
+		if !ok {
+			return 0, false, errNotInteger
+		}
+		return count, true, nil
+	default:
+		return 0, false, errSyntax
+	}
+}
+
+func (ks *Keyspace) spop(c *Conn, cmd *radish.Command) {
+	w := c.Writer()
+
+	count, hasCount, e := parseSetCount(cmd)
+	if e == nil && count < 0 {
+		e = errMustBePositive
+	}
+	if e != nil {
+		_ = w.WriteError(e)
+		return
+	}
+
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	key := cmd.Args[1]
+	s, err := ks.lookupSet(key)
+	if err != nil {
+		_ = w.WriteError(errWrongType)
+		return
+	}
+	if s == nil {
+		if hasCount {
+			_ = w.WriteSet(0)
+		} else {
+			_ = w.WriteNull()
+		}
+		return
+	}
+
+	members := s.Members()
+	rand.Shuffle(len(members), func(i, j int) {
+		members[i], members[j] = members[j], members[i]
+	})
+
+	if !hasCount {
+		count = 1
+	}
+	if count > int64(len(members)) {
+		count = int64(len(members))
+	}
+	members = members[:count]
+
+	for _, member := range members {
+		s.Remove(member)
+	}
+	if s.Len() == 0 {
+		ks.delete(key)
+	}
+
+	if !hasCount {
+		_ = w.WriteBytes(members[0])
+		return
+	}
+
+	_ = w.WriteSet(len(members))
+	for _, member := range members {
+		_ = w.WriteBytes(member)
+	}
+}
+
+func (ks *Keyspace) srandmember(c *Conn, cmd *radish.Command) {
+	w := c.Writer()
+
+	count, hasCount, e := parseSetCount(cmd)
+	if e != nil {
+		_ = w.WriteError(e)
+		return
+	}
+
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	s, err := ks.lookupSet(cmd.Args[1])
+	if err != nil {
+		_ = w.WriteError(errWrongType)
+		return
+	}
+	if s == nil {
+		if hasCount {
+			_ = w.WriteArray(0)
+		} else {
+			_ = w.WriteNull()
+		}
+		return
+	}
+
+	members := s.Members()
+
+	if !hasCount {
+		_ = w.WriteBytes(members[rand.Intn(len(members))])
+		return
+	}
+
+	// A negative count allows the same member to be returned multiple times,
+	// a positive one returns distinct members.
+	n := int(count)
+	switch {
+	case count < 0:
+		if count == math.MinInt64 {
+			_ = w.WriteError(errValueRange)
+			return
+		}
+		n = int(-count)
+	case count >= int64(len(members)):
+		n = len(members)
+	default:
+		rand.Shuffle(len(members), func(i, j int) {
+			members[i], members[j] = members[j], members[i]
+		})
+	}
+
+	_ = w.WriteArray(n)
+	for i := 0; i < n; i++ {
+		member := members[i%len(members)]
+		if count < 0 {
+			member = members[rand.Intn(len(members))]
+		}
+		_ = w.WriteBytes(member)
+	}
+}
+
+func (ks *Keyspace) smove(c *Conn, cmd *radish.Command) {
+	w := c.Writer()
+
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	srcKey, dstKey, member := cmd.Args[1], cmd.Args[2], cmd.Args[3]
+
+	src, err := ks.lookupSet(srcKey)
+	if err != nil {
+		_ = w.WriteError(errWrongType)
+		return
+	}
+	dst, err := ks.lookupSet(dstKey)
+	if err != nil {
+		_ = w.WriteError(errWrongType)
+		return
+	}
+
+	if src == nil || !src.Has(member) {
+		_ = w.WriteInt(0)
+		return
+	}
+
+	if src == dst {
+		_ = w.WriteInt(1)
+		return
+	}
+
+	src.Remove(member)
+	if src.Len() == 0 {
+		ks.delete(srcKey)
+	}
+
+	if dst == nil {
+		dst = newSet()
+		ks.setKey(dstKey, dst, false)
+	}
+	ks.setAdd(dst, member)
+
+	_ = w.WriteInt(1)
+}
+
+// Operations of the set algebra commands.
+const (
+	setInter = iota
+	setUnion
+	setDiff
+)
+
+// setAlgebraGeneric implements SINTER, SUNION, SDIFF and their STORE
+// variants. The dst is nil for non-STORE commands.
+func (ks *Keyspace) setAlgebraGeneric(c *Conn, keys []radish.Arg, dst radish.Arg, op int) {
+	w := c.Writer()
+
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	sets := make([]*set, len(keys))
+	for i, key := range keys {
+		s, err := ks.lookupSet(key)
+		if err != nil {
+			_ = w.WriteError(errWrongType)
+			return
+		}
+		sets[i] = s
+	}
+
+	var result [][]byte
+	switch op {
+	case setInter:
+		result = setIntersection(sets, 0)
+	case setUnion:
+		result = setUnionOf(sets)
+	case setDiff:
+		result = setDifference(sets)
+	}
+
+	if dst != nil {
+		_ = w.WriteInt(ks.storeSet(dst, result))
+		return
+	}
+
+	_ = w.WriteSet(len(result))
+	for _, member := range result {
+		_ = w.WriteBytes(member)
+	}
+}
+
+// setIntersection returns the members of the intersection of the sets, or up
+// to the limit of them if it is not zero. Nil sets are empty.
+func setIntersection(sets []*set, limit int) [][]byte {
+	for _, s := range sets {
+		if s == nil {
+			return nil
+		}
+	}
+
+	// Iterate over the smallest set to check as few members as possible.
+	sorted := append([]*set(nil), sets...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Len() < sorted[j].Len()
+	})
+
+	var result [][]byte
+	sorted[0].Range(func(member []byte) bool {
+		for _, other := range sorted[1:] {
+			if !other.Has(member) {
+				return true
+			}
+		}
+
+		result = append(result, append([]byte(nil), member...))
+		return limit == 0 || len(result) < limit
+	})
+	return result
+}
+
+// setUnionOf returns the members of the union of the sets. Nil sets are
+// empty.
+func setUnionOf(sets []*set) [][]byte {
+	seen := make(map[string]struct{})
+
+	var result [][]byte
+	for _, s := range sets {
+		if s == nil {
+			continue
+		}
+
+		s.Range(func(member []byte) bool {
+			if _, ok := seen[string(member)]; !ok {
+				seen[string(member)] = struct{}{}
+				result = append(result, append([]byte(nil), member...))
+			}
+			return true
+		})
+	}
+	return result
+}
+
+// setDifference returns the members of the first set that are not in the
+// other sets. Nil sets are empty.
+func setDifference(sets []*set) [][]byte {
+	if sets[0] == nil {
+		return nil
+	}
+
+	var result [][]byte
+	sets[0].Range(func(member []byte) bool {
+		for _, other := range sets[1:] {
+			if other != nil && other.Has(member) {
+				return true
+			}
+		}
+
+		result = append(result, append([]byte(nil), member...))
+		return true
+	})
+	return result
+}
+
+func (ks *Keyspace) sintercard(c *Conn, cmd *radish.Command) {
+	w := c.Writer()
+
+	numkeys, ok := parseInt(cmd.Args[1])
+	if !ok {
+		_ = w.WriteError(errNotInteger)
+		return
+	}
+	if numkeys <= 0 {
+		_ = w.WriteError(errNumkeysZero)
+		return
+	}
+	if numkeys > int64(len(cmd.Args)-2) {
+		_ = w.WriteError(errKeysGreaterThanArgs)
+		return
+	}
+
+	keys := cmd.Args[2 : 2+numkeys]
+	args := cmd.Args[2+numkeys:]
+
+	var limit int64
+	switch {
+	case len(args) == 0:
+	case len(args) == 2 && equalFold(args[0], "LIMIT"):
+		limit, ok = parseInt(args[1])
+		if !ok {
+			_ = w.WriteError(errNotInteger)
+			return
+		}
+		if limit < 0 {
+			_ = w.WriteError(errLimitNegative)
+			return
+		}
+	default:
+		_ = w.WriteError(errSyntax)
+		return
+	}
+	if limit > math.MaxInt32 {
+		limit = math.MaxInt32
+	}
+
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	sets := make([]*set, len(keys))
+	for i, key := range keys {
+		s, err := ks.lookupSet(key)
+		if err != nil {
+			_ = w.WriteError(errWrongType)
+			return
+		}
+		sets[i] = s
+	}
+
+	_ = w.WriteInt(len(setIntersection(sets, int(limit))))
+}
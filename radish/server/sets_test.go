@@ -0,0 +1,210 @@
+package server
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestKeyspace_sets(t *testing.T) {
+	tt := []struct {
+		name  string
+		steps []testStep
+	}{
+		{
+			name: "add and members",
+			steps: []testStep{
+				{"SADD s 3 1 2 1", "(integer) 3"},
+				{"SADD s 2 4", "(integer) 1"},
+				{"SMEMBERS s", `["1", "2", "3", "4"]`},
+				{"SMEMBERS missing", "[]"},
+				{"SCARD s", "(integer) 4"},
+				{"SCARD missing", "(integer) 0"},
+				{"SISMEMBER s 2", "(integer) 1"},
+				{"SISMEMBER s 5", "(integer) 0"},
+				{"SISMEMBER s x", "(integer) 0"},
+				{"SISMEMBER missing 1", "(integer) 0"},
+				{"SMISMEMBER s 1 5 x 4", "[(integer) 1, (integer) 0, (integer) 0, (integer) 1]"},
+				{"TYPE s", "set"},
+				{"OBJECT ENCODING s", `"intset"`},
+			},
+		},
+		{
+			name: "intset widths",
+			steps: []testStep{
+				{"SADD s 1 -32768 32767", "(integer) 3"},
+				{"SADD s 2147483647 -2147483648", "(integer) 2"},
+				{"SADD s 9223372036854775807 -9223372036854775808", "(integer) 2"},
+				{"SADD s 1 2147483647 9223372036854775807", "(integer) 0"},
+				{"SMEMBERS s", `["-9223372036854775808", "-2147483648", "-32768", "1", "32767", "2147483647", "9223372036854775807"]`},
+				{"OBJECT ENCODING s", `"intset"`},
+				{"SREM s 1 2147483647 5", "(integer) 2"},
+				{"SMEMBERS s", `["-9223372036854775808", "-2147483648", "-32768", "32767", "9223372036854775807"]`},
+			},
+		},
+		{
+			name: "non-integer members",
+			steps: []testStep{
+				{"SADD s 1 01 -0 +1", "(integer) 4"},
+				{"OBJECT ENCODING s", `"hashtable"`},
+				{"SISMEMBER s 01", "(integer) 1"},
+				{"SISMEMBER s 1", "(integer) 1"},
+				{"SCARD s", "(integer) 4"},
+			},
+		},
+		{
+			name: "rem",
+			steps: []testStep{
+				{"SADD s a b c", "(integer) 3"},
+				{"SREM s a x", "(integer) 1"},
+				{"SREM missing a", "(integer) 0"},
+				{"SREM s b c", "(integer) 2"},
+				{"EXISTS s", "(integer) 0"},
+			},
+		},
+		{
+			name: "pop and randmember",
+			steps: []testStep{
+				{"SPOP missing", "(nil)"},
+				{"SPOP missing 1", "[]"},
+				{"SRANDMEMBER missing", "(nil)"},
+				{"SRANDMEMBER missing 1", "[]"},
+				{"SADD s a", "(integer) 1"},
+				{"SRANDMEMBER s", `"a"`},
+				{"SRANDMEMBER s 5", `["a"]`},
+				{"SRANDMEMBER s -3", `["a", "a", "a"]`},
+				{"SRANDMEMBER s 0", "[]"},
+				{"SPOP s 0", "[]"},
+				{"SPOP s -1", "(error) ERR value is out of range, must be positive"},
+				{"SPOP s x", "(error) ERR value is not an integer or out of range"},
+				{"SPOP s", `"a"`},
+				{"EXISTS s", "(integer) 0"},
+				{"SADD s 1", "(integer) 1"},
+				{"SPOP s 5", `["1"]`},
+				{"EXISTS s", "(integer) 0"},
+			},
+		},
+		{
+			name: "move",
+			steps: []testStep{
+				{"SADD src a b", "(integer) 2"},
+				{"SMOVE src dst a", "(integer) 1"},
+				{"SMOVE src dst x", "(integer) 0"},
+				{"SMOVE src src b", "(integer) 1"},
+				{"SMOVE src dst b", "(integer) 1"},
+				{"EXISTS src", "(integer) 0"},
+				{"SCARD dst", "(integer) 2"},
+				{"SMOVE missing dst a", "(integer) 0"},
+			},
+		},
+		{
+			name: "algebra",
+			steps: []testStep{
+				{"SADD a 1 2 3 4", "(integer) 4"},
+				{"SADD b 3 4 5", "(integer) 3"},
+				{"SADD c 4 5 6", "(integer) 3"},
+				{"SINTER a b", `["3", "4"]`},
+				{"SINTER a b c", `["4"]`},
+				{"SINTER a missing", "[]"},
+				{"SUNION a b c", `["1", "2", "3", "4", "5", "6"]`},
+				{"SUNION missing", "[]"},
+				{"SDIFF a b", `["1", "2"]`},
+				{"SDIFF a b c", `["1", "2"]`},
+				{"SDIFF a a", "[]"},
+				{"SDIFF missing a", "[]"},
+				{"SDIFF a missing", `["1", "2", "3", "4"]`},
+				{"SINTERSTORE dst a b", "(integer) 2"},
+				{"SMEMBERS dst", `["3", "4"]`},
+				{"OBJECT ENCODING dst", `"intset"`},
+				{"SUNIONSTORE dst a c", "(integer) 6"},
+				{"SDIFFSTORE dst a b", "(integer) 2"},
+				{"SMEMBERS dst", `["1", "2"]`},
+				{"SINTERSTORE dst a missing", "(integer) 0"},
+				{"EXISTS dst", "(integer) 0"},
+				{"SET str v", "OK"},
+				{"SUNIONSTORE str a", "(integer) 4"},
+				{"TYPE str", "set"},
+			},
+		},
+		{
+			name: "intercard",
+			steps: []testStep{
+				{"SADD a 1 2 3 4", "(integer) 4"},
+				{"SADD b 2 3 4 5", "(integer) 4"},
+				{"SINTERCARD 2 a b", "(integer) 3"},
+				{"SINTERCARD 2 a b LIMIT 2", "(integer) 2"},
+				{"SINTERCARD 2 a b LIMIT 0", "(integer) 3"},
+				{"SINTERCARD 1 a", "(integer) 4"},
+				{"SINTERCARD 2 a missing", "(integer) 0"},
+				{"SINTERCARD 0 a", "(error) ERR numkeys should be greater than 0"},
+				{"SINTERCARD 3 a b", "(error) ERR Number of keys can't be greater than number of args"},
+				{"SINTERCARD 2 a b LIMIT -1", "(error) ERR LIMIT can't be negative"},
+				{"SINTERCARD 2 a b LIMIT", "(error) ERR syntax error"},
+				{"SINTERCARD 1 a b", "(error) ERR syntax error"},
+			},
+		},
+		{
+			name: "wrong type",
+			steps: []testStep{
+				{"SET str v", "OK"},
+				{"SADD str a", "(error) WRONGTYPE Operation against a key holding the wrong kind of value"},
+				{"SADD s a", "(integer) 1"},
+				{"SINTER s str", "(error) WRONGTYPE Operation against a key holding the wrong kind of value"},
+				{"SUNIONSTORE dst s str", "(error) WRONGTYPE Operation against a key holding the wrong kind of value"},
+				{"SMOVE s str a", "(error) WRONGTYPE Operation against a key holding the wrong kind of value"},
+				{"SCARD s", "(integer) 1"},
+				{"GET s", "(error) WRONGTYPE Operation against a key holding the wrong kind of value"},
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			c := dialTestClient(t, startTestKeyspaceServer(t, NewKeyspace()))
+
+			runTestSteps(t, c, tc.steps)
+		})
+	}
+}
+
+func TestKeyspace_setEncoding(t *testing.T) {
+	tt := []struct {
+		name    string
+		members []string
+		want    string
+	}{
+		{"integers", []string{"1", "2", "3"}, "intset"},
+		{"too many integers", []string{"1", "2", "3", "4", "5"}, "hashtable"},
+		{"string", []string{"1", "a"}, "hashtable"},
+		{"leading zero", []string{"1", "007"}, "hashtable"},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			ks := NewKeyspace()
+			ks.SetMaxIntsetEntries = 4
+			c := dialTestClient(t, startTestKeyspaceServer(t, ks))
+
+			c.do(append([]string{"SADD", "s"}, tc.members...)...)
+
+			if got, want := c.do("OBJECT", "ENCODING", "s"), strconv.Quote(tc.want); got != want {
+				t.Errorf("OBJECT ENCODING s = %s, want %s", got, want)
+			}
+
+			// The content must be the same in all encodings.
+			want := append([]string(nil), tc.members...)
+			sort.Strings(want)
+
+			var got []string
+			for _, member := range strings.Split(strings.Trim(c.do("SMEMBERS", "s"), "[]"), ", ") {
+				got = append(got, unquote(t, member))
+			}
+			sort.Strings(got)
+
+			if strings.Join(got, " ") != strings.Join(want, " ") {
+				t.Errorf("SMEMBERS s = %v, want %v", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,372 @@
+package server
+
+import (
+	"math/rand"
+)
+
+// Parameters of the skiplist, the same as Redis uses.
+const (
+	skiplistMaxLevel = 32
+	skiplistP        = 0.25
+)
+
+// skiplistNode is an element of a sorted set.
+type skiplistNode struct {
+	member   string
+	score    float64
+	backward *skiplistNode
+	level    []skiplistLevel
+}
+
+type skiplistLevel struct {
+	forward *skiplistNode
+	span    int // Number of nodes between the node and the forward.
+}
+
+// skiplist is a port of the Redis zskiplist: a skiplist of members ordered by
+// score and then lexicographically, with spans that allow to get the rank of
+// a member and a member by rank in O(log(N)).
+type skiplist struct {
+	header *skiplistNode
+	tail   *skiplistNode
+	length int
+	level  int
+}
+
+func newSkiplist() *skiplist {
+	return &skiplist{
+		header: &skiplistNode{level: make([]skiplistLevel, skiplistMaxLevel)},
+		level:  1,
+	}
+}
+
+func randomSkiplistLevel() int {
+	level := 1
+	for level < skiplistMaxLevel && rand.Float64() < skiplistP {
+		level++
+	}
+	return level
+}
+
+// less reports whether the node is ordered before the score and member.
+func (x *skiplistNode) less(score float64, member string) bool {
+	return x.score < score || (x.score == score && x.member < member)
+}
+
+// Insert inserts a new member. The member must not already be in the list.
+func (zsl *skiplist) Insert(score float64, member string) *skiplistNode {
+	var (
+		update [skiplistMaxLevel]*skiplistNode
+		rank   [skiplistMaxLevel]int
+	)
+
+	x := zsl.header
+	for i := zsl.level - 1; i >= 0; i-- {
+		if i != zsl.level-1 {
+			rank[i] = rank[i+1]
+		}
+		for x.level[i].forward != nil && x.level[i].forward.less(score, member) {
+			rank[i] += x.level[i].span
+			x = x.level[i].forward
+		}
+		update[i] = x
+	}
+
+	level := randomSkiplistLevel()
+	if level > zsl.level {
+		for i := zsl.level; i < level; i++ {
+			rank[i] = 0
+			update[i] = zsl.header
+			update[i].level[i].span = zsl.length
+		}
+		zsl.level = level
+	}
+
+	x = &skiplistNode{
+		member: member,
+		score:  score,
+		level:  make([]skiplistLevel, level),
+	}
+	for i := 0; i < level; i++ {
+		x.level[i].forward = update[i].level[i].forward
+		update[i].level[i].forward = x
+
+		x.level[i].span = update[i].level[i].span - (rank[0] - rank[i])
+		update[i].level[i].span = (rank[0] - rank[i]) + 1
+	}
+
+	// Increment the span for untouched levels.
+	for i := level; i < zsl.level; i++ {
+		update[i].level[i].span++
+	}
+
+	if update[0] != zsl.header {
+		x.backward = update[0]
+	}
+	if x.level[0].forward != nil {
+		x.level[0].forward.backward = x
+	} else {
+		zsl.tail = x
+	}
+	zsl.length++
+
+	return x
+}
+
+func (zsl *skiplist) deleteNode(x *skiplistNode, update *[skiplistMaxLevel]*skiplistNode) {
+	for i := 0; i < zsl.level; i++ {
+		if update[i].level[i].forward == x {
+			update[i].level[i].span += x.level[i].span - 1
+			update[i].level[i].forward = x.level[i].forward
+		} else {
+			update[i].level[i].span--
+		}
+	}
+
+	if x.level[0].forward != nil {
+		x.level[0].forward.backward = x.backward
+	} else {
+		zsl.tail = x.backward
+	}
+
+	for zsl.level > 1 && zsl.header.level[zsl.level-1].forward == nil {
+		zsl.level--
+	}
+	zsl.length--
+}
+
+// Delete deletes the member with the score and reports whether it was found.
+func (zsl *skiplist) Delete(score float64, member string) bool {
+	var update [skiplistMaxLevel]*skiplistNode
+
+	x := zsl.header
+	for i := zsl.level - 1; i >= 0; i-- {
+		for x.level[i].forward != nil && x.level[i].forward.less(score, member) {
+			x = x.level[i].forward
+		}
+		update[i] = x
+	}
+
+	x = x.level[0].forward
+	if x == nil || x.score != score || x.member != member {
+		return false
+	}
+
+	zsl.deleteNode(x, &update)
+	return true
+}
+
+// Rank returns the 1-based rank of the member with the score, or 0 if it is
+// not found.
+func (zsl *skiplist) Rank(score float64, member string) int {
+	rank := 0
+
+	x := zsl.header
+	for i := zsl.level - 1; i >= 0; i-- {
+		for x.level[i].forward != nil &&
+			(x.level[i].forward.less(score, member) ||
+				(x.level[i].forward.score == score && x.level[i].forward.member == member)) {
+			rank += x.level[i].span
+			x = x.level[i].forward
+		}
+
+		if x != zsl.header && x.member == member {
+			return rank
+		}
+	}
+
+	return 0
+}
+
+// ByRank returns the node with the 1-based rank, or nil if it is out of
+// range.
+func (zsl *skiplist) ByRank(rank int) *skiplistNode {
+	traversed := 0
+
+	x := zsl.header
+	for i := zsl.level - 1; i >= 0; i-- {
+		for x.level[i].forward != nil && traversed+x.level[i].span <= rank {
+			traversed += x.level[i].span
+			x = x.level[i].forward
+		}
+
+		if traversed == rank {
+			if x == zsl.header {
+				return nil
+			}
+			return x
+		}
+	}
+
+	return nil
+}
+
+// First returns the first node of the list, or nil if the list is empty.
+func (zsl *skiplist) First() *skiplistNode {
+	return zsl.header.level[0].forward
+}
+
+// Last returns the last node of the list, or nil if the list is empty.
+func (zsl *skiplist) Last() *skiplistNode {
+	return zsl.tail
+}
+
+// scoreRange is a range of scores of ZRANGE BYSCORE, ZCOUNT and others.
+type scoreRange struct {
+	min, max     float64
+	minex, maxex bool // Exclusive bounds.
+}
+
+func (r *scoreRange) gteMin(score float64) bool {
+	if r.minex {
+		return score > r.min
+	}
+	return score >= r.min
+}
+
+func (r *scoreRange) lteMax(score float64) bool {
+	if r.maxex {
+		return score < r.max
+	}
+	return score <= r.max
+}
+
+func (r *scoreRange) empty() bool {
+	return r.min > r.max || (r.min == r.max && (r.minex || r.maxex))
+}
+
+// lexBound is a bound of a lexicographical range. The inf is -1 for the "-"
+// and +1 for the "+" bounds.
+type lexBound struct {
+	value string
+	ex    bool
+	inf   int
+}
+
+// lexRange is a lexicographical range of ZRANGE BYLEX and ZLEXCOUNT.
+type lexRange struct {
+	min, max lexBound
+}
+
+func (r *lexRange) gteMin(member string) bool {
+	switch r.min.inf {
+	case -1:
+		return true
+	case 1:
+		return false
+	}
+
+	if r.min.ex {
+		return member > r.min.value
+	}
+	return member >= r.min.value
+}
+
+func (r *lexRange) lteMax(member string) bool {
+	switch r.max.inf {
+	case 1:
+		return true
+	case -1:
+		return false
+	}
+
+	if r.max.ex {
+		return member < r.max.value
+	}
+	return member <= r.max.value
+}
+
+func (r *lexRange) empty() bool {
+	if r.min.inf == 1 || r.max.inf == -1 {
+		return true
+	}
+	if r.min.inf == -1 || r.max.inf == 1 {
+		return false
+	}
+	return r.min.value > r.max.value ||
+		(r.min.value == r.max.value && (r.min.ex || r.max.ex))
+}
+
+// FirstInRange returns the first node in the range, or nil if there is no
+// such node.
+func (zsl *skiplist) FirstInRange(r *scoreRange) *skiplistNode {
+	if r.empty() || zsl.tail == nil || !r.gteMin(zsl.tail.score) {
+		return nil
+	}
+
+	x := zsl.header
+	for i := zsl.level - 1; i >= 0; i-- {
+		for x.level[i].forward != nil && !r.gteMin(x.level[i].forward.score) {
+			x = x.level[i].forward
+		}
+	}
+
+	x = x.level[0].forward
+	if x == nil || !r.lteMax(x.score) {
+		return nil
+	}
+	return x
+}
+
+// LastInRange returns the last node in the range, or nil if there is no such
+// node.
+func (zsl *skiplist) LastInRange(r *scoreRange) *skiplistNode {
+	first := zsl.First()
+	if r.empty() || first == nil || !r.lteMax(first.score) {
+		return nil
+	}
+
+	x := zsl.header
+	for i := zsl.level - 1; i >= 0; i-- {
+		for x.level[i].forward != nil && r.lteMax(x.level[i].forward.score) {
+			x = x.level[i].forward
+		}
+	}
+
+	if x == zsl.header || !r.gteMin(x.score) {
+		return nil
+	}
+	return x
+}
+
+// FirstInLexRange returns the first node in the range, or nil if there is no
+// such node.
+func (zsl *skiplist) FirstInLexRange(r *lexRange) *skiplistNode {
+	if r.empty() || zsl.tail == nil || !r.gteMin(zsl.tail.member) {
+		return nil
+	}
+
+	x := zsl.header
+	for i := zsl.level - 1; i >= 0; i-- {
+		for x.level[i].forward != nil && !r.gteMin(x.level[i].forward.member) {
+			x = x.level[i].forward
+		}
+	}
+
+	x = x.level[0].forward
+	if x == nil || !r.lteMax(x.member) {
+		return nil
+	}
+	return x
+}
+
+// LastInLexRange returns the last node in the range, or nil if there is no
+// such node.
+func (zsl *skiplist) LastInLexRange(r *lexRange) *skiplistNode {
+	first := zsl.First()
+	if r.empty() || first == nil || !r.lteMax(first.member) {
+		return nil
+	}
+
+	x := zsl.header
+	for i := zsl.level - 1; i >= 0; i-- {
+		for x.level[i].forward != nil && r.lteMax(x.level[i].forward.member) {
+			x = x.level[i].forward
+		}
+	}
+
+	if x == zsl.header || !r.gteMin(x.member) {
+		return nil
+	}
+	return x
+}
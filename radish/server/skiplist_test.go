@@ -0,0 +1,134 @@
+package server
+
+import (
+	"math/rand"
+	"sort"
+	"strconv"
+	"testing"
+)
+
+func TestSkiplist(t *testing.T) {
+	type elem struct {
+		score  float64
+		member string
+	}
+
+	rnd := rand.New(rand.NewSource(1))
+	zsl := newSkiplist()
+	scores := make(map[string]float64)
+
+	for i := 0; i < 2000; i++ {
+		member := strconv.Itoa(rnd.Intn(500))
+		if score, ok := scores[member]; ok && rnd.Intn(2) == 0 {
+			if !zsl.Delete(score, member) {
+				t.Fatalf("Delete(%v, %q) = false, want true", score, member)
+			}
+			delete(scores, member)
+			continue
+		}
+
+		if score, ok := scores[member]; ok {
+			zsl.Delete(score, member)
+		}
+		score := float64(rnd.Intn(50))
+		zsl.Insert(score, member)
+		scores[member] = score
+	}
+
+	want := make([]elem, 0, len(scores))
+	for member, score := range scores {
+		want = append(want, elem{score, member})
+	}
+	sort.Slice(want, func(i, j int) bool {
+		if want[i].score != want[j].score {
+			return want[i].score < want[j].score
+		}
+		return want[i].member < want[j].member
+	})
+
+	if zsl.length != len(want) {
+		t.Fatalf("length = %d, want %d", zsl.length, len(want))
+	}
+
+	var prev *skiplistNode
+	x := zsl.First()
+	for i, e := range want {
+		if x == nil {
+			t.Fatalf("list ends at %d, want %d elements", i, len(want))
+		}
+		if x.score != e.score || x.member != e.member {
+			t.Fatalf("#%d = (%v, %q), want (%v, %q)", i, x.score, x.member, e.score, e.member)
+		}
+		if x.backward != prev {
+			t.Fatalf("#%d has wrong backward link", i)
+		}
+		if rank := zsl.Rank(e.score, e.member); rank != i+1 {
+			t.Fatalf("Rank(%v, %q) = %d, want %d", e.score, e.member, rank, i+1)
+		}
+		if got := zsl.ByRank(i + 1); got != x {
+			t.Fatalf("ByRank(%d) returned wrong node", i+1)
+		}
+
+		prev = x
+		x = x.level[0].forward
+	}
+
+	if zsl.Last() != prev {
+		t.Errorf("Last() returned wrong node")
+	}
+	if zsl.ByRank(len(want)+1) != nil {
+		t.Errorf("ByRank(%d) = non-nil, want nil", len(want)+1)
+	}
+	if zsl.Rank(-1, "missing") != 0 {
+		t.Errorf("Rank() of a missing member != 0")
+	}
+}
+
+func TestSkiplist_ranges(t *testing.T) {
+	zsl := newSkiplist()
+	for i, member := range []string{"a", "b", "c", "d", "e"} {
+		zsl.Insert(float64(i+1), member)
+	}
+
+	scoreRanges := []struct {
+		r           scoreRange
+		first, last string
+	}{
+		{scoreRange{min: 2, max: 4}, "b", "d"},
+		{scoreRange{min: 2, max: 4, minex: true, maxex: true}, "c", "c"},
+		{scoreRange{min: 0, max: 10}, "a", "e"},
+		{scoreRange{min: 6, max: 10}, "", ""},
+		{scoreRange{min: 4, max: 2}, "", ""},
+		{scoreRange{min: 3, max: 3, minex: true}, "", ""},
+	}
+	for _, tc := range scoreRanges {
+		first, last := zsl.FirstInRange(&tc.r), zsl.LastInRange(&tc.r)
+		if nodeMember(first) != tc.first || nodeMember(last) != tc.last {
+			t.Errorf("range %+v = [%q, %q], want [%q, %q]", tc.r, nodeMember(first), nodeMember(last), tc.first, tc.last)
+		}
+	}
+
+	lexRanges := []struct {
+		r           lexRange
+		first, last string
+	}{
+		{lexRange{min: lexBound{value: "b"}, max: lexBound{value: "d"}}, "b", "d"},
+		{lexRange{min: lexBound{value: "b", ex: true}, max: lexBound{value: "d", ex: true}}, "c", "c"},
+		{lexRange{min: lexBound{inf: -1}, max: lexBound{inf: 1}}, "a", "e"},
+		{lexRange{min: lexBound{inf: 1}, max: lexBound{inf: -1}}, "", ""},
+		{lexRange{min: lexBound{value: "f"}, max: lexBound{inf: 1}}, "", ""},
+	}
+	for _, tc := range lexRanges {
+		first, last := zsl.FirstInLexRange(&tc.r), zsl.LastInLexRange(&tc.r)
+		if nodeMember(first) != tc.first || nodeMember(last) != tc.last {
+			t.Errorf("lex range %+v = [%q, %q], want [%q, %q]", tc.r, nodeMember(first), nodeMember(last), tc.first, tc.last)
+		}
+	}
+}
+
+func nodeMember(x *skiplistNode) string {
+	if x == nil {
+		return ""
+	}
+	return x.member
+}
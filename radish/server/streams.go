@@ -0,0 +1,2169 @@
+package server
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/SuperPaintman/mini-redis/radish"
+)
+
+var (
+	errInvalidStreamID   = &radish.Error{Kind: "ERR", Msg: "Invalid stream ID specified as stream command argument"}
+	errStreamIDTooSmall  = &radish.Error{Kind: "ERR", Msg: "The ID specified in XADD is equal or smaller than the target stream top item"}
+	errStreamIDZero      = &radish.Error{Kind: "ERR", Msg: "The ID specified in XADD must be greater than 0-0"}
+	errStreamExhausted   = &radish.Error{Kind: "ERR", Msg: "The stream has exhausted the last possible ID, unable to add more items"}
+	errInvalidStartID    = &radish.Error{Kind: "ERR", Msg: "invalid start ID for the interval"}
+	errInvalidEndID      = &radish.Error{Kind: "ERR", Msg: "invalid end ID for the interval"}
+	errMaxLenNegative    = &radish.Error{Kind: "ERR", Msg: "The MAXLEN argument must be >= 0."}
+	errTrimLimitNegative = &radish.Error{Kind: "ERR", Msg: "The LIMIT argument must be >= 0."}
+	errTrimLimitExact    = &radish.Error{Kind: "ERR", Msg: "syntax error, LIMIT cannot be used without the special ~ option"}
+	errTrimStrategies    = &radish.Error{Kind: "ERR", Msg: "syntax error, MAXLEN and MINID options at the same time are not compatible"}
+	errBlockNotInteger   = &radish.Error{Kind: "ERR", Msg: "timeout is not an integer or out of range"}
+	errXreadGroup        = &radish.Error{Kind: "ERR", Msg: "The GROUP option is only supported by XREADGROUP. You called XREAD instead."}
+	errXreadGreaterID    = &radish.Error{Kind: "ERR", Msg: "The > ID can be specified only when calling XREADGROUP using the GROUP <group> <consumer> option."}
+	errXreadgroupNoGroup = &radish.Error{Kind: "ERR", Msg: "Missing GROUP option for XREADGROUP"}
+	errXreadgroupLastID  = &radish.Error{Kind: "ERR", Msg: "The $ ID is meaningless in the context of XREADGROUP: you want to read the history of this consumer by specifying a proper ID, or use the > ID to get new messages. The $ ID would just return an empty result set."}
+	errXgroupNoKey       = &radish.Error{Kind: "ERR", Msg: "The XGROUP subcommand requires the key to exist. Note that for CREATE you may want to use the MKSTREAM option to create an empty stream automatically."}
+	errBusyGroup         = &radish.Error{Kind: "BUSYGROUP", Msg: "Consumer Group name already exists"}
+	errXautoclaimCount   = &radish.Error{Kind: "ERR", Msg: "COUNT must be > 0"}
+)
+
+func errNoGroup(key radish.Arg, group string) *radish.Error {
+	return &radish.Error{
+		Kind: "NOGROUP",
+		Msg:  "No such consumer group '" + group + "' for key name '" + string(key) + "'",
+	}
+}
+
+func errNoKeyOrGroup(key radish.Arg, group string) *radish.Error {
+	return &radish.Error{
+		Kind: "NOGROUP",
+		Msg:  "No such key '" + string(key) + "' or consumer group '" + group + "'",
+	}
+}
+
+// streamNodeMaxEntries is the number of entries in a node of the Redis stream
+// radix tree. Entries here are stored in a flat slice, but the approximate
+// trimming removes them in multiples of the node size to behave like Redis
+// does.
+const streamNodeMaxEntries = 100
+
+// streamID is an ID of a stream entry.
+type streamID struct {
+	ms  uint64
+	seq uint64
+}
+
+var maxStreamID = streamID{ms: math.MaxUint64, seq: math.MaxUint64}
+
+func (id streamID) less(other streamID) bool {
+	return id.ms < other.ms || (id.ms == other.ms && id.seq < other.seq)
+}
+
+// next returns the smallest ID greater than the id. It reports false if the
+// id is the maximum ID.
+func (id streamID) next() (streamID, bool) {
+	switch {
+	case id.seq < math.MaxUint64:
+		return streamID{ms: id.ms, seq: id.seq + 1}, true
+	case id.ms < math.MaxUint64:
+		return streamID{ms: id.ms + 1}, true
+	default:
+		return id, false
+	}
+}
+
+// prev returns the greatest ID less than the id. It reports false if the id
+// is 0-0.
+func (id streamID) prev() (streamID, bool) {
+	switch {
+	case id.seq > 0:
+		return streamID{ms: id.ms, seq: id.seq - 1}, true
+	case id.ms > 0:
+		return streamID{ms: id.ms - 1, seq: math.MaxUint64}, true
+	default:
+		return id, false
+	}
+}
+
+func appendStreamID(b []byte, id streamID) []byte {
+	b = strconv.AppendUint(b, id.ms, 10)
+	b = append(b, '-')
+	return strconv.AppendUint(b, id.seq, 10)
+}
+
+func writeStreamID(w *radish.Writer, id streamID) error {
+	var buf [41]byte
+	return w.WriteBytes(appendStreamID(buf[:0], id))
+}
+
+func parseUint(b []byte) (uint64, bool) {
+	if len(b) == 0 {
+		return 0, false
+	}
+
+	n, err := strconv.ParseUint(string(b), 10, 64)
+	return n, err == nil
+}
+
+// parseStreamID parses an ID in the "<ms>-<seq>" form. The seq may be
+// omitted, then it is the missingSeq.
+func parseStreamID(b []byte, missingSeq uint64) (id streamID, ok bool) {
+	ms, seq, hasSeq := splitStreamID(b)
+
+	if id.ms, ok = parseUint(ms); !ok {
+		return id, false
+	}
+	if !hasSeq {
+		id.seq = missingSeq
+		return id, true
+	}
+	if id.seq, ok = parseUint(seq); !ok {
+		return id, false
+	}
+	return id, true
+}
+
+func splitStreamID(b []byte) (ms, seq []byte, hasSeq bool) {
+	for i, ch := range b {
+		if ch == '-' {
+			return b[:i], b[i+1:], true
+		}
+	}
+	return b, nil, false
+}
+
+// parseXaddID parses an ID of XADD: "*", "<ms>-*" or an explicit ID. The
+// msGiven and seqGiven report which parts of the ID must be generated.
+func parseXaddID(b []byte) (id streamID, msGiven, seqGiven, ok bool) {
+	if len(b) == 1 && b[0] == '*' {
+		return id, false, false, true
+	}
+
+	ms, seq, hasSeq := splitStreamID(b)
+	if hasSeq && len(seq) == 1 && seq[0] == '*' {
+		id.ms, ok = parseUint(ms)
+		return id, true, false, ok
+	}
+
+	id, ok = parseStreamID(b, 0)
+	return id, true, true, ok
+}
+
+// parseRangeID parses a bound of a range of IDs: "-", "+", or an ID prefixed
+// with "(" if it is exclusive. The seq of an incomplete ID is the minimum for
+// the start and the maximum for the end of the range.
+func parseRangeID(b []byte, start bool) (streamID, *radish.Error) {
+	var missingSeq uint64
+	if !start {
+		missingSeq = math.MaxUint64
+	}
+
+	if len(b) > 1 && b[0] == '(' {
+		id, ok := parseStreamID(b[1:], missingSeq)
+		if !ok {
+			return id, errInvalidStreamID
+		}
+
+		if start {
+			if id, ok = id.next(); !ok {
+				return id, errInvalidStartID
+			}
+		} else {
+			if id, ok = id.prev(); !ok {
+				return id, errInvalidEndID
+			}
+		}
+		return id, nil
+	}
+
+	if len(b) == 1 {
+		switch b[0] {
+		case '-':
+			return streamID{}, nil
+		case '+':
+			return maxStreamID, nil
+		}
+	}
+
+	id, ok := parseStreamID(b, missingSeq)
+	if !ok {
+		return id, errInvalidStreamID
+	}
+	return id, nil
+}
+
+// nextStreamID returns the ID of a new entry after the last one, generating
+// the missing parts of the id like XADD does.
+func nextStreamID(last, id streamID, msGiven, seqGiven bool, now int64) (streamID, *radish.Error) {
+	if last == maxStreamID {
+		return id, errStreamExhausted
+	}
+
+	switch {
+	case !msGiven:
+		if ms := uint64(now); ms > last.ms {
+			return streamID{ms: ms}, nil
+		}
+		id, _ = last.next()
+		return id, nil
+	case !seqGiven:
+		if id.ms == last.ms {
+			if last.seq == math.MaxUint64 {
+				return id, errStreamIDTooSmall
+			}
+			id.seq = last.seq + 1
+			return id, nil
+		}
+		if id.ms < last.ms {
+			return id, errStreamIDTooSmall
+		}
+		return id, nil
+	default:
+		if !last.less(id) {
+			return id, errStreamIDTooSmall
+		}
+		return id, nil
+	}
+}
+
+// streamEntry is an entry of a stream.
+type streamEntry struct {
+	id     streamID
+	fields [][]byte // Field-value pairs.
+}
+
+func writeStreamEntry(w *radish.Writer, e *streamEntry) {
+	_ = w.WriteArray(2)
+	_ = writeStreamID(w, e.id)
+	_ = w.WriteArray(len(e.fields))
+	for _, f := range e.fields {
+		_ = w.WriteBytes(f)
+	}
+}
+
+func writeStreamEntries(w *radish.Writer, entries []streamEntry) {
+	_ = w.WriteArray(len(entries))
+	for i := range entries {
+		writeStreamEntry(w, &entries[i])
+	}
+}
+
+// stream is an append-only log of entries ordered by IDs, with consumer
+// groups.
+type stream struct {
+	entries []streamEntry // Ordered by ID.
+	lastID  streamID
+	groups  map[string]*streamGroup
+}
+
+func newStream() *stream {
+	return &stream{}
+}
+
+func (s *stream) Len() int {
+	return len(s.entries)
+}
+
+// search returns the index of the first entry with an ID greater than or
+// equal to the id.
+func (s *stream) search(id streamID) int {
+	return sort.Search(len(s.entries), func(i int) bool {
+		return !s.entries[i].id.less(id)
+	})
+}
+
+// Get returns the entry with the id, or nil if there is no such entry.
+func (s *stream) Get(id streamID) *streamEntry {
+	i := s.search(id)
+	if i < len(s.entries) && s.entries[i].id == id {
+		return &s.entries[i]
+	}
+	return nil
+}
+
+// Add appends the entry. The id must be greater than the last ID.
+func (s *stream) Add(id streamID, fields [][]byte) {
+	s.entries = append(s.entries, streamEntry{id: id, fields: fields})
+	s.lastID = id
+}
+
+// Delete deletes the entry with the id and reports whether it was found.
+func (s *stream) Delete(id streamID) bool {
+	i := s.search(id)
+	if i == len(s.entries) || s.entries[i].id != id {
+		return false
+	}
+
+	copy(s.entries[i:], s.entries[i+1:])
+	s.entries[len(s.entries)-1] = streamEntry{}
+	s.entries = s.entries[:len(s.entries)-1]
+	return true
+}
+
+// Range returns the entries with IDs from the start to the end inclusive.
+func (s *stream) Range(start, end streamID) []streamEntry {
+	if end.less(start) {
+		return nil
+	}
+
+	i := s.search(start)
+	j := sort.Search(len(s.entries), func(j int) bool {
+		return end.less(s.entries[j].id)
+	})
+	if j < i {
+		return nil
+	}
+	return s.entries[i:j]
+}
+
+// After returns up to count entries with IDs greater than the id. A zero
+// count means no limit.
+func (s *stream) After(id streamID, count int64) []streamEntry {
+	start, ok := id.next()
+	if !ok {
+		return nil
+	}
+
+	entries := s.entries[s.search(start):]
+	if count > 0 && int64(len(entries)) > count {
+		entries = entries[:count]
+	}
+	return entries
+}
+
+// Strategies of the streamTrim.
+const (
+	streamTrimNone = iota
+	streamTrimMaxLen
+	streamTrimMinID
+)
+
+// streamTrim is the trimming options of XADD and XTRIM.
+type streamTrim struct {
+	strategy   int
+	approx     bool
+	maxLen     int64
+	minID      streamID
+	limit      int64
+	limitGiven bool
+}
+
+// parseOption parses a trimming option at the args[i] and returns the index
+// of the next argument, or the i if the argument is not a trimming option.
+func (st *streamTrim) parseOption(args []radish.Arg, i int) (int, *radish.Error) {
+	arg := args[i]
+	switch {
+	case equalFold(arg, "LIMIT") && i+1 < len(args):
+		limit, ok := parseInt(args[i+1])
+		if !ok {
+			return i, errNotInteger
+		}
+		if limit < 0 {
+			return i, errTrimLimitNegative
+		}
+
+		st.limit = limit
+		st.limitGiven = true
+		return i + 2, nil
+
+	case (equalFold(arg, "MAXLEN") || equalFold(arg, "MINID")) && i+1 < len(args):
+		strategy := streamTrimMaxLen
+		if equalFold(arg, "MINID") {
+			strategy = streamTrimMinID
+		}
+		if st.strategy != streamTrimNone && st.strategy != strategy {
+			return i, errTrimStrategies
+		}
+		st.strategy = strategy
+
+		i++
+		if op := args[i]; len(op) == 1 && (op[0] == '~' || op[0] == '=') && i+1 < len(args) {
+			st.approx = op[0] == '~'
+			i++
+		}
+
+		if strategy == streamTrimMaxLen {
+			maxLen, ok := parseInt(args[i])
+			if !ok {
+				return i, errNotInteger
+			}
+			if maxLen < 0 {
+				return i, errMaxLenNegative
+			}
+			st.maxLen = maxLen
+		} else {
+			minID, ok := parseStreamID(args[i], 0)
+			if !ok {
+				return i, errInvalidStreamID
+			}
+			st.minID = minID
+		}
+		return i + 1, nil
+	}
+
+	return i, nil
+}
+
+// validate checks the combination of the options and sets the default limit
+// of the approximate trimming.
+func (st *streamTrim) validate() *radish.Error {
+	if st.limitGiven && !st.approx {
+		return errTrimLimitExact
+	}
+	if st.approx && !st.limitGiven {
+		st.limit = 100 * streamNodeMaxEntries
+	}
+	return nil
+}
+
+// Trim removes the oldest entries according to the st and returns the number
+// of removed entries.
+func (s *stream) Trim(st *streamTrim) int64 {
+	var n int
+	switch st.strategy {
+	case streamTrimMaxLen:
+		if int64(len(s.entries)) > st.maxLen {
+			n = len(s.entries) - int(st.maxLen)
+		}
+	case streamTrimMinID:
+		n = s.search(st.minID)
+	}
+
+	if st.approx {
+		if st.limit > 0 && int64(n) > st.limit {
+			n = int(st.limit)
+		}
+		n -= n % streamNodeMaxEntries
+	}
+	if n == 0 {
+		return 0
+	}
+
+	for i := 0; i < n; i++ {
+		s.entries[i] = streamEntry{}
+	}
+	s.entries = s.entries[n:]
+	return int64(n)
+}
+
+// streamGroup is a consumer group of a stream.
+type streamGroup struct {
+	name      string
+	lastID    streamID      // The last delivered ID.
+	pel       []*streamNACK // Pending entries ordered by ID.
+	consumers map[string]*streamConsumer
+}
+
+// streamNACK is a pending entry: delivered to a consumer, but not
+// acknowledged yet.
+type streamNACK struct {
+	id            streamID
+	consumer      *streamConsumer
+	deliveryTime  int64 // Unix time in milliseconds.
+	deliveryCount int64
+}
+
+// streamConsumer is a consumer of a group.
+type streamConsumer struct {
+	name     string
+	seenTime int64 // Unix time in milliseconds.
+	pending  int
+}
+
+// Group returns the consumer group, or nil if there is no such group.
+func (s *stream) Group(name string) *streamGroup {
+	return s.groups[name]
+}
+
+// CreateGroup creates a new consumer group and reports whether it was
+// created.
+func (s *stream) CreateGroup(name string, lastID streamID) bool {
+	if _, ok := s.groups[name]; ok {
+		return false
+	}
+
+	if s.groups == nil {
+		s.groups = make(map[string]*streamGroup)
+	}
+	s.groups[name] = &streamGroup{
+		name:      name,
+		lastID:    lastID,
+		consumers: make(map[string]*streamConsumer),
+	}
+	return true
+}
+
+// Groups returns the consumer groups ordered by name.
+func (s *stream) Groups() []*streamGroup {
+	groups := make([]*streamGroup, 0, len(s.groups))
+	for _, g := range s.groups {
+		groups = append(groups, g)
+	}
+
+	sort.Slice(groups, func(i, j int) bool {
+		return groups[i].name < groups[j].name
+	})
+	return groups
+}
+
+// searchPEL returns the index of the first pending entry with an ID greater
+// than or equal to the id.
+func (g *streamGroup) searchPEL(id streamID) int {
+	return sort.Search(len(g.pel), func(i int) bool {
+		return !g.pel[i].id.less(id)
+	})
+}
+
+// indexNACK returns the index of the pending entry with the id, or -1 if
+// there is no such entry.
+func (g *streamGroup) indexNACK(id streamID) int {
+	i := g.searchPEL(id)
+	if i < len(g.pel) && g.pel[i].id == id {
+		return i
+	}
+	return -1
+}
+
+func (g *streamGroup) addNACK(nack *streamNACK) {
+	i := g.searchPEL(nack.id)
+	g.pel = append(g.pel, nil)
+	copy(g.pel[i+1:], g.pel[i:])
+	g.pel[i] = nack
+
+	nack.consumer.pending++
+}
+
+func (g *streamGroup) removeNACK(i int) {
+	g.pel[i].consumer.pending--
+
+	copy(g.pel[i:], g.pel[i+1:])
+	g.pel[len(g.pel)-1] = nil
+	g.pel = g.pel[:len(g.pel)-1]
+}
+
+// assign transfers the ownership of the pending entry to the consumer.
+func (nack *streamNACK) assign(consumer *streamConsumer) {
+	nack.consumer.pending--
+	nack.consumer = consumer
+	consumer.pending++
+}
+
+// Consumer returns the consumer, or nil if there is no such consumer.
+func (g *streamGroup) Consumer(name string) *streamConsumer {
+	return g.consumers[name]
+}
+
+// CreateConsumer returns the consumer, creating it if it does not exist.
+func (g *streamGroup) CreateConsumer(name string, now int64) *streamConsumer {
+	consumer, ok := g.consumers[name]
+	if !ok {
+		consumer = &streamConsumer{name: name, seenTime: now}
+		g.consumers[name] = consumer
+	}
+	return consumer
+}
+
+// DeleteConsumer deletes the consumer with its pending entries and returns
+// the number of deleted pending entries.
+func (g *streamGroup) DeleteConsumer(consumer *streamConsumer) int {
+	pending := consumer.pending
+
+	pel := g.pel[:0]
+	for _, nack := range g.pel {
+		if nack.consumer != consumer {
+			pel = append(pel, nack)
+		}
+	}
+	for i := len(pel); i < len(g.pel); i++ {
+		g.pel[i] = nil
+	}
+	g.pel = pel
+
+	delete(g.consumers, consumer.name)
+	return pending
+}
+
+// Consumers returns the consumers ordered by name.
+func (g *streamGroup) Consumers() []*streamConsumer {
+	consumers := make([]*streamConsumer, 0, len(g.consumers))
+	for _, consumer := range g.consumers {
+		consumers = append(consumers, consumer)
+	}
+
+	sort.Slice(consumers, func(i, j int) bool {
+		return consumers[i].name < consumers[j].name
+	})
+	return consumers
+}
+
+// deliver records the delivery of the entry to the consumer. The entry stays
+// pending until it is acknowledged, unless noAck is set.
+func (g *streamGroup) deliver(id streamID, consumer *streamConsumer, noAck bool, now int64) {
+	if g.lastID.less(id) {
+		g.lastID = id
+	}
+	if noAck {
+		return
+	}
+
+	// The entry may be already pending if the last ID of the group was moved
+	// back by XGROUP SETID.
+	if i := g.indexNACK(id); i >= 0 {
+		nack := g.pel[i]
+		nack.assign(consumer)
+		nack.deliveryTime = now
+		nack.deliveryCount = 1
+		return
+	}
+
+	g.addNACK(&streamNACK{
+		id:            id,
+		consumer:      consumer,
+		deliveryTime:  now,
+		deliveryCount: 1,
+	})
+}
+
+// lookupStream returns the stream value of the key or nil if the key does not
+// exist.
+func (ks *Keyspace) lookupStream(key []byte) (*stream, error) {
+	v := ks.lookup(key)
+	if v == nil {
+		return nil, nil
+	}
+
+	s, ok := v.(*stream)
+	if !ok {
+		return nil, errWrongType
+	}
+	return s, nil
+}
+
+func (ks *Keyspace) registerStreamCommands(m *Mux) {
+	m.HandleFunc(CommandInfo{
+		Name:       "xadd",
+		Arity:      -5,
+		Flags:      FlagWrite | FlagDenyOOM | FlagFast,
+		FirstKey:   1,
+		LastKey:    1,
+		KeyStep:    1,
+		Group:      "stream",
+		Summary:    "Appends a new message to a stream. Creates the key if it doesn't exist.",
+		Since:      "5.0.0",
+		Complexity: "O(1) when adding a new entry, O(N) when trimming where N being the number of entries evicted.",
+	}, ks.xadd)
+
+	m.HandleFunc(CommandInfo{
+		Name:       "xrange",
+		Arity:      -4,
+		Flags:      FlagReadonly,
+		FirstKey:   1,
+		LastKey:    1,
+		KeyStep:    1,
+		Group:      "stream",
+		Summary:    "Returns the messages from a stream within a range of IDs.",
+		Since:      "5.0.0",
+		Complexity: "O(N) with N being the number of elements being returned. If N is constant (e.g. always asking for the first 10 elements with COUNT), you can consider it O(1).",
+	}, func(c *Conn, cmd *radish.Command) {
+		ks.xrangeGeneric(c, cmd, false)
+	})
+
+	m.HandleFunc(CommandInfo{
+		Name:       "xrevrange",
+		Arity:      -4,
+		Flags:      FlagReadonly,
+		FirstKey:   1,
+		LastKey:    1,
+		KeyStep:    1,
+		Group:      "stream",
+		Summary:    "Returns the messages from a stream within a range of IDs in reverse order.",
+		Since:      "5.0.0",
+		Complexity: "O(N) with N being the number of elements returned. If N is constant (e.g. always asking for the first 10 elements with COUNT), you can consider it O(1).",
+	}, func(c *Conn, cmd *radish.Command) {
+		ks.xrangeGeneric(c, cmd, true)
+	})
+
+	m.HandleFunc(CommandInfo{
+		Name:       "xlen",
+		Arity:      2,
+		Flags:      FlagReadonly | FlagFast,
+		FirstKey:   1,
+		LastKey:    1,
+		KeyStep:    1,
+		Group:      "stream",
+		Summary:    "Return the number of messages in a stream.",
+		Since:      "5.0.0",
+		Complexity: "O(1)",
+	}, ks.xlen)
+
+	m.HandleFunc(CommandInfo{
+		Name:       "xdel",
+		Arity:      -3,
+		Flags:      FlagWrite | FlagFast,
+		FirstKey:   1,
+		LastKey:    1,
+		KeyStep:    1,
+		Group:      "stream",
+		Summary:    "Returns the number of messages after removing them from a stream.",
+		Since:      "5.0.0",
+		Complexity: "O(1) for each single item to delete in the stream, regardless of the stream size.",
+	}, ks.xdel)
+
+	m.HandleFunc(CommandInfo{
+		Name:       "xtrim",
+		Arity:      -4,
+		Flags:      FlagWrite,
+		FirstKey:   1,
+		LastKey:    1,
+		KeyStep:    1,
+		Group:      "stream",
+		Summary:    "Deletes messages from the beginning of a stream.",
+		Since:      "5.0.0",
+		Complexity: "O(N), with N being the number of evicted entries.",
+	}, ks.xtrim)
+
+	m.HandleFunc(CommandInfo{
+		Name:       "xread",
+		Arity:      -4,
+		Flags:      FlagReadonly | FlagBlocking,
+		FirstKey:   0,
+		LastKey:    0,
+		KeyStep:    0,
+		Group:      "stream",
+		Summary:    "Returns messages from multiple streams with IDs greater than the ones requested. Blocks until a message is available otherwise.",
+		Since:      "5.0.0",
+		Complexity: "O(N) where N is the number of elements being returned.",
+	}, func(c *Conn, cmd *radish.Command) {
+		ks.xreadGeneric(c, cmd, false)
+	})
+
+	m.HandleFunc(CommandInfo{
+		Name:       "xreadgroup",
+		Arity:      -7,
+		Flags:      FlagWrite | FlagBlocking,
+		FirstKey:   0,
+		LastKey:    0,
+		KeyStep:    0,
+		Group:      "stream",
+		Summary:    "Returns new or historical messages from a stream for a consumer in a group. Blocks until a message is available otherwise.",
+		Since:      "5.0.0",
+		Complexity: "For each stream mentioned: O(M) with M being the number of elements returned.",
+	}, func(c *Conn, cmd *radish.Command) {
+		ks.xreadGeneric(c, cmd, true)
+	})
+
+	m.HandleFunc(CommandInfo{
+		Name:       "xgroup",
+		Arity:      -2,
+		Flags:      FlagWrite,
+		FirstKey:   2,
+		LastKey:    2,
+		KeyStep:    1,
+		Group:      "stream",
+		Summary:    "A container for consumer groups commands.",
+		Since:      "5.0.0",
+		Complexity: "Depends on subcommand.",
+	}, ks.xgroup)
+
+	m.HandleFunc(CommandInfo{
+		Name:       "xack",
+		Arity:      -4,
+		Flags:      FlagWrite | FlagFast,
+		FirstKey:   1,
+		LastKey:    1,
+		KeyStep:    1,
+		Group:      "stream",
+		Summary:    "Returns the number of messages that were successfully acknowledged by the consumer group member of a stream.",
+		Since:      "5.0.0",
+		Complexity: "O(1) for each message ID processed.",
+	}, ks.xack)
+
+	m.HandleFunc(CommandInfo{
+		Name:       "xpending",
+		Arity:      -3,
+		Flags:      FlagReadonly,
+		FirstKey:   1,
+		LastKey:    1,
+		KeyStep:    1,
+		Group:      "stream",
+		Summary:    "Returns the information and entries from a stream consumer group's pending entries list.",
+		Since:      "5.0.0",
+		Complexity: "O(N) with N being the number of elements returned, so asking for a small fixed number of entries per call is O(1). O(M), where M is the total number of entries scanned when used with the IDLE filter. When the command returns just the summary and the list of consumers is small, it runs in O(1) time; otherwise, an additional O(N) time for iterating every consumer.",
+	}, ks.xpending)
+
+	m.HandleFunc(CommandInfo{
+		Name:       "xclaim",
+		Arity:      -6,
+		Flags:      FlagWrite | FlagFast,
+		FirstKey:   1,
+		LastKey:    1,
+		KeyStep:    1,
+		Group:      "stream",
+		Summary:    "Changes, or acquires, ownership of a message in a consumer group, as if the message was delivered a consumer group member.",
+		Since:      "5.0.0",
+		Complexity: "O(log N) with N being the number of messages in the PEL of the consumer group.",
+	}, ks.xclaim)
+
+	m.HandleFunc(CommandInfo{
+		Name:       "xautoclaim",
+		Arity:      -6,
+		Flags:      FlagWrite | FlagFast,
+		FirstKey:   1,
+		LastKey:    1,
+		KeyStep:    1,
+		Group:      "stream",
+		Summary:    "Changes, or acquires, ownership of messages in a consumer group, as if the messages were delivered to as consumer group member.",
+		Since:      "6.2.0",
+		Complexity: "O(1) if COUNT is small.",
+	}, ks.xautoclaim)
+
+	m.HandleFunc(CommandInfo{
+		Name:       "xinfo",
+		Arity:      -2,
+		Flags:      FlagReadonly,
+		FirstKey:   2,
+		LastKey:    2,
+		KeyStep:    1,
+		Group:      "stream",
+		Summary:    "A container for stream introspection commands.",
+		Since:      "5.0.0",
+		Complexity: "Depends on subcommand.",
+	}, ks.xinfo)
+}
+
+func (ks *Keyspace) xadd(c *Conn, cmd *radish.Command) {
+	w := c.Writer()
+	args := cmd.Args
+	key := args[1]
+
+	var (
+		noMkStream bool
+		trim       streamTrim
+	)
+
+	i := 2
+loop:
+	for i < len(args) {
+		if equalFold(args[i], "NOMKSTREAM") {
+			noMkStream = true
+			i++
+			continue
+		}
+
+		next, e := trim.parseOption(args, i)
+		if e != nil {
+			_ = w.WriteError(e)
+			return
+		}
+		if next == i {
+			break loop
+		}
+		i = next
+	}
+
+	if e := trim.validate(); e != nil {
+		_ = w.WriteError(e)
+		return
+	}
+
+	// The ID and field-value pairs.
+	if len(args)-i < 3 || (len(args)-i-1)%2 != 0 {
+		_ = writeWrongArity(w, "xadd")
+		return
+	}
+
+	id, msGiven, seqGiven, ok := parseXaddID(args[i])
+	if !ok {
+		_ = w.WriteError(errInvalidStreamID)
+		return
+	}
+	if msGiven && seqGiven && id == (streamID{}) {
+		_ = w.WriteError(errStreamIDZero)
+		return
+	}
+
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	s, err := ks.lookupStream(key)
+	if err != nil {
+		_ = w.WriteError(errWrongType)
+		return
+	}
+	if s == nil && noMkStream {
+		_ = w.WriteNull()
+		return
+	}
+
+	var last streamID
+	if s != nil {
+		last = s.lastID
+	}
+
+	id, e := nextStreamID(last, id, msGiven, seqGiven, ks.now())
+	if e != nil {
+		_ = w.WriteError(e)
+		return
+	}
+
+	if s == nil {
+		s = newStream()
+		ks.setKey(key, s, false)
+	}
+
+	// Copy all fields and values into a single buffer.
+	pairs := args[i+1:]
+	size := 0
+	for _, arg := range pairs {
+		size += len(arg)
+	}
+	buf := make([]byte, 0, size)
+	fields := make([][]byte, len(pairs))
+	for j, arg := range pairs {
+		buf = append(buf, arg...)
+		fields[j] = buf[len(buf)-len(arg) : len(buf) : len(buf)]
+	}
+
+	s.Add(id, fields)
+	s.Trim(&trim)
+
+	_ = writeStreamID(w, id)
+
+	ks.signalKeyAsReady(key)
+}
+
+func (ks *Keyspace) xrangeGeneric(c *Conn, cmd *radish.Command, rev bool) {
+	w := c.Writer()
+
+	startArg, endArg := cmd.Args[2], cmd.Args[3]
+	if rev {
+		startArg, endArg = endArg, startArg
+	}
+
+	start, e := parseRangeID(startArg, true)
+	if e != nil {
+		_ = w.WriteError(e)
+		return
+	}
+	end, e := parseRangeID(endArg, false)
+	if e != nil {
+		_ = w.WriteError(e)
+		return
+	}
+
+	count := int64(-1)
+	args := cmd.Args[4:]
+	for i := 0; i < len(args); i++ {
+		if !equalFold(args[i], "COUNT") || i+1 == len(args) {
+			_ = w.WriteError(errSyntax)
+			return
+		}
+
+		n, ok := parseInt(args[i+1])
+		if !ok {
+			_ = w.WriteError(errNotInteger)
+			return
+		}
+		if n < 0 {
+			n = 0
+		}
+		count = n
+		i++
+	}
+
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	s, err := ks.lookupStream(cmd.Args[1])
+	if err != nil {
+		_ = w.WriteError(errWrongType)
+		return
+	}
+	if s == nil || count == 0 {
+		_ = w.WriteArray(0)
+		return
+	}
+
+	entries := s.Range(start, end)
+	if count > 0 && int64(len(entries)) > count {
+		if rev {
+			entries = entries[len(entries)-int(count):]
+		} else {
+			entries = entries[:count]
+		}
+	}
+
+	if !rev {
+		writeStreamEntries(w, entries)
+		return
+	}
+
+	_ = w.WriteArray(len(entries))
+	for i := len(entries) - 1; i >= 0; i-- {
+		writeStreamEntry(w, &entries[i])
+	}
+}
+
+func (ks *Keyspace) xlen(c *Conn, cmd *radish.Command) {
+	w := c.Writer()
+
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	s, err := ks.lookupStream(cmd.Args[1])
+	if err != nil {
+		_ = w.WriteError(errWrongType)
+		return
+	}
+	if s == nil {
+		_ = w.WriteInt(0)
+		return
+	}
+
+	_ = w.WriteInt(s.Len())
+}
+
+// parseStreamIDs parses IDs of XDEL and XACK. They all must be valid.
+func parseStreamIDs(args []radish.Arg) ([]streamID, bool) {
+	ids := make([]streamID, len(args))
+	for i, arg := range args {
+		id, ok := parseStreamID(arg, 0)
+		if !ok {
+			return nil, false
+		}
+		ids[i] = id
+	}
+	return ids, true
+}
+
+func (ks *Keyspace) xdel(c *Conn, cmd *radish.Command) {
+	w := c.Writer()
+
+	ids, ok := parseStreamIDs(cmd.Args[2:])
+	if !ok {
+		_ = w.WriteError(errInvalidStreamID)
+		return
+	}
+
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	s, err := ks.lookupStream(cmd.Args[1])
+	if err != nil {
+		_ = w.WriteError(errWrongType)
+		return
+	}
+	if s == nil {
+		_ = w.WriteInt(0)
+		return
+	}
+
+	deleted := 0
+	for _, id := range ids {
+		if s.Delete(id) {
+			deleted++
+		}
+	}
+
+	_ = w.WriteInt(deleted)
+}
+
+func (ks *Keyspace) xtrim(c *Conn, cmd *radish.Command) {
+	w := c.Writer()
+	args := cmd.Args
+
+	var trim streamTrim
+	for i := 2; i < len(args); {
+		next, e := trim.parseOption(args, i)
+		if e != nil {
+			_ = w.WriteError(e)
+			return
+		}
+		if next == i {
+			_ = w.WriteError(errSyntax)
+			return
+		}
+		i = next
+	}
+
+	if trim.strategy == streamTrimNone {
+		_ = w.WriteError(errSyntax)
+		return
+	}
+	if e := trim.validate(); e != nil {
+		_ = w.WriteError(e)
+		return
+	}
+
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	s, err := ks.lookupStream(args[1])
+	if err != nil {
+		_ = w.WriteError(errWrongType)
+		return
+	}
+	if s == nil {
+		_ = w.WriteInt(0)
+		return
+	}
+
+	_ = w.WriteInt64(s.Trim(&trim))
+}
+
+func (ks *Keyspace) xreadGeneric(c *Conn, cmd *radish.Command, group bool) {
+	w := c.Writer()
+	args := cmd.Args
+
+	var (
+		count        int64
+		timeout      time.Duration
+		block        bool
+		noAck        bool
+		groupName    string
+		consumerName string
+		streamsIdx   int
+	)
+
+	for i := 1; i < len(args) && streamsIdx == 0; i++ {
+		arg := args[i]
+		rem := len(args) - i - 1
+
+		switch {
+		case equalFold(arg, "COUNT") && rem >= 1:
+			n, ok := parseInt(args[i+1])
+			if !ok {
+				_ = w.WriteError(errNotInteger)
+				return
+			}
+			if n < 0 {
+				n = 0
+			}
+			count = n
+			i++
+		case equalFold(arg, "BLOCK") && rem >= 1:
+			ms, ok := parseInt(args[i+1])
+			if !ok {
+				_ = w.WriteError(errBlockNotInteger)
+				return
+			}
+			if ms < 0 {
+				_ = w.WriteError(errTimeoutNegative)
+				return
+			}
+			if ms > math.MaxInt64/int64(time.Millisecond) {
+				_ = w.WriteError(errTimeoutRange)
+				return
+			}
+			timeout = time.Duration(ms) * time.Millisecond
+			block = true
+			i++
+		case equalFold(arg, "STREAMS"):
+			streamsIdx = i + 1
+		case equalFold(arg, "GROUP") && rem >= 2:
+			if !group {
+				_ = w.WriteError(errXreadGroup)
+				return
+			}
+			groupName, consumerName = string(args[i+1]), string(args[i+2])
+			i += 2
+		case equalFold(arg, "NOACK") && group:
+			noAck = true
+		default:
+			_ = w.WriteError(errSyntax)
+			return
+		}
+	}
+
+	if streamsIdx == 0 {
+		_ = w.WriteError(errSyntax)
+		return
+	}
+
+	rest := len(args) - streamsIdx
+	if rest == 0 || rest%2 != 0 {
+		name := "xread"
+		if group {
+			name = "xreadgroup"
+		}
+		_ = w.WriteError(&radish.Error{
+			Kind: "ERR",
+			Msg:  "Unbalanced '" + name + "' list of streams: for each stream key an ID or '$' must be specified.",
+		})
+		return
+	}
+	if group && groupName == "" {
+		_ = w.WriteError(errXreadgroupNoGroup)
+		return
+	}
+
+	keys := args[streamsIdx : streamsIdx+rest/2]
+	idArgs := args[streamsIdx+rest/2:]
+
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	now := ks.now()
+
+	// Entries after the IDs are served. For groups, the newOnly means the ">"
+	// ID, otherwise the history of the consumer is served.
+	ids := make([]streamID, len(keys))
+	newOnly := make([]bool, len(keys))
+
+	for i, key := range keys {
+		s, err := ks.lookupStream(key)
+		if err != nil {
+			_ = w.WriteError(errWrongType)
+			return
+		}
+
+		if group {
+			var g *streamGroup
+			if s != nil {
+				g = s.Group(groupName)
+			}
+			if g == nil {
+				_ = w.WriteError(&radish.Error{
+					Kind: "NOGROUP",
+					Msg:  "No such key '" + string(key) + "' or consumer group '" + groupName + "' in XREADGROUP with GROUP option",
+				})
+				return
+			}
+
+			consumer := g.CreateConsumer(consumerName, now)
+			consumer.seenTime = now
+		}
+
+		idArg := idArgs[i]
+		switch {
+		case len(idArg) == 1 && idArg[0] == '$':
+			if group {
+				_ = w.WriteError(errXreadgroupLastID)
+				return
+			}
+			if s != nil {
+				ids[i] = s.lastID
+			}
+		case len(idArg) == 1 && idArg[0] == '>':
+			if !group {
+				_ = w.WriteError(errXreadGreaterID)
+				return
+			}
+			newOnly[i] = true
+		default:
+			id, ok := parseStreamID(idArg, 0)
+			if !ok {
+				_ = w.WriteError(errInvalidStreamID)
+				return
+			}
+			ids[i] = id
+		}
+	}
+
+	isReady := func(i int) bool {
+		s, _ := ks.lookupStream(keys[i])
+		if s == nil {
+			return false
+		}
+
+		if !group {
+			return len(s.After(ids[i], 1)) > 0
+		}
+
+		g := s.Group(groupName)
+		if g == nil {
+			return false
+		}
+		if newOnly[i] {
+			return len(s.After(g.lastID, 1)) > 0
+		}
+		return true
+	}
+
+	writeStream := func(i int) {
+		key := keys[i]
+		s, _ := ks.lookupStream(key)
+
+		// RESP3 replies with a map of streams, RESP2 with an array of pairs.
+		if w.Protocol() != radish.RESP3 {
+			_ = w.WriteArray(2)
+		}
+		_ = w.WriteBytes(key)
+
+		if !group {
+			writeStreamEntries(w, s.After(ids[i], count))
+			return
+		}
+
+		now := ks.now()
+		g := s.Group(groupName)
+		consumer := g.CreateConsumer(consumerName, now)
+		consumer.seenTime = now
+
+		if !newOnly[i] {
+			writeConsumerHistory(w, s, g, consumer, ids[i], count, now)
+			return
+		}
+
+		entries := s.After(g.lastID, count)
+		_ = w.WriteArray(len(entries))
+		for j := range entries {
+			e := &entries[j]
+			writeStreamEntry(w, e)
+			g.deliver(e.id, consumer, noAck, now)
+		}
+	}
+
+	ready := make([]bool, len(keys))
+	n := 0
+	for i := range keys {
+		if isReady(i) {
+			ready[i] = true
+			n++
+		}
+	}
+
+	writeStreams := func(n int) {
+		if w.Protocol() == radish.RESP3 {
+			_ = w.WriteMap(n)
+		} else {
+			_ = w.WriteArray(n)
+		}
+	}
+
+	if n > 0 {
+		writeStreams(n)
+		for i := range keys {
+			if ready[i] {
+				writeStream(i)
+			}
+		}
+		return
+	}
+
+	if !block {
+		_ = w.WriteArray(-1)
+		return
+	}
+
+	served := ks.block(c, keys, timeout, func(key string) bool {
+		for i := range keys {
+			if string(keys[i]) == key && isReady(i) {
+				writeStreams(1)
+				writeStream(i)
+				return true
+			}
+		}
+		return false
+	})
+	if !served {
+		_ = w.WriteArray(-1)
+	}
+}
+
+// writeConsumerHistory writes up to count pending entries of the consumer
+// with IDs greater than the id. Entries deleted from the stream are written
+// with null fields.
+func writeConsumerHistory(w *radish.Writer, s *stream, g *streamGroup, consumer *streamConsumer, id streamID, count int64, now int64) {
+	var nacks []*streamNACK
+	if start, ok := id.next(); ok {
+		for _, nack := range g.pel[g.searchPEL(start):] {
+			if count > 0 && int64(len(nacks)) == count {
+				break
+			}
+			if nack.consumer == consumer {
+				nacks = append(nacks, nack)
+			}
+		}
+	}
+
+	_ = w.WriteArray(len(nacks))
+	for _, nack := range nacks {
+		e := s.Get(nack.id)
+		if e == nil {
+			_ = w.WriteArray(2)
+			_ = writeStreamID(w, nack.id)
+			_ = w.WriteArray(-1)
+			continue
+		}
+
+		writeStreamEntry(w, e)
+		nack.deliveryTime = now
+		nack.deliveryCount++
+	}
+}
+
+func (ks *Keyspace) xgroup(c *Conn, cmd *radish.Command) {
+	w := c.Writer()
+	args := cmd.Args
+	sub := args[1]
+
+	var (
+		name     string
+		min, max int
+	)
+	switch {
+	case equalFold(sub, "CREATE"):
+		name, min, max = "create", 5, 6
+	case equalFold(sub, "SETID"):
+		name, min, max = "setid", 5, 5
+	case equalFold(sub, "DESTROY"):
+		name, min, max = "destroy", 4, 4
+	case equalFold(sub, "CREATECONSUMER"):
+		name, min, max = "createconsumer", 5, 5
+	case equalFold(sub, "DELCONSUMER"):
+		name, min, max = "delconsumer", 5, 5
+	default:
+		_ = w.WriteError(unknownSubcommand(sub, "XGROUP"))
+		return
+	}
+	if len(args) < min || len(args) > max {
+		_ = writeWrongArity(w, "xgroup|"+name)
+		return
+	}
+
+	key, groupName := args[2], string(args[3])
+
+	mkStream := false
+	if name == "create" && len(args) == 6 {
+		if !equalFold(args[5], "MKSTREAM") {
+			_ = w.WriteError(errSyntax)
+			return
+		}
+		mkStream = true
+	}
+
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	s, err := ks.lookupStream(key)
+	if err != nil {
+		_ = w.WriteError(errWrongType)
+		return
+	}
+	if s == nil && !mkStream {
+		_ = w.WriteError(errXgroupNoKey)
+		return
+	}
+
+	var g *streamGroup
+	if s != nil {
+		g = s.Group(groupName)
+	}
+	if g == nil && (name == "setid" || name == "createconsumer" || name == "delconsumer") {
+		_ = w.WriteError(errNoGroup(key, groupName))
+		return
+	}
+
+	var id streamID
+	if name == "create" || name == "setid" {
+		if arg := args[4]; len(arg) == 1 && arg[0] == '$' {
+			if s != nil {
+				id = s.lastID
+			}
+		} else {
+			var ok bool
+			if id, ok = parseStreamID(arg, 0); !ok {
+				_ = w.WriteError(errInvalidStreamID)
+				return
+			}
+		}
+	}
+
+	switch name {
+	case "create":
+		if s == nil {
+			s = newStream()
+			ks.setKey(key, s, false)
+		}
+		if !s.CreateGroup(groupName, id) {
+			_ = w.WriteError(errBusyGroup)
+			return
+		}
+		_ = w.WriteSimpleString("OK")
+
+	case "setid":
+		g.lastID = id
+		_ = w.WriteSimpleString("OK")
+
+	case "destroy":
+		if g == nil {
+			_ = w.WriteInt(0)
+			return
+		}
+		delete(s.groups, groupName)
+		_ = w.WriteInt(1)
+
+	case "createconsumer":
+		if g.Consumer(string(args[4])) != nil {
+			_ = w.WriteInt(0)
+			return
+		}
+		g.CreateConsumer(string(args[4]), ks.now())
+		_ = w.WriteInt(1)
+
+	case "delconsumer":
+		consumer := g.Consumer(string(args[4]))
+		if consumer == nil {
+			_ = w.WriteInt(0)
+			return
+		}
+		_ = w.WriteInt(g.DeleteConsumer(consumer))
+	}
+}
+
+func (ks *Keyspace) xack(c *Conn, cmd *radish.Command) {
+	w := c.Writer()
+
+	ids, ok := parseStreamIDs(cmd.Args[3:])
+	if !ok {
+		_ = w.WriteError(errInvalidStreamID)
+		return
+	}
+
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	s, err := ks.lookupStream(cmd.Args[1])
+	if err != nil {
+		_ = w.WriteError(errWrongType)
+		return
+	}
+
+	var g *streamGroup
+	if s != nil {
+		g = s.Group(string(cmd.Args[2]))
+	}
+	if g == nil {
+		_ = w.WriteInt(0)
+		return
+	}
+
+	acked := 0
+	for _, id := range ids {
+		if i := g.indexNACK(id); i >= 0 {
+			g.removeNACK(i)
+			acked++
+		}
+	}
+
+	_ = w.WriteInt(acked)
+}
+
+// lookupStreamGroup returns the stream of the key and its consumer group, or
+// replies with an error.
+func (ks *Keyspace) lookupStreamGroup(w *radish.Writer, key radish.Arg, groupName string) (*stream, *streamGroup, bool) {
+	s, err := ks.lookupStream(key)
+	if err != nil {
+		_ = w.WriteError(errWrongType)
+		return nil, nil, false
+	}
+
+	var g *streamGroup
+	if s != nil {
+		g = s.Group(groupName)
+	}
+	if g == nil {
+		_ = w.WriteError(errNoKeyOrGroup(key, groupName))
+		return nil, nil, false
+	}
+
+	return s, g, true
+}
+
+func (ks *Keyspace) xpending(c *Conn, cmd *radish.Command) {
+	w := c.Writer()
+	args := cmd.Args
+	key, groupName := args[1], string(args[2])
+
+	summary := len(args) == 3
+	if !summary && (len(args) < 6 || len(args) > 9) {
+		_ = w.WriteError(errSyntax)
+		return
+	}
+
+	var (
+		minIdle    int64
+		start, end streamID
+		count      int64
+		consumer   radish.Arg
+	)
+	if !summary {
+		i := 3
+		if equalFold(args[i], "IDLE") {
+			if len(args) < 8 {
+				_ = w.WriteError(errSyntax)
+				return
+			}
+
+			var ok bool
+			if minIdle, ok = parseInt(args[i+1]); !ok {
+				_ = w.WriteError(errNotInteger)
+				return
+			}
+			i += 2
+		}
+
+		if rem := len(args) - i; rem < 3 || rem > 4 {
+			_ = w.WriteError(errSyntax)
+			return
+		}
+
+		var e *radish.Error
+		if start, e = parseRangeID(args[i], true); e != nil {
+			_ = w.WriteError(e)
+			return
+		}
+		if end, e = parseRangeID(args[i+1], false); e != nil {
+			_ = w.WriteError(e)
+			return
+		}
+
+		var ok bool
+		if count, ok = parseInt(args[i+2]); !ok {
+			_ = w.WriteError(errNotInteger)
+			return
+		}
+		if count < 0 {
+			count = 0
+		}
+
+		if i+3 < len(args) {
+			consumer = args[i+3]
+		}
+	}
+
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	_, g, ok := ks.lookupStreamGroup(w, key, groupName)
+	if !ok {
+		return
+	}
+
+	if summary {
+		_ = w.WriteArray(4)
+		_ = w.WriteInt(len(g.pel))
+		if len(g.pel) == 0 {
+			_ = w.WriteNull()
+			_ = w.WriteNull()
+			_ = w.WriteArray(-1)
+			return
+		}
+
+		_ = writeStreamID(w, g.pel[0].id)
+		_ = writeStreamID(w, g.pel[len(g.pel)-1].id)
+
+		var consumers []*streamConsumer
+		for _, consumer := range g.Consumers() {
+			if consumer.pending > 0 {
+				consumers = append(consumers, consumer)
+			}
+		}
+
+		_ = w.WriteArray(len(consumers))
+		for _, consumer := range consumers {
+			_ = w.WriteArray(2)
+			_ = w.WriteString(consumer.name)
+			_ = w.WriteString(strconv.Itoa(consumer.pending))
+		}
+		return
+	}
+
+	now := ks.now()
+
+	var nacks []*streamNACK
+	for _, nack := range g.pel[g.searchPEL(start):] {
+		if int64(len(nacks)) == count || end.less(nack.id) {
+			break
+		}
+		if consumer != nil && nack.consumer.name != string(consumer) {
+			continue
+		}
+		if minIdle > 0 && now-nack.deliveryTime < minIdle {
+			continue
+		}
+		nacks = append(nacks, nack)
+	}
+
+	_ = w.WriteArray(len(nacks))
+	for _, nack := range nacks {
+		_ = w.WriteArray(4)
+		_ = writeStreamID(w, nack.id)
+		_ = w.WriteString(nack.consumer.name)
+		_ = w.WriteInt64(now - nack.deliveryTime)
+		_ = w.WriteInt64(nack.deliveryCount)
+	}
+}
+
+func (ks *Keyspace) xclaim(c *Conn, cmd *radish.Command) {
+	w := c.Writer()
+	args := cmd.Args
+	key, groupName, consumerName := args[1], string(args[2]), string(args[3])
+
+	minIdle, ok := parseInt(args[4])
+	if !ok {
+		_ = w.WriteError(&radish.Error{Kind: "ERR", Msg: "Invalid min-idle-time argument for XCLAIM"})
+		return
+	}
+	if minIdle < 0 {
+		minIdle = 0
+	}
+
+	// IDs are followed by options.
+	var ids []streamID
+	i := 5
+	for ; i < len(args); i++ {
+		id, ok := parseStreamID(args[i], 0)
+		if !ok {
+			break
+		}
+		ids = append(ids, id)
+	}
+
+	now := ks.now()
+
+	var (
+		deliveryTime = now
+		retryCount   = int64(-1)
+		force        bool
+		justID       bool
+		lastID       streamID
+	)
+	for ; i < len(args); i++ {
+		arg := args[i]
+		rem := len(args) - i - 1
+
+		switch {
+		case equalFold(arg, "FORCE"):
+			force = true
+		case equalFold(arg, "JUSTID"):
+			justID = true
+		case equalFold(arg, "IDLE") && rem >= 1:
+			idle, ok := parseInt(args[i+1])
+			if !ok {
+				_ = w.WriteError(&radish.Error{Kind: "ERR", Msg: "Invalid IDLE option argument for XCLAIM"})
+				return
+			}
+			deliveryTime = now - idle
+			i++
+		case equalFold(arg, "TIME") && rem >= 1:
+			t, ok := parseInt(args[i+1])
+			if !ok {
+				_ = w.WriteError(&radish.Error{Kind: "ERR", Msg: "Invalid TIME option argument for XCLAIM"})
+				return
+			}
+			deliveryTime = t
+			i++
+		case equalFold(arg, "RETRYCOUNT") && rem >= 1:
+			n, ok := parseInt(args[i+1])
+			if !ok {
+				_ = w.WriteError(&radish.Error{Kind: "ERR", Msg: "Invalid RETRYCOUNT option argument for XCLAIM"})
+				return
+			}
+			retryCount = n
+			i++
+		case equalFold(arg, "LASTID") && rem >= 1:
+			id, ok := parseStreamID(args[i+1], 0)
+			if !ok {
+				_ = w.WriteError(errInvalidStreamID)
+				return
+			}
+			lastID = id
+			i++
+		default:
+			_ = w.WriteError(&radish.Error{Kind: "ERR", Msg: "Unrecognized XCLAIM option '" + string(arg) + "'"})
+			return
+		}
+	}
+
+	if deliveryTime < 0 || deliveryTime > now {
+		deliveryTime = now
+	}
+
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	s, g, ok := ks.lookupStreamGroup(w, key, groupName)
+	if !ok {
+		return
+	}
+
+	if g.lastID.less(lastID) {
+		g.lastID = lastID
+	}
+
+	consumer := g.CreateConsumer(consumerName, now)
+	consumer.seenTime = now
+
+	var claimed []*streamEntry
+	for _, id := range ids {
+		e := s.Get(id)
+
+		i := g.indexNACK(id)
+		forced := false
+		if i < 0 {
+			if !force || e == nil {
+				continue
+			}
+
+			// FORCE creates the pending entry if the entry is in the
+			// stream.
+			g.addNACK(&streamNACK{id: id, consumer: consumer})
+			i = g.indexNACK(id)
+			forced = true
+		}
+
+		// Entries deleted from the stream can not be claimed anymore.
+		if e == nil {
+			g.removeNACK(i)
+			continue
+		}
+
+		nack := g.pel[i]
+		if !forced && minIdle > 0 && now-nack.deliveryTime < minIdle {
+			continue
+		}
+
+		nack.assign(consumer)
+		nack.deliveryTime = deliveryTime
+		if retryCount >= 0 {
+			nack.deliveryCount = retryCount
+		} else if !justID {
+			nack.deliveryCount++
+		}
+
+		claimed = append(claimed, e)
+	}
+
+	_ = w.WriteArray(len(claimed))
+	for _, e := range claimed {
+		if justID {
+			_ = writeStreamID(w, e.id)
+		} else {
+			writeStreamEntry(w, e)
+		}
+	}
+}
+
+func (ks *Keyspace) xautoclaim(c *Conn, cmd *radish.Command) {
+	w := c.Writer()
+	args := cmd.Args
+	key, groupName, consumerName := args[1], string(args[2]), string(args[3])
+
+	minIdle, ok := parseInt(args[4])
+	if !ok {
+		_ = w.WriteError(&radish.Error{Kind: "ERR", Msg: "Invalid min-idle-time argument for XAUTOCLAIM"})
+		return
+	}
+	if minIdle < 0 {
+		minIdle = 0
+	}
+
+	start, e := parseRangeID(args[5], true)
+	if e != nil {
+		_ = w.WriteError(e)
+		return
+	}
+
+	// Redis scans up to 10 pending entries per claimed one.
+	const attemptsFactor = 10
+
+	var (
+		count  int64 = 100
+		justID bool
+	)
+	for i := 6; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case equalFold(arg, "COUNT") && i+1 < len(args):
+			n, ok := parseInt(args[i+1])
+			if !ok {
+				_ = w.WriteError(errNotInteger)
+				return
+			}
+			if n < 1 || n > math.MaxInt64/attemptsFactor {
+				_ = w.WriteError(errXautoclaimCount)
+				return
+			}
+			count = n
+			i++
+		case equalFold(arg, "JUSTID"):
+			justID = true
+		default:
+			_ = w.WriteError(errSyntax)
+			return
+		}
+	}
+
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	s, g, ok := ks.lookupStreamGroup(w, key, groupName)
+	if !ok {
+		return
+	}
+
+	now := ks.now()
+	consumer := g.CreateConsumer(consumerName, now)
+	consumer.seenTime = now
+
+	var (
+		claimed  []*streamEntry
+		deleted  []streamID
+		attempts = count * attemptsFactor
+	)
+
+	i := g.searchPEL(start)
+	for ; attempts > 0 && i < len(g.pel) && int64(len(claimed)) < count; attempts-- {
+		nack := g.pel[i]
+
+		e := s.Get(nack.id)
+		if e == nil {
+			deleted = append(deleted, nack.id)
+			g.removeNACK(i)
+			continue
+		}
+
+		if minIdle > 0 && now-nack.deliveryTime < minIdle {
+			i++
+			continue
+		}
+
+		nack.assign(consumer)
+		nack.deliveryTime = now
+		if !justID {
+			nack.deliveryCount++
+		}
+
+		claimed = append(claimed, e)
+		i++
+	}
+
+	var next streamID
+	if i < len(g.pel) {
+		next = g.pel[i].id
+	}
+
+	_ = w.WriteArray(3)
+	_ = writeStreamID(w, next)
+
+	_ = w.WriteArray(len(claimed))
+	for _, e := range claimed {
+		if justID {
+			_ = writeStreamID(w, e.id)
+		} else {
+			writeStreamEntry(w, e)
+		}
+	}
+
+	_ = w.WriteArray(len(deleted))
+	for _, id := range deleted {
+		_ = writeStreamID(w, id)
+	}
+}
+
+func (ks *Keyspace) xinfo(c *Conn, cmd *radish.Command) {
+	w := c.Writer()
+	args := cmd.Args
+	sub := args[1]
+
+	var (
+		name     string
+		min, max int
+	)
+	switch {
+	case equalFold(sub, "STREAM"):
+		name, min, max = "stream", 3, 6
+	case equalFold(sub, "GROUPS"):
+		name, min, max = "groups", 3, 3
+	case equalFold(sub, "CONSUMERS"):
+		name, min, max = "consumers", 4, 4
+	default:
+		_ = w.WriteError(unknownSubcommand(sub, "XINFO"))
+		return
+	}
+	if len(args) < min || len(args) > max {
+		_ = writeWrongArity(w, "xinfo|"+name)
+		return
+	}
+
+	full := false
+	count := int64(10)
+	if name == "stream" && len(args) > 3 {
+		if !equalFold(args[3], "FULL") {
+			_ = w.WriteError(errSyntax)
+			return
+		}
+		full = true
+
+		switch {
+		case len(args) == 4:
+		case len(args) == 6 && equalFold(args[4], "COUNT"):
+			n, ok := parseInt(args[5])
+			if !ok {
+				_ = w.WriteError(errNotInteger)
+				return
+			}
+			if n < 0 {
+				n = 0
+			}
+			count = n
+		default:
+			_ = w.WriteError(errSyntax)
+			return
+		}
+	}
+
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	key := args[2]
+	s, err := ks.lookupStream(key)
+	if err != nil {
+		_ = w.WriteError(errWrongType)
+		return
+	}
+	if s == nil {
+		_ = w.WriteError(errNoSuchKey)
+		return
+	}
+
+	now := ks.now()
+
+	switch {
+	case name == "stream" && full:
+		writeStreamInfoFull(w, s, count)
+
+	case name == "stream":
+		_ = w.WriteMap(5)
+		_ = w.WriteString("length")
+		_ = w.WriteInt(s.Len())
+		_ = w.WriteString("last-generated-id")
+		_ = writeStreamID(w, s.lastID)
+		_ = w.WriteString("groups")
+		_ = w.WriteInt(len(s.groups))
+		_ = w.WriteString("first-entry")
+		if s.Len() == 0 {
+			_ = w.WriteNull()
+		} else {
+			writeStreamEntry(w, &s.entries[0])
+		}
+		_ = w.WriteString("last-entry")
+		if s.Len() == 0 {
+			_ = w.WriteNull()
+		} else {
+			writeStreamEntry(w, &s.entries[s.Len()-1])
+		}
+
+	case name == "groups":
+		groups := s.Groups()
+		_ = w.WriteArray(len(groups))
+		for _, g := range groups {
+			_ = w.WriteMap(4)
+			_ = w.WriteString("name")
+			_ = w.WriteString(g.name)
+			_ = w.WriteString("consumers")
+			_ = w.WriteInt(len(g.consumers))
+			_ = w.WriteString("pending")
+			_ = w.WriteInt(len(g.pel))
+			_ = w.WriteString("last-delivered-id")
+			_ = writeStreamID(w, g.lastID)
+		}
+
+	case name == "consumers":
+		groupName := string(args[3])
+		g := s.Group(groupName)
+		if g == nil {
+			_ = w.WriteError(errNoGroup(key, groupName))
+			return
+		}
+
+		consumers := g.Consumers()
+		_ = w.WriteArray(len(consumers))
+		for _, consumer := range consumers {
+			_ = w.WriteMap(3)
+			_ = w.WriteString("name")
+			_ = w.WriteString(consumer.name)
+			_ = w.WriteString("pending")
+			_ = w.WriteInt(consumer.pending)
+			_ = w.WriteString("idle")
+			_ = w.WriteInt64(now - consumer.seenTime)
+		}
+	}
+}
+
+// writeStreamInfoFull writes the reply of XINFO STREAM FULL. The count limits
+// the number of entries and pending entries, zero means no limit.
+func writeStreamInfoFull(w *radish.Writer, s *stream, count int64) {
+	limit := func(n int) int {
+		if count > 0 && int64(n) > count {
+			return int(count)
+		}
+		return n
+	}
+
+	_ = w.WriteMap(4)
+	_ = w.WriteString("length")
+	_ = w.WriteInt(s.Len())
+	_ = w.WriteString("last-generated-id")
+	_ = writeStreamID(w, s.lastID)
+	_ = w.WriteString("entries")
+	writeStreamEntries(w, s.entries[:limit(s.Len())])
+
+	groups := s.Groups()
+	_ = w.WriteString("groups")
+	_ = w.WriteArray(len(groups))
+	for _, g := range groups {
+		_ = w.WriteMap(5)
+		_ = w.WriteString("name")
+		_ = w.WriteString(g.name)
+		_ = w.WriteString("last-delivered-id")
+		_ = writeStreamID(w, g.lastID)
+		_ = w.WriteString("pel-count")
+		_ = w.WriteInt(len(g.pel))
+
+		pel := g.pel[:limit(len(g.pel))]
+		_ = w.WriteString("pel")
+		_ = w.WriteArray(len(pel))
+		for _, nack := range pel {
+			_ = w.WriteArray(4)
+			_ = writeStreamID(w, nack.id)
+			_ = w.WriteString(nack.consumer.name)
+			_ = w.WriteInt64(nack.deliveryTime)
+			_ = w.WriteInt64(nack.deliveryCount)
+		}
+
+		consumers := g.Consumers()
+		_ = w.WriteString("consumers")
+		_ = w.WriteArray(len(consumers))
+		for _, consumer := range consumers {
+			var nacks []*streamNACK
+			for _, nack := range g.pel {
+				if nack.consumer == consumer {
+					nacks = append(nacks, nack)
+				}
+			}
+			nacks = nacks[:limit(len(nacks))]
+
+			_ = w.WriteMap(4)
+			_ = w.WriteString("name")
+			_ = w.WriteString(consumer.name)
+			_ = w.WriteString("seen-time")
+			_ = w.WriteInt64(consumer.seenTime)
+			_ = w.WriteString("pel-count")
+			_ = w.WriteInt(consumer.pending)
+			_ = w.WriteString("pel")
+			_ = w.WriteArray(len(nacks))
+			for _, nack := range nacks {
+				_ = w.WriteArray(3)
+				_ = writeStreamID(w, nack.id)
+				_ = w.WriteInt64(nack.deliveryTime)
+				_ = w.WriteInt64(nack.deliveryCount)
+			}
+		}
+	}
+}
@@ -0,0 +1,398 @@
+package server
+
+import (
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestKeyspace_streams(t *testing.T) {
+	tt := []struct {
+		name  string
+		steps []testStep
+	}{
+		{
+			name: "add and range",
+			steps: []testStep{
+				{"XADD s 1-1 a 1", `"1-1"`},
+				{"XADD s 1-2 b 2 c 3", `"1-2"`},
+				{"XADD s * d 4", `"1700000000000-0"`},
+				{"XADD s * e 5", `"1700000000000-1"`},
+				{"XLEN s", "(integer) 4"},
+				{"XLEN missing", "(integer) 0"},
+				{"TYPE s", "stream"},
+				{"OBJECT ENCODING s", `"stream"`},
+				{"XRANGE s - + COUNT 2", `[["1-1", ["a", "1"]], ["1-2", ["b", "2", "c", "3"]]]`},
+				{"XRANGE s 1 1", `[["1-1", ["a", "1"]], ["1-2", ["b", "2", "c", "3"]]]`},
+				{"XRANGE s (1-1 1", `[["1-2", ["b", "2", "c", "3"]]]`},
+				{"XRANGE s 1-2 (1700000000000-1", `[["1-2", ["b", "2", "c", "3"]], ["1700000000000-0", ["d", "4"]]]`},
+				{"XREVRANGE s + - COUNT 1", `[["1700000000000-1", ["e", "5"]]]`},
+				{"XREVRANGE s 1 - ", `[["1-2", ["b", "2", "c", "3"]], ["1-1", ["a", "1"]]]`},
+				{"XRANGE s - + COUNT 0", "[]"},
+				{"XRANGE s 2 1", "[]"},
+				{"XRANGE missing - +", "[]"},
+				{"XRANGE s x +", "(error) ERR Invalid stream ID specified as stream command argument"},
+				{"XRANGE s (- +", "(error) ERR Invalid stream ID specified as stream command argument"},
+				{"XRANGE s (18446744073709551615-18446744073709551615 +", "(error) ERR invalid start ID for the interval"},
+				{"XRANGE s - (0-0", "(error) ERR invalid end ID for the interval"},
+				{"XRANGE s - + LIMIT 1", "(error) ERR syntax error"},
+			},
+		},
+		{
+			name: "add ids",
+			steps: []testStep{
+				{"XADD s 0-* a 1", `"0-1"`},
+				{"XADD s 5-* a 1", `"5-0"`},
+				{"XADD s 5-* a 1", `"5-1"`},
+				{"XADD s 5 a 1", "(error) ERR The ID specified in XADD is equal or smaller than the target stream top item"},
+				{"XADD s 4-* a 1", "(error) ERR The ID specified in XADD is equal or smaller than the target stream top item"},
+				{"XADD s 5-1 a 1", "(error) ERR The ID specified in XADD is equal or smaller than the target stream top item"},
+				{"XADD s 0-0 a 1", "(error) ERR The ID specified in XADD must be greater than 0-0"},
+				{"XADD s x a 1", "(error) ERR Invalid stream ID specified as stream command argument"},
+				{"XADD s 6-x a 1", "(error) ERR Invalid stream ID specified as stream command argument"},
+				{"XADD s 6 a", "(error) ERR wrong number of arguments for 'xadd' command"},
+				{"XADD s 6 a 1 b", "(error) ERR wrong number of arguments for 'xadd' command"},
+				{"XADD s 18446744073709551615-18446744073709551615 a 1", `"18446744073709551615-18446744073709551615"`},
+				{"XADD s * a 1", "(error) ERR The stream has exhausted the last possible ID, unable to add more items"},
+				{"XADD t NOMKSTREAM * a 1", "(nil)"},
+				{"EXISTS t", "(integer) 0"},
+			},
+		},
+		{
+			name: "delete and trim",
+			steps: []testStep{
+				{"XADD s 1 a 1", `"1-0"`},
+				{"XADD s 2 a 1", `"2-0"`},
+				{"XADD s 3 a 1", `"3-0"`},
+				{"XADD s MAXLEN 3 4 a 1", `"4-0"`},
+				{"XLEN s", "(integer) 3"},
+				{"XRANGE s - + COUNT 1", `[["2-0", ["a", "1"]]]`},
+				{"XDEL s 3 5 x", "(error) ERR Invalid stream ID specified as stream command argument"},
+				{"XDEL s 3 5", "(integer) 1"},
+				{"XDEL missing 1", "(integer) 0"},
+				{"XTRIM s MINID 4", "(integer) 1"},
+				{"XRANGE s - +", `[["4-0", ["a", "1"]]]`},
+				{"XTRIM s MAXLEN ~ 0", "(integer) 0"},
+				{"XTRIM s MAXLEN = 0", "(integer) 1"},
+				{"XLEN s", "(integer) 0"},
+				{"EXISTS s", "(integer) 1"},
+				{"XADD s 5 a 1", `"5-0"`},
+				{"XTRIM missing MAXLEN 0", "(integer) 0"},
+				{"XTRIM s MAXLEN -1", "(error) ERR The MAXLEN argument must be >= 0."},
+				{"XTRIM s MAXLEN x", "(error) ERR value is not an integer or out of range"},
+				{"XTRIM s MINID x", "(error) ERR Invalid stream ID specified as stream command argument"},
+				{"XTRIM s MAXLEN 1 LIMIT 10", "(error) ERR syntax error, LIMIT cannot be used without the special ~ option"},
+				{"XTRIM s MAXLEN ~ 1 LIMIT -1", "(error) ERR The LIMIT argument must be >= 0."},
+				{"XTRIM s MAXLEN 1 MINID 0", "(error) ERR syntax error, MAXLEN and MINID options at the same time are not compatible"},
+				{"XTRIM s LIMIT 10 MAXLEN ~ 1", "(integer) 0"},
+				{"XTRIM s FOO 1", "(error) ERR syntax error"},
+				{"XADD s MAXLEN 0 6 a 1", `"6-0"`},
+				{"XLEN s", "(integer) 0"},
+			},
+		},
+		{
+			name: "read",
+			steps: []testStep{
+				{"XADD a 1 f 1", `"1-0"`},
+				{"XADD a 2 f 2", `"2-0"`},
+				{"XADD b 3 f 3", `"3-0"`},
+				{"XREAD STREAMS a b 0 0", `[["a", [["1-0", ["f", "1"]], ["2-0", ["f", "2"]]]], ["b", [["3-0", ["f", "3"]]]]]`},
+				{"XREAD COUNT 1 STREAMS a b 1 0", `[["a", [["2-0", ["f", "2"]]]], ["b", [["3-0", ["f", "3"]]]]]`},
+				{"XREAD STREAMS a b 2 0", `[["b", [["3-0", ["f", "3"]]]]]`},
+				{"XREAD STREAMS a b $ $", "(nil)"},
+				{"XREAD STREAMS missing 0", "(nil)"},
+				{"XREAD BLOCK 10 STREAMS a $", "(nil)"},
+				{"XREAD STREAMS a b 0", "(error) ERR Unbalanced 'xread' list of streams: for each stream key an ID or '$' must be specified."},
+				{"XREAD STREAMS a >", "(error) ERR The > ID can be specified only when calling XREADGROUP using the GROUP <group> <consumer> option."},
+				{"XREAD STREAMS a x", "(error) ERR Invalid stream ID specified as stream command argument"},
+				{"XREAD GROUP g c STREAMS a 0", "(error) ERR The GROUP option is only supported by XREADGROUP. You called XREAD instead."},
+				{"XREAD BLOCK -1 STREAMS a 0", "(error) ERR timeout is negative"},
+				{"XREAD BLOCK x STREAMS a 0", "(error) ERR timeout is not an integer or out of range"},
+				{"XREAD COUNT 1 a 0", "(error) ERR syntax error"},
+			},
+		},
+		{
+			name: "groups",
+			steps: []testStep{
+				{"XGROUP CREATE s g $", "(error) ERR The XGROUP subcommand requires the key to exist. Note that for CREATE you may want to use the MKSTREAM option to create an empty stream automatically."},
+				{"XGROUP CREATE s g $ MKSTREAM", "OK"},
+				{"XGROUP CREATE s g $", "(error) BUSYGROUP Consumer Group name already exists"},
+				{"XGROUP CREATE s h x", "(error) ERR Invalid stream ID specified as stream command argument"},
+				{"XGROUP CREATE s h 0 FOO", "(error) ERR syntax error"},
+				{"XGROUP CREATE s", "(error) ERR wrong number of arguments for 'xgroup|create' command"},
+				{"XGROUP FOO s", "(error) ERR unknown subcommand 'FOO'. Try XGROUP HELP."},
+				{"XADD s 1 f 1", `"1-0"`},
+				{"XADD s 2 f 2", `"2-0"`},
+				{"XADD s 3 f 3", `"3-0"`},
+				{"XREADGROUP GROUP g alice COUNT 1 STREAMS s >", `[["s", [["1-0", ["f", "1"]]]]]`},
+				{"XREADGROUP GROUP g bob STREAMS s >", `[["s", [["2-0", ["f", "2"]], ["3-0", ["f", "3"]]]]]`},
+				{"XREADGROUP GROUP g bob STREAMS s >", "(nil)"},
+				{"XPENDING s g", `[(integer) 3, "1-0", "3-0", [["alice", "1"], ["bob", "2"]]]`},
+				{"XPENDING s g - + 10", `[["1-0", "alice", (integer) 0, (integer) 1], ["2-0", "bob", (integer) 0, (integer) 1], ["3-0", "bob", (integer) 0, (integer) 1]]`},
+				{"XPENDING s g - + 10 alice", `[["1-0", "alice", (integer) 0, (integer) 1]]`},
+				{"XPENDING s g (1 + 1", `[["2-0", "bob", (integer) 0, (integer) 1]]`},
+				{"XPENDING s g IDLE 1 - + 10", "[]"},
+				{"XPENDING s g - + 10 alice extra", "(error) ERR syntax error"},
+				{"XPENDING s g - +", "(error) ERR syntax error"},
+				{"XREADGROUP GROUP g bob STREAMS s 0", `[["s", [["2-0", ["f", "2"]], ["3-0", ["f", "3"]]]]]`},
+				{"XREADGROUP GROUP g bob COUNT 1 STREAMS s 2", `[["s", [["3-0", ["f", "3"]]]]]`},
+				{"XPENDING s g - + 10 bob", `[["2-0", "bob", (integer) 0, (integer) 2], ["3-0", "bob", (integer) 0, (integer) 3]]`},
+				{"XDEL s 2", "(integer) 1"},
+				{"XREADGROUP GROUP g bob STREAMS s 0", `[["s", [["2-0", (nil)], ["3-0", ["f", "3"]]]]]`},
+				{"XACK s g 1 2 5", "(integer) 2"},
+				{"XACK s g x", "(error) ERR Invalid stream ID specified as stream command argument"},
+				{"XACK s missing 3", "(integer) 0"},
+				{"XACK missing g 3", "(integer) 0"},
+				{"XPENDING s g", `[(integer) 1, "3-0", "3-0", [["bob", "1"]]]`},
+				{"XACK s g 3", "(integer) 1"},
+				{"XPENDING s g", "[(integer) 0, (nil), (nil), (nil)]"},
+				{"XREADGROUP GROUP g alice STREAMS s 0", `[["s", []]]`},
+				{"XPENDING s missing", "(error) NOGROUP No such key 's' or consumer group 'missing'"},
+				{"XREADGROUP GROUP missing alice STREAMS s >", "(error) NOGROUP No such key 's' or consumer group 'missing' in XREADGROUP with GROUP option"},
+				{"XREADGROUP GROUP g alice STREAMS s $", "(error) ERR The $ ID is meaningless in the context of XREADGROUP: you want to read the history of this consumer by specifying a proper ID, or use the > ID to get new messages. The $ ID would just return an empty result set."},
+				{"XREADGROUP COUNT 1 STREAMS s t > >", "(error) ERR Missing GROUP option for XREADGROUP"},
+			},
+		},
+		{
+			name: "noack and setid",
+			steps: []testStep{
+				{"XADD s 1 f 1", `"1-0"`},
+				{"XADD s 2 f 2", `"2-0"`},
+				{"XGROUP CREATE s g 0", "OK"},
+				{"XREADGROUP GROUP g c NOACK STREAMS s >", `[["s", [["1-0", ["f", "1"]], ["2-0", ["f", "2"]]]]]`},
+				{"XPENDING s g", "[(integer) 0, (nil), (nil), (nil)]"},
+				{"XGROUP SETID s g 1", "OK"},
+				{"XREADGROUP GROUP g c STREAMS s >", `[["s", [["2-0", ["f", "2"]]]]]`},
+				{"XGROUP SETID s g 0", "OK"},
+				{"XREADGROUP GROUP g d STREAMS s >", `[["s", [["1-0", ["f", "1"]], ["2-0", ["f", "2"]]]]]`},
+				{"XPENDING s g", `[(integer) 2, "1-0", "2-0", [["d", "2"]]]`},
+				{"XGROUP SETID s g $", "OK"},
+				{"XREADGROUP GROUP g c STREAMS s >", "(nil)"},
+				{"XGROUP SETID s missing 0", "(error) NOGROUP No such consumer group 'missing' for key name 's'"},
+			},
+		},
+		{
+			name: "consumers",
+			steps: []testStep{
+				{"XADD s 1 f 1", `"1-0"`},
+				{"XGROUP CREATE s g 0", "OK"},
+				{"XGROUP CREATECONSUMER s g alice", "(integer) 1"},
+				{"XGROUP CREATECONSUMER s g alice", "(integer) 0"},
+				{"XREADGROUP GROUP g bob STREAMS s >", `[["s", [["1-0", ["f", "1"]]]]]`},
+				{"XINFO CONSUMERS s g", `[["name", "alice", "pending", (integer) 0, "idle", (integer) 0], ["name", "bob", "pending", (integer) 1, "idle", (integer) 0]]`},
+				{"XINFO GROUPS s", `[["name", "g", "consumers", (integer) 2, "pending", (integer) 1, "last-delivered-id", "1-0"]]`},
+				{"XGROUP DELCONSUMER s g bob", "(integer) 1"},
+				{"XGROUP DELCONSUMER s g bob", "(integer) 0"},
+				{"XPENDING s g", "[(integer) 0, (nil), (nil), (nil)]"},
+				{"XGROUP DESTROY s g", "(integer) 1"},
+				{"XGROUP DESTROY s g", "(integer) 0"},
+				{"XINFO GROUPS s", "[]"},
+				{"XINFO CONSUMERS s g", "(error) NOGROUP No such consumer group 'g' for key name 's'"},
+				{"XGROUP CREATECONSUMER s g alice", "(error) NOGROUP No such consumer group 'g' for key name 's'"},
+				{"XGROUP DESTROY missing g", "(error) ERR The XGROUP subcommand requires the key to exist. Note that for CREATE you may want to use the MKSTREAM option to create an empty stream automatically."},
+			},
+		},
+		{
+			name: "info",
+			steps: []testStep{
+				{"XINFO STREAM s", "(error) ERR no such key"},
+				{"XGROUP CREATE s g $ MKSTREAM", "OK"},
+				{"XINFO STREAM s", `["length", (integer) 0, "last-generated-id", "0-0", "groups", (integer) 1, "first-entry", (nil), "last-entry", (nil)]`},
+				{"XADD s 1 a 1", `"1-0"`},
+				{"XADD s 2 b 2", `"2-0"`},
+				{"XINFO STREAM s", `["length", (integer) 2, "last-generated-id", "2-0", "groups", (integer) 1, "first-entry", ["1-0", ["a", "1"]], "last-entry", ["2-0", ["b", "2"]]]`},
+				{"XREADGROUP GROUP g c COUNT 1 STREAMS s >", `[["s", [["1-0", ["a", "1"]]]]]`},
+				{"XINFO STREAM s FULL COUNT 1", `["length", (integer) 2, "last-generated-id", "2-0", "entries", [["1-0", ["a", "1"]]], "groups", [["name", "g", "last-delivered-id", "1-0", "pel-count", (integer) 1, "pel", [["1-0", "c", (integer) 1700000000000, (integer) 1]], "consumers", [["name", "c", "seen-time", (integer) 1700000000000, "pel-count", (integer) 1, "pel", [["1-0", (integer) 1700000000000, (integer) 1]]]]]]]`},
+				{"XINFO STREAM s FULL COUNT", "(error) ERR syntax error"},
+				{"XINFO STREAM s PARTIAL", "(error) ERR syntax error"},
+				{"XINFO GROUPS", "(error) ERR wrong number of arguments for 'xinfo|groups' command"},
+				{"XINFO FOO s", "(error) ERR unknown subcommand 'FOO'. Try XINFO HELP."},
+			},
+		},
+		{
+			name: "claim",
+			steps: []testStep{
+				{"XADD s 1 f 1", `"1-0"`},
+				{"XADD s 2 f 2", `"2-0"`},
+				{"XADD s 3 f 3", `"3-0"`},
+				{"XGROUP CREATE s g 0", "OK"},
+				{"XREADGROUP GROUP g alice STREAMS s >", `[["s", [["1-0", ["f", "1"]], ["2-0", ["f", "2"]], ["3-0", ["f", "3"]]]]]`},
+				{"XCLAIM s g bob 0 1 2", `[["1-0", ["f", "1"]], ["2-0", ["f", "2"]]]`},
+				{"XCLAIM s g bob 0 3 JUSTID", `["3-0"]`},
+				{"XPENDING s g - + 10", `[["1-0", "bob", (integer) 0, (integer) 2], ["2-0", "bob", (integer) 0, (integer) 2], ["3-0", "bob", (integer) 0, (integer) 1]]`},
+				{"XCLAIM s g alice 0 1 RETRYCOUNT 7 IDLE 0", `[["1-0", ["f", "1"]]]`},
+				{"XCLAIM s g alice 0 4", "[]"},
+				{"XADD s 4 f 4", `"4-0"`},
+				{"XCLAIM s g alice 0 4 FORCE JUSTID", `["4-0"]`},
+				{"XPENDING s g - + 10 alice", `[["1-0", "alice", (integer) 0, (integer) 7], ["4-0", "alice", (integer) 0, (integer) 0]]`},
+				{"XDEL s 2", "(integer) 1"},
+				{"XCLAIM s g alice 0 2", "[]"},
+				{"XPENDING s g", `[(integer) 3, "1-0", "4-0", [["alice", "2"], ["bob", "1"]]]`},
+				{"XCLAIM s g alice 0 1 FOO", "(error) ERR Unrecognized XCLAIM option 'FOO'"},
+				{"XCLAIM s g alice x 1", "(error) ERR Invalid min-idle-time argument for XCLAIM"},
+				{"XCLAIM s missing alice 0 1", "(error) NOGROUP No such key 's' or consumer group 'missing'"},
+				{"XCLAIM s g alice 0 1 LASTID 10", `[["1-0", ["f", "1"]]]`},
+				{"XINFO GROUPS s", `[["name", "g", "consumers", (integer) 2, "pending", (integer) 3, "last-delivered-id", "10-0"]]`},
+			},
+		},
+		{
+			name: "autoclaim",
+			steps: []testStep{
+				{"XADD s 1 f 1", `"1-0"`},
+				{"XADD s 2 f 2", `"2-0"`},
+				{"XADD s 3 f 3", `"3-0"`},
+				{"XGROUP CREATE s g 0", "OK"},
+				{"XREADGROUP GROUP g alice STREAMS s >", `[["s", [["1-0", ["f", "1"]], ["2-0", ["f", "2"]], ["3-0", ["f", "3"]]]]]`},
+				{"XDEL s 2", "(integer) 1"},
+				{"XAUTOCLAIM s g bob 0 0 COUNT 1", `["2-0", [["1-0", ["f", "1"]]], []]`},
+				{"XAUTOCLAIM s g bob 0 2 JUSTID", `["0-0", ["3-0"], ["2-0"]]`},
+				{"XPENDING s g", `[(integer) 2, "1-0", "3-0", [["bob", "2"]]]`},
+				{"XAUTOCLAIM s g bob 0 (3", `["0-0", [], []]`},
+				{"XAUTOCLAIM s g bob 0 0 COUNT 0", "(error) ERR COUNT must be > 0"},
+				{"XAUTOCLAIM s g bob 0 0 FOO", "(error) ERR syntax error"},
+				{"XAUTOCLAIM s missing bob 0 0", "(error) NOGROUP No such key 's' or consumer group 'missing'"},
+			},
+		},
+		{
+			name: "wrong type",
+			steps: []testStep{
+				{"SET str v", "OK"},
+				{"XADD str * f v", "(error) WRONGTYPE Operation against a key holding the wrong kind of value"},
+				{"XRANGE str - +", "(error) WRONGTYPE Operation against a key holding the wrong kind of value"},
+				{"XREAD STREAMS str 0", "(error) WRONGTYPE Operation against a key holding the wrong kind of value"},
+				{"XGROUP CREATE str g 0", "(error) WRONGTYPE Operation against a key holding the wrong kind of value"},
+				{"XINFO STREAM str", "(error) WRONGTYPE Operation against a key holding the wrong kind of value"},
+				{"XADD s * f v", `"1700000000000-0"`},
+				{"GET s", "(error) WRONGTYPE Operation against a key holding the wrong kind of value"},
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			ks := NewKeyspace()
+			ks.Clock = newTestClock()
+			c := dialTestClient(t, startTestKeyspaceServer(t, ks))
+
+			runTestSteps(t, c, tc.steps)
+		})
+	}
+}
+
+func TestKeyspace_xtrimApprox(t *testing.T) {
+	c := dialTestClient(t, startTestKeyspaceServer(t, NewKeyspace()))
+
+	for i := 1; i <= 250; i++ {
+		c.do("XADD", "s", strconv.Itoa(i), "f", "v")
+	}
+
+	runTestSteps(t, c, []testStep{
+		// Only whole nodes of 100 entries are removed.
+		{"XTRIM s MAXLEN ~ 200", "(integer) 0"},
+		{"XTRIM s MAXLEN ~ 10 LIMIT 150", "(integer) 100"},
+		{"XTRIM s MINID ~ 250", "(integer) 100"},
+		{"XLEN s", "(integer) 50"},
+		{"XRANGE s - + COUNT 1", `[["201-0", ["f", "v"]]]`},
+		{"XADD s MAXLEN ~ 1 251 f v", `"251-0"`},
+		{"XLEN s", "(integer) 51"},
+	})
+}
+
+func TestKeyspace_xclaimIdle(t *testing.T) {
+	clock := newTestClock()
+	ks := NewKeyspace()
+	ks.Clock = clock
+	c := dialTestClient(t, startTestKeyspaceServer(t, ks))
+
+	runTestSteps(t, c, []testStep{
+		{"XADD s 1 f 1", `"1-0"`},
+		{"XADD s 2 f 2", `"2-0"`},
+		{"XGROUP CREATE s g 0", "OK"},
+		{"XREADGROUP GROUP g alice COUNT 1 STREAMS s >", `[["s", [["1-0", ["f", "1"]]]]]`},
+	})
+
+	clock.Advance(time.Second)
+
+	runTestSteps(t, c, []testStep{
+		{"XREADGROUP GROUP g alice COUNT 1 STREAMS s >", `[["s", [["2-0", ["f", "2"]]]]]`},
+		{"XPENDING s g IDLE 500 - + 10", `[["1-0", "alice", (integer) 1000, (integer) 1]]`},
+		{"XINFO CONSUMERS s g", `[["name", "alice", "pending", (integer) 2, "idle", (integer) 0]]`},
+		{"XCLAIM s g bob 500 1 2", `[["1-0", ["f", "1"]]]`},
+		{"XCLAIM s g bob 500 2 IDLE 600", "[]"},
+		{"XPENDING s g - + 10", `[["1-0", "bob", (integer) 0, (integer) 2], ["2-0", "alice", (integer) 0, (integer) 1]]`},
+	})
+
+	clock.Advance(time.Second)
+
+	runTestSteps(t, c, []testStep{
+		{"XAUTOCLAIM s g carol 1500 0", `["0-0", [], []]`},
+		{"XAUTOCLAIM s g carol 1000 0 JUSTID", `["0-0", ["1-0", "2-0"], []]`},
+		{"XPENDING s g - + 10", `[["1-0", "carol", (integer) 0, (integer) 2], ["2-0", "carol", (integer) 0, (integer) 1]]`},
+	})
+}
+
+func TestKeyspace_xread(t *testing.T) {
+	ks := NewKeyspace()
+	addr := startTestKeyspaceServer(t, ks)
+
+	blocked := dialTestClient(t, addr)
+	c := dialTestClient(t, addr)
+
+	if got, want := c.do("XADD", "s", "1", "f", "1"), `"1-0"`; got != want {
+		t.Fatalf("XADD = %s, want %s", got, want)
+	}
+
+	blocked.send("XREAD", "BLOCK", "0", "STREAMS", "other", "s", "0", "$")
+	blocked.flush()
+	waitBlocked(t, ks, "s", 1)
+
+	if got, want := c.do("XADD", "s", "2", "f", "2"), `"2-0"`; got != want {
+		t.Fatalf("XADD = %s, want %s", got, want)
+	}
+
+	if got, want := blocked.read(), `[["s", [["2-0", ["f", "2"]]]]]`; got != want {
+		t.Errorf("XREAD = %s, want %s", got, want)
+	}
+	waitBlocked(t, ks, "other", 0)
+}
+
+func TestKeyspace_xreadgroup(t *testing.T) {
+	ks := NewKeyspace()
+	addr := startTestKeyspaceServer(t, ks)
+
+	first := dialTestClient(t, addr)
+	second := dialTestClient(t, addr)
+	c := dialTestClient(t, addr)
+
+	if got, want := c.do("XGROUP", "CREATE", "s", "g", "$", "MKSTREAM"), "OK"; got != want {
+		t.Fatalf("XGROUP CREATE = %s, want %s", got, want)
+	}
+
+	first.send("XREADGROUP", "GROUP", "g", "alice", "BLOCK", "0", "STREAMS", "s", ">")
+	first.flush()
+	waitBlocked(t, ks, "s", 1)
+
+	second.send("XREADGROUP", "GROUP", "g", "bob", "BLOCK", "0", "STREAMS", "s", ">")
+	second.flush()
+	waitBlocked(t, ks, "s", 2)
+
+	// Each entry is delivered to a single consumer of the group.
+	if got, want := c.do("XADD", "s", "1", "f", "1"), `"1-0"`; got != want {
+		t.Fatalf("XADD = %s, want %s", got, want)
+	}
+	if got, want := first.read(), `[["s", [["1-0", ["f", "1"]]]]]`; got != want {
+		t.Errorf("first XREADGROUP = %s, want %s", got, want)
+	}
+	waitBlocked(t, ks, "s", 1)
+
+	if got, want := c.do("XADD", "s", "2", "f", "2"), `"2-0"`; got != want {
+		t.Fatalf("XADD = %s, want %s", got, want)
+	}
+	if got, want := second.read(), `[["s", [["2-0", ["f", "2"]]]]]`; got != want {
+		t.Errorf("second XREADGROUP = %s, want %s", got, want)
+	}
+
+	if got, want := c.do("XPENDING", "s", "g"), `[(integer) 2, "1-0", "2-0", [["alice", "1"], ["bob", "1"]]]`; got != want {
+		t.Errorf("XPENDING = %s, want %s", got, want)
+	}
+}
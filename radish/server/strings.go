@@ -0,0 +1,781 @@
+package server
+
+import (
+	"math"
+	"strconv"
+
+	"github.com/SuperPaintman/mini-redis/radish"
+)
+
+// maxStringLength is the maximum length of a string value (the default
+// proto-max-bulk-len of Redis).
+const maxStringLength = 512 * 1024 * 1024
+
+var (
+	errStringTooLong     = &radish.Error{Kind: "ERR", Msg: "string exceeds maximum allowed size (proto-max-bulk-len)"}
+	errOffsetRange       = &radish.Error{Kind: "ERR", Msg: "offset is out of range"}
+	errIncrOverflow      = &radish.Error{Kind: "ERR", Msg: "increment or decrement would overflow"}
+	errDecrOverflow      = &radish.Error{Kind: "ERR", Msg: "decrement would overflow"}
+	errIncrNaNOrInfinity = &radish.Error{Kind: "ERR", Msg: "increment would produce NaN or Infinity"}
+)
+
+func invalidExpireTime(command string) *radish.Error {
+	return &radish.Error{Kind: "ERR", Msg: "invalid expire time in '" + command + "' command"}
+}
+
+// Extended arguments of SET and GETEX.
+const (
+	stringNX = 1 << iota
+	stringXX
+	stringGet
+	stringKeepTTL
+	stringPersist
+	stringEX
+	stringPX
+	stringEXAT
+	stringPXAT
+
+	stringExpire = stringEX | stringPX | stringEXAT | stringPXAT
+)
+
+type stringOptions struct {
+	flags  int
+	expire int64 // Unix time in milliseconds. Only if one of stringExpire is set.
+}
+
+// parseStringOptions parses extended arguments of the SET (if isSet) or GETEX
+// commands.
+func (ks *Keyspace) parseStringOptions(args []radish.Arg, isSet bool) (opts stringOptions, err *radish.Error) {
+	command := "getex"
+	if isSet {
+		command = "set"
+	}
+
+	var expire radish.Arg
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		hasNext := i+1 < len(args)
+
+		switch {
+		case isSet && equalFold(arg, "NX") && opts.flags&stringXX == 0:
+			opts.flags |= stringNX
+
+		case isSet && equalFold(arg, "XX") && opts.flags&stringNX == 0:
+			opts.flags |= stringXX
+
+		case isSet && equalFold(arg, "GET"):
+			opts.flags |= stringGet
+
+		case isSet && equalFold(arg, "KEEPTTL") && opts.flags&stringExpire == 0:
+			opts.flags |= stringKeepTTL
+
+		case !isSet && equalFold(arg, "PERSIST") && opts.flags&stringExpire == 0:
+			opts.flags |= stringPersist
+
+		case hasNext && opts.flags&(stringExpire|stringKeepTTL|stringPersist) == 0:
+			switch {
+			case equalFold(arg, "EX"):
+				opts.flags |= stringEX
+			case equalFold(arg, "PX"):
+				opts.flags |= stringPX
+			case equalFold(arg, "EXAT"):
+				opts.flags |= stringEXAT
+			case equalFold(arg, "PXAT"):
+				opts.flags |= stringPXAT
+			default:
+				return opts, errSyntax
+			}
+
+			i++
+			expire = args[i]
+
+		default:
+			return opts, errSyntax
+		}
+	}
+
+	if opts.flags&stringExpire != 0 {
+		n, ok := parseInt(expire)
+		if !ok {
+			return opts, errNotInteger
+		}
+
+		seconds := opts.flags&(stringEX|stringEXAT) != 0
+		if n <= 0 || (seconds && n > math.MaxInt64/1000) {
+			return opts, invalidExpireTime(command)
+		}
+		if seconds {
+			n *= 1000
+		}
+
+		if opts.flags&(stringEX|stringPX) != 0 {
+			now := ks.now()
+			if n > math.MaxInt64-now {
+				return opts, invalidExpireTime(command)
+			}
+			n += now
+		}
+
+		opts.expire = n
+	}
+
+	return opts, nil
+}
+
+// checkStringLength reports whether a string of the given length can be
+// stored.
+func checkStringLength(length int64) bool {
+	return length <= maxStringLength
+}
+
+func (ks *Keyspace) registerStringCommands(m *Mux) {
+	m.HandleFunc(CommandInfo{
+		Name:       "get",
+		Arity:      2,
+		Flags:      FlagReadonly | FlagFast,
+		FirstKey:   1,
+		LastKey:    1,
+		KeyStep:    1,
+		Group:      "string",
+		Summary:    "Returns the string value of a key.",
+		Since:      "1.0.0",
+		Complexity: "O(1)",
+	}, ks.get)
+
+	m.HandleFunc(CommandInfo{
+		Name:       "set",
+		Arity:      -3,
+		Flags:      FlagWrite | FlagDenyOOM,
+		FirstKey:   1,
+		LastKey:    1,
+		KeyStep:    1,
+		Group:      "string",
+		Summary:    "Sets the string value of a key, ignoring its type. The key is created if it doesn't exist.",
+		Since:      "1.0.0",
+		Complexity: "O(1)",
+	}, ks.set)
+
+	m.HandleFunc(CommandInfo{
+		Name:       "mget",
+		Arity:      -2,
+		Flags:      FlagReadonly | FlagFast,
+		FirstKey:   1,
+		LastKey:    -1,
+		KeyStep:    1,
+		Group:      "string",
+		Summary:    "Atomically returns the string values of one or more keys.",
+		Since:      "1.0.0",
+		Complexity: "O(N) where N is the number of keys to retrieve.",
+	}, ks.mget)
+
+	m.HandleFunc(CommandInfo{
+		Name:       "mset",
+		Arity:      -3,
+		Flags:      FlagWrite | FlagDenyOOM,
+		FirstKey:   1,
+		LastKey:    -1,
+		KeyStep:    2,
+		Group:      "string",
+		Summary:    "Atomically creates or modifies the string values of one or more keys.",
+		Since:      "1.0.1",
+		Complexity: "O(N) where N is the number of keys to set.",
+	}, ks.mset)
+
+	m.HandleFunc(CommandInfo{
+		Name:       "msetnx",
+		Arity:      -3,
+		Flags:      FlagWrite | FlagDenyOOM,
+		FirstKey:   1,
+		LastKey:    -1,
+		KeyStep:    2,
+		Group:      "string",
+		Summary:    "Atomically modifies the string values of one or more keys only when all keys don't exist.",
+		Since:      "1.0.1",
+		Complexity: "O(N) where N is the number of keys to set.",
+	}, ks.msetnx)
+
+	m.HandleFunc(CommandInfo{
+		Name:       "getset",
+		Arity:      3,
+		Flags:      FlagWrite | FlagDenyOOM | FlagFast,
+		FirstKey:   1,
+		LastKey:    1,
+		KeyStep:    1,
+		Group:      "string",
+		Summary:    "Returns the previous string value of a key after setting it to a new value.",
+		Since:      "1.0.0",
+		Complexity: "O(1)",
+	}, ks.getset)
+
+	m.HandleFunc(CommandInfo{
+		Name:       "getdel",
+		Arity:      2,
+		Flags:      FlagWrite | FlagFast,
+		FirstKey:   1,
+		LastKey:    1,
+		KeyStep:    1,
+		Group:      "string",
+		Summary:    "Returns the string value of a key after deleting the key.",
+		Since:      "6.2.0",
+		Complexity: "O(1)",
+	}, ks.getdel)
+
+	m.HandleFunc(CommandInfo{
+		Name:       "getex",
+		Arity:      -2,
+		Flags:      FlagWrite | FlagFast,
+		FirstKey:   1,
+		LastKey:    1,
+		KeyStep:    1,
+		Group:      "string",
+		Summary:    "Returns the string value of a key after setting its expiration time.",
+		Since:      "6.2.0",
+		Complexity: "O(1)",
+	}, ks.getex)
+
+	m.HandleFunc(CommandInfo{
+		Name:       "append",
+		Arity:      3,
+		Flags:      FlagWrite | FlagDenyOOM | FlagFast,
+		FirstKey:   1,
+		LastKey:    1,
+		KeyStep:    1,
+		Group:      "string",
+		Summary:    "Appends a string to the value of a key. Creates the key if it doesn't exist.",
+		Since:      "2.0.0",
+		Complexity: "O(1).",
+	}, ks.append)
+
+	m.HandleFunc(CommandInfo{
+		Name:       "strlen",
+		Arity:      2,
+		Flags:      FlagReadonly | FlagFast,
+		FirstKey:   1,
+		LastKey:    1,
+		KeyStep:    1,
+		Group:      "string",
+		Summary:    "Returns the length of a string value.",
+		Since:      "2.2.0",
+		Complexity: "O(1)",
+	}, ks.strlen)
+
+	m.HandleFunc(CommandInfo{
+		Name:       "setrange",
+		Arity:      4,
+		Flags:      FlagWrite | FlagDenyOOM,
+		FirstKey:   1,
+		LastKey:    1,
+		KeyStep:    1,
+		Group:      "string",
+		Summary:    "Overwrites a part of a string value with another by an offset. Creates the key if it doesn't exist.",
+		Since:      "2.2.0",
+		Complexity: "O(1), not counting the time taken to copy the new string in place.",
+	}, ks.setrange)
+
+	m.HandleFunc(CommandInfo{
+		Name:       "getrange",
+		Arity:      4,
+		Flags:      FlagReadonly,
+		FirstKey:   1,
+		LastKey:    1,
+		KeyStep:    1,
+		Group:      "string",
+		Summary:    "Returns a substring of the string stored at a key.",
+		Since:      "2.4.0",
+		Complexity: "O(N) where N is the length of the returned string.",
+	}, ks.getrange)
+
+	m.HandleFunc(CommandInfo{
+		Name:       "incr",
+		Arity:      2,
+		Flags:      FlagWrite | FlagDenyOOM | FlagFast,
+		FirstKey:   1,
+		LastKey:    1,
+		KeyStep:    1,
+		Group:      "string",
+		Summary:    "Increments the integer value of a key by one. Uses 0 as initial value if the key doesn't exist.",
+		Since:      "1.0.0",
+		Complexity: "O(1)",
+	}, func(c *Conn, cmd *radish.Command) {
+		ks.incrDecr(c, cmd.Args[1], 1)
+	})
+
+	m.HandleFunc(CommandInfo{
+		Name:       "decr",
+		Arity:      2,
+		Flags:      FlagWrite | FlagDenyOOM | FlagFast,
+		FirstKey:   1,
+		LastKey:    1,
+		KeyStep:    1,
+		Group:      "string",
+		Summary:    "Decrements the integer value of a key by one. Uses 0 as initial value if the key doesn't exist.",
+		Since:      "1.0.0",
+		Complexity: "O(1)",
+	}, func(c *Conn, cmd *radish.Command) {
+		ks.incrDecr(c, cmd.Args[1], -1)
+	})
+
+	m.HandleFunc(CommandInfo{
+		Name:       "incrby",
+		Arity:      3,
+		Flags:      FlagWrite | FlagDenyOOM | FlagFast,
+		FirstKey:   1,
+		LastKey:    1,
+		KeyStep:    1,
+		Group:      "string",
+		Summary:    "Increments the integer value of a key by a number. Uses 0 as initial value if the key doesn't exist.",
+		Since:      "1.0.0",
+		Complexity: "O(1)",
+	}, ks.incrby)
+
+	m.HandleFunc(CommandInfo{
+		Name:       "decrby",
+		Arity:      3,
+		Flags:      FlagWrite | FlagDenyOOM | FlagFast,
+		FirstKey:   1,
+		LastKey:    1,
+		KeyStep:    1,
+		Group:      "string",
+		Summary:    "Decrements a number from the integer value of a key. Uses 0 as initial value if the key doesn't exist.",
+		Since:      "1.0.0",
+		Complexity: "O(1)",
+	}, ks.decrby)
+
+	m.HandleFunc(CommandInfo{
+		Name:       "incrbyfloat",
+		Arity:      3,
+		Flags:      FlagWrite | FlagDenyOOM | FlagFast,
+		FirstKey:   1,
+		LastKey:    1,
+		KeyStep:    1,
+		Group:      "string",
+		Summary:    "Increment the floating point value of a key by a number. Uses 0 as initial value if the key doesn't exist.",
+		Since:      "2.6.0",
+		Complexity: "O(1)",
+	}, ks.incrbyfloat)
+}
+
+// writeStringOrNull writes the s as a bulk string or the null if the s is
+// nil.
+func writeStringOrNull(w *radish.Writer, s []byte) {
+	if s == nil {
+		_ = w.WriteNull()
+		return
+	}
+	_ = w.WriteBytes(s)
+}
+
+func (ks *Keyspace) get(c *Conn, cmd *radish.Command) {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	s, err := ks.lookupString(cmd.Args[1])
+	if err != nil {
+		_ = c.Writer().WriteError(errWrongType)
+		return
+	}
+
+	writeStringOrNull(c.Writer(), s)
+}
+
+func (ks *Keyspace) set(c *Conn, cmd *radish.Command) {
+	w := c.Writer()
+
+	opts, e := ks.parseStringOptions(cmd.Args[3:], true)
+	if e != nil {
+		_ = w.WriteError(e)
+		return
+	}
+
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	key := cmd.Args[1]
+	old := ks.lookup(key)
+
+	var oldString []byte
+	if opts.flags&stringGet != 0 && old != nil {
+		var ok bool
+		oldString, ok = old.([]byte)
+		if !ok {
+			_ = w.WriteError(errWrongType)
+			return
+		}
+	}
+
+	if (opts.flags&stringNX != 0 && old != nil) || (opts.flags&stringXX != 0 && old == nil) {
+		if opts.flags&stringGet != 0 {
+			writeStringOrNull(w, oldString)
+		} else {
+			_ = w.WriteNull()
+		}
+		return
+	}
+
+	ks.setKey(key, cmd.Args[2].Bytes(), opts.flags&stringKeepTTL != 0)
+	if opts.flags&stringExpire != 0 {
+		ks.setExpire(key, opts.expire)
+	}
+
+	if opts.flags&stringGet != 0 {
+		writeStringOrNull(w, oldString)
+	} else {
+		_ = w.WriteSimpleString("OK")
+	}
+}
+
+func (ks *Keyspace) mget(c *Conn, cmd *radish.Command) {
+	w := c.Writer()
+
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	keys := cmd.Args[1:]
+	_ = w.WriteArray(len(keys))
+	for _, key := range keys {
+		// Values of other types are returned as nulls.
+		s, _ := ks.lookupString(key)
+		writeStringOrNull(w, s)
+	}
+}
+
+func (ks *Keyspace) mset(c *Conn, cmd *radish.Command) {
+	if len(cmd.Args)%2 != 1 {
+		_ = writeWrongArity(c.Writer(), "mset")
+		return
+	}
+
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	for i := 1; i < len(cmd.Args); i += 2 {
+		ks.setKey(cmd.Args[i], cmd.Args[i+1].Bytes(), false)
+	}
+
+	_ = c.Writer().WriteSimpleString("OK")
+}
+
+func (ks *Keyspace) msetnx(c *Conn, cmd *radish.Command) {
+	if len(cmd.Args)%2 != 1 {
+		_ = writeWrongArity(c.Writer(), "msetnx")
+		return
+	}
+
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	for i := 1; i < len(cmd.Args); i += 2 {
+		if ks.lookup(cmd.Args[i]) != nil {
+			_ = c.Writer().WriteInt(0)
+			return
+		}
+	}
+
+	for i := 1; i < len(cmd.Args); i += 2 {
+		ks.setKey(cmd.Args[i], cmd.Args[i+1].Bytes(), false)
+	}
+
+	_ = c.Writer().WriteInt(1)
+}
+
+func (ks *Keyspace) getset(c *Conn, cmd *radish.Command) {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	key := cmd.Args[1]
+	old, err := ks.lookupString(key)
+	if err != nil {
+		_ = c.Writer().WriteError(errWrongType)
+		return
+	}
+
+	ks.setKey(key, cmd.Args[2].Bytes(), false)
+
+	writeStringOrNull(c.Writer(), old)
+}
+
+func (ks *Keyspace) getdel(c *Conn, cmd *radish.Command) {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	key := cmd.Args[1]
+	s, err := ks.lookupString(key)
+	if err != nil {
+		_ = c.Writer().WriteError(errWrongType)
+		return
+	}
+
+	if s != nil {
+		ks.delete(key)
+	}
+
+	writeStringOrNull(c.Writer(), s)
+}
+
+func (ks *Keyspace) getex(c *Conn, cmd *radish.Command) {
+	w := c.Writer()
+
+	opts, e := ks.parseStringOptions(cmd.Args[2:], false)
+	if e != nil {
+		_ = w.WriteError(e)
+		return
+	}
+
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	key := cmd.Args[1]
+	s, err := ks.lookupString(key)
+	if err != nil {
+		_ = w.WriteError(errWrongType)
+		return
+	}
+	if s == nil {
+		_ = w.WriteNull()
+		return
+	}
+
+	switch {
+	case opts.flags&stringExpire != 0:
+		ks.setExpire(key, opts.expire)
+	case opts.flags&stringPersist != 0:
+		delete(ks.expires, string(key))
+	}
+
+	_ = w.WriteBytes(s)
+}
+
+func (ks *Keyspace) append(c *Conn, cmd *radish.Command) {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	key, value := cmd.Args[1], cmd.Args[2]
+	s, err := ks.lookupString(key)
+	if err != nil {
+		_ = c.Writer().WriteError(errWrongType)
+		return
+	}
+
+	if s == nil {
+		s = value.Bytes()
+		ks.setKey(key, s, false)
+	} else {
+		if !checkStringLength(int64(len(s)) + int64(len(value))) {
+			_ = c.Writer().WriteError(errStringTooLong)
+			return
+		}
+
+		s = append(s, value...)
+		ks.setKey(key, s, true)
+	}
+
+	_ = c.Writer().WriteInt(len(s))
+}
+
+func (ks *Keyspace) strlen(c *Conn, cmd *radish.Command) {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	s, err := ks.lookupString(cmd.Args[1])
+	if err != nil {
+		_ = c.Writer().WriteError(errWrongType)
+		return
+	}
+
+	_ = c.Writer().WriteInt(len(s))
+}
+
+func (ks *Keyspace) setrange(c *Conn, cmd *radish.Command) {
+	w := c.Writer()
+
+	key, value := cmd.Args[1], cmd.Args[3]
+	offset, ok := parseInt(cmd.Args[2])
+	if !ok {
+		_ = w.WriteError(errNotInteger)
+		return
+	}
+	if offset < 0 {
+		_ = w.WriteError(errOffsetRange)
+		return
+	}
+
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	s, err := ks.lookupString(key)
+	if err != nil {
+		_ = w.WriteError(errWrongType)
+		return
+	}
+
+	// Nothing to set, return the current length.
+	if len(value) == 0 {
+		_ = w.WriteInt(len(s))
+		return
+	}
+
+	// Compare without the addition, which overflows for huge offsets.
+	if offset > maxStringLength-int64(len(value)) {
+		_ = w.WriteError(errStringTooLong)
+		return
+	}
+
+	if end := int(offset) + len(value); end > len(s) {
+		s = append(s, make([]byte, end-len(s))...)
+	}
+	copy(s[offset:], value)
+	ks.setKey(key, s, true)
+
+	_ = w.WriteInt(len(s))
+}
+
+func (ks *Keyspace) getrange(c *Conn, cmd *radish.Command) {
+	w := c.Writer()
+
+	start, ok := parseInt(cmd.Args[2])
+	if !ok {
+		_ = w.WriteError(errNotInteger)
+		return
+	}
+	end, ok := parseInt(cmd.Args[3])
+	if !ok {
+		_ = w.WriteError(errNotInteger)
+		return
+	}
+
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	s, err := ks.lookupString(cmd.Args[1])
+	if err != nil {
+		_ = w.WriteError(errWrongType)
+		return
+	}
+
+	// The same rules as Redis uses.
+	length := int64(len(s))
+	if start < 0 && end < 0 && start > end {
+		_ = w.WriteString("")
+		return
+	}
+	if start < 0 {
+		start += length
+	}
+	if end < 0 {
+		end += length
+	}
+	if start < 0 {
+		start = 0
+	}
+	if end < 0 {
+		end = 0
+	}
+	if end >= length {
+		end = length - 1
+	}
+	if length == 0 || start > end {
+		_ = w.WriteString("")
+		return
+	}
+
+	_ = w.WriteBytes(s[start : end+1])
+}
+
+func (ks *Keyspace) incrby(c *Conn, cmd *radish.Command) {
+	incr, ok := parseInt(cmd.Args[2])
+	if !ok {
+		_ = c.Writer().WriteError(errNotInteger)
+		return
+	}
+
+	ks.incrDecr(c, cmd.Args[1], incr)
+}
+
+func (ks *Keyspace) decrby(c *Conn, cmd *radish.Command) {
+	decr, ok := parseInt(cmd.Args[2])
+	if !ok {
+		_ = c.Writer().WriteError(errNotInteger)
+		return
+	}
+	if decr == math.MinInt64 {
+		_ = c.Writer().WriteError(errDecrOverflow)
+		return
+	}
+
+	ks.incrDecr(c, cmd.Args[1], -decr)
+}
+
+func (ks *Keyspace) incrDecr(c *Conn, key []byte, incr int64) {
+	w := c.Writer()
+
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	s, err := ks.lookupString(key)
+	if err != nil {
+		_ = w.WriteError(errWrongType)
+		return
+	}
+
+	var value int64
+	if s != nil {
+		var ok bool
+		value, ok = parseInt(s)
+		if !ok {
+			_ = w.WriteError(errNotInteger)
+			return
+		}
+	}
+
+	if (incr < 0 && value < 0 && incr < math.MinInt64-value) ||
+		(incr > 0 && value > 0 && incr > math.MaxInt64-value) {
+		_ = w.WriteError(errIncrOverflow)
+		return
+	}
+	value += incr
+
+	ks.setKey(key, strconv.AppendInt(nil, value, 10), true)
+
+	_ = w.WriteInt64(value)
+}
+
+func (ks *Keyspace) incrbyfloat(c *Conn, cmd *radish.Command) {
+	w := c.Writer()
+
+	incr, ok := parseFloat(cmd.Args[2])
+	if !ok {
+		_ = w.WriteError(errNotFloat)
+		return
+	}
+
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	key := cmd.Args[1]
+	s, err := ks.lookupString(key)
+	if err != nil {
+		_ = w.WriteError(errWrongType)
+		return
+	}
+
+	var value float64
+	if s != nil {
+		value, ok = parseFloat(s)
+		if !ok {
+			_ = w.WriteError(errNotFloat)
+			return
+		}
+	}
+
+	value += incr
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		_ = w.WriteError(errIncrNaNOrInfinity)
+		return
+	}
+
+	// The human friendly form without an exponent, like Redis does.
+	s = strconv.AppendFloat(nil, value, 'f', -1, 64)
+	ks.setKey(key, s, true)
+
+	_ = w.WriteBytes(s)
+}
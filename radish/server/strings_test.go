@@ -0,0 +1,259 @@
+package server
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestKeyspace_strings(t *testing.T) {
+	tt := []struct {
+		name  string
+		steps []testStep
+	}{
+		{
+			name: "get and set",
+			steps: []testStep{
+				{"GET foo", "(nil)"},
+				{"SET foo bar", "OK"},
+				{"GET foo", `"bar"`},
+				{"SET foo baz", "OK"},
+				{"GET foo", `"baz"`},
+				{"TYPE foo", "string"},
+				{"TYPE missing", "none"},
+			},
+		},
+		{
+			name: "set nx and xx",
+			steps: []testStep{
+				{"SET foo bar XX", "(nil)"},
+				{"GET foo", "(nil)"},
+				{"SET foo bar NX", "OK"},
+				{"SET foo baz NX", "(nil)"},
+				{"GET foo", `"bar"`},
+				{"SET foo baz XX", "OK"},
+				{"GET foo", `"baz"`},
+				{"SET foo bar NX XX", "(error) ERR syntax error"},
+			},
+		},
+		{
+			name: "set get",
+			steps: []testStep{
+				{"SET foo bar GET", "(nil)"},
+				{"SET foo baz GET", `"bar"`},
+				{"SET foo qux NX GET", `"baz"`},
+				{"GET foo", `"baz"`},
+			},
+		},
+		{
+			name: "set options errors",
+			steps: []testStep{
+				{"SET foo bar EX", "(error) ERR syntax error"},
+				{"SET foo bar EX 10 PX 100", "(error) ERR syntax error"},
+				{"SET foo bar EX 10 KEEPTTL", "(error) ERR syntax error"},
+				{"SET foo bar UNKNOWN", "(error) ERR syntax error"},
+				{"SET foo bar EX abc", "(error) ERR value is not an integer or out of range"},
+				{"SET foo bar EX 0", "(error) ERR invalid expire time in 'set' command"},
+				{"SET foo bar PX -1", "(error) ERR invalid expire time in 'set' command"},
+				{"SET foo bar EX 9223372036854775807", "(error) ERR invalid expire time in 'set' command"},
+				{"GET foo", "(nil)"},
+			},
+		},
+		{
+			name: "set expire",
+			steps: []testStep{
+				{"SET foo bar EX 100", "OK"},
+				{"GET foo", `"bar"`},
+				{"SET foo bar PXAT 1", "OK"},
+				{"GET foo", "(nil)"},
+				{"SET foo bar EXAT 1", "OK"},
+				{"EXISTS foo", "(integer) 0"},
+			},
+		},
+		{
+			name: "del and exists",
+			steps: []testStep{
+				{"MSET a 1 b 2 c 3", "OK"},
+				{"EXISTS a b missing a", "(integer) 3"},
+				{"DEL a b missing", "(integer) 2"},
+				{"EXISTS a b c", "(integer) 1"},
+			},
+		},
+		{
+			name: "mget and mset",
+			steps: []testStep{
+				{"MSET a 1 b 2", "OK"},
+				{"MGET a missing b", `["1", (nil), "2"]`},
+				{"MSET a 1 b", "(error) ERR wrong number of arguments for 'mset' command"},
+				{"MSETNX b 3 c 4", "(integer) 0"},
+				{"MSETNX c 3 d 4", "(integer) 1"},
+				{"MGET c d", `["3", "4"]`},
+			},
+		},
+		{
+			name: "getset getdel getex",
+			steps: []testStep{
+				{"GETSET foo bar", "(nil)"},
+				{"GETSET foo baz", `"bar"`},
+				{"GETEX foo EX 100", `"baz"`},
+				{"GETEX foo PERSIST", `"baz"`},
+				{"GETEX foo KEEPTTL", "(error) ERR syntax error"},
+				{"GETEX foo EX 0", "(error) ERR invalid expire time in 'getex' command"},
+				{"GETEX missing", "(nil)"},
+				{"GETDEL foo", `"baz"`},
+				{"GETDEL foo", "(nil)"},
+				{"SET foo bar", "OK"},
+				{"GETEX foo PXAT 1", `"bar"`},
+				{"GET foo", "(nil)"},
+			},
+		},
+		{
+			name: "append and strlen",
+			steps: []testStep{
+				{"STRLEN foo", "(integer) 0"},
+				{"APPEND foo hello", "(integer) 5"},
+				{"APPEND foo -world", "(integer) 11"},
+				{"GET foo", `"hello-world"`},
+				{"STRLEN foo", "(integer) 11"},
+			},
+		},
+		{
+			name: "setrange and getrange",
+			steps: []testStep{
+				{"SETRANGE foo 3 bar", "(integer) 6"},
+				{"GET foo", `"\x00\x00\x00bar"`},
+				{"SET foo hello-world", "OK"},
+				{"SETRANGE foo 6 redis", "(integer) 11"},
+				{"GET foo", `"hello-redis"`},
+				{"SETRANGE foo -1 x", "(error) ERR offset is out of range"},
+				{"SETRANGE foo 536870912 x", "(error) ERR string exceeds maximum allowed size (proto-max-bulk-len)"},
+				{"SETRANGE foo 9223372036854775807 x", "(error) ERR string exceeds maximum allowed size (proto-max-bulk-len)"},
+				{"GETRANGE foo 0 4", `"hello"`},
+				{"GETRANGE foo -5 -1", `"redis"`},
+				{"GETRANGE foo 6 100", `"redis"`},
+				{"GETRANGE foo 5 1", `""`},
+				{"GETRANGE foo -1 -5", `""`},
+				{"GETRANGE missing 0 -1", `""`},
+				{"GETRANGE foo a 1", "(error) ERR value is not an integer or out of range"},
+			},
+		},
+		{
+			name: "incr and decr",
+			steps: []testStep{
+				{"INCR foo", "(integer) 1"},
+				{"INCR foo", "(integer) 2"},
+				{"DECR foo", "(integer) 1"},
+				{"INCRBY foo 10", "(integer) 11"},
+				{"DECRBY foo 20", "(integer) -9"},
+				{"GET foo", `"-9"`},
+				{"DECR missing", "(integer) -1"},
+				{"INCRBY foo abc", "(error) ERR value is not an integer or out of range"},
+				{"INCRBY foo 1.5", "(error) ERR value is not an integer or out of range"},
+				{"INCRBY foo 99999999999999999999", "(error) ERR value is not an integer or out of range"},
+				{"SET foo 9223372036854775806", "OK"},
+				{"INCR foo", "(integer) 9223372036854775807"},
+				{"INCR foo", "(error) ERR increment or decrement would overflow"},
+				{"SET foo -9223372036854775807", "OK"},
+				{"DECR foo", "(integer) -9223372036854775808"},
+				{"DECR foo", "(error) ERR increment or decrement would overflow"},
+				{"DECRBY foo -9223372036854775808", "(error) ERR decrement would overflow"},
+				{"SET foo bar", "OK"},
+				{"INCR foo", "(error) ERR value is not an integer or out of range"},
+				{"SET foo 01", "OK"},
+				{"INCR foo", "(error) ERR value is not an integer or out of range"},
+				{"SET foo -", "OK"},
+				{"INCR foo", "(error) ERR value is not an integer or out of range"},
+			},
+		},
+		{
+			name: "incrbyfloat",
+			steps: []testStep{
+				{"INCRBYFLOAT foo 10.5", `"10.5"`},
+				{"INCRBYFLOAT foo 0.1", `"10.6"`},
+				{"INCRBYFLOAT foo -5", `"5.6"`},
+				{"SET foo 5.0e3", "OK"},
+				{"INCRBYFLOAT foo 2.0e2", `"5200"`},
+				{"INCRBYFLOAT foo abc", "(error) ERR value is not a valid float"},
+				{"INCRBYFLOAT foo nan", "(error) ERR value is not a valid float"},
+				{"INCRBYFLOAT foo inf", "(error) ERR increment would produce NaN or Infinity"},
+				{"GET foo", `"5200"`},
+				{"SET foo bar", "OK"},
+				{"INCRBYFLOAT foo 1", "(error) ERR value is not a valid float"},
+			},
+		},
+		{
+			name: "incr keeps ttl",
+			steps: []testStep{
+				{"SET foo 1 EX 100", "OK"},
+				{"INCR foo", "(integer) 2"},
+				{"TTL foo", "(integer) 100"},
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			c := dialTestClient(t, startTestKeyspaceServer(t, NewKeyspace()))
+			runTestSteps(t, c, tc.steps)
+		})
+	}
+}
+
+func TestKeyspace_set_emptyValue(t *testing.T) {
+	c := dialTestClient(t, startTestKeyspaceServer(t, NewKeyspace()))
+
+	if got := c.do("SET", "foo", ""); got != "OK" {
+		t.Fatalf("SET = %s, want OK", got)
+	}
+	if got := c.do("GET", "foo"); got != `""` {
+		t.Errorf("GET = %s, want %q", got, "")
+	}
+	if got := c.do("SETRANGE", "foo", "0", ""); got != "(integer) 0" {
+		t.Errorf("SETRANGE = %s, want (integer) 0", got)
+	}
+}
+
+// Args of commands alias the pooled Command buffers, which are reused by the
+// Reader of the connection for the next pipelined commands. The keyspace must
+// copy them.
+func TestKeyspace_pipelinedArgsAreCopied(t *testing.T) {
+	c := dialTestClient(t, startTestKeyspaceServer(t, NewKeyspace()))
+
+	const n = 100
+
+	key := func(i int) string { return "key-" + strconv.Itoa(i) }
+	value := func(i int) string { return strings.Repeat(strconv.Itoa(i), 10+i) }
+
+	for i := 0; i < n; i++ {
+		c.send("SET", key(i), value(i))
+		c.send("APPEND", key(i), "!")
+		c.send("MSET", key(i)+"-a", value(i), key(i)+"-b", value(i))
+	}
+	c.flush()
+
+	for i := 0; i < n; i++ {
+		for _, want := range []string{"OK", "(integer) " + strconv.Itoa(len(value(i))+1), "OK"} {
+			if got := c.read(); got != want {
+				t.Fatalf("reply #%d = %s, want %s", i, got, want)
+			}
+		}
+	}
+
+	for i := 0; i < n; i++ {
+		c.send("GET", key(i))
+		c.send("MGET", key(i)+"-a", key(i)+"-b")
+	}
+	c.flush()
+
+	for i := 0; i < n; i++ {
+		want := strconv.Quote(value(i) + "!")
+		if got := c.read(); got != want {
+			t.Errorf("GET %s = %s, want %s", key(i), got, want)
+		}
+
+		want = "[" + strconv.Quote(value(i)) + ", " + strconv.Quote(value(i)) + "]"
+		if got := c.read(); got != want {
+			t.Errorf("MGET %s-a %s-b = %s, want %s", key(i), key(i), got, want)
+		}
+	}
+}
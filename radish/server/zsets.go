@@ -0,0 +1,1411 @@
+package server
+
+import (
+	"math"
+	"sort"
+
+	"github.com/SuperPaintman/mini-redis/radish"
+)
+
+var (
+	errMinMaxNotFloat     = &radish.Error{Kind: "ERR", Msg: "min or max is not a float"}
+	errMinMaxNotLex       = &radish.Error{Kind: "ERR", Msg: "min or max not valid string range item"}
+	errScoreNaN           = &radish.Error{Kind: "ERR", Msg: "resulting score is not a number (NaN)"}
+	errWeightNotFloat     = &radish.Error{Kind: "ERR", Msg: "weight value is not a float"}
+	errZaddNXAndXX        = &radish.Error{Kind: "ERR", Msg: "XX and NX options at the same time are not compatible"}
+	errZaddGTLTAndNX      = &radish.Error{Kind: "ERR", Msg: "GT, LT, and/or NX options at the same time are not compatible"}
+	errZaddIncrPair       = &radish.Error{Kind: "ERR", Msg: "INCR option supports a single increment-element pair"}
+	errZrangeLimit        = &radish.Error{Kind: "ERR", Msg: "syntax error, LIMIT is only supported in combination with either BYSCORE or BYLEX"}
+	errZrangeLexAndScores = &radish.Error{Kind: "ERR", Msg: "syntax error, WITHSCORES not supported in combination with BYLEX"}
+)
+
+// zset is a sorted set: a skiplist of members ordered by score, and a map for
+// O(1) lookups of scores.
+type zset struct {
+	dict map[string]float64
+	zsl  *skiplist
+}
+
+func newZset() *zset {
+	return &zset{
+		dict: make(map[string]float64),
+		zsl:  newSkiplist(),
+	}
+}
+
+func (zs *zset) Len() int {
+	return len(zs.dict)
+}
+
+// Score returns the score of the member.
+func (zs *zset) Score(member []byte) (float64, bool) {
+	score, ok := zs.dict[string(member)]
+	return score, ok
+}
+
+// Remove removes the member and reports whether it was in the set.
+func (zs *zset) Remove(member string) bool {
+	score, ok := zs.dict[member]
+	if !ok {
+		return false
+	}
+
+	delete(zs.dict, member)
+	zs.zsl.Delete(score, member)
+	return true
+}
+
+// Flags of the zsetAdd.
+const (
+	zaddNX = 1 << iota
+	zaddXX
+	zaddGT
+	zaddLT
+	zaddIncr
+	zaddCH
+)
+
+// Results of the zsetAdd.
+const (
+	zaddNop = iota
+	zaddAdded
+	zaddUpdated
+	zaddNaN
+)
+
+// Add adds the member or updates its score according to the flags, like the
+// Redis zsetAdd. It returns the result and the new score of the member.
+func (zs *zset) Add(score float64, member []byte, flags int) (int, float64) {
+	if math.IsNaN(score) {
+		return zaddNaN, 0
+	}
+
+	cur, exists := zs.dict[string(member)]
+	if exists {
+		if flags&zaddNX != 0 {
+			return zaddNop, cur
+		}
+
+		if flags&zaddIncr != 0 {
+			score += cur
+			if math.IsNaN(score) {
+				return zaddNaN, 0
+			}
+		}
+
+		if (flags&zaddLT != 0 && score >= cur) || (flags&zaddGT != 0 && score <= cur) {
+			return zaddNop, cur
+		}
+
+		if score == cur {
+			return zaddNop, score
+		}
+
+		m := string(member)
+		zs.zsl.Delete(cur, m)
+		zs.zsl.Insert(score, m)
+		zs.dict[m] = score
+		return zaddUpdated, score
+	}
+
+	if flags&zaddXX != 0 {
+		return zaddNop, 0
+	}
+
+	m := string(member)
+	zs.zsl.Insert(score, m)
+	zs.dict[m] = score
+	return zaddAdded, score
+}
+
+// lookupZset returns the sorted set value of the key or nil if the key does
+// not exist.
+func (ks *Keyspace) lookupZset(key []byte) (*zset, error) {
+	v := ks.lookup(key)
+	if v == nil {
+		return nil, nil
+	}
+
+	zs, ok := v.(*zset)
+	if !ok {
+		return nil, errWrongType
+	}
+	return zs, nil
+}
+
+// parseScore parses a score, where infinities are allowed.
+func parseScore(b []byte) (float64, bool) {
+	return parseFloat(b)
+}
+
+// parseScoreRange parses the bounds of a score range, prefixed with "(" if
+// they are exclusive.
+func parseScoreRange(min, max []byte) (r scoreRange, err *radish.Error) {
+	var ok bool
+	r.min, r.minex, ok = parseScoreBound(min)
+	if !ok {
+		return r, errMinMaxNotFloat
+	}
+	r.max, r.maxex, ok = parseScoreBound(max)
+	if !ok {
+		return r, errMinMaxNotFloat
+	}
+	return r, nil
+}
+
+func parseScoreBound(b []byte) (score float64, ex bool, ok bool) {
+	if len(b) > 0 && b[0] == '(' {
+		ex = true
+		b = b[1:]
+	}
+
+	score, ok = parseScore(b)
+	return score, ex, ok
+}
+
+// parseLexRange parses the bounds of a lexicographical range: "-", "+", or a
+// value prefixed with "[" if it is inclusive and "(" if it is exclusive.
+func parseLexRange(min, max []byte) (r lexRange, err *radish.Error) {
+	var ok bool
+	r.min, ok = parseLexBound(min)
+	if !ok {
+		return r, errMinMaxNotLex
+	}
+	r.max, ok = parseLexBound(max)
+	if !ok {
+		return r, errMinMaxNotLex
+	}
+	return r, nil
+}
+
+func parseLexBound(b []byte) (lexBound, bool) {
+	if len(b) == 0 {
+		return lexBound{}, false
+	}
+
+	switch b[0] {
+	case '-':
+		if len(b) != 1 {
+			return lexBound{}, false
+		}
+		return lexBound{inf: -1}, true
+	case '+':
+		if len(b) != 1 {
+			return lexBound{}, false
+		}
+		return lexBound{inf: 1}, true
+	case '[':
+		return lexBound{value: string(b[1:])}, true
+	case '(':
+		return lexBound{value: string(b[1:]), ex: true}, true
+	default:
+		return lexBound{}, false
+	}
+}
+
+func (ks *Keyspace) registerZsetCommands(m *Mux) {
+	m.HandleFunc(CommandInfo{
+		Name:       "zadd",
+		Arity:      -4,
+		Flags:      FlagWrite | FlagDenyOOM | FlagFast,
+		FirstKey:   1,
+		LastKey:    1,
+		KeyStep:    1,
+		Group:      "sorted-set",
+		Summary:    "Adds one or more members to a sorted set, or updates their scores. Creates the key if it doesn't exist.",
+		Since:      "1.2.0",
+		Complexity: "O(log(N)) for each item added, where N is the number of elements in the sorted set.",
+	}, ks.zadd)
+
+	m.HandleFunc(CommandInfo{
+		Name:       "zincrby",
+		Arity:      4,
+		Flags:      FlagWrite | FlagDenyOOM | FlagFast,
+		FirstKey:   1,
+		LastKey:    1,
+		KeyStep:    1,
+		Group:      "sorted-set",
+		Summary:    "Increments the score of a member in a sorted set.",
+		Since:      "1.2.0",
+		Complexity: "O(log(N)) where N is the number of elements in the sorted set.",
+	}, ks.zincrby)
+
+	m.HandleFunc(CommandInfo{
+		Name:       "zrem",
+		Arity:      -3,
+		Flags:      FlagWrite | FlagFast,
+		FirstKey:   1,
+		LastKey:    1,
+		KeyStep:    1,
+		Group:      "sorted-set",
+		Summary:    "Removes one or more members from a sorted set. Deletes the sorted set if all members were removed.",
+		Since:      "1.2.0",
+		Complexity: "O(M*log(N)) with N being the number of elements in the sorted set and M the number of elements to be removed.",
+	}, ks.zrem)
+
+	m.HandleFunc(CommandInfo{
+		Name:       "zcard",
+		Arity:      2,
+		Flags:      FlagReadonly | FlagFast,
+		FirstKey:   1,
+		LastKey:    1,
+		KeyStep:    1,
+		Group:      "sorted-set",
+		Summary:    "Returns the number of members in a sorted set.",
+		Since:      "1.2.0",
+		Complexity: "O(1)",
+	}, ks.zcard)
+
+	m.HandleFunc(CommandInfo{
+		Name:       "zscore",
+		Arity:      3,
+		Flags:      FlagReadonly | FlagFast,
+		FirstKey:   1,
+		LastKey:    1,
+		KeyStep:    1,
+		Group:      "sorted-set",
+		Summary:    "Returns the score of a member in a sorted set.",
+		Since:      "1.2.0",
+		Complexity: "O(1)",
+	}, ks.zscore)
+
+	m.HandleFunc(CommandInfo{
+		Name:       "zmscore",
+		Arity:      -3,
+		Flags:      FlagReadonly | FlagFast,
+		FirstKey:   1,
+		LastKey:    1,
+		KeyStep:    1,
+		Group:      "sorted-set",
+		Summary:    "Returns the score of one or more members in a sorted set.",
+		Since:      "6.2.0",
+		Complexity: "O(N) where N is the number of members being requested.",
+	}, ks.zmscore)
+
+	m.HandleFunc(CommandInfo{
+		Name:       "zrank",
+		Arity:      -3,
+		Flags:      FlagReadonly | FlagFast,
+		FirstKey:   1,
+		LastKey:    1,
+		KeyStep:    1,
+		Group:      "sorted-set",
+		Summary:    "Returns the index of a member in a sorted set ordered by ascending scores.",
+		Since:      "2.0.0",
+		Complexity: "O(log(N))",
+	}, func(c *Conn, cmd *radish.Command) {
+		ks.zrankGeneric(c, cmd, false)
+	})
+
+	m.HandleFunc(CommandInfo{
+		Name:       "zrevrank",
+		Arity:      -3,
+		Flags:      FlagReadonly | FlagFast,
+		FirstKey:   1,
+		LastKey:    1,
+		KeyStep:    1,
+		Group:      "sorted-set",
+		Summary:    "Returns the index of a member in a sorted set ordered by descending scores.",
+		Since:      "2.0.0",
+		Complexity: "O(log(N))",
+	}, func(c *Conn, cmd *radish.Command) {
+		ks.zrankGeneric(c, cmd, true)
+	})
+
+	m.HandleFunc(CommandInfo{
+		Name:       "zcount",
+		Arity:      4,
+		Flags:      FlagReadonly | FlagFast,
+		FirstKey:   1,
+		LastKey:    1,
+		KeyStep:    1,
+		Group:      "sorted-set",
+		Summary:    "Returns the count of members in a sorted set that have scores within a range.",
+		Since:      "2.0.0",
+		Complexity: "O(log(N)) with N being the number of elements in the sorted set.",
+	}, ks.zcount)
+
+	m.HandleFunc(CommandInfo{
+		Name:       "zlexcount",
+		Arity:      4,
+		Flags:      FlagReadonly | FlagFast,
+		FirstKey:   1,
+		LastKey:    1,
+		KeyStep:    1,
+		Group:      "sorted-set",
+		Summary:    "Returns the number of members in a sorted set within a lexicographical range.",
+		Since:      "2.8.9",
+		Complexity: "O(log(N)) with N being the number of elements in the sorted set.",
+	}, ks.zlexcount)
+
+	m.HandleFunc(CommandInfo{
+		Name:       "zrange",
+		Arity:      -4,
+		Flags:      FlagReadonly,
+		FirstKey:   1,
+		LastKey:    1,
+		KeyStep:    1,
+		Group:      "sorted-set",
+		Summary:    "Returns members in a sorted set within a range of indexes.",
+		Since:      "1.2.0",
+		Complexity: "O(log(N)+M) with N being the number of elements in the sorted set and M the number of elements returned.",
+	}, func(c *Conn, cmd *radish.Command) {
+		ks.zrangeGeneric(c, cmd.Args[1], nil, cmd.Args[2:])
+	})
+
+	m.HandleFunc(CommandInfo{
+		Name:       "zrangestore",
+		Arity:      -5,
+		Flags:      FlagWrite | FlagDenyOOM,
+		FirstKey:   1,
+		LastKey:    2,
+		KeyStep:    1,
+		Group:      "sorted-set",
+		Summary:    "Stores a range of members from sorted set in a key.",
+		Since:      "6.2.0",
+		Complexity: "O(log(N)+M) with N being the number of elements in the sorted set and M the number of elements stored into the destination key.",
+	}, func(c *Conn, cmd *radish.Command) {
+		ks.zrangeGeneric(c, cmd.Args[2], cmd.Args[1], cmd.Args[3:])
+	})
+
+	m.HandleFunc(CommandInfo{
+		Name:       "zpopmin",
+		Arity:      -2,
+		Flags:      FlagWrite | FlagFast,
+		FirstKey:   1,
+		LastKey:    1,
+		KeyStep:    1,
+		Group:      "sorted-set",
+		Summary:    "Returns the lowest-scoring members from a sorted set after removing them. Deletes the sorted set if the last member was popped.",
+		Since:      "5.0.0",
+		Complexity: "O(log(N)*M) with N being the number of elements in the sorted set, and M being the number of elements popped.",
+	}, func(c *Conn, cmd *radish.Command) {
+		ks.zpopGeneric(c, cmd, false)
+	})
+
+	m.HandleFunc(CommandInfo{
+		Name:       "zpopmax",
+		Arity:      -2,
+		Flags:      FlagWrite | FlagFast,
+		FirstKey:   1,
+		LastKey:    1,
+		KeyStep:    1,
+		Group:      "sorted-set",
+		Summary:    "Returns the highest-scoring members from a sorted set after removing them. Deletes the sorted set if the last member was popped.",
+		Since:      "5.0.0",
+		Complexity: "O(log(N)*M) with N being the number of elements in the sorted set, and M being the number of elements popped.",
+	}, func(c *Conn, cmd *radish.Command) {
+		ks.zpopGeneric(c, cmd, true)
+	})
+
+	m.HandleFunc(CommandInfo{
+		Name:       "bzpopmin",
+		Arity:      -3,
+		Flags:      FlagWrite | FlagNoScript | FlagFast | FlagBlocking,
+		FirstKey:   1,
+		LastKey:    -2,
+		KeyStep:    1,
+		Group:      "sorted-set",
+		Summary:    "Removes and returns the member with the lowest score from one or more sorted sets. Blocks until a member is available otherwise. Deletes the sorted set if the last element was popped.",
+		Since:      "5.0.0",
+		Complexity: "O(log(N)) with N being the number of elements in the sorted set.",
+	}, func(c *Conn, cmd *radish.Command) {
+		ks.bzpopGeneric(c, cmd, false)
+	})
+
+	m.HandleFunc(CommandInfo{
+		Name:       "bzpopmax",
+		Arity:      -3,
+		Flags:      FlagWrite | FlagNoScript | FlagFast | FlagBlocking,
+		FirstKey:   1,
+		LastKey:    -2,
+		KeyStep:    1,
+		Group:      "sorted-set",
+		Summary:    "Removes and returns the member with the highest score from one or more sorted sets. Blocks until a member available otherwise. Deletes the sorted set if the last element was popped.",
+		Since:      "5.0.0",
+		Complexity: "O(log(N)) with N being the number of elements in the sorted set.",
+	}, func(c *Conn, cmd *radish.Command) {
+		ks.bzpopGeneric(c, cmd, true)
+	})
+
+	m.HandleFunc(CommandInfo{
+		Name:       "zunionstore",
+		Arity:      -4,
+		Flags:      FlagWrite | FlagDenyOOM,
+		FirstKey:   1,
+		LastKey:    1,
+		KeyStep:    1,
+		Group:      "sorted-set",
+		Summary:    "Stores the union of multiple sorted sets in a key.",
+		Since:      "2.0.0",
+		Complexity: "O(N)+O(M log(M)) with N being the sum of the sizes of the input sorted sets, and M being the number of elements in the resulting sorted set.",
+	}, func(c *Conn, cmd *radish.Command) {
+		ks.zsetStoreGeneric(c, cmd, "zunionstore", setUnion)
+	})
+
+	m.HandleFunc(CommandInfo{
+		Name:       "zinterstore",
+		Arity:      -4,
+		Flags:      FlagWrite | FlagDenyOOM,
+		FirstKey:   1,
+		LastKey:    1,
+		KeyStep:    1,
+		Group:      "sorted-set",
+		Summary:    "Stores the intersect of multiple sorted sets in a key.",
+		Since:      "2.0.0",
+		Complexity: "O(N*K)+O(M*log(M)) worst case with N being the smallest input sorted set, K being the number of input sorted sets and M being the number of elements in the resulting sorted set.",
+	}, func(c *Conn, cmd *radish.Command) {
+		ks.zsetStoreGeneric(c, cmd, "zinterstore", setInter)
+	})
+}
+
+func (ks *Keyspace) zadd(c *Conn, cmd *radish.Command) {
+	w := c.Writer()
+
+	args := cmd.Args[2:]
+
+	var flags int
+options:
+	for len(args) > 0 {
+		switch arg := args[0]; {
+		case equalFold(arg, "NX"):
+			flags |= zaddNX
+		case equalFold(arg, "XX"):
+			flags |= zaddXX
+		case equalFold(arg, "GT"):
+			flags |= zaddGT
+		case equalFold(arg, "LT"):
+			flags |= zaddLT
+		case equalFold(arg, "CH"):
+			flags |= zaddCH
+		case equalFold(arg, "INCR"):
+			flags |= zaddIncr
+		default:
+			break options
+		}
+		args = args[1:]
+	}
+
+	if len(args) == 0 || len(args)%2 != 0 {
+		_ = w.WriteError(errSyntax)
+		return
+	}
+	if flags&zaddNX != 0 && flags&zaddXX != 0 {
+		_ = w.WriteError(errZaddNXAndXX)
+		return
+	}
+	if (flags&zaddGT != 0 && flags&zaddNX != 0) ||
+		(flags&zaddLT != 0 && flags&zaddNX != 0) ||
+		(flags&zaddGT != 0 && flags&zaddLT != 0) {
+		_ = w.WriteError(errZaddGTLTAndNX)
+		return
+	}
+	if flags&zaddIncr != 0 && len(args) > 2 {
+		_ = w.WriteError(errZaddIncrPair)
+		return
+	}
+
+	// Parse all scores before the set is modified.
+	scores := make([]float64, len(args)/2)
+	for i := range scores {
+		score, ok := parseScore(args[i*2])
+		if !ok {
+			_ = w.WriteError(errNotFloat)
+			return
+		}
+		scores[i] = score
+	}
+
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	key := cmd.Args[1]
+	zs, err := ks.lookupZset(key)
+	if err != nil {
+		_ = w.WriteError(errWrongType)
+		return
+	}
+	if zs == nil {
+		if flags&zaddXX != 0 {
+			if flags&zaddIncr != 0 {
+				_ = w.WriteNull()
+			} else {
+				_ = w.WriteInt(0)
+			}
+			return
+		}
+
+		zs = newZset()
+		ks.setKey(key, zs, false)
+	}
+
+	var (
+		added, updated int
+		result         int
+		score          float64
+	)
+	for i := range scores {
+		result, score = zs.Add(scores[i], args[i*2+1], flags)
+		switch result {
+		case zaddNaN:
+			// Only an increment can produce a NaN.
+			if zs.Len() == 0 {
+				ks.delete(key)
+			}
+			_ = w.WriteError(errScoreNaN)
+			return
+		case zaddAdded:
+			added++
+		case zaddUpdated:
+			updated++
+		}
+	}
+
+	if zs.Len() == 0 {
+		ks.delete(key)
+	} else if added > 0 {
+		ks.signalKeyAsReady(key)
+	}
+
+	if flags&zaddIncr != 0 {
+		if result == zaddNop && (flags&(zaddNX|zaddXX|zaddGT|zaddLT)) != 0 {
+			_ = w.WriteNull()
+		} else {
+			_ = w.WriteDouble(score)
+		}
+		return
+	}
+
+	if flags&zaddCH != 0 {
+		_ = w.WriteInt(added + updated)
+	} else {
+		_ = w.WriteInt(added)
+	}
+}
+
+func (ks *Keyspace) zincrby(c *Conn, cmd *radish.Command) {
+	w := c.Writer()
+
+	incr, ok := parseScore(cmd.Args[2])
+	if !ok {
+		_ = w.WriteError(errNotFloat)
+		return
+	}
+
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	key := cmd.Args[1]
+	zs, err := ks.lookupZset(key)
+	if err != nil {
+		_ = w.WriteError(errWrongType)
+		return
+	}
+
+	created := zs == nil
+	if created {
+		zs = newZset()
+	}
+
+	result, score := zs.Add(incr, cmd.Args[3], zaddIncr)
+	if result == zaddNaN {
+		_ = w.WriteError(errScoreNaN)
+		return
+	}
+
+	if created {
+		ks.setKey(key, zs, false)
+	}
+	if result == zaddAdded {
+		ks.signalKeyAsReady(key)
+	}
+
+	_ = w.WriteDouble(score)
+}
+
+func (ks *Keyspace) zrem(c *Conn, cmd *radish.Command) {
+	w := c.Writer()
+
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	key := cmd.Args[1]
+	zs, err := ks.lookupZset(key)
+	if err != nil {
+		_ = w.WriteError(errWrongType)
+		return
+	}
+	if zs == nil {
+		_ = w.WriteInt(0)
+		return
+	}
+
+	removed := 0
+	for _, member := range cmd.Args[2:] {
+		if zs.Remove(string(member)) {
+			removed++
+		}
+	}
+
+	if zs.Len() == 0 {
+		ks.delete(key)
+	}
+
+	_ = w.WriteInt(removed)
+}
+
+func (ks *Keyspace) zcard(c *Conn, cmd *radish.Command) {
+	w := c.Writer()
+
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	zs, err := ks.lookupZset(cmd.Args[1])
+	if err != nil {
+		_ = w.WriteError(errWrongType)
+		return
+	}
+	if zs == nil {
+		_ = w.WriteInt(0)
+		return
+	}
+
+	_ = w.WriteInt(zs.Len())
+}
+
+func (ks *Keyspace) zscore(c *Conn, cmd *radish.Command) {
+	w := c.Writer()
+
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	zs, err := ks.lookupZset(cmd.Args[1])
+	if err != nil {
+		_ = w.WriteError(errWrongType)
+		return
+	}
+	if zs == nil {
+		_ = w.WriteNull()
+		return
+	}
+
+	score, ok := zs.Score(cmd.Args[2])
+	if !ok {
+		_ = w.WriteNull()
+		return
+	}
+
+	_ = w.WriteDouble(score)
+}
+
+func (ks *Keyspace) zmscore(c *Conn, cmd *radish.Command) {
+	w := c.Writer()
+
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	zs, err := ks.lookupZset(cmd.Args[1])
+	if err != nil {
+		_ = w.WriteError(errWrongType)
+		return
+	}
+
+	members := cmd.Args[2:]
+	_ = w.WriteArray(len(members))
+	for _, member := range members {
+		if zs == nil {
+			_ = w.WriteNull()
+			continue
+		}
+
+		if score, ok := zs.Score(member); ok {
+			_ = w.WriteDouble(score)
+		} else {
+			_ = w.WriteNull()
+		}
+	}
+}
+
+// zrankGeneric implements ZRANK and ZREVRANK.
+func (ks *Keyspace) zrankGeneric(c *Conn, cmd *radish.Command, reverse bool) {
+	w := c.Writer()
+
+	var withScore bool
+	switch {
+	case len(cmd.Args) == 3:
+	case len(cmd.Args) == 4 && equalFold(cmd.Args[3], "WITHSCORE"):
+		withScore = true
+	default:
+		_ = w.WriteError(errSyntax)
+		return
+	}
+
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	zs, err := ks.lookupZset(cmd.Args[1])
+	if err != nil {
+		_ = w.WriteError(errWrongType)
+		return
+	}
+
+	var (
+		score float64
+		ok    bool
+	)
+	if zs != nil {
+		score, ok = zs.Score(cmd.Args[2])
+	}
+	if !ok {
+		if withScore {
+			_ = w.WriteArray(-1)
+		} else {
+			_ = w.WriteNull()
+		}
+		return
+	}
+
+	rank := zs.zsl.Rank(score, string(cmd.Args[2]))
+	if reverse {
+		rank = zs.Len() - rank
+	} else {
+		rank--
+	}
+
+	if withScore {
+		_ = w.WriteArray(2)
+		_ = w.WriteInt(rank)
+		_ = w.WriteDouble(score)
+		return
+	}
+
+	_ = w.WriteInt(rank)
+}
+
+func (ks *Keyspace) zcount(c *Conn, cmd *radish.Command) {
+	w := c.Writer()
+
+	r, e := parseScoreRange(cmd.Args[2], cmd.Args[3])
+	if e != nil {
+		_ = w.WriteError(e)
+		return
+	}
+
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	zs, err := ks.lookupZset(cmd.Args[1])
+	if err != nil {
+		_ = w.WriteError(errWrongType)
+		return
+	}
+	if zs == nil {
+		_ = w.WriteInt(0)
+		return
+	}
+
+	first := zs.zsl.FirstInRange(&r)
+	if first == nil {
+		_ = w.WriteInt(0)
+		return
+	}
+	last := zs.zsl.LastInRange(&r)
+
+	_ = w.WriteInt(zs.zsl.Rank(last.score, last.member) - zs.zsl.Rank(first.score, first.member) + 1)
+}
+
+func (ks *Keyspace) zlexcount(c *Conn, cmd *radish.Command) {
+	w := c.Writer()
+
+	r, e := parseLexRange(cmd.Args[2], cmd.Args[3])
+	if e != nil {
+		_ = w.WriteError(e)
+		return
+	}
+
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	zs, err := ks.lookupZset(cmd.Args[1])
+	if err != nil {
+		_ = w.WriteError(errWrongType)
+		return
+	}
+	if zs == nil {
+		_ = w.WriteInt(0)
+		return
+	}
+
+	first := zs.zsl.FirstInLexRange(&r)
+	if first == nil {
+		_ = w.WriteInt(0)
+		return
+	}
+	last := zs.zsl.LastInLexRange(&r)
+
+	_ = w.WriteInt(zs.zsl.Rank(last.score, last.member) - zs.zsl.Rank(first.score, first.member) + 1)
+}
+
+// Types of ranges of ZRANGE.
+const (
+	zrangeRank = iota
+	zrangeScore
+	zrangeLex
+)
+
+// zrangeGeneric implements ZRANGE and ZRANGESTORE. The dst is nil for ZRANGE.
+// The args start with the min and max.
+func (ks *Keyspace) zrangeGeneric(c *Conn, key, dst radish.Arg, args []radish.Arg) {
+	w := c.Writer()
+
+	var (
+		by         = zrangeRank
+		reverse    bool
+		withScores bool
+		hasLimit   bool
+		offset     int64
+		count      int64 = -1
+	)
+
+	for i := 2; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case equalFold(arg, "BYSCORE") && by == zrangeRank:
+			by = zrangeScore
+		case equalFold(arg, "BYLEX") && by == zrangeRank:
+			by = zrangeLex
+		case equalFold(arg, "REV"):
+			reverse = true
+		case equalFold(arg, "WITHSCORES") && dst == nil:
+			withScores = true
+		case equalFold(arg, "LIMIT") && i+2 < len(args):
+			var ok1, ok2 bool
+			offset, ok1 = parseInt(args[i+1])
+			count, ok2 = parseInt(args[i+2])
+			if !ok1 || !ok2 {
+				_ = w.WriteError(errNotInteger)
+				return
+			}
+			hasLimit = true
+			i += 2
+		default:
+			_ = w.WriteError(errSyntax)
+			return
+		}
+	}
+
+	if hasLimit && by == zrangeRank {
+		_ = w.WriteError(errZrangeLimit)
+		return
+	}
+	if withScores && by == zrangeLex {
+		_ = w.WriteError(errZrangeLexAndScores)
+		return
+	}
+
+	// The reverse ranges of scores and members are given from max to min.
+	min, max := args[0], args[1]
+	if reverse && by != zrangeRank {
+		min, max = max, min
+	}
+
+	var (
+		start, stop int64
+		sr          scoreRange
+		lr          lexRange
+		e           *radish.Error
+	)
+	switch by {
+	case zrangeRank:
+		var ok1, ok2 bool
+		start, ok1 = parseInt(min)
+		stop, ok2 = parseInt(max)
+		if !ok1 || !ok2 {
+			e = errNotInteger
+		}
+	case zrangeScore:
+		sr, e = parseScoreRange(min, max)
+	case zrangeLex:
+		lr, e = parseLexRange(min, max)
+	}
+	if e != nil {
+		_ = w.WriteError(e)
+		return
+	}
+
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	zs, err := ks.lookupZset(key)
+	if err != nil {
+		_ = w.WriteError(errWrongType)
+		return
+	}
+
+	var nodes []*skiplistNode
+	if zs != nil {
+		switch by {
+		case zrangeRank:
+			nodes = zs.rangeByRank(start, stop, reverse)
+		case zrangeScore:
+			nodes = zs.rangeByScore(&sr, reverse, offset, count)
+		case zrangeLex:
+			nodes = zs.rangeByLex(&lr, reverse, offset, count)
+		}
+	}
+
+	if dst != nil {
+		if len(nodes) == 0 {
+			ks.delete(dst)
+			_ = w.WriteInt(0)
+			return
+		}
+
+		result := newZset()
+		for _, x := range nodes {
+			result.Add(x.score, []byte(x.member), 0)
+		}
+		ks.setKey(dst, result, false)
+		ks.signalKeyAsReady(dst)
+
+		_ = w.WriteInt(result.Len())
+		return
+	}
+
+	writeZsetNodes(w, nodes, withScores)
+}
+
+func writeZsetNodes(w *radish.Writer, nodes []*skiplistNode, withScores bool) {
+	if withScores {
+		_ = w.WriteArray(len(nodes) * 2)
+	} else {
+		_ = w.WriteArray(len(nodes))
+	}
+	for _, x := range nodes {
+		_ = w.WriteString(x.member)
+		if withScores {
+			_ = w.WriteDouble(x.score)
+		}
+	}
+}
+
+// rangeByRank returns the nodes in the range of ranks, which are 0-based and
+// may be negative.
+func (zs *zset) rangeByRank(start, stop int64, reverse bool) []*skiplistNode {
+	from, to := normalizeRange(start, stop, zs.Len())
+	if from == to {
+		return nil
+	}
+
+	nodes := make([]*skiplistNode, 0, to-from)
+	if reverse {
+		x := zs.zsl.ByRank(zs.Len() - from)
+		for i := from; i < to; i++ {
+			nodes = append(nodes, x)
+			x = x.backward
+		}
+	} else {
+		x := zs.zsl.ByRank(from + 1)
+		for i := from; i < to; i++ {
+			nodes = append(nodes, x)
+			x = x.level[0].forward
+		}
+	}
+	return nodes
+}
+
+// rangeByScore returns the nodes in the range of scores, skipping offset
+// nodes and returning at most count nodes if it is not negative.
+func (zs *zset) rangeByScore(r *scoreRange, reverse bool, offset, count int64) []*skiplistNode {
+	if offset < 0 {
+		return nil
+	}
+
+	var x *skiplistNode
+	if reverse {
+		x = zs.zsl.LastInRange(r)
+	} else {
+		x = zs.zsl.FirstInRange(r)
+	}
+
+	var nodes []*skiplistNode
+	for ; x != nil && count != 0; count-- {
+		if reverse && !r.gteMin(x.score) || !reverse && !r.lteMax(x.score) {
+			break
+		}
+
+		if offset > 0 {
+			offset--
+			count++
+		} else {
+			nodes = append(nodes, x)
+		}
+
+		if reverse {
+			x = x.backward
+		} else {
+			x = x.level[0].forward
+		}
+	}
+	return nodes
+}
+
+// rangeByLex returns the nodes in the lexicographical range, skipping offset
+// nodes and returning at most count nodes if it is not negative.
+func (zs *zset) rangeByLex(r *lexRange, reverse bool, offset, count int64) []*skiplistNode {
+	if offset < 0 {
+		return nil
+	}
+
+	var x *skiplistNode
+	if reverse {
+		x = zs.zsl.LastInLexRange(r)
+	} else {
+		x = zs.zsl.FirstInLexRange(r)
+	}
+
+	var nodes []*skiplistNode
+	for ; x != nil && count != 0; count-- {
+		if reverse && !r.gteMin(x.member) || !reverse && !r.lteMax(x.member) {
+			break
+		}
+
+		if offset > 0 {
+			offset--
+			count++
+		} else {
+			nodes = append(nodes, x)
+		}
+
+		if reverse {
+			x = x.backward
+		} else {
+			x = x.level[0].forward
+		}
+	}
+	return nodes
+}
+
+// pop removes and returns the lowest-scoring member, or the highest-scoring
+// one if max is set.
+func (zs *zset) pop(max bool) *skiplistNode {
+	x := zs.zsl.First()
+	if max {
+		x = zs.zsl.Last()
+	}
+
+	zs.Remove(x.member)
+	return x
+}
+
+// zpopGeneric implements ZPOPMIN and ZPOPMAX.
+func (ks *Keyspace) zpopGeneric(c *Conn, cmd *radish.Command, max bool) {
+	w := c.Writer()
+
+	if len(cmd.Args) > 3 {
+		_ = w.WriteError(errSyntax)
+		return
+	}
+
+	count := int64(1)
+	if len(cmd.Args) == 3 {
+		var ok bool
+		count, ok = parseInt(cmd.Args[2])
+		if !ok {
+			_ = w.WriteError(errNotInteger)
+			return
+		}
+		if count < 0 {
+			_ = w.WriteError(errMustBePositive)
+			return
+		}
+	}
+
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	key := cmd.Args[1]
+	zs, err := ks.lookupZset(key)
+	if err != nil {
+		_ = w.WriteError(errWrongType)
+		return
+	}
+	if zs == nil {
+		_ = w.WriteArray(0)
+		return
+	}
+
+	if count > int64(zs.Len()) {
+		count = int64(zs.Len())
+	}
+
+	_ = w.WriteArray(int(count) * 2)
+	for i := int64(0); i < count; i++ {
+		x := zs.pop(max)
+		_ = w.WriteString(x.member)
+		_ = w.WriteDouble(x.score)
+	}
+
+	if zs.Len() == 0 {
+		ks.delete(key)
+	}
+}
+
+// bzpopGeneric implements BZPOPMIN and BZPOPMAX.
+func (ks *Keyspace) bzpopGeneric(c *Conn, cmd *radish.Command, max bool) {
+	w := c.Writer()
+
+	timeout, e := parseTimeout(cmd.Args[len(cmd.Args)-1])
+	if e != nil {
+		_ = w.WriteError(e)
+		return
+	}
+
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	pop := func(key []byte, zs *zset) {
+		x := zs.pop(max)
+		if zs.Len() == 0 {
+			ks.delete(key)
+		}
+
+		_ = w.WriteArray(3)
+		_ = w.WriteBytes(key)
+		_ = w.WriteString(x.member)
+		_ = w.WriteDouble(x.score)
+	}
+
+	keys := cmd.Args[1 : len(cmd.Args)-1]
+	for _, key := range keys {
+		zs, err := ks.lookupZset(key)
+		if err != nil {
+			_ = w.WriteError(errWrongType)
+			return
+		}
+		if zs != nil {
+			pop(key, zs)
+			return
+		}
+	}
+
+	served := ks.block(c, keys, timeout, func(key string) bool {
+		zs, _ := ks.lookupZset([]byte(key))
+		if zs == nil {
+			return false
+		}
+		pop([]byte(key), zs)
+		return true
+	})
+	if !served {
+		_ = w.WriteArray(-1)
+	}
+}
+
+// Aggregate functions of ZUNIONSTORE and ZINTERSTORE.
+const (
+	aggregateSum = iota
+	aggregateMin
+	aggregateMax
+)
+
+func aggregate(a, b float64, fn int) float64 {
+	switch fn {
+	case aggregateMin:
+		return math.Min(a, b)
+	case aggregateMax:
+		return math.Max(a, b)
+	default:
+		// The sum of the opposite infinities is zero, like in Redis.
+		sum := a + b
+		if math.IsNaN(sum) {
+			return 0
+		}
+		return sum
+	}
+}
+
+// zsetStoreInput is an input of ZUNIONSTORE and ZINTERSTORE: a sorted set or
+// a set, whose members have the score of 1.
+type zsetStoreInput struct {
+	zs *zset
+	s  *set
+}
+
+func (in *zsetStoreInput) Len() int {
+	switch {
+	case in.zs != nil:
+		return in.zs.Len()
+	case in.s != nil:
+		return in.s.Len()
+	default:
+		return 0
+	}
+}
+
+func (in *zsetStoreInput) Score(member []byte) (float64, bool) {
+	switch {
+	case in.zs != nil:
+		return in.zs.Score(member)
+	case in.s != nil:
+		return 1, in.s.Has(member)
+	default:
+		return 0, false
+	}
+}
+
+func (in *zsetStoreInput) Range(fn func(member []byte, score float64)) {
+	switch {
+	case in.zs != nil:
+		for x := in.zs.zsl.First(); x != nil; x = x.level[0].forward {
+			fn([]byte(x.member), x.score)
+		}
+	case in.s != nil:
+		in.s.Range(func(member []byte) bool {
+			fn(member, 1)
+			return true
+		})
+	}
+}
+
+// zsetStoreGeneric implements ZUNIONSTORE and ZINTERSTORE.
+func (ks *Keyspace) zsetStoreGeneric(c *Conn, cmd *radish.Command, name string, op int) {
+	w := c.Writer()
+
+	numkeys, ok := parseInt(cmd.Args[2])
+	if !ok {
+		_ = w.WriteError(errNotInteger)
+		return
+	}
+	if numkeys < 1 {
+		_ = w.WriteError(&radish.Error{Kind: "ERR", Msg: "at least 1 input key is needed for '" + name + "' command"})
+		return
+	}
+	if numkeys > int64(len(cmd.Args)-3) {
+		_ = w.WriteError(errSyntax)
+		return
+	}
+
+	keys := cmd.Args[3 : 3+numkeys]
+	args := cmd.Args[3+numkeys:]
+
+	weights := make([]float64, len(keys))
+	for i := range weights {
+		weights[i] = 1
+	}
+	fn := aggregateSum
+
+	for len(args) > 0 {
+		switch {
+		case equalFold(args[0], "WEIGHTS") && len(args) > len(keys):
+			for i := range weights {
+				weight, ok := parseScore(args[1+i])
+				if !ok {
+					_ = w.WriteError(errWeightNotFloat)
+					return
+				}
+				weights[i] = weight
+			}
+			args = args[1+len(keys):]
+
+		case equalFold(args[0], "AGGREGATE") && len(args) > 1:
+			switch {
+			case equalFold(args[1], "SUM"):
+				fn = aggregateSum
+			case equalFold(args[1], "MIN"):
+				fn = aggregateMin
+			case equalFold(args[1], "MAX"):
+				fn = aggregateMax
+			default:
+				_ = w.WriteError(errSyntax)
+				return
+			}
+			args = args[2:]
+
+		default:
+			_ = w.WriteError(errSyntax)
+			return
+		}
+	}
+
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	inputs := make([]zsetStoreInput, len(keys))
+	for i, key := range keys {
+		switch v := ks.lookup(key).(type) {
+		case nil:
+		case *zset:
+			inputs[i].zs = v
+		case *set:
+			inputs[i].s = v
+		default:
+			_ = w.WriteError(errWrongType)
+			return
+		}
+	}
+
+	// weighted returns the weighted score, where the product of zero and an
+	// infinity is zero.
+	weighted := func(score float64, i int) float64 {
+		v := score * weights[i]
+		if math.IsNaN(v) {
+			return 0
+		}
+		return v
+	}
+
+	result := newZset()
+	switch op {
+	case setUnion:
+		scores := make(map[string]float64)
+		for i := range inputs {
+			inputs[i].Range(func(member []byte, score float64) {
+				score = weighted(score, i)
+				if cur, ok := scores[string(member)]; ok {
+					score = aggregate(cur, score, fn)
+				}
+				scores[string(member)] = score
+			})
+		}
+		for member, score := range scores {
+			result.Add(score, []byte(member), 0)
+		}
+
+	case setInter:
+		// Iterate over the smallest input to check as few members as
+		// possible.
+		order := make([]int, len(inputs))
+		for i := range order {
+			order[i] = i
+		}
+		sort.Slice(order, func(i, j int) bool {
+			return inputs[order[i]].Len() < inputs[order[j]].Len()
+		})
+
+		first := order[0]
+		inputs[first].Range(func(member []byte, score float64) {
+			score = weighted(score, first)
+			for _, i := range order[1:] {
+				other, ok := inputs[i].Score(member)
+				if !ok {
+					return
+				}
+				score = aggregate(score, weighted(other, i), fn)
+			}
+			result.Add(score, member, 0)
+		})
+	}
+
+	dst := cmd.Args[1]
+	if result.Len() == 0 {
+		ks.delete(dst)
+		_ = w.WriteInt(0)
+		return
+	}
+
+	ks.setKey(dst, result, false)
+	ks.signalKeyAsReady(dst)
+
+	_ = w.WriteInt(result.Len())
+}
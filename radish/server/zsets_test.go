@@ -0,0 +1,277 @@
+package server
+
+import (
+	"testing"
+)
+
+func TestKeyspace_zsets(t *testing.T) {
+	tt := []struct {
+		name  string
+		steps []testStep
+	}{
+		{
+			name: "add and score",
+			steps: []testStep{
+				{"ZADD z 1 a 2 b 1.5 c", "(integer) 3"},
+				{"ZADD z 3 a", "(integer) 0"},
+				{"ZSCORE z a", `"3"`},
+				{"ZSCORE z c", `"1.5"`},
+				{"ZSCORE z missing", "(nil)"},
+				{"ZSCORE missing a", "(nil)"},
+				{"ZMSCORE z a missing b", `["3", (nil), "2"]`},
+				{"ZCARD z", "(integer) 3"},
+				{"ZCARD missing", "(integer) 0"},
+				{"TYPE z", "zset"},
+				{"ZADD z x a", "(error) ERR value is not a valid float"},
+				{"ZADD z nan a", "(error) ERR value is not a valid float"},
+				{"ZADD z 1", "(error) ERR wrong number of arguments for 'zadd' command"},
+				{"ZADD z 1 a 2", "(error) ERR syntax error"},
+			},
+		},
+		{
+			name: "infinite scores",
+			steps: []testStep{
+				{"ZADD z inf a -inf b +inf c 0.1 d", "(integer) 4"},
+				{"ZSCORE z a", `"inf"`},
+				{"ZSCORE z b", `"-inf"`},
+				{"ZRANGE z 0 -1 WITHSCORES", `["b", "-inf", "d", "0.1", "a", "inf", "c", "inf"]`},
+				{"ZADD z 1e300 e 0.30000000000000004 f", "(integer) 2"},
+				{"ZSCORE z e", `"1e+300"`},
+				{"ZSCORE z f", `"0.30000000000000004"`},
+				{"ZINCRBY z -inf a", "(error) ERR resulting score is not a number (NaN)"},
+				{"ZSCORE z a", `"inf"`},
+			},
+		},
+		{
+			name: "add options",
+			steps: []testStep{
+				{"ZADD z XX 1 a", "(integer) 0"},
+				{"EXISTS z", "(integer) 0"},
+				{"ZADD z NX 1 a", "(integer) 1"},
+				{"ZADD z NX 2 a 2 b", "(integer) 1"},
+				{"ZSCORE z a", `"1"`},
+				{"ZADD z XX 5 a 5 c", "(integer) 0"},
+				{"ZSCORE z a", `"5"`},
+				{"ZSCORE z c", "(nil)"},
+				{"ZADD z CH 6 a 2 b 3 c", "(integer) 2"},
+				{"ZADD z GT CH 1 a 10 b", "(integer) 1"},
+				{"ZADD z LT CH 1 a 20 b", "(integer) 1"},
+				{"ZMSCORE z a b", `["1", "10"]`},
+				{"ZADD z GT 1 new", "(integer) 1"},
+				{"ZADD z NX XX 1 a", "(error) ERR XX and NX options at the same time are not compatible"},
+				{"ZADD z NX GT 1 a", "(error) ERR GT, LT, and/or NX options at the same time are not compatible"},
+				{"ZADD z GT LT 1 a", "(error) ERR GT, LT, and/or NX options at the same time are not compatible"},
+			},
+		},
+		{
+			name: "incr",
+			steps: []testStep{
+				{"ZADD z INCR 2 a", `"2"`},
+				{"ZADD z INCR 2.5 a", `"4.5"`},
+				{"ZADD z NX INCR 1 a", "(nil)"},
+				{"ZADD z XX INCR 1 b", "(nil)"},
+				{"ZADD z GT INCR -1 a", "(nil)"},
+				{"ZADD z INCR 1 a 1 b", "(error) ERR INCR option supports a single increment-element pair"},
+				{"ZINCRBY z 0.5 a", `"5"`},
+				{"ZINCRBY z 1 b", `"1"`},
+				{"ZINCRBY z x b", "(error) ERR value is not a valid float"},
+				{"ZADD inf 0 a", "(integer) 1"},
+				{"ZINCRBY inf inf a", `"inf"`},
+				{"ZADD inf INCR -inf a", "(error) ERR resulting score is not a number (NaN)"},
+			},
+		},
+		{
+			name: "rem and rank",
+			steps: []testStep{
+				{"ZADD z 1 a 2 b 3 c", "(integer) 3"},
+				{"ZRANK z a", "(integer) 0"},
+				{"ZRANK z c", "(integer) 2"},
+				{"ZREVRANK z a", "(integer) 2"},
+				{"ZRANK z c WITHSCORE", `[(integer) 2, "3"]`},
+				{"ZRANK z missing", "(nil)"},
+				{"ZRANK z missing WITHSCORE", "(nil)"},
+				{"ZRANK missing a", "(nil)"},
+				{"ZRANK z a WITHSCORES", "(error) ERR syntax error"},
+				{"ZREM z a missing", "(integer) 1"},
+				{"ZRANK z c", "(integer) 1"},
+				{"ZREM z b c", "(integer) 2"},
+				{"EXISTS z", "(integer) 0"},
+				{"ZREM missing a", "(integer) 0"},
+			},
+		},
+		{
+			name: "count",
+			steps: []testStep{
+				{"ZADD z 1 a 2 b 3 c 4 d", "(integer) 4"},
+				{"ZCOUNT z 2 3", "(integer) 2"},
+				{"ZCOUNT z (1 (4", "(integer) 2"},
+				{"ZCOUNT z -inf +inf", "(integer) 4"},
+				{"ZCOUNT z 5 10", "(integer) 0"},
+				{"ZCOUNT z 3 2", "(integer) 0"},
+				{"ZCOUNT missing 0 1", "(integer) 0"},
+				{"ZCOUNT z x 1", "(error) ERR min or max is not a float"},
+				{"ZADD l 0 a 0 b 0 c 0 d", "(integer) 4"},
+				{"ZLEXCOUNT l - +", "(integer) 4"},
+				{"ZLEXCOUNT l [b (d", "(integer) 2"},
+				{"ZLEXCOUNT l (a [a", "(integer) 0"},
+				{"ZLEXCOUNT l + -", "(integer) 0"},
+				{"ZLEXCOUNT l a b", "(error) ERR min or max not valid string range item"},
+			},
+		},
+		{
+			name: "range by rank",
+			steps: []testStep{
+				{"ZADD z 1 a 2 b 3 c 4 d", "(integer) 4"},
+				{"ZRANGE z 0 -1", `["a", "b", "c", "d"]`},
+				{"ZRANGE z 1 2 WITHSCORES", `["b", "2", "c", "3"]`},
+				{"ZRANGE z -2 100", `["c", "d"]`},
+				{"ZRANGE z 0 1 REV", `["d", "c"]`},
+				{"ZRANGE z 5 10", "[]"},
+				{"ZRANGE missing 0 -1", "[]"},
+				{"ZRANGE z 0 -1 LIMIT 0 1", "(error) ERR syntax error, LIMIT is only supported in combination with either BYSCORE or BYLEX"},
+				{"ZRANGE z a b", "(error) ERR value is not an integer or out of range"},
+			},
+		},
+		{
+			name: "range by score",
+			steps: []testStep{
+				{"ZADD z 1 a 2 b 3 c 4 d", "(integer) 4"},
+				{"ZRANGE z 2 3 BYSCORE", `["b", "c"]`},
+				{"ZRANGE z (1 +inf BYSCORE WITHSCORES", `["b", "2", "c", "3", "d", "4"]`},
+				{"ZRANGE z +inf -inf BYSCORE REV", `["d", "c", "b", "a"]`},
+				{"ZRANGE z 3 (1 BYSCORE REV", `["c", "b"]`},
+				{"ZRANGE z -inf +inf BYSCORE LIMIT 1 2", `["b", "c"]`},
+				{"ZRANGE z -inf +inf BYSCORE LIMIT 1 -1", `["b", "c", "d"]`},
+				{"ZRANGE z +inf -inf BYSCORE REV LIMIT 1 1", `["c"]`},
+				{"ZRANGE z -inf +inf BYSCORE LIMIT -1 1", "[]"},
+				{"ZRANGE z 3 2 BYSCORE", "[]"},
+				{"ZRANGE z x 2 BYSCORE", "(error) ERR min or max is not a float"},
+				{"ZRANGE z 0 1 BYSCORE BYLEX", "(error) ERR syntax error"},
+			},
+		},
+		{
+			name: "range by lex",
+			steps: []testStep{
+				{"ZADD z 0 a 0 b 0 c 0 d", "(integer) 4"},
+				{"ZRANGE z - + BYLEX", `["a", "b", "c", "d"]`},
+				{"ZRANGE z [b (d BYLEX", `["b", "c"]`},
+				{"ZRANGE z + - BYLEX REV", `["d", "c", "b", "a"]`},
+				{"ZRANGE z (d [b BYLEX REV", `["c", "b"]`},
+				{"ZRANGE z - + BYLEX LIMIT 2 5", `["c", "d"]`},
+				{"ZRANGE z - + BYLEX WITHSCORES", "(error) ERR syntax error, WITHSCORES not supported in combination with BYLEX"},
+				{"ZRANGE z b d BYLEX", "(error) ERR min or max not valid string range item"},
+			},
+		},
+		{
+			name: "rangestore",
+			steps: []testStep{
+				{"ZADD z 1 a 2 b 3 c", "(integer) 3"},
+				{"ZRANGESTORE dst z 0 1", "(integer) 2"},
+				{"ZRANGE dst 0 -1 WITHSCORES", `["a", "1", "b", "2"]`},
+				{"ZRANGESTORE dst z (1 +inf BYSCORE", "(integer) 2"},
+				{"ZRANGE dst 0 -1", `["b", "c"]`},
+				{"ZRANGESTORE dst z 5 10", "(integer) 0"},
+				{"EXISTS dst", "(integer) 0"},
+				{"ZRANGESTORE dst z 0 1 WITHSCORES", "(error) ERR syntax error"},
+			},
+		},
+		{
+			name: "pop",
+			steps: []testStep{
+				{"ZADD z 1 a 2 b 3 c 4 d", "(integer) 4"},
+				{"ZPOPMIN z", `["a", "1"]`},
+				{"ZPOPMAX z", `["d", "4"]`},
+				{"ZPOPMIN z 0", "[]"},
+				{"ZPOPMAX z 10", `["c", "3", "b", "2"]`},
+				{"EXISTS z", "(integer) 0"},
+				{"ZPOPMIN z", "[]"},
+				{"ZPOPMIN z -1", "(error) ERR value is out of range, must be positive"},
+				{"ZPOPMIN z 1 2", "(error) ERR syntax error"},
+				{"ZADD b 1 x", "(integer) 1"},
+				{"BZPOPMIN a b 0", `["b", "x", "1"]`},
+				{"BZPOPMAX a b 0.01", "(nil)"},
+			},
+		},
+		{
+			name: "union and inter",
+			steps: []testStep{
+				{"ZADD a 1 x 2 y", "(integer) 2"},
+				{"ZADD b 10 y 20 z", "(integer) 2"},
+				{"SADD s x z", "(integer) 2"},
+				{"ZUNIONSTORE dst 2 a b", "(integer) 3"},
+				{"ZRANGE dst 0 -1 WITHSCORES", `["x", "1", "y", "12", "z", "20"]`},
+				{"ZUNIONSTORE dst 2 a b WEIGHTS 2 0.5", "(integer) 3"},
+				{"ZRANGE dst 0 -1 WITHSCORES", `["x", "2", "y", "9", "z", "10"]`},
+				{"ZUNIONSTORE dst 2 a b AGGREGATE MIN", "(integer) 3"},
+				{"ZRANGE dst 0 -1 WITHSCORES", `["x", "1", "y", "2", "z", "20"]`},
+				{"ZUNIONSTORE dst 3 a b s AGGREGATE MAX", "(integer) 3"},
+				{"ZRANGE dst 0 -1 WITHSCORES", `["x", "1", "y", "10", "z", "20"]`},
+				{"ZINTERSTORE dst 2 a b", "(integer) 1"},
+				{"ZRANGE dst 0 -1 WITHSCORES", `["y", "12"]`},
+				{"ZINTERSTORE dst 2 a s WEIGHTS 1 3", "(integer) 1"},
+				{"ZRANGE dst 0 -1 WITHSCORES", `["x", "4"]`},
+				{"ZINTERSTORE dst 2 a missing", "(integer) 0"},
+				{"EXISTS dst", "(integer) 0"},
+				{"ZUNIONSTORE dst 0 a", "(error) ERR at least 1 input key is needed for 'zunionstore' command"},
+				{"ZINTERSTORE dst 3 a b", "(error) ERR syntax error"},
+				{"ZUNIONSTORE dst 2 a b WEIGHTS 1", "(error) ERR syntax error"},
+				{"ZUNIONSTORE dst 2 a b WEIGHTS 1 x", "(error) ERR weight value is not a float"},
+				{"ZUNIONSTORE dst 2 a b AGGREGATE AVG", "(error) ERR syntax error"},
+			},
+		},
+		{
+			name: "union of infinities",
+			steps: []testStep{
+				{"ZADD a inf x", "(integer) 1"},
+				{"ZADD b -inf x", "(integer) 1"},
+				{"ZUNIONSTORE dst 2 a b", "(integer) 1"},
+				{"ZSCORE dst x", `"0"`},
+				{"ZUNIONSTORE dst 1 a WEIGHTS 0", "(integer) 1"},
+				{"ZSCORE dst x", `"0"`},
+			},
+		},
+		{
+			name: "wrong type",
+			steps: []testStep{
+				{"SET str v", "OK"},
+				{"ZADD str 1 a", "(error) WRONGTYPE Operation against a key holding the wrong kind of value"},
+				{"ZRANGE str 0 -1", "(error) WRONGTYPE Operation against a key holding the wrong kind of value"},
+				{"ZUNIONSTORE dst 1 str", "(error) WRONGTYPE Operation against a key holding the wrong kind of value"},
+				{"BZPOPMIN str 0", "(error) WRONGTYPE Operation against a key holding the wrong kind of value"},
+				{"ZADD z 1 a", "(integer) 1"},
+				{"GET z", "(error) WRONGTYPE Operation against a key holding the wrong kind of value"},
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			c := dialTestClient(t, startTestKeyspaceServer(t, NewKeyspace()))
+
+			runTestSteps(t, c, tc.steps)
+		})
+	}
+}
+
+func TestKeyspace_bzpopmin(t *testing.T) {
+	ks := NewKeyspace()
+	addr := startTestKeyspaceServer(t, ks)
+
+	blocked := dialTestClient(t, addr)
+	c := dialTestClient(t, addr)
+
+	blocked.send("BZPOPMIN", "z", "0")
+	blocked.flush()
+	waitBlocked(t, ks, "z", 1)
+
+	if got, want := c.do("ZADD", "z", "2", "b", "1", "a"), "(integer) 2"; got != want {
+		t.Fatalf("ZADD = %s, want %s", got, want)
+	}
+
+	if got, want := blocked.read(), `["z", "a", "1"]`; got != want {
+		t.Errorf("BZPOPMIN = %s, want %s", got, want)
+	}
+	if got, want := c.do("ZRANGE", "z", "0", "-1"), `["b"]`; got != want {
+		t.Errorf("ZRANGE = %s, want %s", got, want)
+	}
+}
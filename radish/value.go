@@ -0,0 +1,292 @@
+package radish
+
+import (
+	"errors"
+	"math/big"
+)
+
+var (
+	errUnknownDataType = errors.New("radish: unknown data type")
+	errNilError        = errors.New("radish: nil Err of an error value")
+	errNilBigNumber    = errors.New("radish: nil BigNumber of a big number value")
+	errOddElements     = errors.New("radish: odd number of elements of a map or attributes")
+)
+
+// Value is a RESP value of any type, with all nested values of aggregate
+// types. It is read by the Reader.ReadValue and written by the
+// Writer.WriteValue.
+type Value struct {
+	Type DataType
+
+	// Null reports whether the value is a RESP2 null bulk string or null
+	// array. The RESP3 null has the DataTypeNull type.
+	Null bool
+
+	Str       string   // Simple, bulk and verbatim strings.
+	Format    string   // Format of a verbatim string, e.g. "txt".
+	Int       int64    // Integers.
+	Bool      bool     // Booleans.
+	Double    float64  // Doubles.
+	BigNumber *big.Int // Big numbers.
+	Err       *Error   // Errors and bulk errors.
+
+	// Elems are elements of arrays, sets and pushes. For maps they are keys
+	// and values one after another.
+	Elems []Value
+
+	// Attrs are keys and values of the RESP3 attribute of the value one after
+	// another, if it has one.
+	Attrs []Value
+}
+
+// IsNull reports whether the value is a RESP3 null or a RESP2 null bulk
+// string or array.
+func (v *Value) IsNull() bool {
+	return v.Type == DataTypeNull || v.Null
+}
+
+// ReadValue reads a RESP value of any type with all nested values from the
+// underlying reader. An attribute is read together with the value it
+// describes, into its Attrs.
+//
+// The nesting depth of aggregate types is limited by the MaxDepth of the
+// ReaderOptions, deeper values are rejected with the ErrMaxDepth.
+func (r *Reader) ReadValue() (Value, error) {
+	return r.readValueTree(0)
+}
+
+func (r *Reader) readValueTree(depth int) (v Value, err error) {
+	if depth > r.opts.MaxDepth {
+		return v, ErrMaxDepth
+	}
+
+	first, err := r.r.Peek(1)
+	if err != nil {
+		return v, err
+	}
+
+	v.Type = DataType(first[0])
+	switch v.Type {
+	case DataTypeSimpleString:
+		v.Str, err = r.ReadSimpleString()
+
+	case DataTypeError:
+		v.Err, err = r.ReadError()
+
+	case DataTypeInteger:
+		var i int
+		i, err = r.ReadInteger()
+		v.Int = int64(i)
+
+	case DataTypeBulkString:
+		v.Str, v.Null, err = r.ReadString()
+
+	case DataTypeRESP3Null:
+		err = r.ReadNull()
+		v.Type = DataTypeNull
+
+	case DataTypeBoolean:
+		v.Bool, err = r.ReadBool()
+
+	case DataTypeDouble:
+		v.Double, err = r.ReadDouble()
+
+	case DataTypeBigNumber:
+		v.BigNumber, err = r.ReadBigNumber()
+
+	case DataTypeBulkError:
+		v.Err, err = r.ReadBulkError()
+
+	case DataTypeVerbatimString:
+		v.Format, v.Str, err = r.ReadVerbatimString()
+
+	case DataTypeArray, DataTypeSet, DataTypePush:
+		var n int
+		n, err = r.readAggregate(v.Type)
+		if err == nil {
+			v.Elems, v.Null, err = r.readValueElems(n, depth)
+		}
+
+	case DataTypeMap:
+		var n int
+		n, err = r.readAggregate(v.Type)
+		if err == nil {
+			v.Elems, v.Null, err = r.readValueElems(2*n, depth)
+		}
+
+	case DataTypeAttribute:
+		var (
+			n     int
+			attrs []Value
+		)
+		n, err = r.readAggregate(v.Type)
+		if err == nil {
+			attrs, _, err = r.readValueElems(2*n, depth)
+		}
+		if err == nil {
+			v, err = r.readValueTree(depth + 1)
+			v.Attrs = attrs
+		}
+
+	default:
+		_, _, err = r.ReadAny() // Returns the protocol error.
+	}
+
+	return v, err
+}
+
+// readValueElems reads n nested values. A negative n means a null.
+func (r *Reader) readValueElems(n, depth int) (elems []Value, null bool, err error) {
+	if n < 0 {
+		return nil, true, nil
+	}
+
+	// Do not trust the announced length, like the ReadCommand.
+	prealloc := n
+	if prealloc > maxArgsPrealloc {
+		prealloc = maxArgsPrealloc
+	}
+
+	elems = make([]Value, 0, prealloc)
+	for i := 0; i < n; i++ {
+		elem, err := r.readValueTree(depth + 1)
+		if err != nil {
+			return nil, false, err
+		}
+		elems = append(elems, elem)
+	}
+
+	return elems, false, nil
+}
+
+// WriteValue writes the RESP value v with all nested values.
+//
+// The RESP3 types are downgraded in RESP2, see the SetProtocol. Attributes
+// are dropped in RESP2.
+func (w *Writer) WriteValue(v Value) error {
+	// Check the whole value before writing anything.
+	if err := checkValue(&v); err != nil {
+		return err
+	}
+	return w.writeValue(&v)
+}
+
+// checkValue returns an error if the v or any of its nested values can not
+// be written.
+func checkValue(v *Value) error {
+	switch v.Type {
+	case DataTypeSimpleString, DataTypeInteger, DataTypeBulkString, DataTypeNull,
+		DataTypeBoolean, DataTypeDouble, DataTypeArray, DataTypeSet, DataTypePush:
+
+	case DataTypeError, DataTypeBulkError:
+		if v.Err == nil {
+			return errNilError
+		}
+
+	case DataTypeBigNumber:
+		if v.BigNumber == nil {
+			return errNilBigNumber
+		}
+
+	case DataTypeVerbatimString:
+		if len(v.Format) != 3 {
+			return ErrVerbatimFormat
+		}
+
+	case DataTypeMap:
+		if len(v.Elems)%2 != 0 {
+			return errOddElements
+		}
+
+	default:
+		return errUnknownDataType
+	}
+
+	if len(v.Attrs)%2 != 0 {
+		return errOddElements
+	}
+	for i := range v.Attrs {
+		if err := checkValue(&v.Attrs[i]); err != nil {
+			return err
+		}
+	}
+	for i := range v.Elems {
+		if err := checkValue(&v.Elems[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *Writer) writeValue(v *Value) error {
+	if len(v.Attrs) > 0 && w.protover == RESP3 {
+		_ = w.WriteAttribute(len(v.Attrs) / 2)
+		if err := w.writeValues(v.Attrs); err != nil {
+			return err
+		}
+	}
+
+	switch v.Type {
+	case DataTypeSimpleString:
+		return w.WriteSimpleString(v.Str)
+
+	case DataTypeError:
+		return w.WriteError(v.Err)
+
+	case DataTypeInteger:
+		return w.WriteInt64(v.Int)
+
+	case DataTypeBulkString:
+		if v.Null {
+			return w.WriteNull()
+		}
+		return w.WriteString(v.Str)
+
+	case DataTypeNull:
+		return w.WriteNull()
+
+	case DataTypeBoolean:
+		return w.WriteBool(v.Bool)
+
+	case DataTypeDouble:
+		return w.WriteDouble(v.Double)
+
+	case DataTypeBigNumber:
+		return w.WriteBigNumber(v.BigNumber)
+
+	case DataTypeBulkError:
+		return w.WriteBulkError(v.Err)
+
+	case DataTypeVerbatimString:
+		return w.WriteVerbatimString(v.Format, v.Str)
+
+	case DataTypeArray:
+		if v.Null {
+			return w.WriteArray(-1)
+		}
+		_ = w.WriteArray(len(v.Elems))
+
+	case DataTypeMap:
+		_ = w.WriteMap(len(v.Elems) / 2)
+
+	case DataTypeSet:
+		_ = w.WriteSet(len(v.Elems))
+
+	case DataTypePush:
+		_ = w.WritePush(len(v.Elems))
+
+	default:
+		return errUnknownDataType
+	}
+
+	return w.writeValues(v.Elems)
+}
+
+func (w *Writer) writeValues(values []Value) error {
+	for i := range values {
+		if err := w.writeValue(&values[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,296 @@
+package radish
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var testValues = []struct {
+	name  string
+	input string
+	want  Value
+	resp2 bool // RESP2 nulls are written as RESP3 nulls in RESP3.
+}{
+	{
+		name:  "simple string",
+		input: "+OK\r\n",
+		want:  Value{Type: DataTypeSimpleString, Str: "OK"},
+	},
+	{
+		name:  "error",
+		input: "-WRONGTYPE Operation against a key\r\n",
+		want:  Value{Type: DataTypeError, Err: &Error{"WRONGTYPE", "Operation against a key"}},
+	},
+	{
+		name:  "integer",
+		input: ":-1337\r\n",
+		want:  Value{Type: DataTypeInteger, Int: -1337},
+	},
+	{
+		name:  "bulk string",
+		input: "$5\r\nhe\r\no\r\n",
+		want:  Value{Type: DataTypeBulkString, Str: "he\r\no"},
+	},
+	{
+		name:  "null bulk string",
+		input: "$-1\r\n",
+		want:  Value{Type: DataTypeBulkString, Null: true},
+		resp2: true,
+	},
+	{
+		name:  "null array",
+		input: "*-1\r\n",
+		want:  Value{Type: DataTypeArray, Null: true},
+		resp2: true,
+	},
+	{
+		name:  "empty array",
+		input: "*0\r\n",
+		want:  Value{Type: DataTypeArray, Elems: []Value{}},
+	},
+	{
+		name:  "nested array",
+		input: "*3\r\n:1\r\n*2\r\n$1\r\na\r\n$-1\r\n+OK\r\n",
+		want: Value{Type: DataTypeArray, Elems: []Value{
+			{Type: DataTypeInteger, Int: 1},
+			{Type: DataTypeArray, Elems: []Value{
+				{Type: DataTypeBulkString, Str: "a"},
+				{Type: DataTypeBulkString, Null: true},
+			}},
+			{Type: DataTypeSimpleString, Str: "OK"},
+		}},
+		resp2: true,
+	},
+	{
+		name:  "null",
+		input: "_\r\n",
+		want:  Value{Type: DataTypeNull},
+	},
+	{
+		name:  "boolean",
+		input: "#t\r\n",
+		want:  Value{Type: DataTypeBoolean, Bool: true},
+	},
+	{
+		name:  "double",
+		input: ",-1.5\r\n",
+		want:  Value{Type: DataTypeDouble, Double: -1.5},
+	},
+	{
+		name:  "big number",
+		input: "(3492890328409238509324850943850943825024385\r\n",
+		want:  Value{Type: DataTypeBigNumber, BigNumber: mustBigInt("3492890328409238509324850943850943825024385")},
+	},
+	{
+		name:  "bulk error",
+		input: "!21\r\nSYNTAX invalid syntax\r\n",
+		want:  Value{Type: DataTypeBulkError, Err: &Error{"SYNTAX", "invalid syntax"}},
+	},
+	{
+		name:  "verbatim string",
+		input: "=15\r\ntxt:Some string\r\n",
+		want:  Value{Type: DataTypeVerbatimString, Format: "txt", Str: "Some string"},
+	},
+	{
+		name:  "map",
+		input: "%2\r\n+first\r\n:1\r\n+second\r\n~1\r\n#f\r\n",
+		want: Value{Type: DataTypeMap, Elems: []Value{
+			{Type: DataTypeSimpleString, Str: "first"},
+			{Type: DataTypeInteger, Int: 1},
+			{Type: DataTypeSimpleString, Str: "second"},
+			{Type: DataTypeSet, Elems: []Value{
+				{Type: DataTypeBoolean, Bool: false},
+			}},
+		}},
+	},
+	{
+		name:  "push",
+		input: ">2\r\n+message\r\n$2\r\nhi\r\n",
+		want: Value{Type: DataTypePush, Elems: []Value{
+			{Type: DataTypeSimpleString, Str: "message"},
+			{Type: DataTypeBulkString, Str: "hi"},
+		}},
+	},
+	{
+		name:  "attribute",
+		input: "*2\r\n|1\r\n+ttl\r\n:100\r\n$1\r\na\r\n:2\r\n",
+		want: Value{Type: DataTypeArray, Elems: []Value{
+			{
+				Type: DataTypeBulkString,
+				Str:  "a",
+				Attrs: []Value{
+					{Type: DataTypeSimpleString, Str: "ttl"},
+					{Type: DataTypeInteger, Int: 100},
+				},
+			},
+			{Type: DataTypeInteger, Int: 2},
+		}},
+	},
+}
+
+func TestReader_ReadValue(t *testing.T) {
+	for _, tc := range testValues {
+		t.Run(tc.name, func(t *testing.T) {
+			reader := NewReader(strings.NewReader(tc.input))
+
+			got, err := reader.ReadValue()
+			if err != nil {
+				t.Fatalf("ReadValue(): unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("ReadValue() = %+v, want %+v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestWriter_WriteValue(t *testing.T) {
+	for _, tc := range testValues {
+		t.Run(tc.name, func(t *testing.T) {
+			testWriter(t, "WriteValue", []byte(tc.input), func(w *Writer) error {
+				if !tc.resp2 {
+					w.SetProtocol(RESP3)
+				}
+				return w.WriteValue(tc.want)
+			})
+		})
+	}
+}
+
+func TestWriter_WriteValue_resp2(t *testing.T) {
+	v := Value{Type: DataTypeMap, Elems: []Value{
+		{Type: DataTypeSimpleString, Str: "key", Attrs: []Value{
+			{Type: DataTypeSimpleString, Str: "ttl"},
+			{Type: DataTypeInteger, Int: 100},
+		}},
+		{Type: DataTypeSet, Elems: []Value{
+			{Type: DataTypeNull},
+			{Type: DataTypeBoolean, Bool: true},
+			{Type: DataTypeDouble, Double: 1.5},
+		}},
+	}}
+
+	want := []byte("*2\r\n+key\r\n*3\r\n$-1\r\n:1\r\n$3\r\n1.5\r\n")
+	testWriter(t, "WriteValue", want, func(w *Writer) error {
+		return w.WriteValue(v)
+	})
+}
+
+func TestWriter_WriteValue_invalid(t *testing.T) {
+	tt := []struct {
+		name string
+		v    Value
+		want error
+	}{
+		{"unknown", Value{Type: '?'}, errUnknownDataType},
+		{"nil error", Value{Type: DataTypeError}, errNilError},
+		{"nil bulk error", Value{Type: DataTypeBulkError}, errNilError},
+		{"nil big number", Value{Type: DataTypeBigNumber}, errNilBigNumber},
+		{
+			name: "nested nil error",
+			v:    Value{Type: DataTypeArray, Elems: []Value{{Type: DataTypeInteger}, {Type: DataTypeError}}},
+			want: errNilError,
+		},
+		{
+			name: "nested nil big number",
+			v:    Value{Type: DataTypeSet, Elems: []Value{{Type: DataTypeInteger}, {Type: DataTypeBigNumber}}},
+			want: errNilBigNumber,
+		},
+		{
+			name: "nil error in attributes",
+			v: Value{Type: DataTypeInteger, Attrs: []Value{
+				{Type: DataTypeSimpleString, Str: "key"},
+				{Type: DataTypeBulkError},
+			}},
+			want: errNilError,
+		},
+		{
+			name: "nested unknown",
+			v:    Value{Type: DataTypeArray, Elems: []Value{{Type: DataTypeInteger}, {Type: '?'}}},
+			want: errUnknownDataType,
+		},
+		{
+			name: "nested verbatim format",
+			v:    Value{Type: DataTypeArray, Elems: []Value{{Type: DataTypeInteger}, {Type: DataTypeVerbatimString, Format: "text"}}},
+			want: ErrVerbatimFormat,
+		},
+		{
+			name: "odd map",
+			v:    Value{Type: DataTypeMap, Elems: []Value{{Type: DataTypeInteger}}},
+			want: errOddElements,
+		},
+		{
+			name: "nested odd map",
+			v:    Value{Type: DataTypeArray, Elems: []Value{{Type: DataTypeInteger}, {Type: DataTypeMap, Elems: []Value{{Type: DataTypeInteger}}}}},
+			want: errOddElements,
+		},
+		{
+			name: "odd attributes",
+			v:    Value{Type: DataTypeInteger, Attrs: []Value{{Type: DataTypeSimpleString, Str: "key"}}},
+			want: errOddElements,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			w := NewWriter(&buf)
+			w.SetProtocol(RESP3)
+
+			if err := w.WriteValue(tc.v); err != tc.want {
+				t.Errorf("WriteValue() error = %v, want %v", err, tc.want)
+			}
+
+			// Nothing is written for an invalid value.
+			if err := w.Flush(); err != nil {
+				t.Fatalf("Flush(): unexpected error: %v", err)
+			}
+			if buf.Len() != 0 {
+				t.Errorf("WriteValue() wrote %q, want nothing", buf.String())
+			}
+		})
+	}
+}
+
+func TestReader_ReadValue_errors(t *testing.T) {
+	tt := []struct {
+		name  string
+		opts  ReaderOptions
+		input string
+		want  error
+	}{
+		{
+			name:  "max depth",
+			opts:  ReaderOptions{MaxDepth: 2},
+			input: "*1\r\n*1\r\n*1\r\n*1\r\n:1\r\n",
+			want:  ErrMaxDepth,
+		},
+		{
+			name:  "default max depth",
+			input: strings.Repeat("*1\r\n", DefaultMaxDepth+2) + ":1\r\n",
+			want:  ErrMaxDepth,
+		},
+		{
+			name:  "invalid nested value",
+			input: "*2\r\n:1\r\n:x\r\n",
+			want:  ErrIntegerValue,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			reader := NewReaderOptions(strings.NewReader(tc.input), tc.opts)
+
+			if _, err := reader.ReadValue(); err != tc.want {
+				t.Errorf("ReadValue() error = %v, want %v", err, tc.want)
+			}
+		})
+	}
+
+	reader := NewReader(strings.NewReader("*1\r\n" + strings.Repeat("*1\r\n", DefaultMaxDepth-1) + ":1\r\n"))
+	if _, err := reader.ReadValue(); err != nil {
+		t.Errorf("ReadValue() of the max depth: unexpected error: %v", err)
+	}
+}
@@ -2,8 +2,16 @@ package radish
 
 import (
 	"bufio"
+	"encoding"
+	"errors"
+	"fmt"
 	"io"
+	"math"
+	"math/big"
+	"os"
+	"reflect"
 	"strconv"
+	"time"
 )
 
 // DataType represents a RESP data type.
@@ -16,9 +24,44 @@ const (
 	DataTypeInteger      DataType = ':'
 	DataTypeBulkString   DataType = '$'
 	DataTypeArray        DataType = '*'
-	DataTypeNull         DataType = 0
+
+	// DataTypeNull is the type of nulls: both the RESP2 null bulk string and
+	// array, and the RESP3 null. It is not a first byte of any RESP type.
+	DataTypeNull DataType = 0
+
+	// RESP3 data types.
+	DataTypeRESP3Null      DataType = '_'
+	DataTypeBoolean        DataType = '#'
+	DataTypeDouble         DataType = ','
+	DataTypeBigNumber      DataType = '('
+	DataTypeBulkError      DataType = '!'
+	DataTypeVerbatimString DataType = '='
+	DataTypeMap            DataType = '%'
+	DataTypeSet            DataType = '~'
+	DataTypeAttribute      DataType = '|'
+	DataTypePush           DataType = '>'
 )
 
+// Versions of the RESP protocol.
+const (
+	RESP2 = 2
+	RESP3 = 3
+)
+
+// ErrAttributeRESP2 is returned by the Writer.WriteAttribute in RESP2, which
+// has no way to encode attributes.
+var ErrAttributeRESP2 = errors.New("radish: attributes are not supported by RESP2")
+
+// ErrUnknownLength is returned by the Writer.ReadFrom for readers with a length
+// that can not be known without reading them.
+var ErrUnknownLength = errors.New("radish: unknown length of the reader")
+
+// ErrVerbatimFormat is returned by the Writer.WriteVerbatimString for formats
+// that are not three bytes long.
+var ErrVerbatimFormat = errors.New("radish: verbatim string format must be three bytes long")
+
+var errNegativeBulkLength = errors.New("radish: negative bulk length")
+
 // Error represents a RESP error.
 //
 // If a read error or any other non-standard RESP error occurs, the actual
@@ -47,6 +90,7 @@ func (e *Error) Error() string {
 type Writer struct {
 	w        *bufio.Writer
 	smallbuf []byte // A buffer for small values (e.g. results of strconv.AppendInt).
+	protover int
 }
 
 // NewWriter returns a new Writer writing RESP data types.
@@ -57,9 +101,26 @@ func NewWriter(w io.Writer) *Writer {
 	return &Writer{
 		w:        bufio.NewWriter(w),
 		smallbuf: make([]byte, 0, smallbufSize),
+		protover: RESP2,
 	}
 }
 
+// SetProtocol sets the version of the RESP protocol of the Writer: RESP2
+// (default) or RESP3.
+//
+// In RESP2 the RESP3 types are downgraded the same way Redis does it for
+// legacy clients: nulls are written as null bulk strings, booleans as 1 and 0
+// integers, doubles, big numbers and verbatim strings as bulk strings, bulk
+// errors as simple errors, and maps, sets and pushes as flat arrays.
+func (w *Writer) SetProtocol(protover int) {
+	w.protover = protover
+}
+
+// Protocol returns the version of the RESP protocol of the Writer.
+func (w *Writer) Protocol() int {
+	return w.protover
+}
+
 // Reset discards any unflushed buffered data, and resets w to write
 // its output to wr.
 func (w *Writer) Reset(wr io.Writer) {
@@ -131,6 +192,41 @@ func (w *Writer) WriteUint64(i uint64) error {
 	return w.writeTerminator()
 }
 
+// WriteFloat64 writes a RESP bulk string of the 64-bit float, in the shortest
+// form that parses back to the same value, like Redis replies with scores.
+// Infinities are written as "inf" and "-inf".
+func (w *Writer) WriteFloat64(f float64) error {
+	// The smallbuf is used by the writePrefix.
+	var buf [32]byte
+	b := appendFloat(buf[:0], f, 64)
+	_ = w.writePrefix(byte(DataTypeBulkString), len(b))
+	_, _ = w.w.Write(b)
+	return w.writeTerminator()
+}
+
+// WriteFloat32 writes a RESP bulk string of the 32-bit float, in the shortest
+// form that parses back to the same float32, see the WriteFloat64.
+func (w *Writer) WriteFloat32(f float32) error {
+	var buf [32]byte
+	b := appendFloat(buf[:0], float64(f), 32)
+	_ = w.writePrefix(byte(DataTypeBulkString), len(b))
+	_, _ = w.w.Write(b)
+	return w.writeTerminator()
+}
+
+func appendFloat(b []byte, f float64, bitSize int) []byte {
+	switch {
+	case math.IsInf(f, 1):
+		return append(b, "inf"...)
+	case math.IsInf(f, -1):
+		return append(b, "-inf"...)
+	case math.IsNaN(f):
+		return append(b, "nan"...)
+	default:
+		return strconv.AppendFloat(b, f, 'g', -1, bitSize)
+	}
+}
+
 // WriteString writes a RESP bulk string.
 func (w *Writer) WriteString(s string) error {
 	_ = w.writePrefix(byte(DataTypeBulkString), len(s))
@@ -145,17 +241,386 @@ func (w *Writer) WriteBytes(b []byte) error {
 	return w.writeTerminator()
 }
 
-// WriteBytes writes the RESP null.
+// WriteDuration writes a RESP bulk string of the duration in nanoseconds, like
+// the go-redis formats time.Duration arguments. Commands take seconds or
+// milliseconds, e.g. the PX of the SET, so usually the d must be converted
+// into an integer first.
+func (w *Writer) WriteDuration(d time.Duration) error {
+	return w.writeBulkInt(int64(d))
+}
+
+// WriteTime writes a RESP bulk string of the time in the RFC 3339 format with
+// nanoseconds.
+func (w *Writer) WriteTime(t time.Time) error {
+	var buf [64]byte
+	b := t.AppendFormat(buf[:0], time.RFC3339Nano)
+	_ = w.writePrefix(byte(DataTypeBulkString), len(b))
+	_, _ = w.w.Write(b)
+	return w.writeTerminator()
+}
+
+// WriteStringer writes a RESP bulk string of the s.String().
+func (w *Writer) WriteStringer(s fmt.Stringer) error {
+	return w.WriteString(s.String())
+}
+
+// WriteTextMarshaler writes a RESP bulk string of the m.MarshalText(). If the
+// m fails, its error is returned and nothing is written.
+func (w *Writer) WriteTextMarshaler(m encoding.TextMarshaler) error {
+	b, err := m.MarshalText()
+	if err != nil {
+		return err
+	}
+	return w.WriteBytes(b)
+}
+
+// WriteArg writes the v as a RESP bulk string of a command argument, the
+// same way the go-redis formats arguments:
+//   - nil as an empty string;
+//   - strings and []byte as is;
+//   - integers in the decimal form;
+//   - floats in the shortest form, see the WriteFloat64;
+//   - bool as "1" or "0";
+//   - time.Duration in nanoseconds;
+//   - time.Time in the RFC 3339 format with nanoseconds;
+//   - encoding.BinaryMarshaler, encoding.TextMarshaler and fmt.Stringer
+//     values by their methods, in this order.
+//
+// Values of other types are not written, and an *UnsupportedTypeError is
+// returned.
+func (w *Writer) WriteArg(v interface{}) error {
+	switch v := v.(type) {
+	case nil:
+		return w.WriteString("")
+	case string:
+		return w.WriteString(v)
+	case []byte:
+		return w.WriteBytes(v)
+	case int:
+		return w.writeBulkInt(int64(v))
+	case int8:
+		return w.writeBulkInt(int64(v))
+	case int16:
+		return w.writeBulkInt(int64(v))
+	case int32:
+		return w.writeBulkInt(int64(v))
+	case int64:
+		return w.writeBulkInt(v)
+	case uint:
+		return w.writeBulkUint(uint64(v))
+	case uint8:
+		return w.writeBulkUint(uint64(v))
+	case uint16:
+		return w.writeBulkUint(uint64(v))
+	case uint32:
+		return w.writeBulkUint(uint64(v))
+	case uint64:
+		return w.writeBulkUint(v)
+	case float32:
+		return w.WriteFloat32(v)
+	case float64:
+		return w.WriteFloat64(v)
+	case bool:
+		if v {
+			return w.WriteString("1")
+		}
+		return w.WriteString("0")
+	case time.Duration:
+		return w.WriteDuration(v)
+	case time.Time:
+		return w.WriteTime(v)
+	case encoding.BinaryMarshaler:
+		b, err := v.MarshalBinary()
+		if err != nil {
+			return err
+		}
+		return w.WriteBytes(b)
+	case encoding.TextMarshaler:
+		return w.WriteTextMarshaler(v)
+	case fmt.Stringer:
+		return w.WriteStringer(v)
+	default:
+		return &UnsupportedTypeError{reflect.TypeOf(v)}
+	}
+}
+
+// WriteCommand writes a command: a RESP array of bulk strings of the name and
+// the args, formatted by the WriteArg.
+//
+// If one of the args can not be written, its error is returned. The command
+// is partially written in this case, so the output is broken and must be
+// discarded.
+func (w *Writer) WriteCommand(name string, args ...interface{}) error {
+	_ = w.WriteArray(len(args) + 1)
+	err := w.WriteString(name)
+	for _, arg := range args {
+		if err = w.WriteArg(arg); err != nil {
+			return err
+		}
+	}
+	return err
+}
+
+// WriteCommandArgs writes a command of already formatted args, including the
+// name, as a RESP array of bulk strings.
+func (w *Writer) WriteCommandArgs(args [][]byte) error {
+	// Errors of the bufio.Writer are sticky, so the last one is enough.
+	err := w.WriteArray(len(args))
+	for _, arg := range args {
+		err = w.WriteBytes(arg)
+	}
+	return err
+}
+
+func (w *Writer) writeBulkInt(i int64) error {
+	var buf [20]byte
+	b := strconv.AppendInt(buf[:0], i, 10)
+	_ = w.writePrefix(byte(DataTypeBulkString), len(b))
+	_, _ = w.w.Write(b)
+	return w.writeTerminator()
+}
+
+func (w *Writer) writeBulkUint(i uint64) error {
+	var buf [20]byte
+	b := strconv.AppendUint(buf[:0], i, 10)
+	_ = w.writePrefix(byte(DataTypeBulkString), len(b))
+	_, _ = w.w.Write(b)
+	return w.writeTerminator()
+}
+
+// WriteBulkFrom writes a RESP bulk string of n bytes copied from the r, so
+// large values can be written straight from files without copying them into
+// a []byte.
+//
+// If the r has less than n bytes, it returns the io.ErrUnexpectedEOF. The
+// bulk string is partially written in this case, so the output is broken and
+// must be discarded.
+func (w *Writer) WriteBulkFrom(r io.Reader, n int64) error {
+	if n < 0 {
+		return errNegativeBulkLength
+	}
+
+	_ = w.writePrefix(byte(DataTypeBulkString), int(n))
+
+	written, err := io.CopyN(w.w, r, n)
+	if err != nil {
+		if err == io.EOF && written < n {
+			err = io.ErrUnexpectedEOF
+		}
+		return err
+	}
+
+	return w.writeTerminator()
+}
+
+// ReadFrom writes a RESP bulk string with the content of the r read until
+// EOF, and returns the number of bytes read from the r.
+//
+// The length of the bulk string must be known ahead, since the content is
+// streamed: it is taken from the N of an io.LimitedReader, from the Len
+// method of the r (e.g. of bytes.Reader, strings.Reader and bytes.Buffer),
+// or from the Stat method of a regular file. For other readers nothing is
+// written and the ErrUnknownLength is returned, see the WriteBulkFrom.
+func (w *Writer) ReadFrom(r io.Reader) (n int64, err error) {
+	size, ok := readerLen(r)
+	if !ok {
+		return 0, ErrUnknownLength
+	}
+
+	if err := w.WriteBulkFrom(r, size); err != nil {
+		return 0, err
+	}
+	return size, nil
+}
+
+// readerLen returns the number of unread bytes of the r, if it is known.
+func readerLen(r io.Reader) (int64, bool) {
+	switch r := r.(type) {
+	case *io.LimitedReader:
+		return r.N, true
+
+	case interface{ Len() int }:
+		return int64(r.Len()), true
+
+	case interface{ Stat() (os.FileInfo, error) }:
+		fi, err := r.Stat()
+		if err != nil || !fi.Mode().IsRegular() {
+			return 0, false
+		}
+
+		// Only the rest of the file is read.
+		var offset int64
+		if s, ok := r.(io.Seeker); ok {
+			offset, err = s.Seek(0, io.SeekCurrent)
+			if err != nil {
+				return 0, false
+			}
+		}
+		return fi.Size() - offset, true
+
+	default:
+		return 0, false
+	}
+}
+
+// WriteNull writes the RESP null: a null bulk string in RESP2 and the null
+// type in RESP3.
 func (w *Writer) WriteNull() error {
+	if w.protover == RESP3 {
+		_, err := w.w.WriteString("_\r\n")
+		return err
+	}
+
 	_, err := w.w.WriteString("$-1\r\n")
 	return err
 }
 
-// WriteBytes writes a RESP array type of n elements.
+// WriteArray writes a RESP array type of n elements. A negative n writes a
+// null array in RESP2 and the null type in RESP3.
 func (w *Writer) WriteArray(n int) error {
+	if n < 0 && w.protover == RESP3 {
+		return w.WriteNull()
+	}
+
 	return w.writePrefix(byte(DataTypeArray), n)
 }
 
+// WriteBool writes a RESP3 boolean, or a 1 or 0 integer in RESP2.
+func (w *Writer) WriteBool(b bool) error {
+	if w.protover != RESP3 {
+		if b {
+			return w.WriteInt(1)
+		}
+		return w.WriteInt(0)
+	}
+
+	_ = w.writeType(DataTypeBoolean)
+	if b {
+		_ = w.w.WriteByte('t')
+	} else {
+		_ = w.w.WriteByte('f')
+	}
+	return w.writeTerminator()
+}
+
+// WriteDouble writes a RESP3 double, or a bulk string in RESP2. Infinities
+// are written as "inf" and "-inf", a NaN as "nan".
+func (w *Writer) WriteDouble(f float64) error {
+	if w.protover != RESP3 {
+		return w.WriteFloat64(f)
+	}
+
+	var buf [32]byte
+	_ = w.writeType(DataTypeDouble)
+	_, _ = w.w.Write(appendFloat(buf[:0], f, 64))
+	return w.writeTerminator()
+}
+
+// WriteBigNumber writes a RESP3 big number, or a bulk string in RESP2.
+func (w *Writer) WriteBigNumber(n *big.Int) error {
+	var buf [64]byte
+	b := n.Append(buf[:0], 10)
+	if w.protover != RESP3 {
+		return w.WriteBytes(b)
+	}
+
+	_ = w.writeType(DataTypeBigNumber)
+	_, _ = w.w.Write(b)
+	return w.writeTerminator()
+}
+
+// WriteBulkError writes a RESP3 bulk error. Unlike the WriteError, the msg
+// may contain newlines.
+//
+// In RESP2 it is written as a simple error with escaped newlines.
+func (w *Writer) WriteBulkError(e *Error) error {
+	if w.protover != RESP3 {
+		return w.WriteError(e)
+	}
+
+	kind := e.Kind
+	if kind == "" {
+		kind = "ERR"
+	}
+
+	n := len(kind)
+	if e.Msg != "" {
+		n += 1 + len(e.Msg)
+	}
+
+	_ = w.writePrefix(byte(DataTypeBulkError), n)
+	_, _ = w.w.WriteString(kind)
+	if e.Msg != "" {
+		_ = w.w.WriteByte(' ')
+		_, _ = w.w.WriteString(e.Msg)
+	}
+	return w.writeTerminator()
+}
+
+// WriteVerbatimString writes a RESP3 verbatim string. The format must be
+// three bytes long, e.g. "txt" for plain text or "mkd" for markdown,
+// otherwise nothing is written and the ErrVerbatimFormat is returned.
+//
+// In RESP2 only the s is written as a bulk string.
+func (w *Writer) WriteVerbatimString(format, s string) error {
+	if len(format) != 3 {
+		return ErrVerbatimFormat
+	}
+	if w.protover != RESP3 {
+		return w.WriteString(s)
+	}
+
+	_ = w.writePrefix(byte(DataTypeVerbatimString), len(format)+1+len(s))
+	_, _ = w.w.WriteString(format)
+	_ = w.w.WriteByte(':')
+	_, _ = w.w.WriteString(s)
+	return w.writeTerminator()
+}
+
+// WriteMap writes a RESP3 map type of n key-value pairs. The pairs are
+// written next as 2*n values.
+//
+// In RESP2 it is written as a flat array of 2*n elements.
+func (w *Writer) WriteMap(n int) error {
+	if w.protover != RESP3 {
+		return w.WriteArray(2 * n)
+	}
+
+	return w.writePrefix(byte(DataTypeMap), n)
+}
+
+// WriteSet writes a RESP3 set type of n elements, or an array in RESP2.
+func (w *Writer) WriteSet(n int) error {
+	if w.protover != RESP3 {
+		return w.WriteArray(n)
+	}
+
+	return w.writePrefix(byte(DataTypeSet), n)
+}
+
+// WriteAttribute writes a RESP3 attribute type of n key-value pairs. The
+// attribute describes the next value.
+//
+// RESP2 has no attributes, so it writes nothing and returns the
+// ErrAttributeRESP2. Callers must check the Protocol before writing the
+// pairs.
+func (w *Writer) WriteAttribute(n int) error {
+	if w.protover != RESP3 {
+		return ErrAttributeRESP2
+	}
+
+	return w.writePrefix(byte(DataTypeAttribute), n)
+}
+
+// WritePush writes a RESP3 push type of n elements, or an array in RESP2.
+func (w *Writer) WritePush(n int) error {
+	if w.protover != RESP3 {
+		return w.WriteArray(n)
+	}
+
+	return w.writePrefix(byte(DataTypePush), n)
+}
+
 func (w *Writer) writeType(t DataType) error {
 	return w.w.WriteByte(byte(t))
 }
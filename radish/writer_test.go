@@ -2,7 +2,16 @@ package radish
 
 import (
 	"bytes"
+	"errors"
+	"io"
+	"math"
+	"math/big"
+	"os"
+	"reflect"
+	"strings"
 	"testing"
+	"testing/iotest"
+	"time"
 )
 
 var longString string // ~64KB
@@ -231,6 +240,83 @@ func TestWriter_WriteUint64(t *testing.T) {
 	}
 }
 
+var testFloats = []struct {
+	name string
+	f    float64
+	want []byte
+}{
+	{
+		name: "zero",
+		f:    0,
+		want: []byte("$1\r\n0\r\n"),
+	},
+	{
+		name: "integral",
+		f:    -42,
+		want: []byte("$3\r\n-42\r\n"),
+	},
+	{
+		name: "fraction",
+		f:    0.1,
+		want: []byte("$3\r\n0.1\r\n"),
+	},
+	{
+		name: "shortest round trip",
+		f:    1.0 / 3,
+		want: []byte("$18\r\n0.3333333333333333\r\n"),
+	},
+	{
+		name: "large",
+		f:    1e300,
+		want: []byte("$6\r\n1e+300\r\n"),
+	},
+	{
+		name: "max",
+		f:    math.MaxFloat64,
+		want: []byte("$23\r\n1.7976931348623157e+308\r\n"),
+	},
+	{
+		name: "inf",
+		f:    math.Inf(1),
+		want: []byte("$3\r\ninf\r\n"),
+	},
+	{
+		name: "negative inf",
+		f:    math.Inf(-1),
+		want: []byte("$4\r\n-inf\r\n"),
+	},
+}
+
+func TestWriter_WriteFloat64(t *testing.T) {
+	for _, tc := range testFloats {
+		t.Run(tc.name, func(t *testing.T) {
+			testWriter(t, "WriteFloat64", tc.want, func(w *Writer) error {
+				return w.WriteFloat64(tc.f)
+			})
+		})
+	}
+}
+
+func TestWriter_WriteFloat32(t *testing.T) {
+	tt := []struct {
+		name string
+		f    float32
+		want []byte
+	}{
+		{"integer", 2, []byte("$1\r\n2\r\n")},
+		{"fraction", 0.1, []byte("$3\r\n0.1\r\n")},
+		{"inf", float32(math.Inf(-1)), []byte("$4\r\n-inf\r\n")},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			testWriter(t, "WriteFloat32", tc.want, func(w *Writer) error {
+				return w.WriteFloat32(tc.f)
+			})
+		})
+	}
+}
+
 var testBulkStrings = []struct {
 	name string
 	b    []byte
@@ -273,6 +359,290 @@ func TestWriter_WriteBytes(t *testing.T) {
 	}
 }
 
+var testBulkFroms = []struct {
+	name    string
+	r       io.Reader
+	n       int64
+	want    []byte
+	wantErr error
+}{
+	{
+		name: "empty",
+		r:    strings.NewReader(""),
+		n:    0,
+		want: []byte("$0\r\n\r\n"),
+	},
+	{
+		name: "exact",
+		r:    strings.NewReader("hello"),
+		n:    5,
+		want: []byte("$5\r\nhello\r\n"),
+	},
+	{
+		name: "longer source",
+		r:    strings.NewReader("hello world"),
+		n:    5,
+		want: []byte("$5\r\nhello\r\n"),
+	},
+	{
+		name: "one byte reader",
+		r:    iotest.OneByteReader(strings.NewReader("hello")),
+		n:    5,
+		want: []byte("$5\r\nhello\r\n"),
+	},
+	{
+		name:    "short source",
+		r:       strings.NewReader("hel"),
+		n:       5,
+		want:    []byte("$5\r\nhel"),
+		wantErr: io.ErrUnexpectedEOF,
+	},
+	{
+		name:    "source error",
+		r:       iotest.TimeoutReader(iotest.OneByteReader(strings.NewReader("hello"))),
+		n:       5,
+		wantErr: iotest.ErrTimeout,
+	},
+}
+
+func TestWriter_WriteBulkFrom(t *testing.T) {
+	for _, tc := range testBulkFroms {
+		t.Run(tc.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			w := NewWriter(&buf)
+
+			if err := w.WriteBulkFrom(tc.r, tc.n); err != tc.wantErr {
+				t.Fatalf("WriteBulkFrom() error = %v, want %v", err, tc.wantErr)
+			}
+			if err := w.Flush(); err != nil {
+				t.Fatalf("Flush(): unexpected error: %v", err)
+			}
+
+			if tc.want != nil && !bytes.Equal(buf.Bytes(), tc.want) {
+				t.Errorf("WriteBulkFrom() = %q, want %q", buf.Bytes(), tc.want)
+			}
+		})
+	}
+}
+
+func TestWriter_ReadFrom(t *testing.T) {
+	f, err := os.CreateTemp("", "radish")
+	if err != nil {
+		t.Fatalf("unexpected error: failed to create a file: %v", err)
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	if _, err := f.WriteString("skip hello from file"); err != nil {
+		t.Fatalf("unexpected error: failed to write the file: %v", err)
+	}
+	if _, err := f.Seek(int64(len("skip ")), io.SeekStart); err != nil {
+		t.Fatalf("unexpected error: failed to seek the file: %v", err)
+	}
+
+	tt := []struct {
+		name  string
+		r     io.Reader
+		want  []byte
+		wantN int64
+	}{
+		{"strings.Reader", strings.NewReader("hello"), []byte("$5\r\nhello\r\n"), 5},
+		{"bytes.Buffer", bytes.NewBufferString("hello world"), []byte("$11\r\nhello world\r\n"), 11},
+		{"file", f, []byte("$15\r\nhello from file\r\n"), 15},
+		{"io.LimitedReader", io.LimitReader(iotest.OneByteReader(strings.NewReader("hello world")), 5), []byte("$5\r\nhello\r\n"), 5},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			w := NewWriter(&buf)
+
+			n, err := w.ReadFrom(tc.r)
+			if err != nil {
+				t.Fatalf("ReadFrom(): unexpected error: %v", err)
+			}
+			if n != tc.wantN {
+				t.Errorf("ReadFrom() = %d, want %d", n, tc.wantN)
+			}
+			if err := w.Flush(); err != nil {
+				t.Fatalf("Flush(): unexpected error: %v", err)
+			}
+
+			if !bytes.Equal(buf.Bytes(), tc.want) {
+				t.Errorf("ReadFrom() wrote %q, want %q", buf.Bytes(), tc.want)
+			}
+		})
+	}
+}
+
+func TestWriter_ReadFrom_unknownLength(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+
+	n, err := w.ReadFrom(iotest.OneByteReader(strings.NewReader("hello")))
+	if err != ErrUnknownLength {
+		t.Fatalf("ReadFrom(): got error %v, want %v", err, ErrUnknownLength)
+	}
+	if n != 0 {
+		t.Errorf("ReadFrom() = %d, want 0", n)
+	}
+	_ = w.Flush()
+	if buf.Len() != 0 {
+		t.Errorf("ReadFrom() wrote %q, want nothing", buf.Bytes())
+	}
+}
+
+type testArgStringer struct{}
+
+func (testArgStringer) String() string { return "stringer" }
+
+type testArgBinaryMarshaler struct{}
+
+func (testArgBinaryMarshaler) MarshalBinary() ([]byte, error) { return []byte("\x00\x01\r\n"), nil }
+
+func (testArgBinaryMarshaler) MarshalText() ([]byte, error) { return []byte("text"), nil }
+
+type testArgTextMarshaler struct{ err error }
+
+func (m testArgTextMarshaler) MarshalText() ([]byte, error) { return []byte("text"), m.err }
+
+func TestWriter_WriteArg(t *testing.T) {
+	tt := []struct {
+		name string
+		v    interface{}
+		want []byte
+	}{
+		{"nil", nil, []byte("$0\r\n\r\n")},
+		{"string", "hello", []byte("$5\r\nhello\r\n")},
+		{"bytes", []byte("hello"), []byte("$5\r\nhello\r\n")},
+		{"int", -1337, []byte("$5\r\n-1337\r\n")},
+		{"int8", int8(-8), []byte("$2\r\n-8\r\n")},
+		{"int64", int64(math.MinInt64), []byte("$20\r\n-9223372036854775808\r\n")},
+		{"uint16", uint16(16), []byte("$2\r\n16\r\n")},
+		{"uint64", uint64(math.MaxUint64), []byte("$20\r\n18446744073709551615\r\n")},
+		{"float32", float32(1.5), []byte("$3\r\n1.5\r\n")},
+		{"float64", 0.25, []byte("$4\r\n0.25\r\n")},
+		{"true", true, []byte("$1\r\n1\r\n")},
+		{"false", false, []byte("$1\r\n0\r\n")},
+		{"duration", 1500 * time.Millisecond, []byte("$10\r\n1500000000\r\n")},
+		{
+			name: "time",
+			v:    time.Date(2022, 9, 1, 12, 30, 0, 500, time.UTC),
+			want: []byte("$28\r\n2022-09-01T12:30:00.0000005Z\r\n"),
+		},
+		{"binary marshaler", testArgBinaryMarshaler{}, []byte("$4\r\n\x00\x01\r\n\r\n")},
+		{"text marshaler", testArgTextMarshaler{}, []byte("$4\r\ntext\r\n")},
+		{"stringer", testArgStringer{}, []byte("$8\r\nstringer\r\n")},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			testWriter(t, "WriteArg", tc.want, func(w *Writer) error {
+				return w.WriteArg(tc.v)
+			})
+		})
+	}
+}
+
+func TestWriter_WriteArg_errors(t *testing.T) {
+	errMarshal := errors.New("marshal error")
+
+	tt := []struct {
+		name    string
+		v       interface{}
+		wantErr error
+	}{
+		{"text marshaler", testArgTextMarshaler{errMarshal}, errMarshal},
+		{"unsupported type", struct{}{}, &UnsupportedTypeError{reflect.TypeOf(struct{}{})}},
+		{"pointer", new(int), &UnsupportedTypeError{reflect.TypeOf(new(int))}},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			w := NewWriter(&buf)
+
+			err := w.WriteArg(tc.v)
+			if !reflect.DeepEqual(err, tc.wantErr) {
+				t.Fatalf("WriteArg(): got error = %v, want error = %v", err, tc.wantErr)
+			}
+
+			if err := w.Flush(); err != nil {
+				t.Fatalf("Flush(): unexpected error: %v", err)
+			}
+			if buf.Len() != 0 {
+				t.Errorf("WriteArg() wrote %q, want nothing", buf.Bytes())
+			}
+		})
+	}
+}
+
+func TestWriter_WriteCommand(t *testing.T) {
+	tt := []struct {
+		name string
+		cmd  string
+		args []interface{}
+		want []byte
+	}{
+		{"no args", "PING", nil, []byte("*1\r\n$4\r\nPING\r\n")},
+		{
+			name: "zadd",
+			cmd:  "ZADD",
+			args: []interface{}{"key", 1.5, []byte("member")},
+			want: []byte("*4\r\n$4\r\nZADD\r\n$3\r\nkey\r\n$3\r\n1.5\r\n$6\r\nmember\r\n"),
+		},
+		{
+			name: "set",
+			cmd:  "SET",
+			args: []interface{}{"k", "v", "PX", 1500},
+			want: []byte("*5\r\n$3\r\nSET\r\n$1\r\nk\r\n$1\r\nv\r\n$2\r\nPX\r\n$4\r\n1500\r\n"),
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			testWriter(t, "WriteCommand", tc.want, func(w *Writer) error {
+				return w.WriteCommand(tc.cmd, tc.args...)
+			})
+		})
+	}
+}
+
+func TestWriter_WriteCommand_error(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+
+	err := w.WriteCommand("SET", "key", struct{}{})
+	want := &UnsupportedTypeError{reflect.TypeOf(struct{}{})}
+	if !reflect.DeepEqual(err, want) {
+		t.Fatalf("WriteCommand(): got error = %v, want error = %v", err, want)
+	}
+}
+
+func TestWriter_WriteCommandArgs(t *testing.T) {
+	tt := []struct {
+		name string
+		args [][]byte
+		want []byte
+	}{
+		{"empty", nil, []byte("*0\r\n")},
+		{"ping", [][]byte{[]byte("PING")}, []byte("*1\r\n$4\r\nPING\r\n")},
+		{
+			name: "set",
+			args: [][]byte{[]byte("SET"), []byte("key"), []byte("")},
+			want: []byte("*3\r\n$3\r\nSET\r\n$3\r\nkey\r\n$0\r\n\r\n"),
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			testWriter(t, "WriteCommandArgs", tc.want, func(w *Writer) error {
+				return w.WriteCommandArgs(tc.args)
+			})
+		})
+	}
+}
+
 func TestWriter_WriteNull(t *testing.T) {
 	want := []byte("$-1\r\n")
 	testWriter(t, "WriteNull", want, func(w *Writer) error {
@@ -280,6 +650,15 @@ func TestWriter_WriteNull(t *testing.T) {
 	})
 }
 
+func TestWriter_WriteNull_resp3(t *testing.T) {
+	want := []byte("_\r\n_\r\n")
+	testWriter(t, "WriteNull", want, func(w *Writer) error {
+		w.SetProtocol(RESP3)
+		_ = w.WriteNull()
+		return w.WriteArray(-1)
+	})
+}
+
 var testArrays = []struct {
 	name string
 	n    int
@@ -312,6 +691,197 @@ func TestWriter_WriteArray(t *testing.T) {
 	}
 }
 
+func TestWriter_WriteBool(t *testing.T) {
+	testWriter(t, "WriteBool", []byte("#t\r\n#f\r\n"), func(w *Writer) error {
+		w.SetProtocol(RESP3)
+		_ = w.WriteBool(true)
+		return w.WriteBool(false)
+	})
+
+	testWriter(t, "WriteBool", []byte(":1\r\n:0\r\n"), func(w *Writer) error {
+		_ = w.WriteBool(true)
+		return w.WriteBool(false)
+	})
+}
+
+var testDoubles = []struct {
+	name      string
+	f         float64
+	want      []byte
+	wantRESP2 []byte
+}{
+	{
+		name:      "zero",
+		f:         0,
+		want:      []byte(",0\r\n"),
+		wantRESP2: []byte("$1\r\n0\r\n"),
+	},
+	{
+		name:      "fraction",
+		f:         -3.14,
+		want:      []byte(",-3.14\r\n"),
+		wantRESP2: []byte("$5\r\n-3.14\r\n"),
+	},
+	{
+		name:      "exponent",
+		f:         1e300,
+		want:      []byte(",1e+300\r\n"),
+		wantRESP2: []byte("$6\r\n1e+300\r\n"),
+	},
+	{
+		name:      "inf",
+		f:         math.Inf(1),
+		want:      []byte(",inf\r\n"),
+		wantRESP2: []byte("$3\r\ninf\r\n"),
+	},
+	{
+		name:      "-inf",
+		f:         math.Inf(-1),
+		want:      []byte(",-inf\r\n"),
+		wantRESP2: []byte("$4\r\n-inf\r\n"),
+	},
+	{
+		name:      "nan",
+		f:         math.NaN(),
+		want:      []byte(",nan\r\n"),
+		wantRESP2: []byte("$3\r\nnan\r\n"),
+	},
+}
+
+func TestWriter_WriteDouble(t *testing.T) {
+	for _, tc := range testDoubles {
+		t.Run(tc.name, func(t *testing.T) {
+			testWriter(t, "WriteDouble", tc.want, func(w *Writer) error {
+				w.SetProtocol(RESP3)
+				return w.WriteDouble(tc.f)
+			})
+			testWriter(t, "WriteDouble", tc.wantRESP2, func(w *Writer) error {
+				return w.WriteDouble(tc.f)
+			})
+		})
+	}
+}
+
+func TestWriter_WriteBigNumber(t *testing.T) {
+	n, _ := new(big.Int).SetString("-3492890328409238509324850943850943825024385", 10)
+	want := []byte("(-3492890328409238509324850943850943825024385\r\n")
+	testWriter(t, "WriteBigNumber", want, func(w *Writer) error {
+		w.SetProtocol(RESP3)
+		return w.WriteBigNumber(n)
+	})
+
+	wantRESP2 := []byte("$44\r\n-3492890328409238509324850943850943825024385\r\n")
+	testWriter(t, "WriteBigNumber", wantRESP2, func(w *Writer) error {
+		return w.WriteBigNumber(n)
+	})
+}
+
+var testBulkErrors = []struct {
+	name      string
+	e         *Error
+	want      []byte
+	wantRESP2 []byte
+}{
+	{
+		name:      "error",
+		e:         &Error{Kind: "SYNTAX", Msg: "invalid syntax"},
+		want:      []byte("!21\r\nSYNTAX invalid syntax\r\n"),
+		wantRESP2: []byte("-SYNTAX invalid syntax\r\n"),
+	},
+	{
+		name:      "default kind",
+		e:         &Error{Msg: "multi\r\nline"},
+		want:      []byte("!15\r\nERR multi\r\nline\r\n"),
+		wantRESP2: []byte("-ERR multi\\r\\nline\r\n"),
+	},
+	{
+		name:      "without msg",
+		e:         &Error{Kind: "ERR"},
+		want:      []byte("!3\r\nERR\r\n"),
+		wantRESP2: []byte("-ERR\r\n"),
+	},
+}
+
+func TestWriter_WriteBulkError(t *testing.T) {
+	for _, tc := range testBulkErrors {
+		t.Run(tc.name, func(t *testing.T) {
+			testWriter(t, "WriteBulkError", tc.want, func(w *Writer) error {
+				w.SetProtocol(RESP3)
+				return w.WriteBulkError(tc.e)
+			})
+			testWriter(t, "WriteBulkError", tc.wantRESP2, func(w *Writer) error {
+				return w.WriteBulkError(tc.e)
+			})
+		})
+	}
+}
+
+func TestWriter_WriteVerbatimString(t *testing.T) {
+	want := []byte("=15\r\ntxt:Some string\r\n")
+	testWriter(t, "WriteVerbatimString", want, func(w *Writer) error {
+		w.SetProtocol(RESP3)
+		return w.WriteVerbatimString("txt", "Some string")
+	})
+
+	wantRESP2 := []byte("$11\r\nSome string\r\n")
+	testWriter(t, "WriteVerbatimString", wantRESP2, func(w *Writer) error {
+		return w.WriteVerbatimString("txt", "Some string")
+	})
+
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	w.SetProtocol(RESP3)
+	if err := w.WriteVerbatimString("text", "Some string"); err != ErrVerbatimFormat {
+		t.Errorf("WriteVerbatimString() with a bad format: got error %v, want %v", err, ErrVerbatimFormat)
+	}
+	_ = w.Flush()
+	if buf.Len() != 0 {
+		t.Errorf("WriteVerbatimString() with a bad format = %q, want nothing", buf.Bytes())
+	}
+}
+
+func TestWriter_aggregates(t *testing.T) {
+	tt := []struct {
+		name      string
+		write     func(w *Writer, n int) error
+		want      []byte
+		wantRESP2 []byte
+	}{
+		{"WriteMap", (*Writer).WriteMap, []byte("%2\r\n"), []byte("*4\r\n")},
+		{"WriteSet", (*Writer).WriteSet, []byte("~2\r\n"), []byte("*2\r\n")},
+		{"WritePush", (*Writer).WritePush, []byte(">2\r\n"), []byte("*2\r\n")},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			testWriter(t, tc.name, tc.want, func(w *Writer) error {
+				w.SetProtocol(RESP3)
+				return tc.write(w, 2)
+			})
+			testWriter(t, tc.name, tc.wantRESP2, func(w *Writer) error {
+				return tc.write(w, 2)
+			})
+		})
+	}
+}
+
+func TestWriter_WriteAttribute(t *testing.T) {
+	testWriter(t, "WriteAttribute", []byte("|2\r\n"), func(w *Writer) error {
+		w.SetProtocol(RESP3)
+		return w.WriteAttribute(2)
+	})
+
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	if err := w.WriteAttribute(2); err != ErrAttributeRESP2 {
+		t.Errorf("WriteAttribute() in RESP2: got error %v, want %v", err, ErrAttributeRESP2)
+	}
+	_ = w.Flush()
+	if buf.Len() != 0 {
+		t.Errorf("WriteAttribute() in RESP2 = %q, want nothing", buf.Bytes())
+	}
+}
+
 var writerRes []byte
 
 func BenchmarkWriter(b *testing.B) {